@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/appleboy/github2gitea/pkg/config"
+	gt "github.com/appleboy/github2gitea/pkg/gitea"
+	gh "github.com/appleboy/github2gitea/pkg/github"
+	"github.com/appleboy/github2gitea/pkg/migrate"
+	"github.com/appleboy/github2gitea/pkg/redact"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/state"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// Exit codes of the commands, for scripts retrying a failed phase.
+const (
+	exitSuccess = 0
+	// exitFailure means the command could not run, or some of its results failed.
+	exitFailure = 1
+)
+
+// phases returns the phases of the migration a command runs, all of them when nil.
+func phases(command string) migrate.Phases {
+	switch command {
+	case config.CommandMigrateUsers:
+		return migrate.Phases{migrate.PhaseUsers}
+	case config.CommandMigrateOrg:
+		return migrate.Phases{migrate.PhaseOrg}
+	case config.CommandMigrateRepo:
+		return migrate.Phases{migrate.PhaseRepos}
+	default:
+		return nil
+	}
+}
+
+// removeOrg deletes all repositories of a Gitea organization, then the organization.
+// The deleted repositories are dropped from the state store, so a later sync migrates
+// them again.
+func removeOrg(gtClient *gt.Client, org string, store *state.Store, logger *slog.Logger) error {
+	var repos []*gsdk.Repository
+	for page := 1; ; page++ {
+		list, _, err := gtClient.ListOrgRepos(org, gsdk.ListOrgReposOptions{
+			ListOptions: gsdk.ListOptions{
+				Page:     page,
+				PageSize: 100,
+			},
+		})
+		if err != nil {
+			logger.Error("failed to list org repos", "org", org, "error", err)
+			return err
+		}
+		repos = append(repos, list...)
+		if len(list) < 100 {
+			break
+		}
+	}
+	for _, repo := range repos {
+		logger.Info("removing repo", "repo", repo.Name)
+		if err := gtClient.DeleteRepository(gt.DeleteRepoOption{
+			Owner: org,
+			Repo:  repo.Name,
+		}); err != nil {
+			logger.Error("failed to delete repo", "repo", repo.Name, "error", err)
+			continue
+		}
+		logger.Info("repo deleted", "repo", repo.Name)
+		if err := store.DropRepo(org, repo.Name); err != nil {
+			logger.Warn("failed to drop repo from state file", "repo", repo.Name, "error", err)
+		}
+	}
+	// Remove the org itself
+	logger.Info("removing org", "org", org)
+	if err := gtClient.DeleteOrg(gt.DeleteOrgOption{
+		OrgName: org,
+	}); err != nil {
+		logger.Error("failed to delete org", "org", org, "error", err)
+		return err
+	}
+	logger.Info("org deleted", "org", org)
+	return nil
+}
+
+// verifyRepos compares the repositories given as arguments, all the repositories of
+// the source when none, with the ones of the same name in the target owner, and
+// records the results in the report. Repository overrides are not applied.
+func verifyRepos(ctx context.Context, cfg *config.Config, m *migrate.Migrator, ghClient *gh.Client, rpt *report.Report) error {
+	target := cfg.TargetOwner()
+	if cfg.SourceOrg != "" && len(cfg.Args) == 0 {
+		result, err := m.VerifyOrg(ctx, cfg.SourceOrg, target)
+		if err != nil {
+			return err
+		}
+		for _, repo := range result.Repos {
+			recordVerification(rpt, repo)
+		}
+		for _, team := range result.MissingTeams {
+			rpt.Add(report.Item{Kind: report.KindVerify, Name: "team " + target + "/" + team, Status: report.StatusFailed, Error: "missing in gitea"})
+		}
+		return nil
+	}
+
+	// GitHub full names
+	var sources []string
+	for _, name := range cfg.Args {
+		sources = append(sources, cfg.SourceOrg+cfg.SourceUser+"/"+name)
+	}
+	if len(sources) == 0 {
+		ghRepos, err := ghClient.ListAccessibleUserRepos(ctx, cfg.SourceUser)
+		if err != nil {
+			return err
+		}
+		for _, repo := range ghRepos {
+			sources = append(sources, repo.GetFullName())
+		}
+	}
+	for _, source := range sources {
+		owner, name, _ := strings.Cut(source, "/")
+		result, err := m.VerifyRepo(ctx, owner, name, target, name)
+		if err != nil {
+			rpt.Add(report.Item{Kind: report.KindVerify, Name: source, Status: report.StatusFailed, Error: err.Error()})
+			continue
+		}
+		recordVerification(rpt, result)
+	}
+	return nil
+}
+
+// recordVerification adds the result of a repository verification to the report.
+func recordVerification(rpt *report.Report, result migrate.RepoVerification) {
+	item := report.Item{Kind: report.KindVerify, Name: result.Target, Status: report.StatusSuccess}
+	if !result.OK {
+		item.Status = report.StatusFailed
+		item.Error = result.Failure()
+	}
+	rpt.Add(item)
+}
+
+// preflight runs the checks of the check command, prints them with the hints to fix
+// them, writes the report file when given and reports whether none failed.
+func preflight(ctx context.Context, cfg *config.Config, m *migrate.Migrator, gtClient *gt.Client, rpt *report.Report, logger *slog.Logger) bool {
+	csvColumns, err := parseCSVColumns(cfg.CSVColumns)
+	if err != nil {
+		logger.Error("invalid csv columns", "error", err)
+		return false
+	}
+	users, err := readUserList(cfg.UserListFile, csvColumns)
+	if err != nil {
+		logger.Error("failed to read user list", "error", err)
+		return false
+	}
+	for _, u := range users {
+		if u.Username != "" {
+			gtClient.MapUsername(u.Login, u.Username)
+		}
+	}
+
+	checks := m.Preflight(ctx, migrate.PreflightOption{
+		SourceOrg:  cfg.SourceOrg,
+		SourceUser: cfg.SourceUser,
+		TargetOrg:  cfg.TargetOrg,
+		TargetUser: cfg.TargetUser,
+		Users:      users,
+		Report:     rpt,
+	})
+	ok := true
+	for _, check := range checks {
+		fmt.Printf("%-9s %s: %s\n", "["+check.Status+"]", check.Name, redact.String(check.Detail))
+		if check.Hint != "" {
+			fmt.Printf("%-9s %s\n", "", check.Hint)
+		}
+		if check.Status == migrate.PreflightFailed {
+			ok = false
+		}
+	}
+	if cfg.ReportFile != "" {
+		rpt.Stable = cfg.ReportStable
+		if err := rpt.WriteFile(cfg.ReportFile, cfg.ReportFormat); err != nil {
+			logger.Error("failed to write report", "file", cfg.ReportFile, "error", err)
+			return false
+		}
+		logger.Info("check report written", "file", cfg.ReportFile)
+		signFile(cfg, cfg.ReportFile, logger)
+	}
+	return ok
+}
+
+// stateReport rebuilds the report of the last run from the state file: the results
+// it recorded, and the repositories migrated by any run.
+func stateReport(store *state.Store) (*report.Report, error) {
+	results, at := store.LastResults()
+	repos := store.AllRepos()
+	if len(results) == 0 && len(repos) == 0 {
+		return nil, errors.New("no migration recorded in the state file")
+	}
+	rpt := report.New()
+	rpt.StartedAt, rpt.FinishedAt = at, at
+	for key, status := range results {
+		kind, name, _ := strings.Cut(key, ":")
+		if kind != report.KindRepo {
+			rpt.Add(report.Item{Kind: kind, Name: name, Status: status})
+			continue
+		}
+		owner, repoName, _ := strings.Cut(name, "/")
+		recorded := repos[strings.ToLower(name)]
+		delete(repos, strings.ToLower(name))
+		rpt.AddRepo(report.Repo{Owner: owner, Name: repoName, Source: recorded.Source, Status: status})
+	}
+	// migrated by earlier runs
+	for name, recorded := range repos {
+		owner, repoName, _ := strings.Cut(name, "/")
+		rpt.AddRepo(report.Repo{Owner: owner, Name: repoName, Source: recorded.Source, Status: report.StatusSuccess})
+	}
+	return rpt, nil
+}
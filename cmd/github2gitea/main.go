@@ -1,24 +1,31 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
-	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/appleboy/github2gitea/pkg/config"
 	gt "github.com/appleboy/github2gitea/pkg/gitea"
 	gh "github.com/appleboy/github2gitea/pkg/github"
+	"github.com/appleboy/github2gitea/pkg/i18n"
 	"github.com/appleboy/github2gitea/pkg/migrate"
+	"github.com/appleboy/github2gitea/pkg/progress"
+	"github.com/appleboy/github2gitea/pkg/redact"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/state"
+	"github.com/appleboy/github2gitea/pkg/trace"
 	"github.com/appleboy/github2gitea/pkg/version"
-
-	gsdk "code.gitea.io/sdk/gitea"
-	"github.com/appleboy/com/convert"
-	"github.com/google/go-github/v71/github"
 )
 
 func setupLogger(debug bool) *slog.Logger {
@@ -26,369 +33,888 @@ func setupLogger(debug bool) *slog.Logger {
 	if debug {
 		logLevel = slog.LevelDebug
 	}
-	return slog.New(slog.NewTextHandler(log.Writer(), &slog.HandlerOptions{
+	// the secrets are masked in every log line, and in the dashboard keeping them
+	return slog.New(slog.NewTextHandler(redact.NewWriter(log.Writer()), &slog.HandlerOptions{
 		Level: logLevel,
 	}))
 }
 
-func createClients(ctx context.Context, cfg *config.Config, logger *slog.Logger) (ghClient *gh.Client, gtClient *gt.Client, err error) {
-	ghClient, err = gh.NewClient(&gh.Config{
-		Token:      cfg.GHToken,
-		Server:     cfg.GHServer,
-		SkipVerify: cfg.GHSkipVerify,
-		Logger:     logger,
-	})
+func createClients(ctx context.Context, cfg *config.Config, logger *slog.Logger, fake *gt.Fake) (ghClient *gh.Client, gtClient *gt.Client, err error) {
+	usernames, err := migrate.LoadUsernames(cfg.UsernamesFile)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	gtClient, err = gt.New(ctx, &gt.Config{
-		Server:     cfg.GTServer,
-		Token:      cfg.GTToken,
-		SkipVerify: cfg.GTSkipVerify,
-		Logger:     logger,
-		SourceID:   cfg.GTSourceID,
+	ghClient, err = gh.NewClient(&gh.Config{
+		Token:              cfg.GHToken,
+		Server:             cfg.GHServer,
+		SkipVerify:         cfg.GHSkipVerify,
+		Logger:             logger,
+		RateLimitThreshold: cfg.GHRateLimitThreshold,
+		MaxRetries:         cfg.MaxRetries,
+		RetryBackoff:       cfg.RetryBackoff,
+		GraphQL:            cfg.GHGraphQL,
+		PageSize:           cfg.GHPageSize,
+		RecordDir:          cfg.GHRecordDir,
+		ReplayDir:          cfg.GHReplayDir,
+		SlowThreshold:      cfg.SlowAPIThreshold,
 	})
 	if err != nil {
 		return nil, nil, err
 	}
-	return ghClient, gtClient, nil
-}
 
-func printUserInfo(logger *slog.Logger, ghUser *github.User, gtUser *gsdk.User) {
-	logger.Info("github user",
-		"login", convert.FromPtr(ghUser.Login),
-		"name", convert.FromPtr(ghUser.Name),
-		"email", convert.FromPtr(ghUser.Email),
-	)
-	logger.Info("gitea user",
-		"login", gtUser.UserName,
-		"name", gtUser.FullName,
-		"email", gtUser.Email,
-	)
-}
-
-func migrateOrgAndRepos(ctx context.Context, cfg *config.Config, logger *slog.Logger, ghClient *gh.Client, gtClient *gt.Client) error {
-	// get github current user
-	ghUser, err := ghClient.GetCurrentUser(ctx)
-	if err != nil {
-		logger.Error("failed to get current github user", "error", err)
-		return err
+	gtCfg := &gt.Config{
+		Server:         cfg.GTServer,
+		Token:          cfg.GTToken,
+		SkipVerify:     cfg.GTSkipVerify,
+		Logger:         logger,
+		SourceID:       cfg.GTSourceID,
+		MaxRetries:     cfg.MaxRetries,
+		RetryBackoff:   cfg.RetryBackoff,
+		ReconcileEmail: cfg.GTReconcileEmail,
+		SlowThreshold:  cfg.SlowAPIThreshold,
+		Usernames:      usernames,
+		GitCredentials: cfg.GitCredentials,
 	}
-
-	// get gitea current user
-	gtUser, err := gtClient.GetCurrentUser()
-	if err != nil {
-		logger.Error("failed to get current gitea user", "error", err)
-		return err
+	if prog := progress.FromContext(ctx); prog != nil {
+		gtCfg.OnMigrationState = func(owner, name string, state gt.MigrationState, message string) {
+			prog.MigrationState(owner, name, string(state), message)
+		}
 	}
-
-	printUserInfo(logger, ghUser, gtUser)
-
-	// get github organization
-	ghOrg, err := ghClient.GetOrg(ctx, cfg.SourceOrg)
-	if err != nil {
-		logger.Error("failed to get github org", "error", err)
-		return err
+	if fake != nil {
+		// rehearsal: every Gitea call is served from memory
+		gtCfg.Server = gt.FakeServer
+		gtCfg.Token = "fake"
+		gtCfg.Transport = fake
 	}
-
-	m := migrate.New(
-		ghClient,
-		gtClient,
-		logger,
-	)
-
-	// create new gitea organization
-	org, err := m.CreateNewOrg(ctx, migrate.CreateNewOrgOption{
-		OldName:     cfg.SourceOrg,
-		NewName:     cfg.TargetOrg,
-		Description: convert.FromPtr(ghOrg.Description),
-		Public:      false,
-		SourceID:    cfg.GTSourceID,
-	})
+	gtClient, err = gt.New(ctx, gtCfg)
 	if err != nil {
-		logger.Error("failed to create gitea org", "error", err)
-		return err
+		return nil, nil, err
 	}
+	return ghClient, gtClient, nil
+}
 
-	// get github repo list from organization
-	ghRepos, err := ghClient.ListOrgRepos(ctx, *ghOrg.Login)
-	if err != nil {
-		logger.Error("failed to get github org repos", "error", err)
-		return err
-	}
-
-	for _, repo := range ghRepos {
-		// create new gitea repository
-		err = m.MigrateNewRepo(ctx, migrate.MigrateNewRepoOption{
-			Owner:        cfg.TargetOrg,
-			Name:         convert.FromPtr(repo.Name),
-			CloneAddr:    convert.FromPtr(repo.CloneURL),
-			Description:  convert.FromPtr(repo.Description),
-			Private:      convert.FromPtr(repo.Private),
-			AuthUsername: convert.FromPtr(ghUser.Login),
-			AuthToken:    cfg.GHToken,
-		})
-		if err != nil {
-			logger.Error("migration repository error", "error", err)
-		}
-
-		if teams, ok := org.RepoTeams[convert.FromPtr(repo.Name)]; ok {
-			for _, team := range teams {
-				// Add the team to the repository
-				err = gtClient.AddTeamRepository(
-					team.ID,
-					cfg.TargetOrg,
-					convert.FromPtr(repo.Name),
-				)
-				if err != nil {
-					logger.Error("failed to add team to repo", "error", err)
-					continue
-				}
-				logger.Info("added team to repo",
-					"org", cfg.TargetOrg,
-					"repo", convert.FromPtr(repo.Name),
-					"team", team.Name,
-				)
-			}
-		}
-	}
+// userListColumns are the default 0-based columns of the user list fields.
+var userListColumns = map[string]int{"login": 2, "email": 3, "role": 4}
 
-	return nil
+// userListHeaders are the header names detecting the user list fields, e.g. in
+// CSV files exported from other systems.
+var userListHeaders = map[string][]string{
+	"login": {"login", "github_login", "username", "user"},
+	"email": {"email", "mail", "email_address"},
+	"role":  {"role"},
 }
 
-type UserCSV struct {
-	Login string
-	Email string
-	Role  string
+// parseCSVColumns parses a comma separated list of field=column user list mappings,
+// with 1-based columns, e.g. login=1,email=2,role=3.
+func parseCSVColumns(s string) (map[string]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	columns := make(map[string]int)
+	for pair := range strings.SplitSeq(s, ",") {
+		field, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		column, err := strconv.Atoi(strings.TrimSpace(value))
+		if _, known := userListColumns[field]; !ok || !known || err != nil || column < 1 {
+			return nil, fmt.Errorf("invalid csv column %q, expected login, email or role=column", pair)
+		}
+		columns[field] = column - 1
+	}
+	if _, ok := columns["login"]; !ok {
+		return nil, errors.New("csv columns require the login column")
+	}
+	return columns, nil
 }
 
-func readUserList(path string) ([]UserCSV, error) {
+// readUserList reads the users of the file given with --user-list, as JSON or YAML by
+// its extension, else as CSV. The CSV fields are found in the given columns, else by
+// their header name, else in the default columns. The first row is a header unless it
+// holds an email.
+func readUserList(path string, columns map[string]int) ([]migrate.User, error) {
 	if path == "" {
 		return nil, nil
 	}
+	if migrate.IsStructuredUserList(path) {
+		return migrate.LoadUserList(path)
+	}
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 	r := csv.NewReader(f)
+	// files exported from other systems do not always have the same number of fields per row
+	r.FieldsPerRecord = -1
 	records, err := r.ReadAll()
 	if err != nil {
 		return nil, err
 	}
-	var users []UserCSV
-	for index, rec := range records {
-		// Skip the header row and invalid lines
-		if index == 0 || len(rec) < 5 {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	// optional opt-in and password columns, found by their header name
+	headers := make(map[string]int)
+	for column, name := range records[0] {
+		headers[strings.ToLower(strings.TrimSpace(name))] = column
+	}
+	if columns == nil {
+		columns = detectUserListColumns(headers)
+	}
+	if strings.Contains(field(records[0], columns, "email"), "@") {
+		// no header row
+		headers = nil
+	} else {
+		records = records[1:]
+	}
+
+	var users []migrate.User
+	for _, rec := range records {
+		login := field(rec, columns, "login")
+		// Skip invalid lines
+		if login == "" {
 			continue
 		}
-		users = append(users, UserCSV{
-			Login: rec[2],
-			Email: rec[3],
-			Role:  rec[4],
+		users = append(users, migrate.User{
+			Login:          login,
+			Email:          field(rec, columns, "email"),
+			Role:           field(rec, columns, "role"),
+			Password:       field(rec, headers, "password"),
+			MigrateGists:   optedIn(rec, headers, "migrate_gists"),
+			MigrateStars:   optedIn(rec, headers, "migrate_stars"),
+			MigrateWatches: optedIn(rec, headers, "migrate_watches"),
+			MigrateFollows: optedIn(rec, headers, "migrate_follows"),
 		})
 	}
 	return users, nil
 }
 
-// createUsersFromCSV creates users in Gitea from a list of GitHub users in CSV,
-// migrates their SSH keys, and logs the migration summary.
-func createUsersFromCSV(ctx context.Context, ghClient *gh.Client, gtClient *gt.Client, users []UserCSV, sourceID int64, logger *slog.Logger) {
-	for _, u := range users {
-		// Get user information from GitHub
-		ghUser, err := ghClient.GetUser(ctx, u.Login)
-		if err != nil {
-			logger.Error("failed to get github user", "login", u.Login, "error", err)
-			continue
-		}
-
-		// Create or get the user in Gitea
-		opt := gt.CreateUserOption{
-			SourceID:  sourceID,
-			LoginName: u.Login,
-			Username:  u.Login,
-			FullName:  convert.FromPtr(ghUser.Name),
-			Email:     u.Email,
-		}
-		_, err = gtClient.CreateOrGetUser(opt)
-		if err != nil {
-			logger.Error("failed to create user", "login", u.Login, "email", u.Email, "err", err)
-			continue
-		}
-		logger.Info("user created or exists",
-			"login", u.Login,
-			"role", u.Role,
-			"fullName", opt.FullName,
-		)
-
-		// Retrieve the user's SSH keys from GitHub
-		sshKeys, err := ghClient.ListUserKeys(ctx, u.Login)
-		if err != nil {
-			logger.Error("failed to get user ssh keys", "login", u.Login, "error", err)
-			continue
+// detectUserListColumns finds the user list fields by their header names, and falls
+// back to the default columns when the login or email header is missing.
+func detectUserListColumns(headers map[string]int) map[string]int {
+	columns := make(map[string]int)
+	for name, aliases := range userListHeaders {
+		for _, alias := range aliases {
+			if column, ok := headers[alias]; ok {
+				columns[name] = column
+				break
+			}
 		}
+	}
+	_, login := columns["login"]
+	_, email := columns["email"]
+	if !login || !email {
+		return userListColumns
+	}
+	return columns
+}
 
-		var (
-			successCount  int            // Number of successfully migrated keys
-			existCount    int            // Number of keys that already exist in Gitea
-			failedCount   int            // Number of failed key migrations
-			totalKeyCount = len(sshKeys) // Total number of keys to migrate
-		)
+// field returns the trimmed value of a user list field, empty when the record has no such column.
+func field(rec []string, columns map[string]int, name string) string {
+	column, ok := columns[name]
+	if !ok || column >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[column])
+}
 
-		for index, key := range sshKeys {
-			keyTitle := key.GetTitle()
-			if keyTitle == "" {
-				keyTitle = fmt.Sprintf("Migrate key-%d from %s", index, u.Login)
-			}
-			// Attempt to create the SSH key in Gitea
-			_, err := gtClient.CreateUserPublicKey(
-				u.Login,
-				gt.CreatePublicKeyOption{
-					Title: keyTitle,
-					Key:   key.GetKey(),
-				})
-			if err != nil {
-				// Check if the key already exists in Gitea
-				if giteaErr, ok := err.(*gt.GiteaError); ok && giteaErr.Code == http.StatusUnprocessableEntity && giteaErr.Message != "" && (containsKeyUsedMsg(giteaErr.Message)) {
-					existCount++
-					logger.Info("ssh key already exists in gitea",
-						"login", u.Login,
-						"title", keyTitle,
-					)
-					continue
-				}
-				failedCount++
-				logger.Warn("failed to migrate ssh key",
-					"login", u.Login,
-					"title", keyTitle,
-					"error", err,
-				)
-				continue
-			}
-			successCount++
-			logger.Info("successfully migrated ssh key",
-				"login", u.Login,
-				"title", keyTitle,
-			)
-		}
-
-		// Log the migration summary for this user
-		logger.Info("ssh key migration summary",
-			"login", u.Login,
-			"total", totalKeyCount,
-			"success", successCount,
-			"exists", existCount,
-			"failed", failedCount,
-		)
+// optedIn reports whether the opt-in column of a user list record is set to true, yes or 1.
+func optedIn(rec []string, columns map[string]int, name string) bool {
+	column, ok := columns[name]
+	if !ok || column >= len(rec) {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(rec[column])) {
+	case "true", "yes", "y", "1":
+		return true
 	}
+	return false
 }
 
-/*
-containsKeyUsedMsg checks if the Gitea error message indicates that the SSH key already exists.
-*/
-func containsKeyUsedMsg(msg string) bool {
-	return (msg != "" && (contains(msg, "key content has been used") || contains(msg, "Key content has been used")))
+// stdin is shared by the questions, so buffered answers are not lost.
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes/no question on the terminal, answering no when stdin is not a terminal.
+func confirm(question string) bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
 
-/*
-contains checks if substr is present in s.
-This is a simple implementation to avoid importing the strings package.
-*/
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || (len(s) > len(substr) && (indexOf(s, substr) >= 0)))
+func main() {
+	os.Exit(execute(os.Args[1:]))
 }
 
-/*
-indexOf returns the index of substr in s, or -1 if not found.
-*/
-func indexOf(s, substr string) int {
-	for i := 0; i+len(substr) <= len(s); i++ {
-		if s[i:i+len(substr)] == substr {
-			return i
-		}
+// execute parses the command line and runs its command, returning the exit code.
+func execute(args []string) int {
+	code := exitSuccess
+	root := config.NewCommand(func(cfg *config.Config) {
+		code = run(cfg)
+	})
+	root.SetArgs(config.LongFlags(args))
+	if err := root.Execute(); err != nil {
+		return exitFailure
 	}
-	return -1
+	return code
 }
 
-func main() {
-	cfg := config.LoadConfig()
+// run runs the command of the configuration, the migration when none, and returns
+// the exit code.
+func run(cfg *config.Config) int {
+	redact.Add(cfg.Secrets()...)
+	// q and Ctrl-C in the dashboard cancel the run
+	base, interrupt := context.WithCancel(context.Background())
+	defer interrupt()
+	// paused and resumed from the dashboard or with signals
+	controls := migrate.NewControls()
+	var prog *progress.Display
+	switch {
+	case cfg.UI:
+		// log lines are kept by the dashboard and printed when it stops
+		prog = progress.NewDashboard(os.Stdin, os.Stderr, controls, interrupt)
+		log.SetOutput(prog)
+	case cfg.Progress:
+		// log lines are printed above the progress display
+		prog = progress.New(os.Stderr)
+		log.SetOutput(prog)
+	}
 	logger := setupLogger(cfg.Debug)
+	p := i18n.New(i18n.Detect(cfg.Lang))
 
 	if cfg.Version {
 		fmt.Printf("%s version %s: %s (%.7s %s)", version.App, version.Version, version.Description, version.GitCommit, version.BuildTime)
-		return
+		return exitSuccess
 	}
 
 	if err := cfg.IsVaild(); err != nil {
 		logger.Error("invalid config", "error", err)
-		return
+		fmt.Fprintln(os.Stderr, p.Sprintf(i18n.HintInvalidConfig, err))
+		return exitFailure
+	}
+	// every command uses the Gitea name of the target organization, the given name
+	// only becomes the full name of a new one
+	targetOrgFullName := cfg.TargetOrg
+	if name := migrate.SanitizeOrgName(cfg.TargetOrg); name != cfg.TargetOrg {
+		if name == "" {
+			logger.Error("invalid target organization name", "org", cfg.TargetOrg)
+			return exitFailure
+		}
+		logger.Warn("organization name sanitized", "name", cfg.TargetOrg, "sanitized", name)
+		cfg.TargetOrg = name
+	}
+
+	// the report of the last run needs neither GitHub nor Gitea
+	if cfg.Command == config.CommandReport {
+		store, err := state.Open(cfg.StateFile)
+		if err != nil {
+			logger.Error("failed to read state file", "file", cfg.StateFile, "error", err)
+			return exitFailure
+		}
+		rpt, err := stateReport(store)
+		if err != nil {
+			logger.Error("failed to rebuild report", "file", cfg.StateFile, "error", err)
+			return exitFailure
+		}
+		writeReport(cfg, rpt, logger, p)
+		return exitSuccess
 	}
 
 	// check timeout format
 	timeout, err := time.ParseDuration(cfg.APITimeout)
 	if err != nil {
 		logger.Error("failed to parse timeout", "error", err)
-		return
+		fmt.Fprintln(os.Stderr, p.Sprintf(i18n.HintTimeout, cfg.APITimeout))
+		return exitFailure
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if cfg.Command == config.CommandServe {
+		// the server runs until it is stopped
+		ctx, cancel = signal.NotifyContext(base, os.Interrupt, syscall.SIGTERM)
+	} else {
+		// command timeout
+		ctx, cancel = context.WithTimeout(base, timeout)
 	}
-	// command timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	ghClient, gtClient, err := createClients(ctx, cfg, logger)
+	if cfg.OTelEndpoint != "" {
+		// validated with the configuration
+		headers, _ := trace.ParseHeaders(cfg.OTelHeaders)
+		provider := trace.NewProvider(trace.Config{
+			Endpoint:    cfg.OTelEndpoint,
+			ServiceName: cfg.OTelServiceName,
+			Version:     version.Version,
+			Headers:     headers,
+			Logger:      logger,
+		})
+		trace.SetProvider(provider)
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			_ = provider.Shutdown(shutdownCtx)
+		}()
+	}
+	ctx = progress.NewContext(ctx, prog)
+	ctx, span := trace.Start(ctx, "github2gitea",
+		trace.String("github.source", cfg.SourceOrg+cfg.SourceUser),
+		trace.String("gitea.target", cfg.TargetOwner()),
+	)
+	defer span.End()
+
+	var fake *gt.Fake
+	if cfg.Command == config.CommandExportInventory || cfg.Command == config.CommandExportUsers {
+		// the exports never touch Gitea
+		fake = gt.NewFake()
+	} else if cfg.Target == config.TargetFake {
+		fake = gt.NewFake()
+		logger.Info("rehearsal mode: migrating into an in-memory gitea, no changes are made")
+	}
+
+	ghClient, gtClient, err := createClients(ctx, cfg, logger, fake)
 	if err != nil {
 		logger.Error("failed to create clients", "error", err)
-		return
+		fmt.Fprintln(os.Stderr, redact.String(p.Sprintf(i18n.HintClients, err)))
+		return exitFailure
+	}
+
+	if cfg.Command == config.CommandServe {
+		if err := serveVerification(ctx, cfg, migrate.New(ghClient, gtClient, logger), logger); err != nil {
+			logger.Error("verification api failed", "error", err)
+			return exitFailure
+		}
+		return exitSuccess
+	}
+
+	if cfg.Command == config.CommandCheck {
+		rpt := report.New()
+		if !preflight(ctx, cfg, migrate.New(ghClient, gtClient, logger), gtClient, rpt, logger) {
+			return exitFailure
+		}
+		return exitSuccess
+	}
+
+	if cfg.Command == config.CommandVerify {
+		rpt := report.New()
+		if err := verifyRepos(ctx, cfg, migrate.New(ghClient, gtClient, logger), ghClient, rpt); err != nil {
+			logger.Error("verify failed", "error", err)
+			return exitFailure
+		}
+		adviseScopes(ghClient, logger, p)
+		writeReport(cfg, rpt, logger, p)
+		if _, failed := rpt.Counts(); failed > 0 {
+			return exitFailure
+		}
+		return exitSuccess
+	}
+
+	if cfg.Command == config.CommandCleanup {
+		if !cfg.Yes && !confirm("Delete the gitea organization "+cfg.TargetOrg+" and all its repositories?") {
+			logger.Error("cleanup not confirmed, run it on a terminal or with --yes", "org", cfg.TargetOrg)
+			return exitFailure
+		}
+		store, err := state.Open(cfg.StateFile)
+		if err != nil {
+			logger.Error("failed to read state file", "file", cfg.StateFile, "error", err)
+			return exitFailure
+		}
+		logger.Info("removing all repos and the org", "org", cfg.TargetOrg)
+		if err := removeOrg(gtClient, cfg.TargetOrg, store, logger); err != nil {
+			return exitFailure
+		}
+		return exitSuccess
+	}
+
+	if cfg.Command == config.CommandBackfillMetadata {
+		rpt := report.New()
+		if err := migrate.New(ghClient, gtClient, logger).BackfillMetadata(ctx, migrate.BackfillOption{
+			SourceOrg:  cfg.SourceOrg,
+			SourceUser: cfg.SourceUser,
+			Owner:      cfg.TargetOwner(),
+			Report:     rpt,
+		}); err != nil {
+			logger.Error("backfill metadata failed", "error", err)
+			return exitFailure
+		}
+		adviseScopes(ghClient, logger, p)
+		writeReport(cfg, rpt, logger, p)
+		return exitSuccess
+	}
+
+	if cfg.Command == config.CommandExportInventory {
+		rpt := report.New()
+		if err := migrate.New(ghClient, gtClient, logger).ExportInventory(ctx, migrate.InventoryOption{
+			SourceOrg:  cfg.SourceOrg,
+			SourceUser: cfg.SourceUser,
+			Report:     rpt,
+		}); err != nil {
+			logger.Error("export inventory failed", "error", err)
+			return exitFailure
+		}
+		adviseScopes(ghClient, logger, p)
+		writeReport(cfg, rpt, logger, p)
+		return exitSuccess
+	}
+
+	if cfg.Command == config.CommandExportUsers {
+		rpt := report.New()
+		if err := migrate.New(ghClient, gtClient, logger).ExportUsers(ctx, migrate.ExportUsersOption{
+			SourceOrg: cfg.SourceOrg,
+			Path:      cfg.UserListFile,
+			Report:    rpt,
+		}); err != nil {
+			logger.Error("export users failed", "error", err)
+			return exitFailure
+		}
+		adviseScopes(ghClient, logger, p)
+		writeReport(cfg, rpt, logger, p)
+		return exitSuccess
+	}
+
+	store, err := state.Open(cfg.StateFile)
+	if err != nil {
+		logger.Error("failed to read state file", "file", cfg.StateFile, "error", err)
+		return exitFailure
 	}
 
 	// If -rm-org is set, remove all repos under the org, then remove the org itself
-	if cfg.RmOrg {
+	if cfg.RmOrg && cfg.TargetOrg != "" {
 		logger.Info("rm-org flag detected, removing all repos and the org before migration", "org", cfg.TargetOrg)
-		// List all repos under the target org
-		repos, _, err := gtClient.ListOrgRepos(cfg.TargetOrg, gsdk.ListOrgReposOptions{
-			ListOptions: gsdk.ListOptions{
-				Page:     1,
-				PageSize: 100,
-			},
-		})
-		if err != nil {
-			logger.Error("failed to list org repos", "org", cfg.TargetOrg, "error", err)
-			return
+		if err := removeOrg(gtClient, cfg.TargetOrg, store, logger); err != nil {
+			return exitFailure
 		}
-		for _, repo := range repos {
-			logger.Info("removing repo", "repo", repo.Name)
-			delErr := gtClient.DeleteRepository(gt.DeleteRepoOption{
-				Owner: cfg.TargetOrg,
-				Repo:  repo.Name,
-			})
-			if delErr != nil {
-				logger.Error("failed to delete repo", "repo", repo.Name, "error", delErr)
-				continue
+	}
+	// only the dashboard shows a run waiting to be resumed, others such as cron jobs
+	// would wait for a signal nobody sends
+	if since := store.PausedSince(); !since.IsZero() {
+		if cfg.UI {
+			logger.Warn("paused by the last run, resume with p in the dashboard", "since", since)
+		} else {
+			logger.Warn("paused by the last run, resuming", "since", since)
+			if err := store.SetPaused(false); err != nil {
+				logger.Error("failed to record resume in state file", "file", cfg.StateFile, "error", err)
 			}
-			logger.Info("repo deleted", "repo", repo.Name)
 		}
-		// Remove the org itself
-		logger.Info("removing org", "org", cfg.TargetOrg)
-		delOrgErr := gtClient.DeleteOrg(gt.DeleteOrgOption{
-			OrgName: cfg.TargetOrg,
-		})
-		if delOrgErr != nil {
-			logger.Error("failed to delete org", "org", cfg.TargetOrg, "error", delOrgErr)
-			return
+	}
+	controls.Persist(store)
+	handlePauseSignals(ctx, controls, logger)
+
+	csvColumns, err := parseCSVColumns(cfg.CSVColumns)
+	if err != nil {
+		logger.Error("invalid csv columns", "error", err)
+		return exitFailure
+	}
+	users, err := readUserList(cfg.UserListFile, csvColumns)
+	if err != nil {
+		logger.Error("failed to read user list", "error", err)
+		return exitFailure
+	}
+	for _, u := range users {
+		if u.Username != "" {
+			gtClient.MapUsername(u.Login, u.Username)
+		}
+	}
+
+	overrides, err := migrate.LoadPermissionOverrides(cfg.TeamOverridesFile)
+	if err != nil {
+		logger.Error("failed to read team overrides", "file", cfg.TeamOverridesFile, "error", err)
+		return exitFailure
+	}
+
+	teamUnits, err := migrate.LoadTeamUnits(cfg.TeamUnitsFile)
+	if err != nil {
+		logger.Error("failed to read team units", "file", cfg.TeamUnitsFile, "error", err)
+		return exitFailure
+	}
+
+	repoOverrides, err := migrate.LoadRepoOverrides(cfg.RepoOverridesFile)
+	if err != nil {
+		logger.Error("failed to read repo overrides", "file", cfg.RepoOverridesFile, "error", err)
+		return exitFailure
+	}
+
+	protection, err := migrate.LoadProtectionPolicy(cfg.ProtectionPolicyFile)
+	if err != nil {
+		logger.Error("failed to read protection policy", "file", cfg.ProtectionPolicyFile, "error", err)
+		return exitFailure
+	}
+
+	var auditLogSince time.Time
+	if cfg.AuditLogSince != "" {
+		// validated with the configuration
+		auditLogSince, _ = time.Parse(time.RFC3339, cfg.AuditLogSince)
+	}
+
+	rewrites, err := migrate.ParseURLRewrites(cfg.WebhooksRewrite)
+	if err != nil {
+		logger.Error("invalid webhooks rewrite", "error", err)
+		return exitFailure
+	}
+
+	var webhookSecrets *migrate.WebhookSecrets
+	if cfg.WebhooksSecretsFile != "" {
+		webhookSecrets, err = migrate.CreateWebhookSecrets(cfg.WebhooksSecretsFile)
+		if err != nil {
+			logger.Error("failed to open webhooks secrets file", "file", cfg.WebhooksSecretsFile, "error", err)
+			return exitFailure
+		}
+		defer webhookSecrets.Close()
+	}
+
+	var passwords *migrate.Passwords
+	if cfg.PasswordsFile != "" {
+		passwords, err = migrate.CreatePasswords(cfg.PasswordsFile)
+		if err != nil {
+			logger.Error("failed to open passwords file", "file", cfg.PasswordsFile, "error", err)
+			return exitFailure
 		}
-		logger.Info("org deleted", "org", cfg.TargetOrg)
+		defer passwords.Close()
 	}
 
-	if cfg.UserListFile != "" {
-		users, err := readUserList(cfg.UserListFile)
+	var runnerTokens *migrate.RunnerTokens
+	if cfg.RunnerTokensFile != "" {
+		runnerTokens, err = migrate.CreateRunnerTokens(cfg.RunnerTokensFile)
+		if err != nil {
+			logger.Error("failed to open runner tokens file", "file", cfg.RunnerTokensFile, "error", err)
+			return exitFailure
+		}
+		defer runnerTokens.Close()
+	}
+
+	var mailer *migrate.Mailer
+	// a rehearsal creates no users to welcome
+	if cfg.WelcomeEmail && fake == nil {
+		mailer = &migrate.Mailer{
+			Addr:     cfg.SMTPAddr,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			URL:      cfg.GTServer,
+		}
+	}
+
+	oauth2Apps, err := migrate.ParseOAuth2Apps(cfg.OAuth2Apps)
+	if err != nil {
+		logger.Error("invalid oauth2 apps", "error", err)
+		return exitFailure
+	}
+	var oauth2Secrets *migrate.OAuth2Secrets
+	if cfg.OAuth2AppsFile != "" {
+		oauth2Secrets, err = migrate.CreateOAuth2Secrets(cfg.OAuth2AppsFile)
 		if err != nil {
-			logger.Error("failed to read user list", "error", err)
+			logger.Error("failed to open oauth2 apps file", "file", cfg.OAuth2AppsFile, "error", err)
+			return exitFailure
+		}
+		defer oauth2Secrets.Close()
+	}
+
+	windows, err := migrate.ParseWindows(cfg.MigrationWindow)
+	if err != nil {
+		logger.Error("invalid migration window", "error", err)
+		return exitFailure
+	}
+
+	botLogins, err := migrate.ParseBotLogins(cfg.BotLogins)
+	if err != nil {
+		logger.Error("invalid bot logins", "error", err)
+		return exitFailure
+	}
+
+	emailRewrites, err := migrate.ParseEmailRewrites(cfg.EmailRewrite)
+	if err != nil {
+		logger.Error("invalid email rewrite", "error", err)
+		return exitFailure
+	}
+
+	secrets, err := migrate.LoadSecretValues(cfg.ActionsSecretsFile)
+	if err != nil {
+		logger.Error("failed to read actions secrets", "file", cfg.ActionsSecretsFile, "error", err)
+		return exitFailure
+	}
+
+	secretsFile, err := migrate.LoadEncryptedSecretValues(cfg.SecretsFile, []byte(cfg.SecretsPassphrase))
+	if err != nil {
+		logger.Error("failed to read secrets file", "file", cfg.SecretsFile, "error", err)
+		return exitFailure
+	}
+
+	runnerLabels, err := migrate.ParseRunnerLabels(cfg.WorkflowsRunnerLabels)
+	if err != nil {
+		logger.Error("invalid workflows runner labels", "error", err)
+		return exitFailure
+	}
+
+	environments, err := migrate.ParseEnvironmentPrefixes(cfg.EnvironmentPrefixes)
+	if err != nil {
+		logger.Error("invalid environment prefixes", "error", err)
+		return exitFailure
+	}
+
+	var orgs *migrate.OrgMapping
+	if cfg.OrgsFile != "" {
+		orgs, err = migrate.LoadOrgMapping(cfg.OrgsFile)
+		if err != nil {
+			logger.Error("failed to read orgs file", "file", cfg.OrgsFile, "error", err)
+			return exitFailure
+		}
+	}
+
+	// validated by IsVaild
+	maxInflight, _ := cfg.MaxInflightBytes()
+
+	skip := cfg.UserSkip()
+	plan := migrate.Plan{
+		SourceOrg:             cfg.SourceOrg,
+		TargetOrg:             cfg.TargetOrg,
+		TargetOrgFullName:     targetOrgFullName,
+		SourceUser:            cfg.SourceUser,
+		TargetUser:            cfg.TargetUser,
+		Users:                 users,
+		UserRepos:             cfg.MigrateUserRepos,
+		SourceID:              cfg.GTSourceID,
+		EmailRewrites:         emailRewrites,
+		SkipUserKeys:          skip[config.UserKeys],
+		SkipUserGPG:           skip[config.UserGPG],
+		SocialRate:            cfg.SocialRate,
+		SocialBatch:           cfg.SocialBatch,
+		UserKeys:              migrate.KeyPolicy{Max: cfg.UserKeysMax},
+		SkipUserAvatars:       skip[config.UserAvatars],
+		SkipUserProfile:       skip[config.UserProfile],
+		AuthToken:             cfg.GHToken,
+		Timeout:               cfg.MigrateTimeout,
+		StallTimeout:          cfg.MigrateStallTimeout,
+		Sync:                  cfg.Sync,
+		State:                 store,
+		TeamOverrides:         overrides,
+		TeamUnits:             teamUnits,
+		RepoOverrides:         repoOverrides,
+		Protection:            protection,
+		AuditLogFile:          cfg.AuditLogFile,
+		AuditLogSince:         auditLogSince,
+		ReportForks:           cfg.ReportForks,
+		ReportLicenses:        cfg.ReportLicenses,
+		LicenseTopics:         cfg.LicenseTopics,
+		ReportOrgRoles:        cfg.ReportOrgRoles,
+		ReportApps:            cfg.ReportApps,
+		OAuth2Apps:            oauth2Apps,
+		OAuth2Secrets:         oauth2Secrets,
+		ReportAuthors:         cfg.ReportAuthors,
+		ReportSigningKeys:     cfg.ReportSigningKeys,
+		GoModulesHost:         cfg.GoModulesHost(),
+		GoModules:             cfg.GoModulesFile != "",
+		GoImports:             cfg.GoImports,
+		MergeMessageTemplates: cfg.MergeMessageTemplates,
+		Webhooks:              cfg.Webhooks,
+		WebhookRewrites:       rewrites,
+		WebhooksInactive:      cfg.WebhooksInactive,
+		WebhookSecrets:        webhookSecrets,
+		Concurrency:           cfg.Concurrency,
+		Canary:                cfg.Canary,
+		ConfirmCanary:         confirm,
+		MaxInflightBytes:      maxInflight,
+		Windows:               windows,
+		BotLogins:             botLogins,
+		OnDrift:               migrate.DriftPolicy(cfg.OnDrift),
+		Suspended:             migrate.SuspendedPolicy(cfg.SuspendedUsers),
+		Passwords:             migrate.PasswordPolicy(cfg.PasswordPolicy),
+		PasswordsFile:         passwords,
+		Welcome:               mailer,
+		OutsideCollaborators:  cfg.OutsideCollaborators,
+		ConfirmDrift:          confirm,
+		Controls:              controls,
+		Actions:               cfg.Actions,
+		ActionsSecrets:        secrets,
+		Environments:          environments,
+		SecretsFile:           secretsFile,
+		Workflows:             cfg.Workflows,
+		RunnerLabels:          runnerLabels,
+		DowngradeArtifacts:    cfg.DowngradeArtifacts,
+		EnableActions:         cfg.EnableActions,
+		RunnerTokens:          runnerTokens,
+		ByTeam:                cfg.ByTeam,
+		Archive:               cfg.Archive,
+		Forks:                 cfg.RecreateForks,
+		Labels:                migrate.LabelMode(cfg.Labels),
+		LFS:                   cfg.LFS,
+		LFSEndpoint:           cfg.LFSEndpoint,
+		Mirror:                cfg.Mirror,
+		MirrorInterval:        cfg.MirrorInterval,
+		Cutover:               cfg.Command == config.CommandCutover,
+		ArchiveSource:         cfg.ArchiveSource,
+		ReleaseAssets:         cfg.ReleaseAssets,
+		Attachments:           cfg.Attachments,
+		Phases:                phases(cfg.Command),
+		Repos:                 cfg.Args,
+		Report:                report.New(),
+	}
+	if cfg.UI {
+		// the dashboard reads the keys, drift and canary questions are answered there
+		plan.ConfirmDrift = prog.Confirm
+		plan.ConfirmCanary = prog.Confirm
+	}
+
+	plans := []migrate.Plan{plan}
+	if orgs != nil {
+		// the organizations share the report and the resources of the run
+		plans, err = orgs.Plans(plan)
+		if err != nil {
+			logger.Error("invalid orgs file", "file", cfg.OrgsFile, "error", err)
+			return exitFailure
+		}
+	}
+
+	prog.Start(plan.Report.Counts, ghClient.RateLimit)
+	defer prog.Stop()
+
+	onEvent := func(event migrate.Event) {
+		switch event.Type {
+		case migrate.EventTotal:
+			prog.AddTotal(event.Total)
+		case migrate.EventRepoQueued:
+			prog.RepoQueued(event.Source)
+		case migrate.EventRepoStarted:
+			prog.RepoDequeued(event.Source)
+			prog.RepoStarted(event.Owner, event.Name)
+		case migrate.EventRepoFinished:
+			// skipped repositories finish without starting
+			prog.RepoDequeued(event.Source)
+			prog.RepoFinished(event.Owner, event.Name)
+			if event.Result.Status == report.StatusFailed || event.Result.Status == report.StatusStuck {
+				prog.Failed("repo "+event.Owner+"/"+event.Name, event.Result.Error)
+			}
+		case migrate.EventItem:
+			if event.Item.Status == report.StatusFailed {
+				prog.Failed(event.Item.Kind+" "+event.Item.Name, event.Item.Error)
+			}
+		}
+	}
+	rpt := plan.Report
+	var errs []error
+	for _, plan := range plans {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		if orgs != nil {
+			logger.Info("migrate organization", "org", plan.SourceOrg, "target", plan.TargetOrg)
+		}
+		if _, err := migrate.New(ghClient, gtClient, logger).Run(ctx, plan, onEvent); err != nil {
+			logger.Error("migration failed", "error", err)
+			fmt.Fprintln(os.Stderr, redact.String(p.Sprintf(i18n.HintMigration, err)))
+			errs = append(errs, err)
+		}
+	}
+	err = errors.Join(errs...)
+
+	prog.Stop()
+
+	if fake != nil {
+		ops := fake.Operations()
+		for _, op := range ops {
+			logger.Info("rehearsal operation", "method", op.Method, "path", op.Path, "status", op.Status)
+		}
+		logger.Info("rehearsal finished", "operations", len(ops))
+	}
+
+	adviseScopes(ghClient, logger, p)
+	writeReport(cfg, rpt, logger, p)
+	// the audit log is written at the end of the run, unless fetching it failed
+	if cfg.AuditLogFile != "" {
+		if _, err := os.Stat(cfg.AuditLogFile); err == nil {
+			signFile(cfg, cfg.AuditLogFile, logger)
+		}
+	}
+	if cfg.GoModulesFile != "" {
+		if err := rpt.WriteGoModulesFile(cfg.GoModulesFile); err != nil {
+			logger.Error("failed to write go modules file", "file", cfg.GoModulesFile, "error", err)
+		} else {
+			logger.Info("go modules file written", "file", cfg.GoModulesFile)
+		}
+	}
+	if err == nil && fake == nil {
+		publishDigest(ctx, cfg, store, rpt, logger)
+	}
+	// a phase to run again
+	if _, failed := rpt.Counts(); err != nil || failed > 0 {
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+// publishDigest writes and posts the changes since the previous sync run, then
+// records the results of this run for the next digest and the report command.
+func publishDigest(ctx context.Context, cfg *config.Config, store *state.Store, rpt *report.Report, logger *slog.Logger) {
+	if cfg.DigestFile != "" || cfg.DigestURL != "" {
+		previous, at := store.LastResults()
+		digest := report.NewDigest(previous, at, rpt)
+		if cfg.DigestFile != "" {
+			if err := report.WriteDigestFile(cfg.DigestFile, digest); err != nil {
+				logger.Error("failed to write sync digest", "file", cfg.DigestFile, "error", err)
+			} else {
+				logger.Info("sync digest written", "file", cfg.DigestFile)
+			}
+		}
+		if cfg.DigestURL != "" {
+			if err := report.PostDigest(ctx, cfg.DigestURL, digest); err != nil {
+				logger.Error("failed to post sync digest", "error", err)
+			} else {
+				logger.Info("sync digest posted")
+			}
+		}
+	}
+	if err := store.SetResults(rpt.StartedAt, rpt.Snapshot()); err != nil {
+		logger.Error("failed to record results in state file", "file", cfg.StateFile, "error", err)
+	}
+}
+
+// adviseScopes prints the GitHub token scopes the denied requests of the run needed.
+func adviseScopes(ghClient *gh.Client, logger *slog.Logger, p *i18n.Printer) {
+	scopes := ghClient.MissingScopes()
+	if len(scopes) == 0 {
+		return
+	}
+	fmt.Fprintln(os.Stderr, p.Sprintf(i18n.HintScopes))
+	for _, scope := range scopes {
+		logger.Warn("github token lacks scope", "scope", scope.Scope, "denied", scope.Count, "endpoints", strings.Join(scope.Endpoints, ","))
+		fmt.Fprintln(os.Stderr, p.Sprintf(i18n.HintScope, scope.Scope, scope.Count, strings.Join(scope.Endpoints, ", ")))
+	}
+}
+
+// writeReport prints the summary of a report, and writes and signs the report file.
+func writeReport(cfg *config.Config, rpt *report.Report, logger *slog.Logger, p *i18n.Printer) {
+	success, failed := rpt.Summary()
+	fmt.Println(p.Sprintf(i18n.MsgSummary, success, failed))
+	if bots := rpt.Names(report.KindBot); len(bots) > 0 {
+		fmt.Println(p.Sprintf(i18n.MsgBots, len(bots), strings.Join(bots, ", ")))
+	}
+
+	if cfg.ReportFile != "" {
+		rpt.Stable = cfg.ReportStable
+		if err := rpt.WriteFile(cfg.ReportFile, cfg.ReportFormat); err != nil {
+			logger.Error("failed to write report", "file", cfg.ReportFile, "error", err)
 			return
 		}
-		createUsersFromCSV(ctx, ghClient, gtClient, users, cfg.GTSourceID, logger)
+		logger.Info("migration report written", "file", cfg.ReportFile)
+		fmt.Println(p.Sprintf(i18n.MsgReportWritten, cfg.ReportFile))
+		signFile(cfg, cfg.ReportFile, logger)
 	}
+}
 
-	if err := migrateOrgAndRepos(ctx, cfg, logger, ghClient, gtClient); err != nil {
-		logger.Error("migration failed", "error", err)
+// signFile writes the checksum of a file the run produced, and its signature when
+// a signing key is given, so the evidence of the migration can be verified later.
+func signFile(cfg *config.Config, path string, logger *slog.Logger) {
+	if err := report.SignFile(path, cfg.ReportSignKey, []byte(cfg.ReportSignPassphrase)); err != nil {
+		logger.Error("failed to sign file", "file", path, "error", err)
+		return
+	}
+	if cfg.ReportSignKey != "" {
+		logger.Info("file signed", "signature", path+".sig", "checksum", path+".sha256")
+	} else {
+		logger.Info("file checksum written", "checksum", path+".sha256")
 	}
 }
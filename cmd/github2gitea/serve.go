@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/config"
+	gh "github.com/appleboy/github2gitea/pkg/github"
+	"github.com/appleboy/github2gitea/pkg/migrate"
+	"github.com/appleboy/github2gitea/pkg/redact"
+)
+
+// serveVerification answers verification requests until the context is done:
+//
+//	GET /verify/repos/{owner}/{repo}?target=owner/name
+//	GET /verify/orgs/{org}?target=org
+//
+// The target defaults to the GitHub owner and name. Only the source organization or
+// user of the configuration is answered, other owners are not found. Results are
+// returned as JSON, with status 200 whether the checks passed or not, 404 when GitHub
+// has no such repository or organization and 502 when GitHub cannot be read.
+func serveVerification(ctx context.Context, cfg *config.Config, m *migrate.Migrator, logger *slog.Logger) error {
+	source := cfg.SourceOrg
+	if source == "" {
+		source = cfg.SourceUser
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /verify/repos/{owner}/{repo}", func(w http.ResponseWriter, r *http.Request) {
+		owner, name := r.PathValue("owner"), r.PathValue("repo")
+		if !strings.EqualFold(owner, source) {
+			http.NotFound(w, r)
+			return
+		}
+		targetOwner, targetName := owner, name
+		if target := r.URL.Query().Get("target"); target != "" {
+			var ok bool
+			targetOwner, targetName, ok = splitFullName(target)
+			if !ok {
+				http.Error(w, "target must be owner/name", http.StatusBadRequest)
+				return
+			}
+		}
+		result, err := m.VerifyRepo(r.Context(), owner, name, targetOwner, targetName)
+		writeVerification(w, result, err, logger)
+	})
+	mux.HandleFunc("GET /verify/orgs/{org}", func(w http.ResponseWriter, r *http.Request) {
+		org := r.PathValue("org")
+		if cfg.SourceOrg == "" || !strings.EqualFold(org, cfg.SourceOrg) {
+			http.NotFound(w, r)
+			return
+		}
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			target = org
+		}
+		result, err := m.VerifyOrg(r.Context(), org, target)
+		writeVerification(w, result, err, logger)
+	})
+
+	srv := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           requireToken(cfg.ServeToken, mux),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	logger.Info("verification api listening", "address", cfg.Listen, "authentication", cfg.ServeToken != "")
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// requireToken rejects requests without the bearer token, or lets every request
+// through when the token is empty, which the configuration only allows on loopback
+// addresses.
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeVerification writes a verification result as JSON, or the error when the
+// GitHub side could not be read.
+func writeVerification(w http.ResponseWriter, result any, err error, logger *slog.Logger) {
+	if gh.IsNotFound(err) {
+		http.Error(w, "not found on github", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("verification failed", "error", err)
+		http.Error(w, redact.String(err.Error()), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		logger.Error("failed to write verification", "error", err)
+	}
+}
+
+// splitFullName splits owner/name.
+func splitFullName(s string) (owner, name string, ok bool) {
+	owner, name, ok = strings.Cut(s, "/")
+	return owner, name, ok && owner != "" && name != "" && !strings.Contains(name, "/")
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/appleboy/github2gitea/pkg/migrate"
+)
+
+// handlePauseSignals does nothing, the platform has no SIGUSR1 and SIGUSR2.
+func handlePauseSignals(context.Context, *migrate.Controls, *slog.Logger) {}
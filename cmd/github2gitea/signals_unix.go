@@ -0,0 +1,41 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/appleboy/github2gitea/pkg/migrate"
+)
+
+// handlePauseSignals pauses the run on SIGUSR1 and resumes it on SIGUSR2 until the
+// context is done, for runs without dashboard.
+func handlePauseSignals(ctx context.Context, controls *migrate.Controls, logger *slog.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-signals:
+				var err error
+				if sig == syscall.SIGUSR1 {
+					err = controls.Pause()
+					logger.Info("paused, running work finishes, resume with SIGUSR2")
+				} else {
+					err = controls.Resume()
+					logger.Info("resumed")
+				}
+				if err != nil {
+					logger.Error("failed to record pause in state file", "error", err)
+				}
+			}
+		}
+	}()
+}
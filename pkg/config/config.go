@@ -1,10 +1,27 @@
 package config
 
 import (
+	"cmp"
 	"errors"
-	"flag"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/github"
+	"github.com/appleboy/github2gitea/pkg/migrate"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/retry"
+	"github.com/appleboy/github2gitea/pkg/trace"
 
 	"github.com/appleboy/com/convert"
+	"github.com/spf13/cobra"
 )
 
 // Config holds all configuration options
@@ -19,61 +36,913 @@ type Config struct {
 	APITimeout   string
 	SourceOrg    string
 	TargetOrg    string
+	SourceUser   string
+	TargetUser   string
+	// OrgsFile is a JSON mapping of several organizations migrated in the run, with
+	// defaults and per organization settings, instead of SourceOrg and TargetOrg.
+	OrgsFile     string
 	UserListFile string
-	Debug        bool
-	Version      bool
+	// CSVColumns maps the user list fields to 1-based columns, e.g. login=1,email=2,role=3.
+	CSVColumns string
+	// MigrateUserRepos determines whether to migrate the personal repositories of users in the user list.
+	MigrateUserRepos bool
+	ReportFile       string
+	Debug            bool
+	Version          bool
 	// RmOrg determines whether to remove the original org and all its repos before migration.
 	RmOrg bool
+	// Yes answers the confirmation of cleanup, for scripts.
+	Yes bool
+	// GHRateLimitThreshold is the remaining GitHub API quota at which requests pause until reset.
+	GHRateLimitThreshold int
+	// MaxRetries is the number of retries for transient GitHub and Gitea API errors.
+	MaxRetries int
+	// RetryBackoff is the initial delay between retries, doubled on every attempt.
+	RetryBackoff time.Duration
+	// GHGraphQL enables organization enumeration through the GitHub GraphQL API.
+	GHGraphQL bool
+	// Lang selects the language of CLI summaries and hints, defaults to the LANG environment variable.
+	Lang string
+	// Target selects the migration target: a real Gitea server or an in-memory fake for rehearsals.
+	Target string
+	// GHPageSize is the number of items per page requested from GitHub list endpoints.
+	GHPageSize int
+	// GHRecordDir stores GitHub API responses as fixtures for later replay.
+	GHRecordDir string
+	// GHReplayDir serves GitHub API responses from recorded fixtures instead of the network.
+	GHReplayDir string
+	// UsersSkip is a comma separated list of user sub-resources not to migrate (keys, gpg, avatars, profile).
+	UsersSkip string
+	// MigrateTimeout limits how long to wait for each repository migration task.
+	MigrateTimeout time.Duration
+	// GTReconcileEmail matches GitHub users to existing LDAP (external) Gitea users by email before creating accounts.
+	GTReconcileEmail bool
+	// GitCredentials is where the git binary of the push fallback gets the GitHub
+	// credentials from: url, netrc or helper.
+	GitCredentials string
+	// ReportFormat is the format of the report file: json, csv or html.
+	ReportFormat string
+	// ReportForks adds the fork network of every migrated repository to the report.
+	ReportForks bool
+	// ReportLicenses adds the license of every migrated repository to the compliance section of the report.
+	ReportLicenses bool
+	// LicenseTopics tags the migrated repositories with a license topic such as license-gpl3.
+	LicenseTopics bool
+	// OTelEndpoint is the OTLP/HTTP endpoint receiving traces, tracing is disabled when empty.
+	OTelEndpoint string
+	// OTelServiceName is the service name reported with the traces.
+	OTelServiceName string
+	// OTelHeaders are the headers sent to the OTLP endpoint, read from
+	// $OTEL_EXPORTER_OTLP_TRACES_HEADERS or $OTEL_EXPORTER_OTLP_HEADERS.
+	OTelHeaders string
+	// TeamOverridesFile is a CSV file adjusting team permissions on single repositories.
+	TeamOverridesFile string
+	// TeamUnitsFile is a CSV file mapping GitHub team permissions to Gitea per-unit permissions.
+	TeamUnitsFile string
+	// AuditLogFile receives the GitHub audit log of the source organization since AuditLogSince, an RFC 3339 time.
+	AuditLogFile  string
+	AuditLogSince string
+	// ProtectionPolicyFile is a CSV file of branch protection rules applied to every migrated repository.
+	ProtectionPolicyFile string
+	// RepoOverridesFile is a CSV file changing the migration options of single repositories.
+	RepoOverridesFile string
+	// Sync re-migrates repositories already in Gitea only when they changed on GitHub.
+	Sync bool
+	// StateFile keeps the GitHub timestamps of migrated repositories between runs.
+	StateFile string
+	// MigrateStallTimeout abandons a migration task without progress for this long.
+	MigrateStallTimeout time.Duration
+	// Progress shows a live progress display in the terminal above the log output.
+	Progress bool
+	// UI shows a full-screen dashboard of the run, with keys to pause it and skip repositories.
+	UI bool
+	// MergeMessageTemplates commits Gitea merge message templates matching the GitHub commit message settings.
+	MergeMessageTemplates bool
+	// ReportSignKey is an SSH private key signing the report and audit log files, next
+	// to their SHA-256 checksums.
+	ReportSignKey string
+	// ReportSignPassphrase decrypts ReportSignKey, read from $REPORT_SIGN_PASSPHRASE.
+	ReportSignPassphrase string
+	// Webhooks migrates the organization and repository webhooks.
+	Webhooks bool
+	// WebhooksRewrite is a comma separated list of from=to URL prefix rewrites for webhooks.
+	WebhooksRewrite string
+	// WebhooksInactive creates the migrated webhooks disabled.
+	WebhooksInactive bool
+	// WebhooksSecretsFile receives the fresh secrets generated for the migrated webhooks.
+	WebhooksSecretsFile string
+	// Concurrency is the number of repositories migrated at once.
+	Concurrency int
+	// Canary migrates and verifies this many representative repositories first, then asks to continue.
+	Canary int
+	// MaxInflightSize limits the total size of the repositories migrated at once, e.g. 10GB.
+	MaxInflightSize string
+	// BotLogins is a comma separated list of glob patterns of the logins of machine accounts not migrated.
+	BotLogins string
+	// MigrationWindow is a comma separated list of HH:MM-HH:MM daily windows for the repository migrations.
+	MigrationWindow string
+	// SuspendedUsers is active, inactive or skip, for the users suspended on GitHub Enterprise Server.
+	SuspendedUsers string
+	// PasswordPolicy is none, random or mapping, for the local password of the created users.
+	PasswordPolicy string
+	// PasswordsFile receives the passwords generated with the random password policy.
+	PasswordsFile string
+	// OutsideCollaborators adds the outside collaborators of the GitHub repositories to the migrated ones.
+	OutsideCollaborators bool
+	// WelcomeEmail emails the created users their username, the Gitea URL and SSH key status.
+	WelcomeEmail bool
+	// SMTPAddr is the host:port of the SMTP server sending the welcome emails, from SMTPFrom.
+	SMTPAddr string
+	SMTPFrom string
+	// SMTPUsername authenticates with the SMTP server, with SMTPPassword read from $SMTP_PASSWORD.
+	SMTPUsername string
+	SMTPPassword string
+	// OnDrift is ask, overwrite or preserve, for teams and repositories changed in Gitea since the last run.
+	OnDrift string
+	// Actions migrates the organization and repository Actions variables and secret names.
+	Actions bool
+	// ActionsSecretsFile is a CSV file (repo,name,value) with the values of the migrated secrets.
+	ActionsSecretsFile string
+	// SlowAPIThreshold logs GitHub and Gitea API calls slower than this, disabled when zero.
+	SlowAPIThreshold time.Duration
+	// ReportOrgRoles adds the GitHub organization role assignments to the report.
+	ReportOrgRoles bool
+	// ReportApps adds the GitHub Apps installed on the source organization to the report.
+	ReportApps bool
+	// OAuth2Apps is a comma separated list of template=url integrations getting a Gitea OAuth2 application.
+	OAuth2Apps string
+	// OAuth2AppsFile receives the client credentials of the created OAuth2 applications.
+	OAuth2AppsFile string
+	// ReportSigningKeys adds the keys which signed verified commits to the report.
+	ReportSigningKeys bool
+	// ReportAuthors adds the GitHub authors of migrated issues not attributed to a Gitea user to the report.
+	ReportAuthors bool
+	// SecretsFile is an openssl encrypted CSV file (repo,name,value) of Actions secrets set after the migration.
+	SecretsFile string
+	// SecretsPassphrase decrypts SecretsFile, read from $SECRETS_PASSPHRASE.
+	SecretsPassphrase string
+	// Workflows converts the GitHub Actions workflows of migrated repositories for Gitea Actions.
+	Workflows bool
+	// WorkflowsRunnerLabels is a comma separated list of from=to runner label mappings.
+	WorkflowsRunnerLabels string
+	// DowngradeArtifacts downgrades the v4 artifact actions for Gitea before 1.22.
+	DowngradeArtifacts bool
+	// Listen is the address of the verification API of the serve command, and ServeToken
+	// the bearer token its clients send, no authentication when empty.
+	Listen     string
+	ServeToken string
+	// EnvironmentPrefixes is a comma separated list of environment=PREFIX mappings of the
+	// GitHub deployment environments to the prefix of their secrets and variables.
+	EnvironmentPrefixes string
+	// EnableActions enables the Actions unit of the migrated repositories.
+	EnableActions bool
+	// RunnerTokensFile receives the Actions runner registration tokens of the migrated
+	// organization and repositories.
+	RunnerTokensFile string
+	// ByTeam is the slug of a GitHub team whose repositories and members are migrated.
+	ByTeam string
+	// Command is the subcommand of the command line, the migration when empty.
+	Command string
+	// Args are the arguments of the command, e.g. the repositories of migrate repo.
+	Args []string
+	// ReportStable leaves the times and durations out of the report.
+	ReportStable bool
+	// Archive archives the migrated repositories archived on GitHub.
+	Archive bool
+	// RecreateForks forks the migrated parent in Gitea for GitHub forks.
+	RecreateForks bool
+	// Labels is check or fix, to compare the migrated issue labels with GitHub.
+	Labels string
+	// LFS migrates the Git LFS objects, from LFSEndpoint when set.
+	LFS         bool
+	LFSEndpoint string
+	// Mirror creates Gitea pull mirrors synced every MirrorInterval instead of one-shot migrations.
+	Mirror         bool
+	MirrorInterval string
+	// ReleaseAssets uploads the GitHub release assets missing after the migration.
+	ReleaseAssets bool
+	// Attachments copies the files attached to GitHub issues and comments into Gitea.
+	Attachments bool
+	// UserKeysMax limits the SSH keys migrated per user.
+	UserKeysMax int
+	// SocialRate and SocialBatch limit the star, watch and follow calls made as the users.
+	SocialRate  float64
+	SocialBatch int
+	// ArchiveSource archives the GitHub repositories once the cutover migrated them.
+	ArchiveSource bool
+	// EmailRewrite is a comma separated list of from=to email domain rewrites for created users.
+	EmailRewrite string
+	// GoModulesFile receives the Go module path changes with GOPRIVATE and replace suggestions.
+	GoModulesFile string
+	// GoImports opens pull requests rewriting the Go import paths of migrated repositories.
+	GoImports bool
+	// UsernamesFile is a CSV file mapping GitHub logins to Gitea usernames.
+	UsernamesFile string
+	// DigestFile and DigestURL receive the changes since the previous sync run.
+	DigestFile string
+	DigestURL  string
+}
+
+// User sub-resources which can be excluded with --users-skip.
+const (
+	UserKeys    = "keys"
+	UserGPG     = "gpg"
+	UserAvatars = "avatars"
+	UserProfile = "profile"
+)
+
+// Migration targets.
+const (
+	TargetGitea = "gitea"
+	TargetFake  = "fake"
+)
+
+// Commands run instead of the migration.
+const (
+	// CommandBackfillMetadata updates the metadata of repositories migrated by earlier versions.
+	CommandBackfillMetadata = "backfill metadata"
+	// CommandExportInventory lists the GitHub repositories with their languages, without Gitea.
+	CommandExportInventory = "export inventory"
+	// CommandExportUsers writes the members of the source organization to the user list, without Gitea.
+	CommandExportUsers = "export users"
+	// CommandCutover replaces the pull mirrors created with Mirror by regular repositories.
+	CommandCutover = "cutover"
+	// CommandServe answers verification requests comparing migrated repositories with
+	// GitHub over HTTP, without migrating.
+	CommandServe = "serve"
+	// CommandMigrate runs every phase of the migration, like no command.
+	CommandMigrate = "migrate"
+	// CommandMigrateUsers creates the users of the user list with their keys and personal data.
+	CommandMigrateUsers = "migrate users"
+	// CommandMigrateOrg creates the target organization with its members and teams, or
+	// the target user namespace, without repositories.
+	CommandMigrateOrg = "migrate org"
+	// CommandMigrateRepo migrates the repositories given as arguments, all of the source
+	// when none, into the organization and teams of an earlier migrate org.
+	CommandMigrateRepo = "migrate repo"
+	// CommandVerify compares the repositories given as arguments, all of the source when
+	// none, with the migrated ones.
+	CommandVerify = "verify"
+	// CommandReport writes the report of the last run recorded in the state file.
+	CommandReport = "report"
+	// CommandCleanup deletes the target organization with all its repositories.
+	CommandCleanup = "cleanup"
+	// CommandCheck checks the tokens, Gitea and the source and target of the migration
+	// without changing anything.
+	CommandCheck = "check"
+)
+
+// commands are the known commands, in the order of the help.
+var commands = []string{
+	CommandCheck,
+	CommandMigrate,
+	CommandMigrateUsers,
+	CommandMigrateOrg,
+	CommandMigrateRepo,
+	CommandVerify,
+	CommandReport,
+	CommandCleanup,
+	CommandCutover,
+	CommandBackfillMetadata,
+	CommandExportInventory,
+	CommandExportUsers,
+	CommandServe,
+}
+
+// commandUsage describes the commands in the usage message.
+var commandUsage = map[string]string{
+	CommandCheck:            "Check the tokens, Gitea, the source and the target before migrating",
+	CommandMigrate:          "Run every phase of the migration (default)",
+	CommandMigrateUsers:     "Create the users of the user list",
+	CommandMigrateOrg:       "Create the target organization with its members and teams",
+	CommandMigrateRepo:      "Migrate the given repositories, all when none, into the existing organization",
+	CommandVerify:           "Compare the given repositories, all when none, with the migrated ones",
+	CommandReport:           "Write the report of the last run recorded in the state file",
+	CommandCleanup:          "Delete the target organization with all its repositories",
+	CommandCutover:          "Replace the pull mirrors by regular repositories",
+	CommandBackfillMetadata: "Update the metadata of repositories migrated by earlier versions",
+	CommandExportInventory:  "List the GitHub repositories with their languages and licenses",
+	CommandExportUsers:      "Write the members of the source organization to the user list",
+	CommandServe:            "Answer verification requests over HTTP",
+}
+
+// Migration reports whether the command runs phases of the migration.
+func (cfg *Config) Migration() bool {
+	switch cfg.Command {
+	case "", CommandMigrate, CommandMigrateUsers, CommandMigrateOrg, CommandMigrateRepo, CommandCutover:
+		return true
+	}
+	return false
 }
 
 func (cfg *Config) IsVaild() error {
-	if cfg.GHToken == "" {
+	if cfg.GHRecordDir != "" && cfg.GHReplayDir != "" {
+		return errors.New("gh-record and gh-replay cannot be used together")
+	}
+	if cfg.Command != "" && !slices.Contains(commands, cfg.Command) {
+		return errors.New("unknown command: " + cfg.Command)
+	}
+	if len(cfg.Args) > 0 && cfg.Command != CommandMigrateRepo && cfg.Command != CommandVerify {
+		return errors.New(cfg.Command + " takes no arguments")
+	}
+	// the report only reads the state file
+	if cfg.Command == CommandReport {
+		if cfg.StateFile == "" || cfg.ReportFile == "" {
+			return errors.New("report requires state-file and report-file")
+		}
+		if !report.ValidFormat(cfg.ReportFormat) {
+			return errors.New("report format must be json, csv or html")
+		}
+		return nil
+	}
+	if cfg.GHToken == "" && cfg.GHReplayDir == "" {
 		return errors.New("github token is required")
 	}
-	if cfg.GTToken == "" {
+	if cfg.Target != TargetGitea && cfg.Target != TargetFake {
+		return errors.New("target must be gitea or fake")
+	}
+	// the exports only read GitHub
+	inventory := cfg.Command == CommandExportInventory || cfg.Command == CommandExportUsers
+	if cfg.GTToken == "" && cfg.Target != TargetFake && !inventory {
 		return errors.New("gitea token is required")
 	}
-	if cfg.SourceOrg == "" {
-		return errors.New("sourceOrg is required")
+	if cfg.Command == CommandMigrateUsers && cfg.UserListFile == "" {
+		return errors.New("migrate users requires user-list")
+	}
+	if cfg.Command == CommandCleanup && cfg.TargetOrg == "" {
+		return errors.New("cleanup requires target-org")
+	}
+	// cleanup and migrate users do not read the source
+	sourceless := cfg.Command == CommandCleanup || cfg.Command == CommandMigrateUsers
+	if cfg.Command == CommandExportInventory && cfg.ReportFile == "" {
+		return errors.New("export inventory requires report-file")
+	}
+	if cfg.Command == CommandExportUsers && (cfg.SourceOrg == "" || cfg.UserListFile == "") {
+		return errors.New("export users requires source-org and user-list")
+	}
+	if cfg.Command == CommandExportUsers && !strings.EqualFold(filepath.Ext(cfg.UserListFile), ".csv") {
+		return errors.New("export users writes a csv user-list")
+	}
+	if cfg.OrgsFile != "" {
+		if !cfg.Migration() || cfg.Command == CommandMigrateUsers {
+			return errors.New("orgs-file only applies to the migration and cutover")
+		}
+		if len(cfg.Args) > 0 {
+			return errors.New("orgs-file selects the repositories with repos, not with arguments")
+		}
+		if cfg.SourceOrg != "" || cfg.SourceUser != "" || cfg.TargetOrg != "" || cfg.TargetUser != "" {
+			return errors.New("orgs-file cannot be used with source-org, source-user, target-org or target-user")
+		}
+		if cfg.RmOrg || cfg.AuditLogFile != "" {
+			return errors.New("orgs-file cannot be used with rm-org or audit-log-file")
+		}
+	} else if cfg.SourceOrg == "" && cfg.SourceUser == "" && !sourceless {
+		return errors.New("sourceOrg or sourceUser is required")
+	}
+	if cfg.Command == CommandServe && cfg.Listen == "" {
+		return errors.New("serve requires listen")
 	}
-	if cfg.TargetOrg == "" {
+	// the api reads GitHub with the token of the migration
+	if cfg.Command == CommandServe && cfg.ServeToken == "" && !loopback(cfg.Listen) {
+		return errors.New("serve requires SERVE_TOKEN to listen beyond the loopback address")
+	}
+	if cfg.SourceOrg != "" && cfg.SourceUser != "" {
+		return errors.New("sourceOrg and sourceUser cannot be used together")
+	}
+	if cfg.TargetOrg != "" && cfg.TargetUser != "" {
+		return errors.New("targetOrg and targetUser cannot be used together")
+	}
+	if cfg.SourceOrg != "" && cfg.TargetOrg == "" && !inventory {
 		return errors.New("targetOrg is required")
 	}
+	if cfg.SourceUser != "" && cfg.TargetOrg == "" && cfg.TargetUser == "" && !inventory {
+		return errors.New("targetOrg or targetUser is required")
+	}
+	if !report.ValidFormat(cfg.ReportFormat) {
+		return errors.New("report format must be json, csv or html")
+	}
+	if cfg.Canary < 0 {
+		return errors.New("canary cannot be negative")
+	}
+	if cfg.Concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+	if _, err := cfg.MaxInflightBytes(); err != nil {
+		return err
+	}
+	switch cfg.GitCredentials {
+	case gitea.GitCredentialsURL, gitea.GitCredentialsNetrc, gitea.GitCredentialsHelper:
+	default:
+		return errors.New("git-credentials must be url, netrc or helper")
+	}
+	if !migrate.ValidDriftPolicy(cfg.OnDrift) {
+		return errors.New("on-drift must be ask, overwrite or preserve")
+	}
+	if cfg.AuditLogFile != "" && cfg.SourceOrg == "" {
+		return errors.New("audit-log-file requires source-org")
+	}
+	if cfg.AuditLogSince != "" {
+		if cfg.AuditLogFile == "" {
+			return errors.New("audit-log-since requires audit-log-file")
+		}
+		if _, err := time.Parse(time.RFC3339, cfg.AuditLogSince); err != nil {
+			return errors.New("audit-log-since must be an RFC 3339 time, e.g. 2024-05-01T18:00:00Z")
+		}
+	}
+	if !migrate.ValidSuspendedPolicy(cfg.SuspendedUsers) {
+		return errors.New("suspended-users must be active, inactive or skip")
+	}
+	if !migrate.ValidPasswordPolicy(cfg.PasswordPolicy) {
+		return errors.New("password-policy must be none, random or mapping")
+	}
+	random := migrate.PasswordPolicy(cfg.PasswordPolicy) == migrate.PasswordRandom
+	if random && cfg.PasswordsFile == "" {
+		return errors.New("password-policy random requires passwords-file")
+	}
+	if !random && cfg.PasswordsFile != "" {
+		return errors.New("passwords-file requires password-policy random")
+	}
+	if migrate.PasswordPolicy(cfg.PasswordPolicy) == migrate.PasswordMapping && cfg.UserListFile == "" {
+		return errors.New("password-policy mapping requires user-list")
+	}
+	if cfg.WelcomeEmail {
+		if _, _, err := net.SplitHostPort(cfg.SMTPAddr); err != nil {
+			return errors.New("welcome-email requires smtp-addr as host:port")
+		}
+		if _, err := mail.ParseAddress(cfg.SMTPFrom); err != nil {
+			return errors.New("welcome-email requires a valid smtp-from address")
+		}
+	}
+	if cfg.ActionsSecretsFile != "" && !cfg.Actions {
+		return errors.New("actions-secrets-file requires actions")
+	}
+	if cfg.Labels != "" && !migrate.ValidLabelMode(cfg.Labels) {
+		return errors.New("labels must be check or fix")
+	}
+	if cfg.LFSEndpoint != "" && !cfg.LFS {
+		return errors.New("lfs-endpoint requires lfs")
+	}
+	if cfg.MirrorInterval != "" {
+		if !cfg.Mirror {
+			return errors.New("mirror-interval requires mirror")
+		}
+		if _, err := time.ParseDuration(cfg.MirrorInterval); err != nil {
+			return errors.New("invalid mirror-interval: " + err.Error())
+		}
+	}
+	if cfg.UI && cfg.Progress {
+		return errors.New("ui and progress cannot be combined")
+	}
+	// mirrors are read-only and have no issues
+	if cfg.Mirror && (cfg.Workflows || cfg.Labels != "" || cfg.RecreateForks || cfg.ReleaseAssets || cfg.Attachments || cfg.GoImports) {
+		return errors.New("mirror cannot be used with workflows, labels, recreate-forks, release-assets, attachments or go-imports")
+	}
+	if cfg.Command == CommandCutover && cfg.Mirror {
+		return errors.New("cutover cannot be used with mirror")
+	}
+	if cfg.SocialRate < 0 || cfg.SocialBatch < 1 {
+		return errors.New("social-rate cannot be negative and social-batch must be at least 1")
+	}
+	if cfg.UserKeysMax < 0 {
+		return errors.New("user-keys-max cannot be negative")
+	}
+	if cfg.ByTeam != "" && cfg.SourceOrg == "" && cfg.OrgsFile == "" {
+		return errors.New("by-team requires source-org")
+	}
+	if cfg.WorkflowsRunnerLabels != "" && !cfg.Workflows {
+		return errors.New("workflows-runner-labels requires workflows")
+	}
+	if cfg.DowngradeArtifacts && !cfg.Workflows {
+		return errors.New("downgrade-artifacts requires workflows")
+	}
+	if cfg.EnvironmentPrefixes != "" && !cfg.Actions && !cfg.Workflows {
+		return errors.New("environment-prefixes requires actions or workflows")
+	}
+	if cfg.SecretsFile != "" && cfg.SecretsPassphrase == "" {
+		return errors.New("secrets-file requires the SECRETS_PASSPHRASE environment variable")
+	}
+	if (cfg.DigestFile != "" || cfg.DigestURL != "") && (!cfg.Sync || cfg.StateFile == "") {
+		return errors.New("digest-file and digest-url require sync and state-file")
+	}
+	if (cfg.OAuth2Apps == "") != (cfg.OAuth2AppsFile == "") {
+		return errors.New("oauth2-apps and oauth2-apps-file must be set together")
+	}
+	if cfg.WebhooksSecretsFile != "" && !cfg.Webhooks {
+		return errors.New("webhooks-secrets-file requires webhooks")
+	}
+	if _, err := trace.ParseHeaders(cfg.OTelHeaders); err != nil {
+		return err
+	}
+	if cfg.ReportSignKey != "" && cfg.ReportFile == "" && cfg.AuditLogFile == "" {
+		return errors.New("report-sign-key requires report-file or audit-log-file")
+	}
+	for item := range cfg.UserSkip() {
+		switch item {
+		case UserKeys, UserGPG, UserAvatars, UserProfile:
+		default:
+			return errors.New("invalid users-skip value: " + item)
+		}
+	}
 	return nil
 }
 
+// UserSkip returns the set of user sub-resources excluded from the migration.
+func (cfg *Config) UserSkip() map[string]bool {
+	skip := make(map[string]bool)
+	for _, item := range strings.Split(cfg.UsersSkip, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			skip[item] = true
+		}
+	}
+	return skip
+}
+
+// sizeUnits are the suffixes of MaxInflightSize, as powers of 1024.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// MaxInflightBytes parses MaxInflightSize, zero when unlimited.
+func (cfg *Config) MaxInflightBytes() (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(cfg.MaxInflightSize))
+	if value == "" {
+		return 0, nil
+	}
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(value, u.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, u.suffix))
+			unit = u.bytes
+			break
+		}
+	}
+	size, err := strconv.ParseFloat(value, 64)
+	if err != nil || size < 0 {
+		return 0, errors.New("invalid max-inflight-size: " + cfg.MaxInflightSize)
+	}
+	return int64(size * float64(unit)), nil
+}
+
+// TargetOwner returns the Gitea namespace (organization or user) receiving the repositories.
+func (cfg *Config) TargetOwner() string {
+	if cfg.TargetUser != "" {
+		return cfg.TargetUser
+	}
+	return cfg.TargetOrg
+}
+
+// Secrets returns the tokens, passwords and passphrases of the configuration, which
+// never appear in logs and reports.
+func (cfg *Config) Secrets() []string {
+	secrets := []string{
+		cfg.GHToken,
+		cfg.GTToken,
+		cfg.ServeToken,
+		cfg.SMTPPassword,
+		cfg.SecretsPassphrase,
+		cfg.ReportSignPassphrase,
+		cfg.DigestURL,
+	}
+	// the values of the collector headers are credentials
+	headers, _ := trace.ParseHeaders(cfg.OTelHeaders)
+	for _, value := range headers {
+		secrets = append(secrets, value)
+	}
+	return secrets
+}
+
+// GoModulesHost returns the Gitea server without scheme, the prefix of the migrated
+// Go module paths, or empty when Go modules are neither listed nor rewritten.
+func (cfg *Config) GoModulesHost() string {
+	if cfg.GoModulesFile == "" && !cfg.GoImports {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(cfg.GTServer, "https://"), "http://")
+	return strings.TrimSuffix(host, "/")
+}
+
+// loopback reports whether a listen address only accepts local connections.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 // LoadConfig parses command-line flags and returns a Config struct
-func LoadConfig() *Config {
-	ghToken := flag.String("gh-token", "", "GitHub Personal Access Token")
-	ghSkipVerify := flag.Bool("gh-skip-verify", false, "Skip TLS verification for GitHub")
-	ghServer := flag.String("gh-server", "", "GitHub Enterprise Server URL")
-	gtServer := flag.String("gt-server", "https://gitea.com", "Gitea Server URL")
-	gtToken := flag.String("gt-token", "", "Gitea Personal Access Token")
-	gtSkipVerify := flag.Bool("gt-skip-verify", false, "Skip TLS verification for Gitea")
-	gtSourceID := flag.Int64("gt-source-id", 0, "Gitea Source ID")
-	apiTimeout := flag.String("timeout", "10m", "Timeout for requests")
-	sourceOrg := flag.String("source-org", "", "Source organization name")
-	targetOrg := flag.String("target-org", "", "Target organization name")
-	userListFile := flag.String("user-list", "", "Path to user list CSV file")
-	debug := flag.Bool("debug", false, "Enable debug logging")
-	version := flag.Bool("version", false, "Show version information")
-	rmOrg := flag.Bool("rm-org", false, "Remove the original org and all its repos before migration")
-	flag.Parse()
-
-	return &Config{
-		GHToken:      convert.FromPtr(ghToken),
-		GHSkipVerify: convert.FromPtr(ghSkipVerify),
-		GHServer:     convert.FromPtr(ghServer),
-		GTServer:     convert.FromPtr(gtServer),
-		GTToken:      convert.FromPtr(gtToken),
-		GTSkipVerify: convert.FromPtr(gtSkipVerify),
-		GTSourceID:   convert.FromPtr(gtSourceID),
-		APITimeout:   convert.FromPtr(apiTimeout),
-		SourceOrg:    convert.FromPtr(sourceOrg),
-		TargetOrg:    convert.FromPtr(targetOrg),
-		UserListFile: convert.FromPtr(userListFile),
-		Debug:        convert.FromPtr(debug),
-		Version:      convert.FromPtr(version),
-		RmOrg:        convert.FromPtr(rmOrg),
+// commandGroups describe the first words shared by commands, which run none of them.
+var commandGroups = map[string]string{
+	"backfill": "Update data of repositories migrated by earlier versions",
+	"export":   "Export data of the source without migrating to Gitea",
+}
+
+// NewCommand returns the command line of the tool: the migration when no command is
+// given, and a subcommand per command with its own flags and help. The flags shared
+// by the commands are accepted before and after them. run is called with the
+// configuration of the command line.
+func NewCommand(run func(*Config)) *cobra.Command {
+	// the commands are listed in the order they run in
+	cobra.EnableCommandSorting = false
+	root := &cobra.Command{
+		Use:          "github2gitea",
+		Short:        "Migrate GitHub organizations, users and repositories to Gitea",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+	root.CompletionOptions.DisableDefaultCmd = true
+	var load func(command string, args []string) *Config
+	root.RunE = func(_ *cobra.Command, args []string) error {
+		run(load("", args))
+		return nil
+	}
+
+	// the commands are one or two words long, the first word groups the second ones
+	subcommands := make(map[string]*cobra.Command)
+	for _, command := range commands {
+		words := strings.Fields(command)
+		parent := root
+		if len(words) == 2 {
+			parent = subcommands[words[0]]
+			if parent == nil {
+				parent = &cobra.Command{Use: words[0], Short: commandGroups[words[0]], Args: cobra.NoArgs}
+				subcommands[words[0]] = parent
+				root.AddCommand(parent)
+			}
+		}
+		cmd := &cobra.Command{
+			Use:   words[len(words)-1],
+			Short: commandUsage[command],
+			Args:  cobra.NoArgs,
+			RunE: func(_ *cobra.Command, args []string) error {
+				run(load(command, args))
+				return nil
+			},
+		}
+		if command == CommandMigrateRepo || command == CommandVerify {
+			cmd.Use += " [repository...]"
+			cmd.Args = cobra.ArbitraryArgs
+		}
+		subcommands[command] = cmd
+		parent.AddCommand(cmd)
+	}
+
+	flags := root.PersistentFlags()
+	ghToken := flags.String("gh-token", "", "GitHub Personal Access Token")
+	ghSkipVerify := flags.Bool("gh-skip-verify", false, "Skip TLS verification for GitHub")
+	ghServer := flags.String("gh-server", "", "GitHub Enterprise Server URL")
+	gtServer := flags.String("gt-server", "https://gitea.com", "Gitea Server URL")
+	gtToken := flags.String("gt-token", "", "Gitea Personal Access Token")
+	gtSkipVerify := flags.Bool("gt-skip-verify", false, "Skip TLS verification for Gitea")
+	gtSourceID := flags.Int64("gt-source-id", 0, "Gitea Source ID")
+	apiTimeout := flags.String("timeout", "10m", "Timeout for requests")
+	sourceOrg := flags.String("source-org", "", "Source organization name")
+	targetOrg := flags.String("target-org", "", "Target organization name")
+	sourceUser := flags.String("source-user", "", "Source GitHub user whose repositories are migrated")
+	targetUser := flags.String("target-user", "", "Target Gitea user namespace")
+	orgsFile := flags.String("orgs-file", "", "JSON mapping of the organizations to migrate, with defaults inherited by every organization, instead of source-org and target-org")
+	userListFile := flags.String("user-list", "", "Path to user list CSV, JSON or YAML file")
+	migrateUserRepos := flags.Bool("migrate-user-repos", false, "Migrate personal repositories of users in the user list")
+	reportFile := flags.String("report-file", "", "Path to write the migration report")
+	debug := flags.Bool("debug", false, "Enable debug logging")
+	version := flags.Bool("version", false, "Show version information")
+	rmOrg := flags.Bool("rm-org", false, "Remove the original org and all its repos before migration")
+	yes := subcommands[CommandCleanup].Flags().Bool("yes", false, "Delete without asking for confirmation")
+	ghRateLimitThreshold := flags.Int("gh-rate-limit-threshold", 100, "Pause GitHub requests when the remaining rate limit drops to this value")
+	maxRetries := flags.Int("max-retries", retry.DefaultMaxRetries, "Maximum number of retries for transient API errors")
+	retryBackoff := flags.Duration("retry-backoff", retry.DefaultBackoff, "Initial backoff between retries, doubled on every attempt")
+	ghGraphQL := flags.Bool("gh-graphql", false, "Use the GitHub GraphQL API to enumerate organization repos, teams and members")
+	lang := flags.String("lang", "", "Language of CLI messages (en, zh-TW, zh-CN), defaults to LANG environment variable")
+	target := flags.String("target", TargetGitea, "Migration target: gitea, or fake to rehearse against an in-memory Gitea")
+	ghPageSize := flags.Int("gh-page-size", github.DefaultPageSize, "Number of items per page for GitHub list requests (max 100)")
+	ghRecord := flags.String("gh-record", "", "Directory to record GitHub API responses to")
+	ghReplay := flags.String("gh-replay", "", "Directory of recorded GitHub API responses to replay instead of calling GitHub")
+	usersSkip := flags.String("users-skip", "", "Comma separated user sub-resources not to migrate: keys, gpg, avatars, profile")
+	migrateTimeout := flags.Duration("migrate-timeout", time.Hour, "Maximum time to wait for each repository migration task, 0 for no limit")
+	gtReconcileEmail := flags.Bool("gt-reconcile-email", false, "Match GitHub users to existing LDAP Gitea users by email instead of creating new accounts")
+	gitCredentials := flags.String("git-credentials", gitea.GitCredentialsURL, "Where the git binary of the push fallback gets the GitHub credentials from: url embeds the token in the clone URL, netrc reads ~/.netrc, helper asks the configured git credential helper")
+	reportFormat := flags.String("report-format", report.FormatJSON, "Format of the migration report: json, csv or html")
+	reportForks := flags.Bool("report-forks", false, "Add the GitHub fork network of every migrated repository to the report")
+	reportLicenses := flags.Bool("report-licenses", false, "Add the license GitHub detected in every migrated repository to the compliance section of the report")
+	licenseTopics := flags.Bool("license-topics", false, "Tag the migrated repositories with a topic naming their license, e.g. license-gpl3")
+	otelEndpoint := flags.String("otel-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTLP/HTTP endpoint to export traces to, e.g. http://localhost:4318")
+	otelServiceName := flags.String("otel-service-name", "github2gitea", "Service name reported with the traces")
+	teamOverrides := flags.String("team-overrides", "", "Path to CSV file (team,repo,permission) overriding team permissions on single repositories")
+	teamUnits := flags.String("team-units", "", "Path to CSV file (role,unit,permission) translating GitHub team permissions to Gitea per-unit permissions, e.g. write,wiki,read")
+	sync := flags.Bool("sync", false, "Skip repositories unchanged since the last run, re-migrate changed ones (existing Gitea repositories are replaced, mirrors are synced)")
+	stateFile := flags.String("state-file", "", "Path to the state file recording migrated repositories between runs")
+	migrateStallTimeout := flags.Duration("migrate-stall-timeout", 0, "Abandon a repository migration task whose state and message do not change for this long, deleting its repository, 0 to disable")
+	progress := flags.Bool("progress", false, "Show live progress of repositories, users, keys, teams and the GitHub rate limit")
+	ui := flags.Bool("ui", false, "Show a full-screen dashboard of the run, with keys to pause and resume the repository migrations and skip queued repositories")
+	mergeMessageTemplates := flags.Bool("merge-message-templates", false, "Commit Gitea merge message templates matching the GitHub default merge and squash commit messages")
+	reportSignKey := flags.String("report-sign-key", "", "SSH private key to sign the report file, of migrations and checks, and the audit log file with into <file>.sig, next to the <file>.sha256 checksum written for each of them")
+	webhooks := flags.Bool("webhooks", false, "Migrate organization and repository webhooks")
+	webhooksRewrite := flags.String("webhooks-rewrite", "", "Comma separated from=to URL prefix rewrites applied to migrated webhooks")
+	webhooksInactive := flags.Bool("webhooks-inactive", false, "Create migrated webhooks disabled, so they can be reviewed before firing")
+	concurrency := flags.Int("concurrency", 1, "Number of repositories migrated at once")
+	canary := flags.Int("canary", 0, "Migrate and verify this many representative repositories first, then ask before migrating the rest")
+	maxInflightSize := flags.String("max-inflight-size", "", "Maximum total size of the repositories migrated at once, e.g. 10GB; a bigger repository is migrated alone")
+	onDrift := flags.String("on-drift", "ask", "What to do with teams and repositories changed in Gitea since the last run (needs --state-file): ask, overwrite or preserve")
+	actions := flags.Bool("actions", false, "Migrate organization and repository Actions variables, and secrets with placeholder values")
+	actionsSecretsFile := flags.String("actions-secrets-file", "", "Path to CSV file (repo,name,value) with the values of migrated Actions secrets")
+	slowAPIThreshold := flags.Duration("slow-api-threshold", 0, "Log GitHub and Gitea API calls slower than this duration, e.g. 5s, 0 to disable")
+	reportOrgRoles := flags.Bool("report-org-roles", false, "Add the GitHub organization role assignments (security managers, app managers, custom roles) to the report")
+	secretsFile := flags.String("secrets-file", "", "Path to an openssl encrypted CSV file (repo,name,value) of Actions secrets set after the migration, decrypted with $SECRETS_PASSPHRASE")
+	workflows := flags.Bool("workflows", false, "Convert GitHub Actions workflows of migrated repositories for Gitea Actions on a branch and pull request")
+	workflowsRunnerLabels := flags.String("workflows-runner-labels", "", "Comma separated from=to runner label mappings applied to converted workflows, e.g. ubuntu-latest=linux")
+	downgradeArtifacts := flags.Bool("downgrade-artifacts", false, "Downgrade the v4 upload and download artifact actions of converted workflows to v3 when Gitea is older than 1.22")
+	listen := subcommands[CommandServe].Flags().String("listen", "127.0.0.1:8080", "Address of the read-only verification API, clients send $SERVE_TOKEN as bearer token, required beyond the loopback address")
+	environmentPrefixes := flags.String("environment-prefixes", "", "Comma separated environment=PREFIX mappings, e.g. production=PROD_, creating the secrets and variables of GitHub deployment environments with the prefix and renaming them in the converted workflows")
+	enableActions := flags.Bool("enable-actions", false, "Enable the Gitea Actions unit of the migrated repositories")
+	runnerTokensFile := flags.String("runner-tokens-file", "", "Write the Gitea Actions runner registration tokens of the migrated organization and repositories to this CSV file (owner,repo,token)")
+	byTeam := flags.String("by-team", "", "Only migrate the repositories of this GitHub team (slug), creating only its members and the organization owners")
+	reportStable := flags.Bool("report-stable", false, "Leave times and durations out of the report, so runs against the same source write identical reports")
+	archive := flags.Bool("archive", false, "Archive migrated repositories which are archived on GitHub, which stay writable otherwise")
+	recreateForks := flags.Bool("recreate-forks", false, "Create GitHub forks whose parent is migrated as Gitea forks of it, pushing their branches and tags (without issues and pull requests)")
+	labels := flags.String("labels", "", "Compare the colors and descriptions of migrated issue labels with GitHub: check reports differences, fix applies them again")
+	lfs := flags.Bool("lfs", false, "Migrate the Git LFS objects of repositories (the Gitea server needs LFS enabled)")
+	lfsEndpoint := flags.String("lfs-endpoint", "", "LFS server URL to fetch the objects from, instead of the GitHub LFS endpoint of each repository")
+	mirror := flags.Bool("mirror", false, "Create Gitea pull mirrors of the GitHub repositories instead of one-shot migrations")
+	mirrorInterval := flags.String("mirror-interval", "", "Interval between mirror syncs, e.g. 1h30m (Gitea default 8h)")
+	archiveSource := subcommands[CommandCutover].Flags().Bool("archive-source", false, "Archive the GitHub repositories once they are migrated")
+	releaseAssets := flags.Bool("release-assets", false, "Upload the GitHub release assets missing in the migrated releases, resuming interrupted downloads")
+	attachments := flags.Bool("attachments", false, "Copy the files attached to GitHub issues, pull requests and comments into Gitea and rewrite their links")
+	userKeysMax := flags.Int("user-keys-max", 0, "Maximum number of SSH keys migrated per user, the most recent ones (0 for no limit)")
+	socialRate := flags.Float64("social-rate", 10, "Maximum star, watch and follow calls per second made as the users (0 for no limit)")
+	socialBatch := flags.Int("social-batch", 10, "Number of star, watch and follow calls sent in a batch before pausing for social-rate")
+	reportAuthors := flags.Bool("report-authors", false, "Add the GitHub authors of migrated issues and comments not attributed to a Gitea user to the report")
+	digestFile := flags.String("digest-file", "", "Write the changes since the previous sync run (new repositories, members, failures) to this file (needs --sync and --state-file)")
+	digestURL := flags.String("digest-url", os.Getenv("DIGEST_URL"), "Post the changes since the previous sync run to this Slack compatible incoming webhook (needs --sync and --state-file)")
+	usernamesFile := flags.String("usernames", "", "Path to CSV file (login,username) creating GitHub users under another Gitea username")
+	emailRewrite := flags.String("email-rewrite", "", "Comma separated from=to email domain rewrites for created users, e.g. users.noreply.github.com=corp.example.com")
+	goModulesFile := flags.String("go-modules-file", "", "Write the Go module paths changed by the migration, with GOPRIVATE and replace suggestions, as JSON to this file")
+	goImports := flags.Bool("go-imports", false, "Open a pull request rewriting the go.mod module paths and import paths of migrated Go repositories to Gitea")
+	reportSigningKeys := flags.Bool("report-signing-keys", false, "Add the GPG and SSH keys which signed the recent verified commits of the migrated repositories to the report, grouped by user")
+	webhooksSecretsFile := flags.String("webhooks-secrets-file", "", "Generate new secrets for the migrated webhooks which had one on GitHub, and append them as CSV (target,url,secret) to this file")
+	reportApps := flags.Bool("report-apps", false, "Add the GitHub Apps installed on the source organization, their permissions and the Gitea token scopes replacing them, to the report")
+	oauth2Apps := flags.String("oauth2-apps", "", "Comma separated template=url integrations (argocd, drone, jenkins, woodpecker) to create a Gitea OAuth2 application for, e.g. jenkins=https://jenkins.example.com")
+	oauth2AppsFile := flags.String("oauth2-apps-file", "", "Append the client id and secret of the created OAuth2 applications as CSV to this file")
+	csvColumns := flags.String("csv-columns", "", "Comma separated 1-based columns of the user list fields, e.g. login=1,email=2,role=3 (default: found by header name, else columns 3, 4 and 5)")
+	migrationWindow := flags.String("migration-window", "", "Comma separated HH:MM-HH:MM daily windows (local time) the repository migrations may start in, e.g. 22:00-06:00; other work runs any time")
+	botLogins := flags.String("bot-logins", "", "Comma separated glob patterns of machine account logins, e.g. *-ci,dependabot, skipped like the GitHub accounts of type Bot")
+	repoOverrides := flags.String("repo-overrides", "", "Path to CSV file (repo,name,private,wiki,mirror) overriding the Gitea name, visibility, wiki and mirror mode of single repositories")
+	protectionPolicy := flags.String("protection-policy", "", "Path to CSV file (branch,push,approvals) of branch protection rules applied to every migrated repository, e.g. release/*,owners,2")
+	suspendedUsers := flags.String("suspended-users", "active", "How to create the users suspended on GitHub Enterprise Server: active, inactive (deactivated Gitea accounts) or skip (no account nor team membership)")
+	passwordPolicy := flags.String("password-policy", "none", "Local password of the created users: none (sign in through the authentication source), random (generated, must be changed at first sign-in) or mapping (password column of the user list)")
+	passwordsFile := flags.String("passwords-file", "", "Write the passwords generated with --password-policy random as CSV (login,username,password) to this file, only readable by its owner")
+	outsideCollaborators := flags.Bool("outside-collaborators", false, "Create the outside collaborators of the GitHub repositories, who are not organization members, and add them to the migrated repositories with their permission")
+	welcomeEmail := flags.Bool("welcome-email", false, "Email the created users their username, the Gitea URL, how to sign in and their SSH key status")
+	smtpAddr := flags.String("smtp-addr", "", "host:port of the SMTP server sending the welcome emails, using STARTTLS when offered")
+	smtpFrom := flags.String("smtp-from", "", "Sender address of the welcome emails, e.g. \"Gitea <gitea@example.com>\"")
+	smtpUsername := flags.String("smtp-username", "", "SMTP username, with the password read from $SMTP_PASSWORD")
+	auditLogFile := flags.String("audit-log-file", "", "Write the GitHub audit log events of the source organization during the run as JSON lines to this file, and log who pushed meanwhile (GitHub Enterprise, read:audit_log scope)")
+	auditLogSince := flags.String("audit-log-since", "", "Start the audit log at this RFC 3339 time, e.g. the code freeze, instead of the start of the run")
+	load = func(command string, args []string) *Config {
+		return &Config{
+			GHToken:               convert.FromPtr(ghToken),
+			GHSkipVerify:          convert.FromPtr(ghSkipVerify),
+			GHServer:              convert.FromPtr(ghServer),
+			GTServer:              convert.FromPtr(gtServer),
+			GTToken:               convert.FromPtr(gtToken),
+			GTSkipVerify:          convert.FromPtr(gtSkipVerify),
+			GTSourceID:            convert.FromPtr(gtSourceID),
+			APITimeout:            convert.FromPtr(apiTimeout),
+			SourceOrg:             convert.FromPtr(sourceOrg),
+			TargetOrg:             convert.FromPtr(targetOrg),
+			SourceUser:            convert.FromPtr(sourceUser),
+			TargetUser:            convert.FromPtr(targetUser),
+			OrgsFile:              convert.FromPtr(orgsFile),
+			UserListFile:          convert.FromPtr(userListFile),
+			CSVColumns:            convert.FromPtr(csvColumns),
+			MigrateUserRepos:      convert.FromPtr(migrateUserRepos),
+			ReportFile:            convert.FromPtr(reportFile),
+			Debug:                 convert.FromPtr(debug),
+			Version:               convert.FromPtr(version),
+			RmOrg:                 convert.FromPtr(rmOrg),
+			Yes:                   convert.FromPtr(yes),
+			GHRateLimitThreshold:  convert.FromPtr(ghRateLimitThreshold),
+			MaxRetries:            convert.FromPtr(maxRetries),
+			RetryBackoff:          convert.FromPtr(retryBackoff),
+			GHGraphQL:             convert.FromPtr(ghGraphQL),
+			Lang:                  convert.FromPtr(lang),
+			Target:                convert.FromPtr(target),
+			GHPageSize:            convert.FromPtr(ghPageSize),
+			GHRecordDir:           convert.FromPtr(ghRecord),
+			GHReplayDir:           convert.FromPtr(ghReplay),
+			UsersSkip:             convert.FromPtr(usersSkip),
+			MigrateTimeout:        convert.FromPtr(migrateTimeout),
+			GTReconcileEmail:      convert.FromPtr(gtReconcileEmail),
+			GitCredentials:        convert.FromPtr(gitCredentials),
+			ReportFormat:          convert.FromPtr(reportFormat),
+			ReportForks:           convert.FromPtr(reportForks),
+			ReportLicenses:        convert.FromPtr(reportLicenses),
+			LicenseTopics:         convert.FromPtr(licenseTopics),
+			OTelEndpoint:          convert.FromPtr(otelEndpoint),
+			OTelServiceName:       convert.FromPtr(otelServiceName),
+			OTelHeaders:           cmp.Or(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS"), os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+			TeamOverridesFile:     convert.FromPtr(teamOverrides),
+			TeamUnitsFile:         convert.FromPtr(teamUnits),
+			RepoOverridesFile:     convert.FromPtr(repoOverrides),
+			ProtectionPolicyFile:  convert.FromPtr(protectionPolicy),
+			AuditLogFile:          convert.FromPtr(auditLogFile),
+			AuditLogSince:         convert.FromPtr(auditLogSince),
+			Sync:                  convert.FromPtr(sync),
+			StateFile:             convert.FromPtr(stateFile),
+			MigrateStallTimeout:   convert.FromPtr(migrateStallTimeout),
+			Progress:              convert.FromPtr(progress),
+			UI:                    convert.FromPtr(ui),
+			MergeMessageTemplates: convert.FromPtr(mergeMessageTemplates),
+			ReportSignKey:         convert.FromPtr(reportSignKey),
+			ReportSignPassphrase:  os.Getenv("REPORT_SIGN_PASSPHRASE"),
+			Webhooks:              convert.FromPtr(webhooks),
+			WebhooksRewrite:       convert.FromPtr(webhooksRewrite),
+			WebhooksInactive:      convert.FromPtr(webhooksInactive),
+			WebhooksSecretsFile:   convert.FromPtr(webhooksSecretsFile),
+			Concurrency:           convert.FromPtr(concurrency),
+			Canary:                convert.FromPtr(canary),
+			MaxInflightSize:       convert.FromPtr(maxInflightSize),
+			MigrationWindow:       convert.FromPtr(migrationWindow),
+			BotLogins:             convert.FromPtr(botLogins),
+			OnDrift:               convert.FromPtr(onDrift),
+			SuspendedUsers:        convert.FromPtr(suspendedUsers),
+			PasswordPolicy:        convert.FromPtr(passwordPolicy),
+			PasswordsFile:         convert.FromPtr(passwordsFile),
+			OutsideCollaborators:  convert.FromPtr(outsideCollaborators),
+			WelcomeEmail:          convert.FromPtr(welcomeEmail),
+			SMTPAddr:              convert.FromPtr(smtpAddr),
+			SMTPFrom:              convert.FromPtr(smtpFrom),
+			SMTPUsername:          convert.FromPtr(smtpUsername),
+			SMTPPassword:          os.Getenv("SMTP_PASSWORD"),
+			Actions:               convert.FromPtr(actions),
+			ActionsSecretsFile:    convert.FromPtr(actionsSecretsFile),
+			SlowAPIThreshold:      convert.FromPtr(slowAPIThreshold),
+			ReportOrgRoles:        convert.FromPtr(reportOrgRoles),
+			ReportApps:            convert.FromPtr(reportApps),
+			OAuth2Apps:            convert.FromPtr(oauth2Apps),
+			OAuth2AppsFile:        convert.FromPtr(oauth2AppsFile),
+			ReportAuthors:         convert.FromPtr(reportAuthors),
+			ReportSigningKeys:     convert.FromPtr(reportSigningKeys),
+			UsernamesFile:         convert.FromPtr(usernamesFile),
+			EmailRewrite:          convert.FromPtr(emailRewrite),
+			GoModulesFile:         convert.FromPtr(goModulesFile),
+			GoImports:             convert.FromPtr(goImports),
+			DigestFile:            convert.FromPtr(digestFile),
+			DigestURL:             convert.FromPtr(digestURL),
+			SecretsFile:           convert.FromPtr(secretsFile),
+			SecretsPassphrase:     os.Getenv("SECRETS_PASSPHRASE"),
+			Workflows:             convert.FromPtr(workflows),
+			WorkflowsRunnerLabels: convert.FromPtr(workflowsRunnerLabels),
+			DowngradeArtifacts:    convert.FromPtr(downgradeArtifacts),
+			Listen:                convert.FromPtr(listen),
+			ServeToken:            os.Getenv("SERVE_TOKEN"),
+			EnvironmentPrefixes:   convert.FromPtr(environmentPrefixes),
+			EnableActions:         convert.FromPtr(enableActions),
+			RunnerTokensFile:      convert.FromPtr(runnerTokensFile),
+			ByTeam:                convert.FromPtr(byTeam),
+			Command:               command,
+			Args:                  args,
+			ReportStable:          convert.FromPtr(reportStable),
+			Archive:               convert.FromPtr(archive),
+			RecreateForks:         convert.FromPtr(recreateForks),
+			Labels:                convert.FromPtr(labels),
+			LFS:                   convert.FromPtr(lfs),
+			LFSEndpoint:           convert.FromPtr(lfsEndpoint),
+			Mirror:                convert.FromPtr(mirror),
+			MirrorInterval:        convert.FromPtr(mirrorInterval),
+			ArchiveSource:         convert.FromPtr(archiveSource),
+			ReleaseAssets:         convert.FromPtr(releaseAssets),
+			Attachments:           convert.FromPtr(attachments),
+			UserKeysMax:           convert.FromPtr(userKeysMax),
+			SocialRate:            convert.FromPtr(socialRate),
+			SocialBatch:           convert.FromPtr(socialBatch),
+		}
+	}
+	return root
+}
+
+// LongFlags rewrites the flags given with a single dash, like -gh-token, to their
+// double dash form, which the flags accepted before the subcommands were added.
+func LongFlags(args []string) []string {
+	long := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(long, args[i:]...)
+		}
+		if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' && unicode.IsLetter(rune(arg[1])) {
+			arg = "-" + arg
+		}
+		long = append(long, arg)
 	}
+	return long
 }
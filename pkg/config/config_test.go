@@ -0,0 +1,87 @@
+package config
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewCommand(t *testing.T) {
+	tests := []struct {
+		args    []string
+		command string
+		rest    []string
+		check   func(*Config) bool
+		err     bool
+	}{
+		{nil, "", nil, nil, false},
+		{[]string{"migrate"}, CommandMigrate, []string{}, nil, false},
+		{[]string{"migrate", "repo"}, CommandMigrateRepo, []string{}, nil, false},
+		{[]string{"migrate", "repo", "api", "web"}, CommandMigrateRepo, []string{"api", "web"}, nil, false},
+		{[]string{"export", "users"}, CommandExportUsers, []string{}, nil, false},
+		{[]string{"verify", "api"}, CommandVerify, []string{"api"}, nil, false},
+		// the shared flags come before or after the command
+		{[]string{"--source-org", "acme", "migrate", "repo", "api", "--concurrency", "4"}, CommandMigrateRepo, []string{"api"}, func(cfg *Config) bool {
+			return cfg.SourceOrg == "acme" && cfg.Concurrency == 4
+		}, false},
+		{[]string{"cleanup", "--yes"}, CommandCleanup, []string{}, func(cfg *Config) bool { return cfg.Yes }, false},
+		{[]string{"serve", "--listen", ":9090"}, CommandServe, []string{}, func(cfg *Config) bool { return cfg.Listen == ":9090" }, false},
+		{[]string{"cutover", "--archive-source"}, CommandCutover, []string{}, func(cfg *Config) bool { return cfg.ArchiveSource }, false},
+		// the flags of a command belong to it
+		{[]string{"migrate", "--yes"}, "", nil, nil, true},
+		{[]string{"migrate", "api"}, "", nil, nil, true},
+		{[]string{"migrate", "org", "api"}, "", nil, nil, true},
+		{[]string{"remove", "org"}, "", nil, nil, true},
+	}
+	for _, tt := range tests {
+		var cfg *Config
+		cmd := NewCommand(func(c *Config) { cfg = c })
+		cmd.SetArgs(tt.args)
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		err := cmd.Execute()
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: no error", tt.args)
+			}
+			continue
+		}
+		if err != nil || cfg == nil {
+			t.Errorf("%q: err = %v", tt.args, err)
+			continue
+		}
+		if cfg.Command != tt.command || !slices.Equal(cfg.Args, tt.rest) {
+			t.Errorf("%q: command %q %q, want %q %q", tt.args, cfg.Command, cfg.Args, tt.command, tt.rest)
+		}
+		if tt.check != nil && !tt.check(cfg) {
+			t.Errorf("%q: flags not set: %+v", tt.args, cfg)
+		}
+	}
+}
+
+func TestLongFlags(t *testing.T) {
+	args := []string{"-gh-token", "x", "--target-org", "acme", "-h", "migrate", "repo", "--", "-api"}
+	want := []string{"--gh-token", "x", "--target-org", "acme", "-h", "migrate", "repo", "--", "-api"}
+	if got := LongFlags(args); !slices.Equal(got, want) {
+		t.Errorf("LongFlags = %q, want %q", got, want)
+	}
+}
+
+func TestIsVaildCommandArgs(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		err     string
+	}{
+		{CommandMigrateOrg, []string{"api"}, "migrate org takes no arguments"},
+		{"remove org", nil, "unknown command: remove org"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{Command: tt.command, Args: tt.args}
+		err := cfg.IsVaild()
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s %q: err = %v, want %q", tt.command, tt.args, err, tt.err)
+		}
+	}
+}
@@ -16,7 +16,9 @@ const (
 	GitHubTeamPush     = "push"
 	GitHubTeamAdmin    = "admin"
 	GitHubTeamMaintain = "maintain"
-	GitHubTeamTriager  = "triager"
+	// GitHubTeamTriage is the triage permission of GitHub, read access which may also
+	// manage issues and pull requests.
+	GitHubTeamTriage = "triage"
 )
 
 var DefaultUnits = []gsdk.RepoUnitType{
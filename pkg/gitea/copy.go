@@ -0,0 +1,125 @@
+package gitea
+
+import (
+	"net/http"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// CopyRepoAccess copies the webhooks, collaborators, teams, branch protections and
+// stars of the repository owner/from to owner/to, e.g. from a pull mirror to the
+// regular repository replacing it. Gitea never returns webhook secrets, so the
+// copied webhooks have none, and the repository ID cannot be copied.
+func (g *Client) CopyRepoAccess(owner, from, to string) error {
+	hooks, err := g.ListRepoHooks(owner, from)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		_, resp, err := g.client.CreateRepoHook(owner, to, gsdk.CreateHookOption{
+			Type:         gsdk.HookType(hook.Type),
+			Config:       hook.Config,
+			Events:       hook.Events,
+			BranchFilter: hook.BranchFilter,
+			Active:       hook.Active,
+		})
+		if err != nil {
+			return wrapError("create_repo_hook", resp, err)
+		}
+	}
+
+	for page := 1; ; page++ {
+		users, resp, err := g.client.ListCollaborators(owner, from, gsdk.ListCollaboratorsOptions{
+			ListOptions: gsdk.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			return wrapError("list_collaborators", resp, err)
+		}
+		for _, user := range users {
+			perm, resp, err := g.client.CollaboratorPermission(owner, from, user.UserName)
+			if err != nil {
+				return wrapError("get_collaborator_permission", resp, err)
+			}
+			access := perm.Permission
+			if resp, err := g.client.AddCollaborator(owner, to, user.UserName, gsdk.AddCollaboratorOption{
+				Permission: &access,
+			}); err != nil {
+				return wrapError("add_collaborator", resp, err)
+			}
+		}
+		if len(users) < 50 {
+			break
+		}
+	}
+
+	teams, resp, err := g.client.GetRepoTeams(owner, from)
+	// only organization repositories have teams
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusMethodNotAllowed) {
+		return wrapError("list_repo_teams", resp, err)
+	}
+	for _, team := range teams {
+		if resp, err := g.client.AddRepoTeam(owner, to, team.Name); err != nil {
+			return wrapError("add_repo_team", resp, err)
+		}
+	}
+
+	rules, resp, err := g.client.ListBranchProtections(owner, from, gsdk.ListBranchProtectionsOptions{})
+	if err != nil {
+		return wrapError("list_branch_protections", resp, err)
+	}
+	for _, rule := range rules {
+		_, resp, err := g.client.CreateBranchProtection(owner, to, gsdk.CreateBranchProtectionOption{
+			BranchName:                    rule.BranchName,
+			RuleName:                      rule.RuleName,
+			EnablePush:                    rule.EnablePush,
+			EnablePushWhitelist:           rule.EnablePushWhitelist,
+			PushWhitelistUsernames:        rule.PushWhitelistUsernames,
+			PushWhitelistTeams:            rule.PushWhitelistTeams,
+			PushWhitelistDeployKeys:       rule.PushWhitelistDeployKeys,
+			EnableMergeWhitelist:          rule.EnableMergeWhitelist,
+			MergeWhitelistUsernames:       rule.MergeWhitelistUsernames,
+			MergeWhitelistTeams:           rule.MergeWhitelistTeams,
+			EnableStatusCheck:             rule.EnableStatusCheck,
+			StatusCheckContexts:           rule.StatusCheckContexts,
+			RequiredApprovals:             rule.RequiredApprovals,
+			EnableApprovalsWhitelist:      rule.EnableApprovalsWhitelist,
+			ApprovalsWhitelistUsernames:   rule.ApprovalsWhitelistUsernames,
+			ApprovalsWhitelistTeams:       rule.ApprovalsWhitelistTeams,
+			BlockOnRejectedReviews:        rule.BlockOnRejectedReviews,
+			BlockOnOfficialReviewRequests: rule.BlockOnOfficialReviewRequests,
+			BlockOnOutdatedBranch:         rule.BlockOnOutdatedBranch,
+			DismissStaleApprovals:         rule.DismissStaleApprovals,
+			RequireSignedCommits:          rule.RequireSignedCommits,
+			ProtectedFilePatterns:         rule.ProtectedFilePatterns,
+			UnprotectedFilePatterns:       rule.UnprotectedFilePatterns,
+		})
+		if err != nil {
+			return wrapError("create_branch_protection", resp, err)
+		}
+	}
+
+	for page := 1; ; page++ {
+		users, resp, err := g.client.ListRepoStargazers(owner, from, gsdk.ListStargazersOptions{
+			ListOptions: gsdk.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			return wrapError("list_stargazers", resp, err)
+		}
+		for _, user := range users {
+			if err := g.StarRepo(user.UserName, owner, to); err != nil {
+				return err
+			}
+		}
+		if len(users) < 50 {
+			return nil
+		}
+	}
+}
+
+// wrapError returns a GiteaError for the failed operation when the server answered.
+func wrapError(operation string, resp *gsdk.Response, err error) error {
+	if resp != nil {
+		return &GiteaError{Operation: operation, Code: resp.StatusCode, Message: err.Error()}
+	}
+	return err
+}
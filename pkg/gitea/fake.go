@@ -0,0 +1,617 @@
+package gitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// FakeServer is the base URL used with a Fake transport.
+const FakeServer = "http://gitea.fake"
+
+// FakeOperation is a write operation received by the fake Gitea server.
+type FakeOperation struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+}
+
+type fakeTeam struct {
+	org     string
+	team    *gsdk.Team
+	members map[string]bool
+	repos   map[string]bool
+}
+
+// Fake is an in-memory Gitea server implemented as an http.RoundTripper.
+// It keeps users, organizations, teams and repositories in memory and records
+// every write operation, so a full migration can be rehearsed without a server.
+// Endpoints it does not model accept writes and answer reads with 404.
+type Fake struct {
+	mux *http.ServeMux
+
+	mu     sync.Mutex
+	nextID int64
+	admin  *gsdk.User
+	users  map[string]*gsdk.User
+	orgs   map[string]*gsdk.Organization
+	teams  map[int64]*fakeTeam
+	repos  map[string]*gsdk.Repository
+	keys   map[string][]*gsdk.PublicKey
+	ops    []FakeOperation
+}
+
+// NewFake creates an empty in-memory Gitea server with a single admin user.
+func NewFake() *Fake {
+	f := &Fake{
+		mux:   http.NewServeMux(),
+		users: make(map[string]*gsdk.User),
+		orgs:  make(map[string]*gsdk.Organization),
+		teams: make(map[int64]*fakeTeam),
+		repos: make(map[string]*gsdk.Repository),
+		keys:  make(map[string][]*gsdk.PublicKey),
+	}
+	f.admin = &gsdk.User{
+		ID:        f.id(),
+		UserName:  "fake-admin",
+		LoginName: "fake-admin",
+		FullName:  "Fake Admin",
+		Email:     "admin@gitea.fake",
+		IsAdmin:   true,
+		IsActive:  true,
+	}
+	f.users[f.admin.UserName] = f.admin
+
+	f.mux.HandleFunc("GET /api/v1/version", f.version)
+	f.mux.HandleFunc("GET /api/v1/user", f.currentUser)
+	f.mux.HandleFunc("GET /api/v1/users/{username}", f.getUser)
+	f.mux.HandleFunc("GET /api/v1/users/{username}/repos", f.listOwnerRepos)
+	f.mux.HandleFunc("GET /api/v1/admin/users", f.listUsers)
+	f.mux.HandleFunc("POST /api/v1/admin/users", f.createUser)
+	f.mux.HandleFunc("POST /api/v1/admin/users/{username}/keys", f.createKey)
+	f.mux.HandleFunc("GET /api/v1/orgs/{org}", f.getOrg)
+	f.mux.HandleFunc("POST /api/v1/orgs", f.createOrg)
+	f.mux.HandleFunc("DELETE /api/v1/orgs/{org}", f.deleteOrg)
+	f.mux.HandleFunc("GET /api/v1/orgs/{org}/repos", f.listOwnerRepos)
+	f.mux.HandleFunc("GET /api/v1/orgs/{org}/teams/search", f.searchTeams)
+	f.mux.HandleFunc("POST /api/v1/orgs/{org}/teams", f.createTeam)
+	f.mux.HandleFunc("PUT /api/v1/teams/{id}/members/{username}", f.addTeamMember)
+	f.mux.HandleFunc("PUT /api/v1/teams/{id}/repos/{org}/{repo}", f.addTeamRepo)
+	f.mux.HandleFunc("POST /api/v1/repos/migrate", f.migrateRepo)
+	f.mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}", f.getRepo)
+	f.mux.HandleFunc("DELETE /api/v1/repos/{owner}/{repo}", f.deleteRepo)
+	f.mux.HandleFunc("GET /{owner}/{repo}/-/migrate/status", f.migrationStatus)
+	f.mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}/hooks", f.emptyList)
+	f.mux.HandleFunc("GET /api/v1/orgs/{org}/hooks", f.emptyList)
+	f.mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}/actions/secrets", f.emptyList)
+	f.mux.HandleFunc("GET /api/v1/orgs/{org}/actions/secrets", f.emptyList)
+	f.mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}/releases", f.emptyList)
+	f.mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}/issues", f.emptyList)
+	f.mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}/issues/comments", f.emptyList)
+	f.mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}/branch_protections", f.emptyList)
+	f.mux.HandleFunc("GET /api/v1/orgs/{org}/actions/runners/registration-token", f.runnerToken)
+	f.mux.HandleFunc("GET /api/v1/repos/{owner}/{repo}/actions/runners/registration-token", f.runnerToken)
+	f.mux.HandleFunc("/", f.fallback)
+
+	return f
+}
+
+// fakeResponse records the response of a handler of the fake server.
+type fakeResponse struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func (w *fakeResponse) Header() http.Header {
+	return w.header
+}
+
+func (w *fakeResponse) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *fakeResponse) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// RoundTrip serves the request from the in-memory state.
+func (f *Fake) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := &fakeResponse{header: make(http.Header)}
+	f.mux.ServeHTTP(rec, req)
+	rec.WriteHeader(http.StatusOK)
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.status, http.StatusText(rec.status)),
+		StatusCode:    rec.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.header,
+		Body:          io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+		ContentLength: int64(rec.body.Len()),
+		Request:       req,
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		f.mu.Lock()
+		f.ops = append(f.ops, FakeOperation{
+			Time:   time.Now(),
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Status: resp.StatusCode,
+		})
+		f.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// Operations returns the write operations received so far, in order.
+func (f *Fake) Operations() []FakeOperation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ops := make([]FakeOperation, len(f.ops))
+	copy(ops, f.ops)
+	return ops
+}
+
+func (f *Fake) id() int64 {
+	f.nextID++
+	return f.nextID
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeMessage(w http.ResponseWriter, code int, format string, args ...any) {
+	writeJSON(w, code, map[string]string{"message": fmt.Sprintf(format, args...)})
+}
+
+func repoKey(owner, name string) string {
+	return strings.ToLower(owner + "/" + name)
+}
+
+func (f *Fake) version(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"version": "1.22.0"})
+}
+
+func (f *Fake) currentUser(w http.ResponseWriter, _ *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	writeJSON(w, http.StatusOK, f.admin)
+}
+
+func (f *Fake) getUser(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := strings.ToLower(r.PathValue("username"))
+	if user, ok := f.users[name]; ok {
+		writeJSON(w, http.StatusOK, user)
+		return
+	}
+	if org, ok := f.orgs[name]; ok {
+		writeJSON(w, http.StatusOK, &gsdk.User{ID: org.ID, UserName: org.UserName, FullName: org.FullName})
+		return
+	}
+	writeMessage(w, http.StatusNotFound, "user redirect does not exist [name: %s]", name)
+}
+
+func (f *Fake) createUser(w http.ResponseWriter, r *http.Request) {
+	var opt gsdk.CreateUserOption
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		writeMessage(w, http.StatusUnprocessableEntity, "%s", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := strings.ToLower(opt.Username)
+	if _, ok := f.users[name]; ok {
+		writeMessage(w, http.StatusUnprocessableEntity, "user already exists [name: %s]", opt.Username)
+		return
+	}
+	if _, ok := f.orgs[name]; ok {
+		writeMessage(w, http.StatusUnprocessableEntity, "user already exists [name: %s]", opt.Username)
+		return
+	}
+	user := &gsdk.User{
+		ID:        f.id(),
+		UserName:  opt.Username,
+		LoginName: opt.LoginName,
+		SourceID:  opt.SourceID,
+		FullName:  opt.FullName,
+		Email:     opt.Email,
+		IsActive:  true,
+		Created:   time.Now(),
+	}
+	f.users[name] = user
+	writeJSON(w, http.StatusCreated, user)
+}
+
+func (f *Fake) listUsers(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := make([]string, 0, len(f.users))
+	for name := range f.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 50
+	}
+	users := make([]*gsdk.User, 0, limit)
+	for i := (page - 1) * limit; i < len(names) && len(users) < limit; i++ {
+		users = append(users, f.users[names[i]])
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+func (f *Fake) createKey(w http.ResponseWriter, r *http.Request) {
+	var opt gsdk.CreateKeyOption
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		writeMessage(w, http.StatusUnprocessableEntity, "%s", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := strings.ToLower(r.PathValue("username"))
+	if _, ok := f.users[name]; !ok {
+		writeMessage(w, http.StatusNotFound, "user does not exist [name: %s]", name)
+		return
+	}
+	for _, keys := range f.keys {
+		for _, key := range keys {
+			if key.Key == opt.Key {
+				writeMessage(w, http.StatusUnprocessableEntity, "Key content has been used as non-deploy key")
+				return
+			}
+		}
+	}
+	key := &gsdk.PublicKey{ID: f.id(), Key: opt.Key, Title: opt.Title}
+	f.keys[name] = append(f.keys[name], key)
+	writeJSON(w, http.StatusCreated, key)
+}
+
+func (f *Fake) getOrg(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	org, ok := f.orgs[strings.ToLower(r.PathValue("org"))]
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "org does not exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, org)
+}
+
+func (f *Fake) createOrg(w http.ResponseWriter, r *http.Request) {
+	var opt gsdk.CreateOrgOption
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		writeMessage(w, http.StatusUnprocessableEntity, "%s", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := strings.ToLower(opt.Name)
+	if _, ok := f.orgs[name]; ok {
+		writeMessage(w, http.StatusUnprocessableEntity, "user already exists [name: %s]", opt.Name)
+		return
+	}
+	org := &gsdk.Organization{
+		ID:          f.id(),
+		UserName:    opt.Name,
+		FullName:    opt.FullName,
+		Description: opt.Description,
+		Website:     opt.Website,
+		Location:    opt.Location,
+		Visibility:  string(opt.Visibility),
+	}
+	f.orgs[name] = org
+
+	// Gitea creates the Owners team together with the organization
+	owners := &gsdk.Team{
+		ID:                      f.id(),
+		Name:                    "Owners",
+		Permission:              gsdk.AccessModeOwner,
+		CanCreateOrgRepo:        true,
+		IncludesAllRepositories: true,
+	}
+	f.teams[owners.ID] = &fakeTeam{
+		org:     name,
+		team:    owners,
+		members: map[string]bool{f.admin.UserName: true},
+		repos:   make(map[string]bool),
+	}
+	writeJSON(w, http.StatusCreated, org)
+}
+
+func (f *Fake) deleteOrg(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := strings.ToLower(r.PathValue("org"))
+	if _, ok := f.orgs[name]; !ok {
+		writeMessage(w, http.StatusNotFound, "org does not exist")
+		return
+	}
+	for key := range f.repos {
+		if strings.HasPrefix(key, name+"/") {
+			writeMessage(w, http.StatusUnprocessableEntity, "org still has ownership of repositories")
+			return
+		}
+	}
+	delete(f.orgs, name)
+	for id, team := range f.teams {
+		if team.org == name {
+			delete(f.teams, id)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *Fake) listOwnerRepos(w http.ResponseWriter, r *http.Request) {
+	owner := r.PathValue("org")
+	if owner == "" {
+		owner = r.PathValue("username")
+	}
+	prefix := strings.ToLower(owner) + "/"
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0)
+	for key := range f.repos {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 50
+	}
+	repos := make([]*gsdk.Repository, 0, limit)
+	for i := (page - 1) * limit; i < len(keys) && len(repos) < limit; i++ {
+		repos = append(repos, f.repos[keys[i]])
+	}
+	writeJSON(w, http.StatusOK, repos)
+}
+
+func (f *Fake) searchTeams(w http.ResponseWriter, r *http.Request) {
+	org := strings.ToLower(r.PathValue("org"))
+	query := strings.ToLower(r.URL.Query().Get("q"))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := make([]int64, 0)
+	for id, team := range f.teams {
+		if team.org == org && strings.Contains(strings.ToLower(team.team.Name), query) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	teams := make([]*gsdk.Team, 0, len(ids))
+	for _, id := range ids {
+		teams = append(teams, f.teams[id].team)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "data": teams})
+}
+
+func (f *Fake) createTeam(w http.ResponseWriter, r *http.Request) {
+	var opt gsdk.CreateTeamOption
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		writeMessage(w, http.StatusUnprocessableEntity, "%s", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	org := strings.ToLower(r.PathValue("org"))
+	if _, ok := f.orgs[org]; !ok {
+		writeMessage(w, http.StatusNotFound, "org does not exist")
+		return
+	}
+	for _, team := range f.teams {
+		if team.org == org && strings.EqualFold(team.team.Name, opt.Name) {
+			writeMessage(w, http.StatusUnprocessableEntity, "team already exists [name: %s]", opt.Name)
+			return
+		}
+	}
+	team := &gsdk.Team{
+		ID:                      f.id(),
+		Name:                    opt.Name,
+		Description:             opt.Description,
+		Permission:              opt.Permission,
+		CanCreateOrgRepo:        opt.CanCreateOrgRepo,
+		IncludesAllRepositories: opt.IncludesAllRepositories,
+		Units:                   opt.Units,
+	}
+	f.teams[team.ID] = &fakeTeam{
+		org:     org,
+		team:    team,
+		members: make(map[string]bool),
+		repos:   make(map[string]bool),
+	}
+	writeJSON(w, http.StatusCreated, team)
+}
+
+func (f *Fake) team(w http.ResponseWriter, r *http.Request) (*fakeTeam, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, "invalid team id")
+		return nil, false
+	}
+	team, ok := f.teams[id]
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "team does not exist")
+		return nil, false
+	}
+	return team, true
+}
+
+func (f *Fake) addTeamMember(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	team, ok := f.team(w, r)
+	if !ok {
+		return
+	}
+	name := strings.ToLower(r.PathValue("username"))
+	if _, ok := f.users[name]; !ok {
+		writeMessage(w, http.StatusNotFound, "user does not exist [name: %s]", name)
+		return
+	}
+	team.members[name] = true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *Fake) addTeamRepo(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	team, ok := f.team(w, r)
+	if !ok {
+		return
+	}
+	key := repoKey(r.PathValue("org"), r.PathValue("repo"))
+	if _, ok := f.repos[key]; !ok {
+		writeMessage(w, http.StatusNotFound, "repository does not exist")
+		return
+	}
+	team.repos[key] = true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *Fake) migrateRepo(w http.ResponseWriter, r *http.Request) {
+	var opt gsdk.MigrateRepoOption
+	if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+		writeMessage(w, http.StatusUnprocessableEntity, "%s", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	owner := strings.ToLower(opt.RepoOwner)
+	ownerUser, isUser := f.users[owner]
+	org, isOrg := f.orgs[owner]
+	if !isUser && !isOrg {
+		writeMessage(w, http.StatusUnprocessableEntity, "owner does not exist [name: %s]", opt.RepoOwner)
+		return
+	}
+	key := repoKey(opt.RepoOwner, opt.RepoName)
+	if _, ok := f.repos[key]; ok {
+		writeMessage(w, http.StatusConflict, "The repository with the same name already exists.")
+		return
+	}
+	if isOrg {
+		ownerUser = &gsdk.User{ID: org.ID, UserName: org.UserName, FullName: org.FullName}
+	}
+	now := time.Now()
+	repo := &gsdk.Repository{
+		ID:              f.id(),
+		Owner:           ownerUser,
+		Name:            opt.RepoName,
+		FullName:        ownerUser.UserName + "/" + opt.RepoName,
+		Description:     opt.Description,
+		Private:         opt.Private,
+		Mirror:          opt.Mirror,
+		MirrorInterval:  opt.MirrorInterval,
+		HTMLURL:         FakeServer + "/" + ownerUser.UserName + "/" + opt.RepoName,
+		CloneURL:        FakeServer + "/" + ownerUser.UserName + "/" + opt.RepoName + ".git",
+		OriginalURL:     opt.CloneAddr,
+		DefaultBranch:   "main",
+		HasIssues:       opt.Issues,
+		HasWiki:         opt.Wiki,
+		HasPullRequests: opt.PullRequests,
+		HasReleases:     opt.Releases,
+		Created:         now,
+		Updated:         now,
+	}
+	f.repos[key] = repo
+	writeJSON(w, http.StatusCreated, repo)
+}
+
+func (f *Fake) getRepo(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	repo, ok := f.repos[repoKey(r.PathValue("owner"), r.PathValue("repo"))]
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "repository does not exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, repo)
+}
+
+func (f *Fake) deleteRepo(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := repoKey(r.PathValue("owner"), r.PathValue("repo"))
+	if _, ok := f.repos[key]; !ok {
+		writeMessage(w, http.StatusNotFound, "repository does not exist")
+		return
+	}
+	delete(f.repos, key)
+	for _, team := range f.teams {
+		delete(team.repos, key)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// migrationStatus reports every migrated repository as done, since fake migrations are synchronous.
+func (f *Fake) migrationStatus(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.repos[repoKey(r.PathValue("owner"), r.PathValue("repo"))]; !ok {
+		writeMessage(w, http.StatusNotFound, "task does not exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": 4, "message": ""})
+}
+
+// emptyList serves list endpoints whose items are not modeled.
+func (f *Fake) emptyList(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, []any{})
+}
+
+// runnerToken serves a fixed runner registration token, rehearsals register no runner.
+func (f *Fake) runnerToken(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"token": "fake-runner-token"})
+}
+
+// fallback accepts writes to endpoints that are not modeled and reports reads as missing.
+func (f *Fake) fallback(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		writeMessage(w, http.StatusNotFound, "not found")
+	case http.MethodPost:
+		writeJSON(w, http.StatusCreated, map[string]any{})
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{})
+	}
+}
@@ -0,0 +1,71 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/appleboy/github2gitea/pkg/core"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+func newFakeClient(t *testing.T) (*Client, *Fake) {
+	t.Helper()
+	fake := NewFake()
+	client, err := New(context.Background(), &Config{
+		Server:    FakeServer,
+		Token:     "fake",
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Transport: fake,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, fake
+}
+
+func TestFakeTeams(t *testing.T) {
+	client, fake := newFakeClient(t)
+	if _, err := client.CreateAndGetOrg(CreateOrgOption{Name: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	team, err := client.CreateOrGetTeam("acme", CreateTeamOption{Name: "readers", Permission: core.GitHubTeamPull})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if team.Permission != gsdk.AccessModeRead {
+		t.Errorf("permission = %s, want read", team.Permission)
+	}
+
+	// the existing team is returned
+	again, err := client.CreateOrGetTeam("acme", CreateTeamOption{Name: "Readers", Permission: core.GitHubTeamPull})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != team.ID {
+		t.Errorf("team id = %d, want %d", again.ID, team.ID)
+	}
+
+	var posts int
+	for _, op := range fake.Operations() {
+		if op.Method == http.MethodPost && op.Path == "/api/v1/orgs/acme/teams" {
+			posts++
+		}
+	}
+	if posts != 1 {
+		t.Errorf("team created %d times, want once", posts)
+	}
+}
+
+func TestFakeNotFound(t *testing.T) {
+	client, _ := newFakeClient(t)
+	_, err := client.GetRepo("acme", "missing")
+	var giteaErr *GiteaError
+	if !errors.As(err, &giteaErr) || giteaErr.Code != http.StatusNotFound {
+		t.Fatalf("err = %v, want 404", err)
+	}
+}
@@ -0,0 +1,102 @@
+package gitea
+
+import (
+	"fmt"
+)
+
+// Feature is an optional Gitea API, used when the server is recent enough.
+type Feature string
+
+// Optional features of the Gitea API.
+const (
+	// FeatureTeamUnits is the per-unit permissions of the teams.
+	FeatureTeamUnits Feature = "per-unit team permissions"
+	// FeatureTopics is the repository topics API.
+	FeatureTopics Feature = "repository topics"
+	// FeatureActionsSecrets is the Actions secrets API of organizations and repositories.
+	FeatureActionsSecrets Feature = "actions secrets"
+	// FeatureActionsVariables is the Actions variables API of organizations and repositories.
+	FeatureActionsVariables Feature = "actions variables"
+	// FeatureChangeFiles commits changes of several files at once.
+	FeatureChangeFiles Feature = "changing several files in one commit"
+	// FeatureRunnerTokens is the Actions runner registration token API.
+	FeatureRunnerTokens Feature = "runner registration tokens"
+	// FeatureArtifactsV4 is the artifact service of the v4 upload and download artifact actions.
+	FeatureArtifactsV4 Feature = "v4 artifact actions"
+	// FeatureExternalAssets is the release attachments linking to an external URL.
+	FeatureExternalAssets Feature = "external release attachments"
+)
+
+// features are the optional features, in the order of the versions introducing them.
+var features = []Feature{
+	FeatureTopics,
+	FeatureTeamUnits,
+	FeatureChangeFiles,
+	FeatureActionsSecrets,
+	FeatureActionsVariables,
+	FeatureRunnerTokens,
+	FeatureArtifactsV4,
+	FeatureExternalAssets,
+}
+
+// featureVersions are the Gitea versions introducing the features.
+var featureVersions = map[Feature]string{
+	FeatureTopics:           "1.9.0",
+	FeatureTeamUnits:        "1.17.0",
+	FeatureChangeFiles:      "1.20.0",
+	FeatureActionsSecrets:   "1.21.0",
+	FeatureActionsVariables: "1.22.0",
+	FeatureRunnerTokens:     "1.22.0",
+	FeatureArtifactsV4:      "1.22.0",
+	FeatureExternalAssets:   "1.23.0",
+}
+
+// UnsupportedError is returned by the calls needing a newer Gitea than the server.
+type UnsupportedError struct {
+	Feature Feature
+	// Version is the version of the server, Needs the version introducing the feature.
+	Version string
+	Needs   string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("gitea %s does not support %s, it needs gitea %s or later", e.Version, e.Feature, e.Needs)
+}
+
+// Version returns the version of the Gitea server detected when the client was created.
+func (g *Client) Version() string {
+	return g.version
+}
+
+// Unsupported returns the features the Gitea server is too old for, without logging them.
+func (g *Client) Unsupported() []*UnsupportedError {
+	var missing []*UnsupportedError
+	for _, feature := range features {
+		needs := featureVersions[feature]
+		if g.client.CheckServerVersionConstraint(">= "+needs) != nil {
+			missing = append(missing, &UnsupportedError{Feature: feature, Version: g.version, Needs: needs})
+		}
+	}
+	return missing
+}
+
+// Supports reports whether the Gitea server has the feature. The first time a
+// feature is missing, it logs that the feature is skipped.
+func (g *Client) Supports(feature Feature) bool {
+	return g.require(feature) == nil
+}
+
+// require returns an UnsupportedError when the Gitea server does not have the feature.
+func (g *Client) require(feature Feature) error {
+	needs := featureVersions[feature]
+	if needs == "" || g.client.CheckServerVersionConstraint(">= "+needs) == nil {
+		return nil
+	}
+	if _, logged := g.skipped.LoadOrStore(feature, true); !logged && g.logger != nil {
+		g.logger.Warn("gitea is too old, skipping "+string(feature),
+			"version", g.version,
+			"needs", needs,
+		)
+	}
+	return &UnsupportedError{Feature: feature, Version: g.version, Needs: needs}
+}
@@ -3,15 +3,27 @@ package gitea
 // Package gitea provides a client and helper functions for interacting with a Gitea server.
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/appleboy/github2gitea/pkg/core"
+	"github.com/appleboy/github2gitea/pkg/redact"
+	"github.com/appleboy/github2gitea/pkg/retry"
+	"github.com/appleboy/github2gitea/pkg/trace"
 
 	gsdk "code.gitea.io/sdk/gitea"
 )
@@ -28,7 +40,7 @@ type GiteaError struct {
 
 func (e *GiteaError) Error() string {
 	// Error implements the error interface for GiteaError.
-	return fmt.Sprintf("gitea %s failed: [%d] %s", e.Operation, e.Code, e.Message)
+	return redact.String(fmt.Sprintf("gitea %s failed: [%d] %s", e.Operation, e.Code, e.Message))
 }
 
 type Config struct {
@@ -42,6 +54,24 @@ type Config struct {
 	SourceID int64
 	// Logger is the logger instance for logging.
 	Logger *slog.Logger
+	// MaxRetries is the number of retries for transient errors.
+	MaxRetries int
+	// RetryBackoff is the initial delay between retries.
+	RetryBackoff time.Duration
+	// Transport replaces the HTTP transport, e.g. with a Fake server for rehearsals.
+	Transport http.RoundTripper
+	// ReconcileEmail matches new users to existing externally authenticated users by email.
+	ReconcileEmail bool
+	// OnMigrationState is called whenever the task status of a repository migration changes.
+	OnMigrationState func(owner, name string, state MigrationState, message string)
+	// SlowThreshold logs API calls taking longer than this, disabled when zero.
+	SlowThreshold time.Duration
+	// Usernames maps lowercase source logins to the Gitea usernames they are created as.
+	Usernames map[string]string
+	// GitCredentials is where the local git binary of the push fallback gets the
+	// credentials from: GitCredentialsURL, GitCredentialsNetrc or GitCredentialsHelper.
+	// Empty is GitCredentialsURL.
+	GitCredentials string
 }
 
 // New creates a new Gitea client with the provided configuration and context.
@@ -51,6 +81,7 @@ func New(ctx context.Context, cfg *Config) (*Client, error) {
 		return nil, errors.New("invalid gitea server: must start with http:// or https://")
 	}
 
+	redact.Add(cfg.Token)
 	g := &Client{
 		ctx:        ctx,
 		server:     cfg.Server,
@@ -58,6 +89,17 @@ func New(ctx context.Context, cfg *Config) (*Client, error) {
 		skipVerify: cfg.SkipVerify,
 		sourceID:   cfg.SourceID,
 		logger:     cfg.Logger,
+		maxRetries: cfg.MaxRetries,
+		backoff:    cfg.RetryBackoff,
+		transport:  cfg.Transport,
+		reconcile:  cfg.ReconcileEmail,
+		onState:    cfg.OnMigrationState,
+		slow:       cfg.SlowThreshold,
+		usernames:  make(map[string]string, len(cfg.Usernames)),
+		gitCreds:   cfg.GitCredentials,
+	}
+	for login, username := range cfg.Usernames {
+		g.usernames[strings.ToLower(login)] = username
 	}
 
 	err := g.init()
@@ -77,6 +119,30 @@ type Client struct {
 	sourceID   int64
 	client     *gsdk.Client
 	logger     *slog.Logger
+	maxRetries int
+	backoff    time.Duration
+	transport  http.RoundTripper
+	httpClient *http.Client
+	onState    func(owner, name string, state MigrationState, message string)
+	slow       time.Duration
+	gitCreds   string
+
+	reconcile bool
+	// emails indexes existing external users by lowercase email, loaded on first use.
+	emails map[string]*gsdk.User
+	// usernames maps source logins to the Gitea usernames they were mapped or reconciled to.
+	usernames map[string]string
+	usersMu   sync.Mutex
+
+	// migrationsBlocked is set once the server refused the migrate API.
+	migrationsBlocked atomic.Bool
+	// gitOnly holds the owner/name of the repositories pushed by the fallback.
+	gitOnly sync.Map
+
+	// version is the version of the server, queried when the client is created.
+	version string
+	// skipped holds the features logged as missing from the server.
+	skipped sync.Map
 }
 
 // init initializes the underlying Gitea SDK client.
@@ -94,15 +160,33 @@ func (g *Client) init() error {
 		gsdk.SetUserAgent("github2gitea"),
 	}
 
-	if g.skipVerify {
-		// add new http client for skip verify
-		httpClient := &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
-			},
+	var transport http.RoundTripper = g.transport
+	if transport == nil {
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		if g.skipVerify {
+			// skip tls verification for self-signed certificates
+			base.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec
 		}
-		opts = append(opts, gsdk.SetHTTPClient(httpClient))
+		transport = base
+	}
+	httpClient := &http.Client{
+		Transport: &trace.Transport{
+			Base: &retry.Transport{
+				Base: &trace.SlowTransport{
+					Base:      transport,
+					System:    "gitea",
+					Threshold: g.slow,
+					Logger:    g.logger,
+				},
+				MaxRetries: g.maxRetries,
+				Backoff:    g.backoff,
+				Logger:     g.logger,
+			},
+			System: "gitea",
+		},
 	}
+	g.httpClient = httpClient
+	opts = append(opts, gsdk.SetHTTPClient(httpClient))
 
 	client, err := gsdk.NewClient(g.server, opts...)
 	if err != nil {
@@ -110,6 +194,15 @@ func (g *Client) init() error {
 	}
 	g.client = client
 
+	version, _, err := client.ServerVersion()
+	if err != nil {
+		return fmt.Errorf("detect gitea version: %w", err)
+	}
+	g.version = version
+	if g.logger != nil {
+		g.logger.Info("detected gitea version", "version", version)
+	}
+
 	return nil
 }
 
@@ -123,14 +216,46 @@ func (g *Client) GetCurrentUser() (*gsdk.User, error) {
 	return user, nil
 }
 
+// GetUser retrieves a user's information by username.
+// Returns a GiteaError with the response status code if the request fails.
+func (g *Client) GetUser(username string) (*gsdk.User, error) {
+	user, resp, err := g.client.GetUserInfo(username)
+	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "get_user_info", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
 // CreateOrgOption contains options for creating a Gitea organization.
 type CreateOrgOption struct {
 	// Name is the organization name.
 	Name string
+	// FullName is the human-readable display name of the organization.
+	FullName string
 	// Description is the organization description.
 	Description string
+	// Website and Location complete the organization profile.
+	Website  string
+	Location string
 	// Visibility sets the visibility of the organization.
 	Visibility gsdk.VisibleType
+	// Avatar is the organization avatar image, uploaded once the organization is created.
+	Avatar []byte
+}
+
+// GetOrg retrieves an existing organization.
+func (g *Client) GetOrg(name string) (*gsdk.Organization, error) {
+	org, resp, err := g.client.GetOrg(name)
+	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "get_org", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return org, nil
 }
 
 // CreateAndGetOrg retrieves an existing organization or creates a new one if it does not exist.
@@ -145,13 +270,22 @@ func (g *Client) CreateAndGetOrg(opts CreateOrgOption) (*gsdk.Organization, erro
 			var createErr error
 			newOrg, _, createErr = g.client.CreateOrg(gsdk.CreateOrgOption{
 				Name:        opts.Name,
+				FullName:    opts.FullName,
 				Description: opts.Description,
+				Website:     opts.Website,
+				Location:    opts.Location,
 				Visibility:  visible,
 			})
 			if createErr != nil {
 				// Use the original 404 status code as per the original logic
 				return nil, &GiteaError{Operation: "create_org", Code: response.StatusCode, Message: createErr.Error()}
 			}
+			if len(opts.Avatar) > 0 {
+				// the organization is usable without its avatar
+				if avatarErr := g.UpdateOrgAvatar(newOrg.UserName, opts.Avatar); avatarErr != nil && g.logger != nil {
+					g.logger.Warn("update org avatar failed", "org", newOrg.UserName, "err", avatarErr)
+				}
+			}
 			// If creation succeeded, reset err so we return the new org below
 			err = nil
 		case response != nil:
@@ -184,35 +318,174 @@ type MigrateRepoOption struct {
 	AuthUsername string
 	// AuthToken is the token/password for authentication to the source repository.
 	AuthToken string
+	// Timeout limits how long to wait for the migration task, no limit when zero.
+	Timeout time.Duration
+	// StallTimeout abandons the migration task when it makes no progress for this long, disabled when zero.
+	StallTimeout time.Duration
+	// LFS migrates the Git LFS objects, from LFSEndpoint when set instead of the
+	// endpoint derived from CloneAddr.
+	LFS         bool
+	LFSEndpoint string
+	// Mirror creates a pull mirror of CloneAddr, synced by Gitea every MirrorInterval
+	// (a Go duration, the server default when empty). Mirrors have no issues, pull
+	// requests, milestones or labels.
+	Mirror         bool
+	MirrorInterval string
+	// SkipWiki leaves out the wiki of the source repository.
+	SkipWiki bool
+	// ForkOwner and ForkName identify the Gitea repository the new repository is a fork of.
+	// The fork is created in Gitea and the git content pushed, without issues and pull requests.
+	ForkOwner string
+	ForkName  string
 }
 
+// errMirrorBlocked is returned for pull mirrors when the server refuses migrations,
+// a pushed repository cannot follow GitHub.
+var errMirrorBlocked = errors.New("gitea refuses repository migrations, pull mirrors cannot be created")
+
 // MigrateRepo migrates a repository from a remote source to Gitea.
+// The migration is submitted and its task status polled until it finishes,
+// fails or the timeout expires; see waitMigration. An empty repository left by an
+// earlier failed migration is deleted first. When the server has migrations
+// disabled, the repository is created empty and the git content pushed instead.
 // Returns a pointer to the new Repository and an error if the migration fails.
 func (g *Client) MigrateRepo(opts MigrateRepoOption) (*gsdk.Repository, error) {
 	if opts.RepoName == "" || opts.RepoOwner == "" || opts.CloneAddr == "" {
 		return nil, errors.New("missing required migration parameters: RepoName, RepoOwner and CloneAddr are required")
 	}
-	newRepo, _, err := g.client.MigrateRepo(gsdk.MigrateRepoOption{
-		RepoName:     opts.RepoName,
-		RepoOwner:    opts.RepoOwner,
-		CloneAddr:    opts.CloneAddr,
-		Private:      opts.Private,
-		Description:  opts.Description,
-		AuthUsername: opts.AuthUsername,
-		AuthToken:    opts.AuthToken,
-		Service:      gsdk.GitServiceGithub,
-		Wiki:         true,
-		Milestones:   true,
-		Issues:       true,
-		Releases:     true,
-		Labels:       true,
-		PullRequests: true,
+	if err := g.removeFailedMigration(opts); err != nil {
+		return nil, err
+	}
+	if opts.ForkOwner != "" {
+		return g.forkRepo(opts)
+	}
+	if g.migrationsBlocked.Load() {
+		if opts.Mirror {
+			return nil, errMirrorBlocked
+		}
+		return g.pushFallback(opts, nil)
+	}
+	repo, err := g.waitMigration(opts, gsdk.MigrateRepoOption{
+		RepoName:       opts.RepoName,
+		RepoOwner:      opts.RepoOwner,
+		CloneAddr:      opts.CloneAddr,
+		Private:        opts.Private,
+		Description:    opts.Description,
+		AuthUsername:   opts.AuthUsername,
+		AuthToken:      opts.AuthToken,
+		Service:        gsdk.GitServiceGithub,
+		Mirror:         opts.Mirror,
+		MirrorInterval: opts.MirrorInterval,
+		Wiki:           !opts.SkipWiki,
+		Milestones:     !opts.Mirror,
+		Issues:         !opts.Mirror,
+		Releases:       true,
+		Labels:         !opts.Mirror,
+		PullRequests:   !opts.Mirror,
+		LFS:            opts.LFS,
+		LFSEndpoint:    opts.LFSEndpoint,
 	})
+	if err != nil && opts.Mirror && migrationBlocked(err) {
+		return nil, errMirrorBlocked
+	}
+	if err != nil && migrationBlocked(err) {
+		// remember the downgrade, so the remaining repositories skip the migrate API
+		g.migrationsBlocked.Store(true)
+		return g.pushFallback(opts, err)
+	}
+	return repo, err
+}
+
+// pushFallback migrates a repository with pushRepo since the server refuses the migrate
+// API, logging the downgrade of every repository and recording it for GitOnly.
+func (g *Client) pushFallback(opts MigrateRepoOption, reason error) (*gsdk.Repository, error) {
+	if g.logger != nil {
+		args := []any{"owner", opts.RepoOwner, "name", opts.RepoName}
+		if reason != nil {
+			args = append(args, "error", reason)
+		}
+		g.logger.Warn("gitea refuses repository migrations, fall back to create repository and git push;"+
+			" issues, pull requests, releases and wiki are not migrated", args...)
+	}
+	repo, err := g.pushRepo(opts)
+	if err == nil {
+		g.gitOnly.Store(opts.RepoOwner+"/"+opts.RepoName, true)
+	}
+	return repo, err
+}
+
+// GitOnly reports whether a repository was migrated by the git push fallback, without
+// its issues, pull requests, releases and wiki, because the server refuses migrations.
+func (g *Client) GitOnly(owner, name string) bool {
+	_, ok := g.gitOnly.Load(owner + "/" + name)
+	return ok
+}
+
+// GetRepo retrieves a repository by owner and name.
+// Returns a GiteaError with the response status code if the request fails.
+func (g *Client) GetRepo(owner, name string) (*gsdk.Repository, error) {
+	repo, resp, err := g.client.GetRepo(owner, name)
+	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "get_repo", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return repo, nil
+}
+
+// EditRepo updates the settings of a repository, nil fields are left unchanged.
+func (g *Client) EditRepo(owner, name string, opts gsdk.EditRepoOption) (*gsdk.Repository, error) {
+	repo, resp, err := g.client.EditRepo(owner, name, opts)
+	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "edit_repo", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return repo, nil
+}
+
+// ListRepoTopics returns the topics of a repository.
+func (g *Client) ListRepoTopics(owner, name string) ([]string, error) {
+	if err := g.require(FeatureTopics); err != nil {
+		return nil, err
+	}
+	topics, resp, err := g.client.ListRepoTopics(owner, name, gsdk.ListRepoTopicsOptions{})
 	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "list_repo_topics", Code: resp.StatusCode, Message: err.Error()}
+		}
 		return nil, err
 	}
+	return topics, nil
+}
+
+// SetRepoTopics replaces the topics of a repository.
+func (g *Client) SetRepoTopics(owner, name string, topics []string) error {
+	if err := g.require(FeatureTopics); err != nil {
+		return err
+	}
+	resp, err := g.client.SetRepoTopics(owner, name, topics)
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "set_repo_topics", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
 
-	return newRepo, nil
+// MirrorSync triggers an update of a pull mirror repository from its remote.
+func (g *Client) MirrorSync(owner, name string) error {
+	resp, err := g.client.MirrorSync(owner, name)
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "mirror_sync", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
 }
 
 // CreateUserOption contains options for creating a Gitea user.
@@ -227,32 +500,51 @@ type CreateUserOption struct {
 	FullName string
 	// Email is the email address of the user.
 	Email string
+	// Password is the local password of a new user, who has to change it at the first
+	// sign-in. Users without one sign in through the authentication source.
+	Password string
+	// Inactive creates the user deactivated, unable to sign in until an admin
+	// activates the account. Existing users are left as they are.
+	Inactive bool
 }
 
 // CreateOrGetUser retrieves an existing user or creates a new one if not found.
-// Returns a pointer to the User and an error if the operation fails.
-func (g *Client) CreateOrGetUser(opts CreateUserOption) (*gsdk.User, error) {
+// The username is replaced by its mapping when the login is mapped to another name.
+// Returns a pointer to the User, whether it was created, and an error if the operation fails.
+func (g *Client) CreateOrGetUser(opts CreateUserOption) (*gsdk.User, bool, error) {
+	opts.Username = g.Username(opts.Username)
 	user, resp, err := g.client.GetUserInfo(opts.Username)
 	if err != nil {
 		if g.logger != nil {
 			g.logger.Warn("get user info failed", "username", opts.Username, "err", err)
 		}
 		if resp != nil && resp.StatusCode != http.StatusNotFound {
-			return nil, &GiteaError{Operation: "get_user_info", Code: resp.StatusCode, Message: err.Error()}
+			return nil, false, &GiteaError{Operation: "get_user_info", Code: resp.StatusCode, Message: err.Error()}
+		}
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotFound && g.reconcile {
+		existing, err := g.reconcileUser(opts)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing != nil {
+			return existing, false, nil
 		}
 	}
 	if resp != nil && resp.StatusCode == http.StatusNotFound {
-		mustChangePassword := false
+		// a local password has to be changed at the first sign-in
+		mustChangePassword := opts.Password != ""
 		user, _, err = g.client.AdminCreateUser(gsdk.CreateUserOption{
 			SourceID:           opts.SourceID,
 			LoginName:          opts.LoginName,
 			Username:           opts.Username,
 			FullName:           opts.FullName,
 			Email:              opts.Email,
+			Password:           opts.Password,
 			MustChangePassword: &mustChangePassword,
 		})
 		if err != nil {
-			return nil, &GiteaError{Operation: "admin_create_user", Code: http.StatusInternalServerError, Message: err.Error()}
+			return nil, false, &GiteaError{Operation: "admin_create_user", Code: http.StatusInternalServerError, Message: err.Error()}
 		}
 		if g.logger != nil {
 			g.logger.Info(
@@ -261,20 +553,164 @@ func (g *Client) CreateOrGetUser(opts CreateUserOption) (*gsdk.User, error) {
 				"fullname", opts.FullName,
 			)
 		}
+		if opts.Inactive {
+			// the create API always activates the account
+			active := false
+			resp, err := g.client.AdminEditUser(user.UserName, gsdk.EditUserOption{
+				SourceID:  user.SourceID,
+				LoginName: user.LoginName,
+				Active:    &active,
+			})
+			if err != nil {
+				// created all the same, with its password
+				if resp != nil {
+					return user, true, &GiteaError{Operation: "admin_edit_user", Code: resp.StatusCode, Message: err.Error()}
+				}
+				return user, true, err
+			}
+			user.IsActive = false
+		}
+		return user, true, nil
+	}
+
+	return user, false, nil
+}
+
+// UserProfileOption contains the profile fields copied to a Gitea user.
+type UserProfileOption struct {
+	// Description is the biography of the user.
+	Description string
+	Website     string
+	Location    string
+}
+
+// FillUserProfile sets the profile fields which are empty on the Gitea user, so the
+// changes made in Gitea are kept. Returns the names of the filled fields.
+func (g *Client) FillUserProfile(user *gsdk.User, opts UserProfileOption) ([]string, error) {
+	edit := gsdk.EditUserOption{SourceID: user.SourceID, LoginName: user.LoginName}
+	var filled []string
+	if user.Description == "" && opts.Description != "" {
+		edit.Description = &opts.Description
+		filled = append(filled, "description")
+	}
+	if user.Website == "" && opts.Website != "" {
+		edit.Website = &opts.Website
+		filled = append(filled, "website")
+	}
+	if user.Location == "" && opts.Location != "" {
+		edit.Location = &opts.Location
+		filled = append(filled, "location")
+	}
+	if len(filled) == 0 {
+		return nil, nil
+	}
+	resp, err := g.client.AdminEditUser(user.UserName, edit)
+	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "admin_edit_user", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return filled, nil
+}
+
+// SetUserAdmin makes an existing user a site administrator.
+func (g *Client) SetUserAdmin(user *gsdk.User) error {
+	admin := true
+	resp, err := g.client.AdminEditUser(user.UserName, gsdk.EditUserOption{
+		SourceID:  user.SourceID,
+		LoginName: user.LoginName,
+		Admin:     &admin,
+	})
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "admin_edit_user", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
+
+// reconcileUser looks up an existing externally authenticated user (e.g. synced from LDAP)
+// with the same email, so no duplicate account is created under the source username.
+// Returns nil when there is no match.
+func (g *Client) reconcileUser(opts CreateUserOption) (*gsdk.User, error) {
+	if opts.Email == "" {
+		return nil, nil
+	}
+
+	g.usersMu.Lock()
+	defer g.usersMu.Unlock()
+
+	if g.emails == nil {
+		emails := make(map[string]*gsdk.User)
+		for page := 1; ; page++ {
+			users, resp, err := g.client.AdminListUsers(gsdk.AdminListUsersOptions{
+				ListOptions: gsdk.ListOptions{Page: page, PageSize: 50},
+			})
+			if err != nil {
+				if resp != nil {
+					return nil, &GiteaError{Operation: "admin_list_users", Code: resp.StatusCode, Message: err.Error()}
+				}
+				return nil, err
+			}
+			for _, user := range users {
+				// local accounts are not managed by a directory
+				if user.SourceID == 0 || (opts.SourceID != 0 && user.SourceID != opts.SourceID) {
+					continue
+				}
+				emails[strings.ToLower(user.Email)] = user
+			}
+			if len(users) < 50 {
+				break
+			}
+		}
+		g.emails = emails
 	}
 
+	user, ok := g.emails[strings.ToLower(opts.Email)]
+	if !ok {
+		return nil, nil
+	}
+	g.usernames[strings.ToLower(opts.LoginName)] = user.UserName
+	if g.logger != nil {
+		g.logger.Info("reconciled user with existing account by email",
+			"username", opts.Username,
+			"gitea_username", user.UserName,
+			"email", opts.Email,
+		)
+	}
 	return user, nil
 }
 
+// Username returns the Gitea username for a source login, which differs from
+// the login when it is mapped to another name or the user was reconciled with
+// an existing account.
+func (g *Client) Username(login string) string {
+	g.usersMu.Lock()
+	defer g.usersMu.Unlock()
+	if name, ok := g.usernames[strings.ToLower(login)]; ok {
+		return name
+	}
+	return login
+}
+
+// MapUsername maps a source login to the Gitea username it is created as, taking
+// precedence over the usernames given in the configuration.
+func (g *Client) MapUsername(login, username string) {
+	g.usersMu.Lock()
+	defer g.usersMu.Unlock()
+	g.usernames[strings.ToLower(login)] = username
+}
+
 // AddCollaborator adds a user as a collaborator to the specified repository with the given permissions.
 // Returns the response and an error if the operation fails.
 func (g *Client) AddCollaborator(org, repo, user string, permission map[string]bool) (*gsdk.Response, error) {
 	var access gsdk.AccessMode
 	switch {
-	case permission[core.GitHubTeamAdmin]:
+	case permission[core.GitHubTeamAdmin], permission[core.GitHubTeamMaintain]:
+		// collaborators cannot be owners, admin is the closest to maintain
 		access = gsdk.AccessModeAdmin
-	case permission[core.GitHubTeamMaintain]:
-		access = gsdk.AccessModeOwner
 	case permission[core.GitHubTeamPush]:
 		access = gsdk.AccessModeWrite
 	case permission[core.GitHubTeamPull]:
@@ -296,53 +732,178 @@ type CreateTeamOption struct {
 	Description string
 	// Permission is the permission level for the team.
 	Permission string
+	// IncludesAllRepositories grants the team access to every repository of the
+	// organization, present and future.
+	IncludesAllRepositories bool
+	// CanCreateOrgRepo lets the members create repositories in the organization,
+	// always set for admin teams.
+	CanCreateOrgRepo bool
+	// Units are per-unit permissions keyed by unit such as repo.code, none, read or
+	// write, replacing the access of Permission on the listed units. Admin teams have
+	// every unit and ignore them.
+	Units map[string]string
 }
 
 // CreateOrGetTeam retrieves an existing team or creates a new one in the specified organization.
 // Returns a pointer to the Team and an error if the operation fails.
 func (g *Client) CreateOrGetTeam(org string, opts CreateTeamOption) (*gsdk.Team, error) {
+	opt, units, err := teamOption(opts)
+	if err != nil {
+		return nil, err
+	}
+	if units != nil && !g.Supports(FeatureTeamUnits) {
+		// the access mode of the team only
+		units = nil
+	}
+
+	teams, _, err := g.client.SearchOrgTeams(org, &gsdk.SearchTeamsOptions{
+		Query: opt.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(teams) > 0 {
+		return teams[0], nil
+	}
+
+	// create team
+	if units != nil {
+		team := new(gsdk.Team)
+		path := "/api/v1/orgs/" + url.PathEscape(org) + "/teams"
+		if err := g.request(g.ctx, "create_team", http.MethodPost, path, "", newTeamUnitsOption(opt, units), team); err != nil {
+			return nil, err
+		}
+		return team, nil
+	}
+	team, _, err := g.client.CreateTeam(org, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+// teamUnitsOption is the team body of the Gitea API with per-unit permissions, which
+// the SDK does not cover.
+type teamUnitsOption struct {
+	Name                    string              `json:"name"`
+	Description             string              `json:"description"`
+	Permission              gsdk.AccessMode     `json:"permission"`
+	CanCreateOrgRepo        bool                `json:"can_create_org_repo"`
+	IncludesAllRepositories bool                `json:"includes_all_repositories"`
+	Units                   []gsdk.RepoUnitType `json:"units"`
+	UnitsMap                map[string]string   `json:"units_map"`
+}
+
+func newTeamUnitsOption(opt gsdk.CreateTeamOption, units map[string]string) teamUnitsOption {
+	return teamUnitsOption{
+		Name:                    opt.Name,
+		Description:             opt.Description,
+		Permission:              opt.Permission,
+		CanCreateOrgRepo:        opt.CanCreateOrgRepo,
+		IncludesAllRepositories: opt.IncludesAllRepositories,
+		Units:                   opt.Units,
+		UnitsMap:                units,
+	}
+}
+
+// triageUnits returns the per-unit permissions of a GitHub triage team: read access,
+// with write access to the issues and pull requests to label, assign and close them.
+func triageUnits() map[string]string {
+	units := make(map[string]string, len(core.DefaultUnits))
+	for _, unit := range core.DefaultUnits {
+		units[string(unit)] = core.GiteaRepoRead
+	}
+	units[string(gsdk.RepoUnitIssues)] = core.GiteaRepoWrite
+	units[string(gsdk.RepoUnitPulls)] = core.GiteaRepoWrite
+	return units
+}
+
+// teamOption maps the GitHub team permission to the Gitea team options, and to
+// per-unit permissions when a single access mode does not match it or opts.Units
+// changes some units.
+func teamOption(opts CreateTeamOption) (gsdk.CreateTeamOption, map[string]string, error) {
 	opt := gsdk.CreateTeamOption{
-		Name:        opts.Name,
-		Description: opts.Description,
-		Permission:  gsdk.AccessMode(opts.Permission),
-		Units:       core.DefaultUnits,
+		Name:                    opts.Name,
+		Description:             opts.Description,
+		Permission:              gsdk.AccessMode(opts.Permission),
+		CanCreateOrgRepo:        opts.CanCreateOrgRepo,
+		IncludesAllRepositories: opts.IncludesAllRepositories,
+		Units:                   core.DefaultUnits,
 	}
 
+	var units map[string]string
 	switch opts.Permission {
 	case core.GitHubTeamAdmin:
 		opt.Permission = gsdk.AccessModeAdmin
 		opt.CanCreateOrgRepo = true
 	case core.GitHubTeamPush:
 		opt.Permission = gsdk.AccessModeWrite
-	case core.GitHubTeamPull:
-		opt.Permission = gsdk.AccessModeWrite
 	case core.GitHubTeamMaintain:
 		opt.Permission = gsdk.AccessModeWrite
-	case core.GitHubTeamTriager:
+	case core.GitHubTeamPull:
+		opt.Permission = gsdk.AccessModeRead
+	case core.GitHubTeamTriage:
 		opt.Permission = gsdk.AccessModeRead
+		units = triageUnits()
 	default:
-		return nil, errors.New("permission mode invalid")
+		return opt, nil, errors.New("permission mode invalid")
 	}
 
-	teams, _, err := g.client.SearchOrgTeams(org, &gsdk.SearchTeamsOptions{
-		Query: opt.Name,
-	})
-	if err != nil {
-		return nil, err
-	}
-	if len(teams) > 0 {
-		return teams[0], nil
+	if len(opts.Units) > 0 && opt.Permission != gsdk.AccessModeAdmin {
+		if units == nil {
+			units = make(map[string]string, len(opt.Units))
+			for _, unit := range opt.Units {
+				units[string(unit)] = string(opt.Permission)
+			}
+		}
+		maps.Copy(units, opts.Units)
 	}
+	return opt, units, nil
+}
 
-	// create team
-	team, _, err := g.client.CreateTeam(org, opt)
+// GetTeam retrieves a team by ID.
+func (g *Client) GetTeam(id int64) (*gsdk.Team, error) {
+	team, resp, err := g.client.GetTeam(id)
 	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "get_team", Code: resp.StatusCode, Message: err.Error()}
+		}
 		return nil, err
 	}
-
 	return team, nil
 }
 
+// EditTeam resets the permission and units of a team to the ones CreateOrGetTeam creates it with.
+func (g *Client) EditTeam(id int64, opts CreateTeamOption) error {
+	opt, units, err := teamOption(opts)
+	if err != nil {
+		return err
+	}
+	if units != nil && !g.Supports(FeatureTeamUnits) {
+		units = nil
+	}
+	if units != nil {
+		path := "/api/v1/teams/" + strconv.FormatInt(id, 10)
+		return g.request(g.ctx, "edit_team", http.MethodPatch, path, "", newTeamUnitsOption(opt, units), nil)
+	}
+	resp, err := g.client.EditTeam(id, gsdk.EditTeamOption{
+		Name:                    opt.Name,
+		Description:             &opt.Description,
+		Permission:              opt.Permission,
+		CanCreateOrgRepo:        &opt.CanCreateOrgRepo,
+		IncludesAllRepositories: &opt.IncludesAllRepositories,
+		Units:                   opt.Units,
+	})
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "edit_team", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
+
 // SearchOrgTeams retrieves a list of teams in the specified organization.
 func (g *Client) SearchOrgTeams(org string, opts *gsdk.SearchTeamsOptions) ([]*gsdk.Team, error) {
 	teams, _, err := g.client.SearchOrgTeams(org, opts)
@@ -359,6 +920,23 @@ func (g *Client) AddTeamMember(id int64, user string) error {
 	return err
 }
 
+// ListTeamMembers lists all members of the specified team.
+func (g *Client) ListTeamMembers(id int64) ([]*gsdk.User, error) {
+	var members []*gsdk.User
+	for page := 1; ; page++ {
+		users, _, err := g.client.ListTeamMembers(id, gsdk.ListTeamMembersOptions{
+			ListOptions: gsdk.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, users...)
+		if len(users) < 50 {
+			return members, nil
+		}
+	}
+}
+
 // AddTeamRepository adds a repository to the specified team.
 // Returns an error if the operation fails.
 func (g *Client) AddTeamRepository(id int64, org, repo string) error {
@@ -393,6 +971,14 @@ func (g *Client) CreateUserPublicKey(username string, opts CreatePublicKeyOption
 	return key, nil
 }
 
+// CreateUserGPGKey adds an armored GPG public key to the specified user through the
+// Sudo header, Gitea has no admin endpoint for GPG keys. Gitea refuses a key without
+// an email of the user, and a key already added, with a 422 GiteaError.
+func (g *Client) CreateUserGPGKey(username, armoredKey string) error {
+	body := map[string]string{"armored_public_key": armoredKey}
+	return g.request(g.ctx, "create_gpg_key", http.MethodPost, "/api/v1/user/gpg_keys", username, body, nil)
+}
+
 // DeleteOrgOption contains options for deleting a Gitea organization.
 type DeleteOrgOption struct {
 	// OrgName is the organization name to delete.
@@ -455,3 +1041,852 @@ func (g *Client) ListOrgRepos(org string, opts ...gsdk.ListOrgReposOptions) ([]*
 	}
 	return g.client.ListOrgRepos(org, opt)
 }
+
+// ListOwnerRepos lists all repositories of a Gitea organization or user.
+func (g *Client) ListOwnerRepos(owner string, isOrg bool) ([]*gsdk.Repository, error) {
+	var repos []*gsdk.Repository
+	for page := 1; ; page++ {
+		opt := gsdk.ListOptions{Page: page, PageSize: 50}
+		var list []*gsdk.Repository
+		var resp *gsdk.Response
+		var err error
+		if isOrg {
+			list, resp, err = g.client.ListOrgRepos(owner, gsdk.ListOrgReposOptions{ListOptions: opt})
+		} else {
+			list, resp, err = g.client.ListUserRepos(owner, gsdk.ListReposOptions{ListOptions: opt})
+		}
+		if err != nil {
+			if resp != nil {
+				return nil, &GiteaError{Operation: "list_owner_repos", Code: resp.StatusCode, Message: err.Error()}
+			}
+			return nil, err
+		}
+		repos = append(repos, list...)
+		if len(list) < 50 {
+			return repos, nil
+		}
+	}
+}
+
+// UpdateUserAvatar replaces the avatar of the specified user with the given image.
+// The request is performed as that user through the Sudo header.
+func (g *Client) UpdateUserAvatar(username string, image []byte) error {
+	return g.request(g.ctx, "update_user_avatar", http.MethodPost, "/api/v1/user/avatar", username, map[string]string{
+		"image": base64.StdEncoding.EncodeToString(image),
+	}, nil)
+}
+
+// UpdateOrgAvatar replaces the avatar of the specified organization with the given image.
+func (g *Client) UpdateOrgAvatar(org string, image []byte) error {
+	return g.request(g.ctx, "update_org_avatar", http.MethodPost, "/api/v1/orgs/"+url.PathEscape(org)+"/avatar", "", map[string]string{
+		"image": base64.StdEncoding.EncodeToString(image),
+	}, nil)
+}
+
+// RunnerRegistrationToken returns the token registering Gitea Actions runners for an
+// organization, or for a repository of it when repo is set.
+func (g *Client) RunnerRegistrationToken(owner, repo string) (string, error) {
+	if err := g.require(FeatureRunnerTokens); err != nil {
+		return "", err
+	}
+	path := "/api/v1/orgs/" + url.PathEscape(owner) + "/actions/runners/registration-token"
+	if repo != "" {
+		path = "/api/v1/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/actions/runners/registration-token"
+	}
+	var out struct {
+		Token string `json:"token"`
+	}
+	if err := g.request(g.ctx, "runner_registration_token", http.MethodGet, path, "", nil, &out); err != nil {
+		return "", err
+	}
+	return out.Token, nil
+}
+
+// SetOrgMembershipPublic publicizes or conceals the membership of a user in an
+// organization. Gitea only lets members change their own visibility, so the request
+// is sent as the user through the Sudo header.
+func (g *Client) SetOrgMembershipPublic(org, username string, public bool) error {
+	method := http.MethodDelete
+	if public {
+		method = http.MethodPut
+	}
+	path := "/api/v1/orgs/" + url.PathEscape(org) + "/public_members/" + url.PathEscape(username)
+	return g.request(g.ctx, "set_org_membership_public", method, path, username, nil, nil)
+}
+
+// StarRepo stars a repository as the specified user through the Sudo header.
+func (g *Client) StarRepo(username, owner, repo string) error {
+	path := "/api/v1/user/starred/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+	return g.request(g.ctx, "star_repo", http.MethodPut, path, username, nil, nil)
+}
+
+// WatchRepo watches a repository as the specified user through the Sudo header.
+func (g *Client) WatchRepo(username, owner, repo string) error {
+	path := "/api/v1/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/subscription"
+	return g.request(g.ctx, "watch_repo", http.MethodPut, path, username, nil, nil)
+}
+
+// FollowUser follows another user as the specified user through the Sudo header.
+func (g *Client) FollowUser(username, target string) error {
+	return g.request(g.ctx, "follow_user", http.MethodPut, "/api/v1/user/following/"+url.PathEscape(target), username, nil, nil)
+}
+
+// ListRepoActionSecrets lists the names of the Actions secrets of a repository.
+func (g *Client) ListRepoActionSecrets(owner, repo string) ([]string, error) {
+	if err := g.require(FeatureActionsSecrets); err != nil {
+		return nil, err
+	}
+	var names []string
+	for page := 1; ; page++ {
+		var secrets []struct {
+			Name string `json:"name"`
+		}
+		path := fmt.Sprintf("/api/v1/repos/%s/%s/actions/secrets?page=%d&limit=50", url.PathEscape(owner), url.PathEscape(repo), page)
+		if err := g.request(g.ctx, "list_repo_secrets", http.MethodGet, path, "", nil, &secrets); err != nil {
+			return nil, err
+		}
+		for _, secret := range secrets {
+			names = append(names, secret.Name)
+		}
+		if len(secrets) < 50 {
+			return names, nil
+		}
+	}
+}
+
+// SetRepoActionSecret creates or updates an Actions secret of a repository.
+func (g *Client) SetRepoActionSecret(owner, repo, name, value string) error {
+	if err := g.require(FeatureActionsSecrets); err != nil {
+		return err
+	}
+	path := "/api/v1/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/actions/secrets/" + url.PathEscape(name)
+	return g.request(g.ctx, "set_repo_secret", http.MethodPut, path, "", map[string]string{"data": value}, nil)
+}
+
+// CreateRepoActionVariable creates an Actions variable of a repository.
+// A variable which already exists fails with a 409 GiteaError.
+func (g *Client) CreateRepoActionVariable(owner, repo, name, value string) error {
+	if err := g.require(FeatureActionsVariables); err != nil {
+		return err
+	}
+	path := "/api/v1/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/actions/variables/" + url.PathEscape(name)
+	return g.request(g.ctx, "create_repo_variable", http.MethodPost, path, "", map[string]string{"value": value}, nil)
+}
+
+// GetRepoActionVariable returns the value of an Actions variable of a repository.
+func (g *Client) GetRepoActionVariable(owner, repo, name string) (string, error) {
+	if err := g.require(FeatureActionsVariables); err != nil {
+		return "", err
+	}
+	var variable struct {
+		Data string `json:"data"`
+	}
+	path := "/api/v1/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/actions/variables/" + url.PathEscape(name)
+	if err := g.request(g.ctx, "get_repo_variable", http.MethodGet, path, "", nil, &variable); err != nil {
+		return "", err
+	}
+	return variable.Data, nil
+}
+
+// UpdateRepoActionVariable replaces the value of an existing Actions variable of a repository.
+func (g *Client) UpdateRepoActionVariable(owner, repo, name, value string) error {
+	if err := g.require(FeatureActionsVariables); err != nil {
+		return err
+	}
+	path := "/api/v1/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/actions/variables/" + url.PathEscape(name)
+	return g.request(g.ctx, "update_repo_variable", http.MethodPut, path, "", map[string]string{"name": name, "value": value}, nil)
+}
+
+// ListOrgActionSecrets lists the names of the Actions secrets of an organization.
+func (g *Client) ListOrgActionSecrets(org string) ([]string, error) {
+	if err := g.require(FeatureActionsSecrets); err != nil {
+		return nil, err
+	}
+	var names []string
+	for page := 1; ; page++ {
+		var secrets []struct {
+			Name string `json:"name"`
+		}
+		path := fmt.Sprintf("/api/v1/orgs/%s/actions/secrets?page=%d&limit=50", url.PathEscape(org), page)
+		if err := g.request(g.ctx, "list_org_secrets", http.MethodGet, path, "", nil, &secrets); err != nil {
+			return nil, err
+		}
+		for _, secret := range secrets {
+			names = append(names, secret.Name)
+		}
+		if len(secrets) < 50 {
+			return names, nil
+		}
+	}
+}
+
+// SetOrgActionSecret creates or updates an Actions secret of an organization.
+func (g *Client) SetOrgActionSecret(org, name, value string) error {
+	if err := g.require(FeatureActionsSecrets); err != nil {
+		return err
+	}
+	path := "/api/v1/orgs/" + url.PathEscape(org) + "/actions/secrets/" + url.PathEscape(name)
+	return g.request(g.ctx, "set_org_secret", http.MethodPut, path, "", map[string]string{"data": value}, nil)
+}
+
+// CreateOrgActionVariable creates an Actions variable of an organization.
+// A variable which already exists fails with a 409 GiteaError.
+func (g *Client) CreateOrgActionVariable(org, name, value string) error {
+	if err := g.require(FeatureActionsVariables); err != nil {
+		return err
+	}
+	path := "/api/v1/orgs/" + url.PathEscape(org) + "/actions/variables/" + url.PathEscape(name)
+	return g.request(g.ctx, "create_org_variable", http.MethodPost, path, "", map[string]string{"value": value}, nil)
+}
+
+// GetOrgActionVariable returns the value of an Actions variable of an organization.
+func (g *Client) GetOrgActionVariable(org, name string) (string, error) {
+	if err := g.require(FeatureActionsVariables); err != nil {
+		return "", err
+	}
+	var variable struct {
+		Data string `json:"data"`
+	}
+	path := "/api/v1/orgs/" + url.PathEscape(org) + "/actions/variables/" + url.PathEscape(name)
+	if err := g.request(g.ctx, "get_org_variable", http.MethodGet, path, "", nil, &variable); err != nil {
+		return "", err
+	}
+	return variable.Data, nil
+}
+
+// UpdateOrgActionVariable replaces the value of an existing Actions variable of an organization.
+func (g *Client) UpdateOrgActionVariable(org, name, value string) error {
+	if err := g.require(FeatureActionsVariables); err != nil {
+		return err
+	}
+	path := "/api/v1/orgs/" + url.PathEscape(org) + "/actions/variables/" + url.PathEscape(name)
+	return g.request(g.ctx, "update_org_variable", http.MethodPut, path, "", map[string]string{"name": name, "value": value}, nil)
+}
+
+// CreateFileOption contains options for committing a new file to a repository.
+type CreateFileOption struct {
+	Owner string
+	Repo  string
+	// Path is the file path in the repository.
+	Path    string
+	Content []byte
+	// Message is the commit message, the file is committed to the default branch.
+	Message string
+}
+
+// CreateFile commits a new file to the default branch of a repository.
+// Returns a GiteaError with the response status code if the request fails.
+func (g *Client) CreateFile(opts CreateFileOption) error {
+	_, resp, err := g.client.CreateFile(opts.Owner, opts.Repo, opts.Path, gsdk.CreateFileOptions{
+		FileOptions: gsdk.FileOptions{Message: opts.Message},
+		Content:     base64.StdEncoding.EncodeToString(opts.Content),
+	})
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "create_file", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
+
+// ListContents lists the entries of a directory on the default branch of a repository.
+func (g *Client) ListContents(owner, repo, dir string) ([]*gsdk.ContentsResponse, error) {
+	entries, resp, err := g.client.ListContents(owner, repo, "", dir)
+	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "list_contents", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetFile returns the raw content of a file on the default branch of a repository.
+func (g *Client) GetFile(owner, repo, path string) ([]byte, error) {
+	data, resp, err := g.client.GetFile(owner, repo, "", path)
+	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "get_file", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// CreateBranch creates a branch from another one.
+// A branch which already exists fails with a 409 GiteaError.
+func (g *Client) CreateBranch(owner, repo, branch, from string) error {
+	_, resp, err := g.client.CreateBranch(owner, repo, gsdk.CreateBranchOption{
+		BranchName:    branch,
+		OldBranchName: from,
+	})
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "create_branch", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateFileOption contains options for committing a change of an existing file.
+type UpdateFileOption struct {
+	Owner string
+	Repo  string
+	// Path is the file path in the repository.
+	Path    string
+	Content []byte
+	// SHA is the blob SHA of the file being replaced.
+	SHA string
+	// Branch is the branch to commit to, the default branch when empty.
+	Branch  string
+	Message string
+}
+
+// UpdateFile commits a new content of an existing file.
+func (g *Client) UpdateFile(opts UpdateFileOption) error {
+	_, resp, err := g.client.UpdateFile(opts.Owner, opts.Repo, opts.Path, gsdk.UpdateFileOptions{
+		FileOptions: gsdk.FileOptions{Message: opts.Message, BranchName: opts.Branch},
+		SHA:         opts.SHA,
+		Content:     base64.StdEncoding.EncodeToString(opts.Content),
+	})
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "update_file", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
+
+// TreeEntry is a file or directory of a repository tree.
+type TreeEntry struct {
+	Path string `json:"path"`
+	// Type is blob for files, tree for directories and commit for submodules.
+	Type string `json:"type"`
+	SHA  string `json:"sha"`
+}
+
+// treePageSize is the default maximum of tree entries Gitea returns per page.
+const treePageSize = 1000
+
+// ListTree lists every entry of a repository tree at ref, recursively.
+func (g *Client) ListTree(owner, repo, ref string) ([]TreeEntry, error) {
+	var entries []TreeEntry
+	for page := 1; ; page++ {
+		var tree struct {
+			Tree       []TreeEntry `json:"tree"`
+			TotalCount int         `json:"total_count"`
+		}
+		path := fmt.Sprintf("/api/v1/repos/%s/%s/git/trees/%s?recursive=true&page=%d&per_page=%d",
+			url.PathEscape(owner), url.PathEscape(repo), url.PathEscape(ref), page, treePageSize)
+		if err := g.request(g.ctx, "list_tree", http.MethodGet, path, "", nil, &tree); err != nil {
+			return nil, err
+		}
+		entries = append(entries, tree.Tree...)
+		if len(tree.Tree) == 0 || len(entries) >= tree.TotalCount {
+			return entries, nil
+		}
+	}
+}
+
+// FileChange is the new content of an existing file.
+type FileChange struct {
+	Path    string
+	Content []byte
+	// SHA is the blob SHA of the file being replaced.
+	SHA string
+}
+
+// ChangeFilesOption contains options for committing changes of several files at once.
+type ChangeFilesOption struct {
+	Owner string
+	Repo  string
+	Files []FileChange
+	// Branch is the branch to commit to, the default branch when empty.
+	Branch  string
+	Message string
+}
+
+// ChangeFiles commits new contents of several existing files in a single commit.
+// It needs Gitea 1.20 or later.
+func (g *Client) ChangeFiles(opts ChangeFilesOption) error {
+	if err := g.require(FeatureChangeFiles); err != nil {
+		return err
+	}
+	type file struct {
+		Operation string `json:"operation"`
+		Path      string `json:"path"`
+		Content   string `json:"content"`
+		SHA       string `json:"sha"`
+	}
+	body := struct {
+		Files   []file `json:"files"`
+		Branch  string `json:"branch,omitempty"`
+		Message string `json:"message"`
+	}{Branch: opts.Branch, Message: opts.Message}
+	for _, f := range opts.Files {
+		body.Files = append(body.Files, file{
+			Operation: "update",
+			Path:      f.Path,
+			Content:   base64.StdEncoding.EncodeToString(f.Content),
+			SHA:       f.SHA,
+		})
+	}
+	path := "/api/v1/repos/" + url.PathEscape(opts.Owner) + "/" + url.PathEscape(opts.Repo) + "/contents"
+	return g.request(g.ctx, "change_files", http.MethodPost, path, "", body, nil)
+}
+
+// CreatePullRequestOption contains options for opening a pull request.
+type CreatePullRequestOption struct {
+	Owner string
+	Repo  string
+	// Head is the branch with the changes, Base the branch to merge them into.
+	Head  string
+	Base  string
+	Title string
+	Body  string
+}
+
+// CreatePullRequest opens a pull request.
+func (g *Client) CreatePullRequest(opts CreatePullRequestOption) (*gsdk.PullRequest, error) {
+	pr, resp, err := g.client.CreatePullRequest(opts.Owner, opts.Repo, gsdk.CreatePullRequestOption{
+		Head:  opts.Head,
+		Base:  opts.Base,
+		Title: opts.Title,
+		Body:  opts.Body,
+	})
+	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "create_pull_request", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return pr, nil
+}
+
+// CreateHookOption contains options for creating a Gitea webhook.
+type CreateHookOption struct {
+	URL         string
+	ContentType string
+	Secret      string
+	Events      []string
+	Active      bool
+}
+
+// ListRepoHooks lists all webhooks of a repository.
+func (g *Client) ListRepoHooks(owner, repo string) ([]*gsdk.Hook, error) {
+	var hooks []*gsdk.Hook
+	for page := 1; ; page++ {
+		list, resp, err := g.client.ListRepoHooks(owner, repo, gsdk.ListHooksOptions{
+			ListOptions: gsdk.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			if resp != nil {
+				return nil, &GiteaError{Operation: "list_repo_hooks", Code: resp.StatusCode, Message: err.Error()}
+			}
+			return nil, err
+		}
+		hooks = append(hooks, list...)
+		if len(list) < 50 {
+			return hooks, nil
+		}
+	}
+}
+
+// CreateRepoHook creates a Gitea type webhook on a repository.
+func (g *Client) CreateRepoHook(owner, repo string, opts CreateHookOption) (*gsdk.Hook, error) {
+	hook, resp, err := g.client.CreateRepoHook(owner, repo, gsdk.CreateHookOption{
+		Type:   gsdk.HookTypeGitea,
+		Config: hookConfig(opts),
+		Events: opts.Events,
+		Active: opts.Active,
+	})
+	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "create_repo_hook", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return hook, nil
+}
+
+// ListRepoLabels lists the issue labels of a repository.
+func (g *Client) ListRepoLabels(owner, repo string) ([]*gsdk.Label, error) {
+	var labels []*gsdk.Label
+	for page := 1; ; page++ {
+		list, resp, err := g.client.ListRepoLabels(owner, repo, gsdk.ListLabelsOptions{
+			ListOptions: gsdk.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			if resp != nil {
+				return nil, &GiteaError{Operation: "list_repo_labels", Code: resp.StatusCode, Message: err.Error()}
+			}
+			return nil, err
+		}
+		labels = append(labels, list...)
+		if len(list) < 50 {
+			return labels, nil
+		}
+	}
+}
+
+// ListReleases lists the releases of a repository, with their attachments.
+func (g *Client) ListReleases(owner, repo string) ([]*gsdk.Release, error) {
+	var releases []*gsdk.Release
+	for page := 1; ; page++ {
+		list, resp, err := g.client.ListReleases(owner, repo, gsdk.ListReleasesOptions{
+			ListOptions: gsdk.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			if resp != nil {
+				return nil, &GiteaError{Operation: "list_releases", Code: resp.StatusCode, Message: err.Error()}
+			}
+			return nil, err
+		}
+		releases = append(releases, list...)
+		if len(list) < 50 {
+			return releases, nil
+		}
+	}
+}
+
+// UploadReleaseAttachment uploads the file at path as an attachment of a release.
+// The file is streamed, the SDK would read it in memory first.
+func (g *Client) UploadReleaseAttachment(owner, repo string, releaseID int64, name, path string) (*gsdk.Attachment, error) {
+	endpoint := fmt.Sprintf("/api/v1/repos/%s/%s/releases/%d/assets?name=%s",
+		url.PathEscape(owner), url.PathEscape(repo), releaseID, url.QueryEscape(name))
+	var attachment gsdk.Attachment
+	if err := g.upload(g.ctx, "upload_release_attachment", endpoint, "attachment", name, path, &attachment); err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// LinkReleaseAttachment adds an attachment to a release which links to an external
+// URL instead of an uploaded file.
+func (g *Client) LinkReleaseAttachment(owner, repo string, releaseID int64, name, link string) error {
+	if err := g.require(FeatureExternalAssets); err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := mw.WriteField("external_url", link); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("/api/v1/repos/%s/%s/releases/%d/assets?name=%s",
+		url.PathEscape(owner), url.PathEscape(repo), releaseID, url.QueryEscape(name))
+	req, err := http.NewRequestWithContext(g.ctx, http.MethodPost, g.server+endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return g.do(req, "link_release_attachment", nil)
+}
+
+// AttachmentExists reports whether an attachment download URL of the server still
+// serves a file, which it no longer does once its repository is deleted.
+func (g *Client) AttachmentExists(link string) (bool, error) {
+	req, err := http.NewRequestWithContext(g.ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "token "+g.token)
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode >= http.StatusMultipleChoices:
+		return false, &GiteaError{Operation: "get_attachment", Code: resp.StatusCode, Message: resp.Status}
+	}
+	return true, nil
+}
+
+// DeleteReleaseAttachment deletes an attachment of a release.
+func (g *Client) DeleteReleaseAttachment(owner, repo string, releaseID, attachmentID int64) error {
+	resp, err := g.client.DeleteReleaseAttachment(owner, repo, releaseID, attachmentID)
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "delete_release_attachment", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
+
+// ListIssues lists the issues and pull requests of a repository, open and closed.
+func (g *Client) ListIssues(owner, repo string) ([]*gsdk.Issue, error) {
+	var issues []*gsdk.Issue
+	for page := 1; ; page++ {
+		list, resp, err := g.client.ListRepoIssues(owner, repo, gsdk.ListIssueOption{
+			ListOptions: gsdk.ListOptions{Page: page, PageSize: 50},
+			State:       gsdk.StateAll,
+			Type:        gsdk.IssueTypeAll,
+		})
+		if err != nil {
+			if resp != nil {
+				return nil, &GiteaError{Operation: "list_issues", Code: resp.StatusCode, Message: err.Error()}
+			}
+			return nil, err
+		}
+		issues = append(issues, list...)
+		if len(list) < 50 {
+			return issues, nil
+		}
+	}
+}
+
+// ListIssueComments lists the comments of every issue and pull request of a repository.
+func (g *Client) ListIssueComments(owner, repo string) ([]*gsdk.Comment, error) {
+	var comments []*gsdk.Comment
+	for page := 1; ; page++ {
+		list, resp, err := g.client.ListRepoIssueComments(owner, repo, gsdk.ListIssueCommentOptions{
+			ListOptions: gsdk.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			if resp != nil {
+				return nil, &GiteaError{Operation: "list_issue_comments", Code: resp.StatusCode, Message: err.Error()}
+			}
+			return nil, err
+		}
+		comments = append(comments, list...)
+		if len(list) < 50 {
+			return comments, nil
+		}
+	}
+}
+
+// EditIssueBody replaces the body of an issue or pull request.
+func (g *Client) EditIssueBody(owner, repo string, index int64, body string) error {
+	_, resp, err := g.client.EditIssue(owner, repo, index, gsdk.EditIssueOption{Body: &body})
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "edit_issue", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
+
+// EditIssueComment replaces the body of an issue or pull request comment.
+func (g *Client) EditIssueComment(owner, repo string, id int64, body string) error {
+	_, resp, err := g.client.EditIssueComment(owner, repo, id, gsdk.EditIssueCommentOption{Body: body})
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "edit_issue_comment", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
+
+// UploadIssueAttachment uploads the file at path as an attachment of an issue or pull request.
+func (g *Client) UploadIssueAttachment(owner, repo string, index int64, name, path string) (*gsdk.Attachment, error) {
+	endpoint := fmt.Sprintf("/api/v1/repos/%s/%s/issues/%d/assets?name=%s",
+		url.PathEscape(owner), url.PathEscape(repo), index, url.QueryEscape(name))
+	var attachment gsdk.Attachment
+	if err := g.upload(g.ctx, "upload_issue_attachment", endpoint, "attachment", name, path, &attachment); err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// UploadCommentAttachment uploads the file at path as an attachment of an issue comment.
+func (g *Client) UploadCommentAttachment(owner, repo string, id int64, name, path string) (*gsdk.Attachment, error) {
+	endpoint := fmt.Sprintf("/api/v1/repos/%s/%s/issues/comments/%d/assets?name=%s",
+		url.PathEscape(owner), url.PathEscape(repo), id, url.QueryEscape(name))
+	var attachment gsdk.Attachment
+	if err := g.upload(g.ctx, "upload_comment_attachment", endpoint, "attachment", name, path, &attachment); err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// CreateLabel creates an issue label in a repository.
+func (g *Client) CreateLabel(owner, repo string, opts gsdk.CreateLabelOption) error {
+	_, resp, err := g.client.CreateLabel(owner, repo, opts)
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "create_label", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
+
+// EditLabel changes an issue label of a repository.
+func (g *Client) EditLabel(owner, repo string, id int64, opts gsdk.EditLabelOption) error {
+	_, resp, err := g.client.EditLabel(owner, repo, id, opts)
+	if err != nil {
+		if resp != nil {
+			return &GiteaError{Operation: "edit_label", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
+
+// ListOAuth2Apps lists the OAuth2 applications of the token user.
+func (g *Client) ListOAuth2Apps() ([]*gsdk.Oauth2, error) {
+	var apps []*gsdk.Oauth2
+	for page := 1; ; page++ {
+		list, resp, err := g.client.ListOauth2(gsdk.ListOauth2Option{
+			ListOptions: gsdk.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			if resp != nil {
+				return nil, &GiteaError{Operation: "list_oauth2_apps", Code: resp.StatusCode, Message: err.Error()}
+			}
+			return nil, err
+		}
+		apps = append(apps, list...)
+		if len(list) < 50 {
+			return apps, nil
+		}
+	}
+}
+
+// CreateOAuth2App creates a confidential OAuth2 application owned by the token user.
+// The client secret is only returned here, Gitea never shows it again.
+func (g *Client) CreateOAuth2App(name string, redirectURIs []string) (*gsdk.Oauth2, error) {
+	app, resp, err := g.client.CreateOauth2(gsdk.CreateOauth2Option{
+		Name:               name,
+		ConfidentialClient: true,
+		RedirectURIs:       redirectURIs,
+	})
+	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "create_oauth2_app", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return app, nil
+}
+
+// BranchProtectionOption is a branch protection rule of a repository.
+type BranchProtectionOption struct {
+	// RuleName is a branch name or glob pattern, e.g. release/*.
+	RuleName string
+	// EnablePush allows pushing to the matching branches, only for PushTeams and
+	// PushUsers when either is set. Merging pull requests is always allowed.
+	EnablePush bool
+	PushTeams  []string
+	PushUsers  []string
+	// RequiredApprovals is the number of approvals needed to merge a pull request.
+	RequiredApprovals int64
+}
+
+// ProtectBranch creates the branch protection rule of a repository, or updates the
+// rule with the same name. It reports whether the rule was created.
+func (g *Client) ProtectBranch(owner, repo string, opts BranchProtectionOption) (bool, error) {
+	rules, resp, err := g.client.ListBranchProtections(owner, repo, gsdk.ListBranchProtectionsOptions{})
+	if err != nil {
+		if resp != nil {
+			return false, &GiteaError{Operation: "list_branch_protections", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return false, err
+	}
+	whitelist := len(opts.PushTeams) > 0 || len(opts.PushUsers) > 0
+	for _, rule := range rules {
+		// rules created before Gitea 1.19 only have a branch name
+		if rule.RuleName != opts.RuleName && (rule.RuleName != "" || rule.BranchName != opts.RuleName) {
+			continue
+		}
+		name := rule.RuleName
+		if name == "" {
+			name = rule.BranchName
+		}
+		_, resp, err := g.client.EditBranchProtection(owner, repo, name, gsdk.EditBranchProtectionOption{
+			EnablePush:             &opts.EnablePush,
+			EnablePushWhitelist:    &whitelist,
+			PushWhitelistTeams:     opts.PushTeams,
+			PushWhitelistUsernames: opts.PushUsers,
+			RequiredApprovals:      &opts.RequiredApprovals,
+		})
+		if err != nil {
+			if resp != nil {
+				return false, &GiteaError{Operation: "edit_branch_protection", Code: resp.StatusCode, Message: err.Error()}
+			}
+			return false, err
+		}
+		return false, nil
+	}
+
+	_, resp, err = g.client.CreateBranchProtection(owner, repo, gsdk.CreateBranchProtectionOption{
+		RuleName:               opts.RuleName,
+		EnablePush:             opts.EnablePush,
+		EnablePushWhitelist:    whitelist,
+		PushWhitelistTeams:     opts.PushTeams,
+		PushWhitelistUsernames: opts.PushUsers,
+		RequiredApprovals:      opts.RequiredApprovals,
+	})
+	if err != nil {
+		if resp != nil {
+			return false, &GiteaError{Operation: "create_branch_protection", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ListOrgHooks lists the webhooks of an organization.
+func (g *Client) ListOrgHooks(org string) ([]*gsdk.Hook, error) {
+	var hooks []*gsdk.Hook
+	for page := 1; ; page++ {
+		list, resp, err := g.client.ListOrgHooks(org, gsdk.ListHooksOptions{
+			ListOptions: gsdk.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			if resp != nil {
+				return nil, &GiteaError{Operation: "list_org_hooks", Code: resp.StatusCode, Message: err.Error()}
+			}
+			return nil, err
+		}
+		hooks = append(hooks, list...)
+		if len(list) < 50 {
+			return hooks, nil
+		}
+	}
+}
+
+// CreateOrgHook creates a Gitea type webhook on an organization.
+func (g *Client) CreateOrgHook(org string, opts CreateHookOption) (*gsdk.Hook, error) {
+	hook, resp, err := g.client.CreateOrgHook(org, gsdk.CreateHookOption{
+		Type:   gsdk.HookTypeGitea,
+		Config: hookConfig(opts),
+		Events: opts.Events,
+		Active: opts.Active,
+	})
+	if err != nil {
+		if resp != nil {
+			return nil, &GiteaError{Operation: "create_org_hook", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	return hook, nil
+}
+
+func hookConfig(opts CreateHookOption) map[string]string {
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = "json"
+	}
+	config := map[string]string{
+		"url":          opts.URL,
+		"content_type": contentType,
+		"http_method":  "post",
+	}
+	if opts.Secret != "" {
+		config["secret"] = opts.Secret
+	}
+	return config
+}
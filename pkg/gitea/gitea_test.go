@@ -0,0 +1,137 @@
+package gitea
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/appleboy/github2gitea/pkg/core"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+func TestTeamOption(t *testing.T) {
+	tests := []struct {
+		permission string
+		want       gsdk.AccessMode
+		units      bool
+	}{
+		{core.GitHubTeamAdmin, gsdk.AccessModeAdmin, false},
+		{core.GitHubTeamMaintain, gsdk.AccessModeWrite, false},
+		{core.GitHubTeamPush, gsdk.AccessModeWrite, false},
+		{core.GitHubTeamPull, gsdk.AccessModeRead, false},
+		{core.GitHubTeamTriage, gsdk.AccessModeRead, true},
+	}
+	for _, tt := range tests {
+		opt, units, err := teamOption(CreateTeamOption{Name: "team", Permission: tt.permission})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.permission, err)
+		}
+		if opt.Permission != tt.want {
+			t.Errorf("%s: permission = %s, want %s", tt.permission, opt.Permission, tt.want)
+		}
+		if (units != nil) != tt.units {
+			t.Errorf("%s: units = %v", tt.permission, units)
+		}
+	}
+
+	if _, _, err := teamOption(CreateTeamOption{Name: "team", Permission: "owner"}); err == nil {
+		t.Error("invalid permission: no error")
+	}
+}
+
+func TestTeamOptionTriageUnits(t *testing.T) {
+	_, units, err := teamOption(CreateTeamOption{Name: "team", Permission: core.GitHubTeamTriage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for unit, want := range map[gsdk.RepoUnitType]string{
+		gsdk.RepoUnitCode:   core.GiteaRepoRead,
+		gsdk.RepoUnitIssues: core.GiteaRepoWrite,
+		gsdk.RepoUnitPulls:  core.GiteaRepoWrite,
+	} {
+		if got := units[string(unit)]; got != want {
+			t.Errorf("%s = %q, want %q", unit, got, want)
+		}
+	}
+}
+
+func TestTeamOptionUnits(t *testing.T) {
+	opt, units, err := teamOption(CreateTeamOption{
+		Name:       "team",
+		Permission: core.GitHubTeamPull,
+		Units:      map[string]string{string(gsdk.RepoUnitWiki): core.GiteaRepoWrite},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opt.Permission != gsdk.AccessModeRead {
+		t.Errorf("permission = %s, want read", opt.Permission)
+	}
+	if got := units[string(gsdk.RepoUnitWiki)]; got != core.GiteaRepoWrite {
+		t.Errorf("wiki = %q, want write", got)
+	}
+	if got := units[string(gsdk.RepoUnitCode)]; got != core.GiteaRepoRead {
+		t.Errorf("code = %q, want read", got)
+	}
+
+	// admin teams have every unit
+	_, units, err = teamOption(CreateTeamOption{
+		Name:       "team",
+		Permission: core.GitHubTeamAdmin,
+		Units:      map[string]string{string(gsdk.RepoUnitWiki): "none"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if units != nil {
+		t.Errorf("admin units = %v, want none", units)
+	}
+}
+
+func TestReconcileUser(t *testing.T) {
+	fake := NewFake()
+	client, err := New(context.Background(), &Config{
+		Server:         FakeServer,
+		Token:          "fake",
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Transport:      fake,
+		ReconcileEmail: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// accounts synced from the directory, and a local one
+	for _, user := range []*gsdk.User{
+		{ID: 100, UserName: "jdoe", LoginName: "jdoe", SourceID: 2, Email: "John.Doe@corp.example"},
+		{ID: 101, UserName: "asmith", LoginName: "asmith", SourceID: 3, Email: "alice@corp.example"},
+		{ID: 102, UserName: "local", Email: "bob@corp.example"},
+	} {
+		fake.users[user.UserName] = user
+	}
+
+	tests := []struct {
+		name    string
+		opts    CreateUserOption
+		want    string
+		created bool
+	}{
+		{"same email", CreateUserOption{SourceID: 2, LoginName: "johnd", Username: "johnd", Email: "john.doe@corp.example"}, "jdoe", false},
+		{"other source", CreateUserOption{SourceID: 2, LoginName: "alice-gh", Username: "alice-gh", Email: "alice@corp.example"}, "alice-gh", true},
+		{"local account", CreateUserOption{SourceID: 2, LoginName: "bob-gh", Username: "bob-gh", Email: "bob@corp.example"}, "bob-gh", true},
+	}
+	for _, tt := range tests {
+		user, created, err := client.CreateOrGetUser(tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if user.UserName != tt.want || created != tt.created {
+			t.Errorf("%s: CreateOrGetUser = %s, created %v, want %s, created %v", tt.name, user.UserName, created, tt.want, tt.created)
+		}
+		// later calls, such as team memberships, use the reconciled username
+		if got := client.Username(tt.opts.LoginName); got != tt.want {
+			t.Errorf("%s: Username = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// migrationPollInterval is the delay between two migration task status checks, a
+// variable so tests do not wait for it.
+var migrationPollInterval = 5 * time.Second
+
+// MigrationState is the state of a Gitea repository migration task.
+type MigrationState string
+
+// Migration task states, in the order of the Gitea task status codes.
+const (
+	MigrationQueued  MigrationState = "queued"
+	MigrationRunning MigrationState = "running"
+	MigrationStopped MigrationState = "stopped"
+	MigrationFailed  MigrationState = "failed"
+	MigrationDone    MigrationState = "done"
+)
+
+// ErrMigrationStuck is returned when a migration task made no progress within the stall timeout.
+var ErrMigrationStuck = errors.New("migration stuck")
+
+var migrationStates = []MigrationState{
+	MigrationQueued,
+	MigrationRunning,
+	MigrationStopped,
+	MigrationFailed,
+	MigrationDone,
+}
+
+// MigrationStatus returns the state of the migration task of a repository
+// and the message reported by Gitea, from the /{owner}/{repo}/-/migrate/status endpoint.
+func (g *Client) MigrationStatus(ctx context.Context, owner, repo string) (MigrationState, string, error) {
+	var status struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	}
+	path := "/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + "/-/migrate/status"
+	if err := g.request(ctx, "migration_status", http.MethodGet, path, "", nil, &status); err != nil {
+		return "", "", err
+	}
+	if status.Status < 0 || status.Status >= len(migrationStates) {
+		return "", status.Message, fmt.Errorf("unknown migration status %d", status.Status)
+	}
+	return migrationStates[status.Status], status.Message, nil
+}
+
+type migrateResult struct {
+	repo *gsdk.Repository
+	err  error
+}
+
+// waitMigration submits the migration and polls the task status while the request is running.
+// The request may end before the task does, for example with a gateway timeout from a
+// reverse proxy; polling then continues until the task reports done or failed, so large
+// repositories do not end with an ambiguous HTTP error.
+//
+// A task whose state and message do not change within opts.StallTimeout is abandoned
+// with ErrMigrationStuck, so one hanging repository does not block the whole run.
+// An abandoned task, stalled or past opts.Timeout, has its repository deleted, which
+// stops the task on the server instead of leaving it running behind the failure.
+func (g *Client) waitMigration(opts MigrateRepoOption, body gsdk.MigrateRepoOption) (*gsdk.Repository, error) {
+	ctx, cancel := context.WithCancel(g.ctx)
+	defer cancel()
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	submitted := make(chan migrateResult, 1)
+	go func() {
+		var repo gsdk.Repository
+		err := g.request(ctx, "migrate_repo", http.MethodPost, "/api/v1/repos/migrate", "", body, &repo)
+		submitted <- migrateResult{repo: &repo, err: err}
+	}()
+
+	ticker := time.NewTicker(migrationPollInterval)
+	defer ticker.Stop()
+
+	var (
+		state        MigrationState
+		message      string
+		submitErr    error
+		lastProgress time.Time
+	)
+	for {
+		select {
+		case result := <-submitted:
+			if result.err == nil {
+				return result.repo, nil
+			}
+			// the request failed, but the task may still be running on the server
+			current, _, err := g.MigrationStatus(ctx, opts.RepoOwner, opts.RepoName)
+			if err != nil || current == MigrationFailed || current == MigrationStopped {
+				return nil, result.err
+			}
+			if current == MigrationDone {
+				return g.GetRepo(opts.RepoOwner, opts.RepoName)
+			}
+			submitErr = result.err
+			submitted = nil
+			g.logMigrationState(opts, current, "request ended before the migration task, keep polling", "error", result.err)
+			if current != state {
+				state = current
+				lastProgress = time.Now()
+			}
+
+		case <-ticker.C:
+			current, currentMessage, err := g.MigrationStatus(ctx, opts.RepoOwner, opts.RepoName)
+			if err != nil {
+				var giteaErr *GiteaError
+				if !errors.As(err, &giteaErr) || giteaErr.Code != http.StatusNotFound {
+					g.logMigrationState(opts, state, "unable to get migration status", "error", err)
+				}
+				continue
+			}
+			if current != state || currentMessage != message {
+				g.logMigrationState(opts, current, "migration task status", "message", currentMessage)
+				if g.onState != nil {
+					g.onState(opts.RepoOwner, opts.RepoName, current, currentMessage)
+				}
+				state = current
+				message = currentMessage
+				lastProgress = time.Now()
+			}
+			// stall detection only applies once the task status could be observed
+			if opts.StallTimeout > 0 && !lastProgress.IsZero() && time.Since(lastProgress) > opts.StallTimeout &&
+				(state == MigrationQueued || state == MigrationRunning) {
+				g.logMigrationState(opts, state, "migration task stalled, abandon it", "stall", opts.StallTimeout)
+				g.abandonMigration(opts, state)
+				return nil, fmt.Errorf("%w: %s/%s still %s without progress for %s",
+					ErrMigrationStuck, opts.RepoOwner, opts.RepoName, state, opts.StallTimeout)
+			}
+			if submitted != nil {
+				continue
+			}
+			switch current {
+			case MigrationDone:
+				return g.GetRepo(opts.RepoOwner, opts.RepoName)
+			case MigrationFailed, MigrationStopped:
+				return nil, &GiteaError{
+					Operation: "migrate_repo",
+					Code:      http.StatusInternalServerError,
+					Message:   fmt.Sprintf("migration %s: %s (%v)", current, message, submitErr),
+				}
+			}
+
+		case <-ctx.Done():
+			if state == "" {
+				state = MigrationQueued
+			}
+			if g.ctx.Err() == nil {
+				g.abandonMigration(opts, state)
+			}
+			return nil, fmt.Errorf("migration of %s/%s still %s after %s: %w",
+				opts.RepoOwner, opts.RepoName, state, opts.Timeout, ctx.Err())
+		}
+	}
+}
+
+// abandonMigration deletes the repository of a migration task given up on. Gitea has
+// no API to cancel a task, deleting its repository makes the task fail.
+func (g *Client) abandonMigration(opts MigrateRepoOption, state MigrationState) {
+	if err := g.DeleteRepository(DeleteRepoOption{Owner: opts.RepoOwner, Repo: opts.RepoName}); err != nil {
+		var giteaErr *GiteaError
+		if !errors.As(err, &giteaErr) || giteaErr.Code != http.StatusNotFound {
+			g.logMigrationState(opts, state, "failed to delete repository of abandoned migration task", "error", err)
+		}
+		return
+	}
+	g.logMigrationState(opts, state, "deleted repository of abandoned migration task")
+}
+
+// removeFailedMigration deletes the empty repository a failed or stopped migration
+// task left behind, which would make the next migration fail with a name conflict.
+// Repositories with content, or without a failed migration task, are kept; lookup
+// errors are ignored and left to the migration to report.
+func (g *Client) removeFailedMigration(opts MigrateRepoOption) error {
+	repo, err := g.GetRepo(opts.RepoOwner, opts.RepoName)
+	if err != nil || !repo.Empty {
+		return nil
+	}
+	state, message, err := g.MigrationStatus(g.ctx, opts.RepoOwner, opts.RepoName)
+	if err != nil || (state != MigrationFailed && state != MigrationStopped) {
+		return nil
+	}
+	g.logMigrationState(opts, state, "remove empty repository left by a failed migration", "message", message)
+	return g.DeleteRepository(DeleteRepoOption{Owner: opts.RepoOwner, Repo: opts.RepoName})
+}
+
+func (g *Client) logMigrationState(opts MigrateRepoOption, state MigrationState, msg string, args ...any) {
+	if g.logger == nil {
+		return
+	}
+	args = append([]any{"owner", opts.RepoOwner, "name", opts.RepoName, "state", state}, args...)
+	g.logger.Info(msg, args...)
+}
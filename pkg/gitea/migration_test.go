@@ -0,0 +1,161 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// migrationServer is a fake Gitea whose migration requests end with a gateway
+// timeout or hang, while the task status follows the given states.
+type migrationServer struct {
+	fake *Fake
+	hang bool
+
+	mu       sync.Mutex
+	statuses []int
+	polls    int
+}
+
+func (s *migrationServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case req.Method == http.MethodPost && req.URL.Path == "/api/v1/repos/migrate":
+		// the task creates the repository and goes on behind the request
+		resp, err := s.fake.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		if s.hang {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return response(http.StatusGatewayTimeout, "gateway timeout"), nil
+	case strings.HasSuffix(req.URL.Path, "/-/migrate/status"):
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		status := s.statuses[min(s.polls, len(s.statuses)-1)]
+		s.polls++
+		return response(http.StatusOK, fmt.Sprintf(`{"status":%d,"message":"cloning"}`, status)), nil
+	}
+	return s.fake.RoundTrip(req)
+}
+
+func response(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func newMigrationClient(t *testing.T, server *migrationServer) *Client {
+	t.Helper()
+	interval := migrationPollInterval
+	migrationPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { migrationPollInterval = interval })
+
+	server.fake = NewFake()
+	client, err := New(context.Background(), &Config{
+		Server:    FakeServer,
+		Token:     "fake",
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Transport: server,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateAndGetOrg(CreateOrgOption{Name: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestMigrateRepoPolling(t *testing.T) {
+	running, failed, done := 1, 3, 4
+	tests := []struct {
+		name     string
+		statuses []int
+		wantErr  bool
+	}{
+		{"task finishes after the request", []int{running, running, done}, false},
+		{"task fails after the request", []int{running, failed}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &migrationServer{statuses: tt.statuses}
+			client := newMigrationClient(t, server)
+			var states []MigrationState
+			client.onState = func(_, _ string, state MigrationState, _ string) {
+				states = append(states, state)
+			}
+
+			repo, err := client.MigrateRepo(MigrateRepoOption{
+				RepoOwner: "acme",
+				RepoName:  "api",
+				CloneAddr: "https://github.com/acme/api",
+				Timeout:   time.Minute,
+			})
+			if tt.wantErr {
+				var giteaErr *GiteaError
+				if !errors.As(err, &giteaErr) || !strings.Contains(giteaErr.Message, "migration failed: cloning") {
+					t.Fatalf("MigrateRepo error = %v, want the failed task", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if repo.FullName != "acme/api" {
+				t.Errorf("repo = %s", repo.FullName)
+			}
+			if len(states) == 0 || states[len(states)-1] != MigrationDone {
+				t.Errorf("states = %v, want the last one done", states)
+			}
+		})
+	}
+}
+
+func TestMigrateRepoStall(t *testing.T) {
+	server := &migrationServer{hang: true, statuses: []int{1}}
+	client := newMigrationClient(t, server)
+
+	start := time.Now()
+	_, err := client.MigrateRepo(MigrateRepoOption{
+		RepoOwner:    "acme",
+		RepoName:     "api",
+		CloneAddr:    "https://github.com/acme/api",
+		StallTimeout: 50 * time.Millisecond,
+	})
+	if !errors.Is(err, ErrMigrationStuck) {
+		t.Fatalf("MigrateRepo error = %v, want ErrMigrationStuck", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stall detected after %s", elapsed)
+	}
+
+	// the repository is deleted, which stops the task
+	deleted := false
+	for _, op := range server.fake.Operations() {
+		if op.Method == http.MethodDelete && op.Path == "/api/v1/repos/acme/api" {
+			deleted = op.Status == http.StatusNoContent
+		}
+	}
+	if !deleted {
+		t.Errorf("repository of the stalled task not deleted: %v", server.fake.Operations())
+	}
+}
@@ -0,0 +1,233 @@
+package gitea
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/appleboy/github2gitea/pkg/redact"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// Credential sources of the local git binary of the push fallback.
+const (
+	// GitCredentialsURL embeds the tokens in the clone and push URLs, visible in the
+	// process arguments while git runs.
+	GitCredentialsURL = "url"
+	// GitCredentialsNetrc reads the source credentials from ~/.netrc only, the git
+	// credential helpers are disabled.
+	GitCredentialsNetrc = "netrc"
+	// GitCredentialsHelper asks the configured git credential helper for the source
+	// credentials.
+	GitCredentialsHelper = "helper"
+)
+
+// migrationBlocked reports whether err means the server refuses repository migrations,
+// either disabled entirely (DISABLE_MIGRATIONS) or for the source host (ALLOWED_DOMAINS,
+// BLOCKED_DOMAINS). Other errors, such as missing permissions on a single repository,
+// are not a reason to fall back to a git push.
+func migrationBlocked(err error) bool {
+	var giteaErr *GiteaError
+	if !errors.As(err, &giteaErr) {
+		return false
+	}
+	message := strings.ToLower(giteaErr.Message)
+	switch giteaErr.Code {
+	case http.StatusForbidden:
+		return strings.Contains(message, "disabled migrations") || strings.Contains(message, "migrations disabled") ||
+			strings.Contains(message, "migrations are disabled")
+	case http.StatusUnprocessableEntity:
+		return strings.Contains(message, "disallowed hosts") || strings.Contains(message, "not allowed to import")
+	}
+	return false
+}
+
+// pushRepo creates an empty repository and pushes the branches and tags of the source
+// with the local git binary. Only the git content is transferred, issues, pull requests,
+// releases and the wiki require the migrate API. The repository is deleted when the
+// push fails and it was created by this call, so a retry does not conflict with it.
+func (g *Client) pushRepo(opts MigrateRepoOption) (*gsdk.Repository, error) {
+	repo, created, err := g.createEmptyRepo(opts)
+	if err != nil {
+		return nil, err
+	}
+	pushed, err := g.pushMirror(opts, repo)
+	if err != nil {
+		if !created {
+			return nil, err
+		}
+		if delErr := g.DeleteRepository(DeleteRepoOption{Owner: opts.RepoOwner, Repo: opts.RepoName}); delErr != nil && g.logger != nil {
+			g.logger.Warn("failed to delete repository after failed push", "owner", opts.RepoOwner, "name", opts.RepoName, "error", delErr)
+		}
+		return nil, err
+	}
+	return pushed, nil
+}
+
+// forkRepo forks the Gitea repository opts.ForkOwner/opts.ForkName into the owner,
+// then pushes the branches and tags of the source over the forked ones.
+func (g *Client) forkRepo(opts MigrateRepoOption) (*gsdk.Repository, error) {
+	path := "/api/v1/repos/" + url.PathEscape(opts.ForkOwner) + "/" + url.PathEscape(opts.ForkName) + "/forks"
+	var repo gsdk.Repository
+	err := g.request(g.ctx, "create_fork", http.MethodPost, path, "", map[string]string{
+		"organization": opts.RepoOwner,
+		"name":         opts.RepoName,
+	}, &repo)
+	var giteaErr *GiteaError
+	if errors.As(err, &giteaErr) && giteaErr.Code == http.StatusUnprocessableEntity {
+		// the owner is a user, forking into a user account needs its identity
+		err = g.request(g.ctx, "create_fork", http.MethodPost, path, opts.RepoOwner, map[string]string{
+			"name": opts.RepoName,
+		}, &repo)
+	}
+	if err != nil {
+		// a conflict is a repository which is not ours to push over
+		return nil, err
+	}
+	return g.pushMirror(opts, &repo)
+}
+
+// pushMirror pushes the branches and tags of the source to a Gitea repository.
+func (g *Client) pushMirror(opts MigrateRepoOption, repo *gsdk.Repository) (*gsdk.Repository, error) {
+	ctx := g.ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	source, err := url.Parse(opts.CloneAddr)
+	if err != nil {
+		return nil, err
+	}
+	if opts.AuthToken != "" && g.embedCredentials() {
+		source.User = url.UserPassword(opts.AuthUsername, opts.AuthToken)
+	}
+	target, err := url.Parse(repo.CloneURL)
+	if err != nil {
+		return nil, err
+	}
+	if g.embedCredentials() {
+		target.User = url.UserPassword("github2gitea", g.token)
+	}
+
+	dir, err := os.MkdirTemp("", "github2gitea-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := g.git(ctx, opts, "", "clone", "--mirror", source.String(), dir); err != nil {
+		return nil, err
+	}
+	// a plain --mirror push also sends refs/pull/*, which Gitea rejects
+	if err := g.git(ctx, opts, dir, "push", "--force", target.String(),
+		"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"); err != nil {
+		return nil, err
+	}
+
+	// LFS objects are not part of the git refs, they need the git-lfs extension
+	if opts.LFS {
+		fetch := []string{"lfs", "fetch", "--all", "origin"}
+		if opts.LFSEndpoint != "" {
+			fetch = append([]string{"-c", "lfs.url=" + opts.LFSEndpoint}, fetch...)
+		}
+		if err := g.git(ctx, opts, dir, fetch...); err != nil {
+			return nil, err
+		}
+		if err := g.git(ctx, opts, dir, "lfs", "push", "--all", target.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	return g.GetRepo(opts.RepoOwner, opts.RepoName)
+}
+
+// createEmptyRepo creates the repository in the owner organization, or in the user
+// namespace through the admin API. An existing repository is only reused when it is
+// empty, like one left by a previous attempt; the force push would otherwise replace
+// its branches. It reports whether the repository was created.
+func (g *Client) createEmptyRepo(opts MigrateRepoOption) (*gsdk.Repository, bool, error) {
+	createOpts := gsdk.CreateRepoOption{
+		Name:        opts.RepoName,
+		Description: opts.Description,
+		Private:     opts.Private,
+	}
+	repo, resp, err := g.client.CreateOrgRepo(opts.RepoOwner, createOpts)
+	if err != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+		repo, resp, err = g.client.AdminCreateRepo(opts.RepoOwner, createOpts)
+	}
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusConflict {
+			existing, getErr := g.GetRepo(opts.RepoOwner, opts.RepoName)
+			if getErr != nil {
+				return nil, false, getErr
+			}
+			if !existing.Empty {
+				return nil, false, &GiteaError{Operation: "create_repo", Code: resp.StatusCode, Message: "repository already exists and is not empty"}
+			}
+			return existing, false, nil
+		}
+		if resp != nil {
+			return nil, false, &GiteaError{Operation: "create_repo", Code: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, false, err
+	}
+	return repo, true, nil
+}
+
+// git runs a git command, masking the credentials in its output on failure.
+func (g *Client) git(ctx context.Context, opts MigrateRepoOption, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if g.skipVerify {
+		cmd.Env = append(cmd.Env, "GIT_SSL_NO_VERIFY=true")
+	}
+	cmd.Env = append(cmd.Env, g.credentialsEnv()...)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	redact.Add(opts.AuthToken)
+	return fmt.Errorf("git %s failed: %w: %s", args[0], err, redact.String(strings.TrimSpace(string(out))))
+}
+
+// embedCredentials reports whether the tokens are part of the git URLs.
+func (g *Client) embedCredentials() bool {
+	return g.gitCreds == "" || g.gitCreds == GitCredentialsURL
+}
+
+// credentialsEnv configures git through the environment, which unlike the arguments
+// other processes cannot read, when the tokens are not embedded in the URLs: the Gitea
+// token is sent as header to the Gitea server only, and the netrc mode disables the
+// credential helpers. The variables need git 2.31 or later.
+func (g *Client) credentialsEnv() []string {
+	if g.embedCredentials() {
+		return nil
+	}
+	basic := base64.StdEncoding.EncodeToString([]byte("github2gitea:" + g.token))
+	config := [][2]string{
+		{"http." + strings.TrimSuffix(g.server, "/") + "/.extraHeader", "Authorization: Basic " + basic},
+	}
+	if g.gitCreds == GitCredentialsNetrc {
+		// an empty value clears the configured helpers
+		config = append(config, [2]string{"credential.helper", ""})
+	}
+	env := []string{"GIT_CONFIG_COUNT=" + strconv.Itoa(len(config))}
+	for i, kv := range config {
+		env = append(env,
+			"GIT_CONFIG_KEY_"+strconv.Itoa(i)+"="+kv[0],
+			"GIT_CONFIG_VALUE_"+strconv.Itoa(i)+"="+kv[1],
+		)
+	}
+	return env
+}
@@ -0,0 +1,119 @@
+package gitea
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+)
+
+// request calls a Gitea endpoint that is not covered by the SDK.
+// path is relative to the server URL. When sudo is set the request is
+// performed as that user, which requires an admin token. The JSON response
+// is decoded into out when it is not nil.
+func (g *Client) request(ctx context.Context, operation, method, path, sudo string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.server+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if sudo != "" {
+		req.Header.Set("Sudo", sudo)
+	}
+	return g.do(req, operation, out)
+}
+
+// upload posts the file at file as the multipart form field of a Gitea endpoint.
+// The file is streamed, so large files are not held in memory, and streamed again
+// from the start when the transport retries the request.
+func (g *Client) upload(ctx context.Context, operation, path, field, filename, file string, out any) error {
+	body, boundary := multipartFile(field, filename, file, "")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.server+path, body)
+	if err != nil {
+		body.Close()
+		return err
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		body, _ := multipartFile(field, filename, file, boundary)
+		return body, nil
+	}
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+	return g.do(req, operation, out)
+}
+
+// multipartFile streams a multipart form with the file as field, with the given
+// boundary or a random one when empty. It returns the body and its boundary.
+func multipartFile(field, filename, file, boundary string) (io.ReadCloser, string) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	if boundary != "" {
+		_ = mw.SetBoundary(boundary)
+	}
+	go func() {
+		f, err := os.Open(file)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		defer f.Close()
+		part, err := mw.CreateFormFile(field, filename)
+		if err == nil {
+			_, err = io.Copy(part, f)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, mw.Boundary()
+}
+
+// do sends a request with the Gitea token and decodes the JSON response into out
+// when it is not nil. Error responses are returned as a GiteaError.
+func (g *Client) do(req *http.Request, operation string, out any) error {
+	req.Header.Set("Authorization", "token "+g.token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "github2gitea")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		message := string(data)
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			message = apiErr.Message
+		}
+		return &GiteaError{Operation: operation, Code: resp.StatusCode, Message: message}
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("gitea %s: invalid response: %w", operation, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// maxAssetAttempts is the number of times a release asset download is resumed.
+const maxAssetAttempts = 5
+
+// ListReleases lists the releases of a repository using paginatedFetch
+func (c *Client) ListReleases(ctx context.Context, owner, repo string) ([]*github.RepositoryRelease, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.RepositoryRelease, *github.Response, error) {
+		return c.gh.Repositories.ListReleases(ctx, owner, repo, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+	})
+}
+
+// DownloadReleaseAsset streams a release asset into f. An interrupted transfer is
+// resumed from the bytes already written with a range request, since large assets
+// rarely come through in one piece.
+func (c *Client) DownloadReleaseAsset(ctx context.Context, owner, repo string, asset *github.ReleaseAsset, f *os.File) error {
+	var written int64
+	for attempt := 1; ; attempt++ {
+		// the redirect URL is signed for a few minutes only, get a new one every attempt
+		rc, redirectURL, err := c.gh.Repositories.DownloadReleaseAsset(ctx, owner, repo, asset.GetID(), nil)
+		if err != nil {
+			return err
+		}
+		if rc != nil {
+			// served without redirect, there is nothing to resume from
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				rc.Close()
+				return err
+			}
+			if err := f.Truncate(0); err != nil {
+				rc.Close()
+				return err
+			}
+			_, err = io.Copy(f, rc)
+			rc.Close()
+			return err
+		}
+
+		written, err = c.downloadFrom(ctx, redirectURL, f, written)
+		if err == nil {
+			return nil
+		}
+		if attempt == maxAssetAttempts || ctx.Err() != nil {
+			return err
+		}
+		c.logger.Warn("release asset download interrupted, resume",
+			"repo", owner+"/"+repo,
+			"asset", asset.GetName(),
+			"offset", written,
+			"error", err,
+		)
+	}
+}
+
+// DownloadAttachment streams a file attached to an issue or comment into f and returns
+// its content type. The token is sent to GitHub only, the redirect to the storage is
+// followed without it.
+func (c *Client) DownloadAttachment(ctx context.Context, url string, f *os.File) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	// private attachments need the token of a user with access to the repository
+	req.Header.Set("Authorization", "token "+c.token)
+	resp, err := c.download.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download attachment: unexpected status %s", resp.Status)
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return "", err
+	}
+	return resp.Header.Get("Content-Type"), nil
+}
+
+// downloadFrom appends the content of url from offset to f and returns the bytes
+// written in total. A server ignoring the range restarts the file.
+func (c *Client) downloadFrom(ctx context.Context, url string, f *os.File, offset int64) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return offset, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	// the signed storage URL refuses the GitHub token
+	resp, err := c.download.Do(req)
+	if err != nil {
+		return offset, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		offset = 0
+		if err := f.Truncate(0); err != nil {
+			return 0, err
+		}
+	default:
+		return offset, fmt.Errorf("download release asset: unexpected status %s", resp.Status)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+	n, err := io.Copy(f, resp.Body)
+	return offset + n, err
+}
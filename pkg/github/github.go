@@ -4,25 +4,70 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/appleboy/github2gitea/pkg/redact"
+	"github.com/appleboy/github2gitea/pkg/retry"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
 	"github.com/google/go-github/v71/github"
 )
 
+// DefaultPageSize is the number of items per page requested from list endpoints, the maximum allowed by GitHub.
+const DefaultPageSize = 100
+
 type Config struct {
 	Server     string
 	Token      string
 	SkipVerify bool
 	Logger     *slog.Logger
+	// RateLimitThreshold is the remaining quota at which requests pause until the rate limit resets.
+	RateLimitThreshold int
+	// MaxRetries is the number of retries for transient errors.
+	MaxRetries int
+	// RetryBackoff is the initial delay between retries.
+	RetryBackoff time.Duration
+	// GraphQL enables organization enumeration through the GraphQL API.
+	GraphQL bool
+	// PageSize is the number of items per page for list endpoints, DefaultPageSize when unset.
+	PageSize int
+	// RecordDir stores every API response as a fixture in this directory when set.
+	RecordDir string
+	// ReplayDir serves API responses from fixtures recorded in this directory, without network access.
+	ReplayDir string
+	// SlowThreshold logs API calls taking longer than this, disabled when zero.
+	SlowThreshold time.Duration
 }
 
 // Client wraps the GitHub client with additional methods
 type Client struct {
-	logger *slog.Logger
-	gh     *github.Client
-	token  string
+	logger  *slog.Logger
+	gh      *github.Client
+	token   string
+	graphQL bool
+	perPage int
+	// rateLimit is nil when replaying recorded responses.
+	rateLimit *rateLimitTransport
+	scopes    *scopeTransport
+	// download fetches files from storage URLs outside the API, with the TLS settings
+	// of the API client but without its token.
+	download *http.Client
+
+	treeMu sync.Mutex
+	trees  map[string]*orgTree
+}
+
+// IsNotFound reports whether a GitHub API call failed with 404, for missing or
+// inaccessible objects.
+func IsNotFound(err error) bool {
+	var ghErr *github.ErrorResponse
+	return errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusNotFound
 }
 
 // NewClient creates a new GitHub Client
@@ -30,19 +75,62 @@ func NewClient(cfg *Config) (*Client, error) {
 	if cfg == nil {
 		return nil, errors.New("github config is required")
 	}
-	if cfg.Token == "" {
+	if cfg.RecordDir != "" && cfg.ReplayDir != "" {
+		return nil, errors.New("github record and replay cannot be used together")
+	}
+	if cfg.Token == "" && cfg.ReplayDir == "" {
 		return nil, errors.New("github token is required")
 	}
+	redact.Add(cfg.Token)
 	var err error
-	httpClient := &http.Client{
-		Timeout: 10 * time.Second,
+	// The timeout applies to the response headers only, since throttled
+	// requests may legitimately wait for the rate limit window to reset.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = 10 * time.Second
+	if cfg.SkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec
 	}
 
-	if cfg.SkipVerify {
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+	var base http.RoundTripper = &trace.SlowTransport{
+		Base:      transport,
+		System:    "github",
+		Threshold: cfg.SlowThreshold,
+		Logger:    cfg.Logger,
+	}
+	download := &http.Client{
+		Transport: &trace.Transport{
+			Base:   base,
+			System: "github",
+		},
+	}
+	if cfg.RecordDir != "" {
+		base, err = newRecordTransport(base, cfg.RecordDir)
+		if err != nil {
+			return nil, err
 		}
 	}
+	rateLimit := newRateLimitTransport(base, cfg.RateLimitThreshold, cfg.Logger)
+	base = rateLimit
+	maxRetries := cfg.MaxRetries
+	if cfg.ReplayDir != "" {
+		// recorded responses are served as is, neither throttled nor retried
+		base = &replayTransport{dir: cfg.ReplayDir}
+		rateLimit = nil
+		maxRetries = 0
+	}
+
+	scopes := newScopeTransport(&retry.Transport{
+		Base:       base,
+		MaxRetries: maxRetries,
+		Backoff:    cfg.RetryBackoff,
+		Logger:     cfg.Logger,
+	})
+	httpClient := &http.Client{
+		Transport: &trace.Transport{
+			Base:   scopes,
+			System: "github",
+		},
+	}
 
 	ghClient := github.NewClient(httpClient).
 		WithAuthToken(cfg.Token)
@@ -58,15 +146,48 @@ func NewClient(cfg *Config) (*Client, error) {
 		}
 	}
 
+	perPage := cfg.PageSize
+	if perPage <= 0 || perPage > DefaultPageSize {
+		perPage = DefaultPageSize
+	}
+
 	return &Client{
-		gh:     ghClient,
-		logger: cfg.Logger,
-		token:  cfg.Token,
+		gh:        ghClient,
+		logger:    cfg.Logger,
+		token:     cfg.Token,
+		graphQL:   cfg.GraphQL,
+		perPage:   perPage,
+		rateLimit: rateLimit,
+		scopes:    scopes,
+		download:  download,
+		trees:     make(map[string]*orgTree),
 	}, nil
 }
 
+// Host returns the host of the GitHub web interface, github.com or the host of
+// the GitHub Enterprise Server.
+func (c *Client) Host() string {
+	if c.gh.BaseURL.Host == "api.github.com" {
+		return "github.com"
+	}
+	return c.gh.BaseURL.Host
+}
+
+// RateLimit returns the remaining GitHub REST quota and its reset time as of the last response.
+// remaining is -1 when unknown: before the first response, while paused for the reset
+// or when replaying recorded responses.
+func (c *Client) RateLimit() (remaining int, reset time.Time) {
+	if c.rateLimit == nil {
+		return -1, time.Time{}
+	}
+	return c.rateLimit.status()
+}
+
 // GetUser gets a user's information by username
 func (c *Client) GetUser(ctx context.Context, username string) (*github.User, error) {
+	if user, ok := c.cachedUser(username); ok {
+		return user, nil
+	}
 	user, _, err := c.gh.Users.Get(ctx, username)
 	if err != nil {
 		return nil, err
@@ -94,6 +215,11 @@ func (c *Client) GetUserPermissionFromRepo(ctx context.Context, owner, repo, use
 
 // GetUserPermissionFromOrg gets a user's permission level in an organization
 func (c *Client) GetUserPermissionFromOrg(ctx context.Context, org, username string) (string, error) {
+	if tree, ok := c.cachedOrgTree(ctx, org); ok {
+		if role, ok := tree.roles[strings.ToLower(username)]; ok {
+			return role, nil
+		}
+	}
 	membership, _, err := c.gh.Organizations.GetOrgMembership(ctx, username, org)
 	if err != nil {
 		return "", err
@@ -110,7 +236,23 @@ func (c *Client) ListRepoCollaborators(ctx context.Context, owner, repo string)
 		return c.gh.Repositories.ListCollaborators(ctx, owner, repo, &github.ListCollaboratorsOptions{
 			ListOptions: github.ListOptions{
 				Page:    page,
-				PerPage: 100,
+				PerPage: c.perPage,
+			},
+		})
+	})
+}
+
+/*
+ListOutsideCollaborators lists the collaborators of a repository who are not members
+of its organization, with their permissions on the repository.
+*/
+func (c *Client) ListOutsideCollaborators(ctx context.Context, owner, repo string) ([]*github.User, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.User, *github.Response, error) {
+		return c.gh.Repositories.ListCollaborators(ctx, owner, repo, &github.ListCollaboratorsOptions{
+			Affiliation: "outside",
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: c.perPage,
 			},
 		})
 	})
@@ -143,21 +285,27 @@ func paginatedFetch[T any](
 // ListOrgTeams lists all teams in an organization
 // permission can be one of: "pull", "triage", "push", "maintain", "admin"
 func (c *Client) ListOrgTeams(ctx context.Context, org string) ([]*github.Team, error) {
+	if tree, ok := c.cachedOrgTree(ctx, org); ok {
+		return tree.teams, nil
+	}
 	return paginatedFetch(ctx, func(page int) ([]*github.Team, *github.Response, error) {
 		return c.gh.Teams.ListTeams(ctx, org, &github.ListOptions{
 			Page:    page,
-			PerPage: 100,
+			PerPage: c.perPage,
 		})
 	})
 }
 
 // ListOrgTeamsMembers lists all members in a team using paginatedFetch
 func (c *Client) ListOrgTeamsMembers(ctx context.Context, org string, slug string) ([]*github.User, error) {
+	if tree, ok := c.cachedOrgTree(ctx, org); ok && !tree.incomplete[slug] {
+		return tree.teamMembers[slug], nil
+	}
 	return paginatedFetch(ctx, func(page int) ([]*github.User, *github.Response, error) {
 		return c.gh.Teams.ListTeamMembersBySlug(ctx, org, slug, &github.TeamListTeamMembersOptions{
 			ListOptions: github.ListOptions{
 				Page:    page,
-				PerPage: 100,
+				PerPage: c.perPage,
 			},
 		})
 	})
@@ -165,21 +313,62 @@ func (c *Client) ListOrgTeamsMembers(ctx context.Context, org string, slug strin
 
 // ListTeamReposBySlug lists all repositories a team has access to using team slug and paginatedFetch
 func (c *Client) ListTeamReposBySlug(ctx context.Context, org string, slug string) ([]*github.Repository, error) {
+	if tree, ok := c.cachedOrgTree(ctx, org); ok && !tree.incomplete[slug] {
+		return tree.teamRepos[slug], nil
+	}
 	return paginatedFetch(ctx, func(page int) ([]*github.Repository, *github.Response, error) {
 		return c.gh.Teams.ListTeamReposBySlug(ctx, org, slug, &github.ListOptions{
 			Page:    page,
-			PerPage: 100,
+			PerPage: c.perPage,
 		})
 	})
 }
 
 // ListOrgUsers lists all members in an organization using paginatedFetch
 func (c *Client) ListOrgUsers(ctx context.Context, org string) ([]*github.User, error) {
+	if tree, ok := c.cachedOrgTree(ctx, org); ok {
+		return tree.members, nil
+	}
+	return paginatedFetch(ctx, func(page int) ([]*github.User, *github.Response, error) {
+		return c.gh.Organizations.ListMembers(ctx, org, &github.ListMembersOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: c.perPage,
+			},
+		})
+	})
+}
+
+// ListOrgAdmins lists the owners of an organization.
+func (c *Client) ListOrgAdmins(ctx context.Context, org string) ([]*github.User, error) {
+	if tree, ok := c.cachedOrgTree(ctx, org); ok {
+		var admins []*github.User
+		for _, member := range tree.members {
+			if tree.roles[strings.ToLower(member.GetLogin())] == "admin" {
+				admins = append(admins, member)
+			}
+		}
+		return admins, nil
+	}
 	return paginatedFetch(ctx, func(page int) ([]*github.User, *github.Response, error) {
 		return c.gh.Organizations.ListMembers(ctx, org, &github.ListMembersOptions{
+			Role: "admin",
 			ListOptions: github.ListOptions{
 				Page:    page,
-				PerPage: 100,
+				PerPage: c.perPage,
+			},
+		})
+	})
+}
+
+// ListOrgPublicMembers lists the members of an organization who made their membership public.
+func (c *Client) ListOrgPublicMembers(ctx context.Context, org string) ([]*github.User, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.User, *github.Response, error) {
+		return c.gh.Organizations.ListMembers(ctx, org, &github.ListMembersOptions{
+			PublicOnly: true,
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: c.perPage,
 			},
 		})
 	})
@@ -187,22 +376,85 @@ func (c *Client) ListOrgUsers(ctx context.Context, org string) ([]*github.User,
 
 // ListOrgRepos lists all repositories in an organization using paginatedFetch
 func (c *Client) ListOrgRepos(ctx context.Context, org string) ([]*github.Repository, error) {
+	if tree, ok := c.cachedOrgTree(ctx, org); ok {
+		return tree.repos, nil
+	}
 	return paginatedFetch(ctx, func(page int) ([]*github.Repository, *github.Response, error) {
 		return c.gh.Repositories.ListByOrg(ctx, org, &github.RepositoryListByOrgOptions{
 			ListOptions: github.ListOptions{
 				Page:    page,
-				PerPage: 10,
+				PerPage: c.perPage,
+			},
+		})
+	})
+}
+
+// ListUserRepos lists all repositories owned by a user using paginatedFetch
+func (c *Client) ListUserRepos(ctx context.Context, username string) ([]*github.Repository, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Repository, *github.Response, error) {
+		return c.gh.Repositories.ListByUser(ctx, username, &github.RepositoryListByUserOptions{
+			Type: "owner",
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: c.perPage,
 			},
 		})
 	})
 }
 
+// ListAccessibleUserRepos lists all repositories owned by a user, including the
+// private repositories the authenticated token has access to.
+func (c *Client) ListAccessibleUserRepos(ctx context.Context, username string) ([]*github.Repository, error) {
+	repos, err := c.ListUserRepos(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	// the public user endpoint never returns private repositories,
+	// so collect them from the authenticated user's accessible list.
+	accessible, err := paginatedFetch(ctx, func(page int) ([]*github.Repository, *github.Response, error) {
+		return c.gh.Repositories.ListByAuthenticatedUser(ctx, &github.RepositoryListByAuthenticatedUserOptions{
+			Visibility:  "private",
+			Affiliation: "owner,collaborator",
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: c.perPage,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]struct{}, len(repos))
+	for _, repo := range repos {
+		seen[repo.GetID()] = struct{}{}
+	}
+	for _, repo := range accessible {
+		if !strings.EqualFold(repo.GetOwner().GetLogin(), username) {
+			continue
+		}
+		if _, ok := seen[repo.GetID()]; ok {
+			continue
+		}
+		seen[repo.GetID()] = struct{}{}
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
 // GetRepo gets a single repository's information
 func (c *Client) GetRepo(ctx context.Context, owner, repo string) (*github.Repository, error) {
 	repository, _, err := c.gh.Repositories.Get(ctx, owner, repo)
 	return repository, err
 }
 
+// ArchiveRepo archives a repository, making it read-only.
+func (c *Client) ArchiveRepo(ctx context.Context, owner, repo string) error {
+	_, _, err := c.gh.Repositories.Edit(ctx, owner, repo, &github.Repository{Archived: github.Ptr(true)})
+	return err
+}
+
 // GetOrg gets a single organization's information
 func (c *Client) GetOrg(ctx context.Context, org string) (*github.Organization, error) {
 	organization, _, err := c.gh.Organizations.Get(ctx, org)
@@ -216,7 +468,7 @@ func (c *Client) ListOrgActionsVariables(ctx context.Context, org string) ([]*gi
 	return paginatedFetch(ctx, func(page int) ([]*github.ActionsVariable, *github.Response, error) {
 		resp, respObj, err := c.gh.Actions.ListOrgVariables(ctx, org, &github.ListOptions{
 			Page:    page,
-			PerPage: 100,
+			PerPage: c.perPage,
 		})
 		if err != nil {
 			return nil, respObj, err
@@ -232,7 +484,7 @@ func (c *Client) ListRepoActionsVariables(ctx context.Context, owner, repo strin
 	return paginatedFetch(ctx, func(page int) ([]*github.ActionsVariable, *github.Response, error) {
 		resp, respObj, err := c.gh.Actions.ListRepoVariables(ctx, owner, repo, &github.ListOptions{
 			Page:    page,
-			PerPage: 100,
+			PerPage: c.perPage,
 		})
 		if err != nil {
 			return nil, respObj, err
@@ -241,12 +493,396 @@ func (c *Client) ListRepoActionsVariables(ctx context.Context, owner, repo strin
 	})
 }
 
+// ListRepoForks lists the direct forks of a repository using paginatedFetch
+func (c *Client) ListRepoForks(ctx context.Context, owner, repo string) ([]*github.Repository, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Repository, *github.Response, error) {
+		return c.gh.Repositories.ListForks(ctx, owner, repo, &github.RepositoryListForksOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: c.perPage,
+			},
+		})
+	})
+}
+
+// ListRepoLanguages returns the bytes of code per language of a repository.
+func (c *Client) ListRepoLanguages(ctx context.Context, owner, repo string) (map[string]int, error) {
+	languages, _, err := c.gh.Repositories.ListLanguages(ctx, owner, repo)
+	return languages, err
+}
+
+// ListRepoLabels lists the issue labels of a repository.
+func (c *Client) ListRepoLabels(ctx context.Context, owner, repo string) ([]*github.Label, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Label, *github.Response, error) {
+		return c.gh.Issues.ListLabels(ctx, owner, repo, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+	})
+}
+
+// ListRepoHooks lists the webhooks of a repository using paginatedFetch
+func (c *Client) ListRepoHooks(ctx context.Context, owner, repo string) ([]*github.Hook, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Hook, *github.Response, error) {
+		return c.gh.Repositories.ListHooks(ctx, owner, repo, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+	})
+}
+
+// ListOrgHooks lists the webhooks of an organization using paginatedFetch
+func (c *Client) ListOrgHooks(ctx context.Context, org string) ([]*github.Hook, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Hook, *github.Response, error) {
+		return c.gh.Organizations.ListHooks(ctx, org, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+	})
+}
+
+// ListUserGists lists the gists of a user using paginatedFetch.
+// Secret gists are only returned for the authenticated user.
+func (c *Client) ListUserGists(ctx context.Context, username string) ([]*github.Gist, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Gist, *github.Response, error) {
+		return c.gh.Gists.List(ctx, username, &github.GistListOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: c.perPage,
+			},
+		})
+	})
+}
+
+// ListStarred lists the repositories starred by a user using paginatedFetch
+func (c *Client) ListStarred(ctx context.Context, username string) ([]*github.Repository, error) {
+	starred, err := paginatedFetch(ctx, func(page int) ([]*github.StarredRepository, *github.Response, error) {
+		return c.gh.Activity.ListStarred(ctx, username, &github.ActivityListStarredOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: c.perPage,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	repos := make([]*github.Repository, 0, len(starred))
+	for _, star := range starred {
+		repos = append(repos, star.GetRepository())
+	}
+	return repos, nil
+}
+
+// ListWatched lists the repositories watched by a user using paginatedFetch
+func (c *Client) ListWatched(ctx context.Context, username string) ([]*github.Repository, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Repository, *github.Response, error) {
+		return c.gh.Activity.ListWatched(ctx, username, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+	})
+}
+
+// ListFollowing lists the users followed by a user using paginatedFetch
+func (c *Client) ListFollowing(ctx context.Context, username string) ([]*github.User, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.User, *github.Response, error) {
+		return c.gh.Users.ListFollowing(ctx, username, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+	})
+}
+
+// ListRepoVariables lists the Actions variables of a repository using paginatedFetch
+func (c *Client) ListRepoVariables(ctx context.Context, owner, repo string) ([]*github.ActionsVariable, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.ActionsVariable, *github.Response, error) {
+		variables, resp, err := c.gh.Actions.ListRepoVariables(ctx, owner, repo, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		return variables.Variables, resp, nil
+	})
+}
+
+// ListRepoSecrets lists the Actions secrets of a repository using paginatedFetch.
+// GitHub only returns the secret names, never their values.
+func (c *Client) ListRepoSecrets(ctx context.Context, owner, repo string) ([]*github.Secret, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Secret, *github.Response, error) {
+		secrets, resp, err := c.gh.Actions.ListRepoSecrets(ctx, owner, repo, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		return secrets.Secrets, resp, nil
+	})
+}
+
+// ListRepoEnvironments lists the deployment environments of a repository using paginatedFetch.
+func (c *Client) ListRepoEnvironments(ctx context.Context, owner, repo string) ([]*github.Environment, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Environment, *github.Response, error) {
+		envs, resp, err := c.gh.Repositories.ListEnvironments(ctx, owner, repo, &github.EnvironmentListOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: c.perPage,
+			},
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		return envs.Environments, resp, nil
+	})
+}
+
+// ListEnvVariables lists the Actions variables of a deployment environment using paginatedFetch
+func (c *Client) ListEnvVariables(ctx context.Context, owner, repo, env string) ([]*github.ActionsVariable, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.ActionsVariable, *github.Response, error) {
+		variables, resp, err := c.gh.Actions.ListEnvVariables(ctx, owner, repo, env, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		return variables.Variables, resp, nil
+	})
+}
+
+// ListEnvSecrets lists the Actions secrets of a deployment environment of the repository
+// with the given ID using paginatedFetch. GitHub only returns the secret names.
+func (c *Client) ListEnvSecrets(ctx context.Context, repoID int64, env string) ([]*github.Secret, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Secret, *github.Response, error) {
+		secrets, resp, err := c.gh.Actions.ListEnvSecrets(ctx, int(repoID), env, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		return secrets.Secrets, resp, nil
+	})
+}
+
+// ListOrgRoles lists the organization roles, predefined and custom, of an organization.
+func (c *Client) ListOrgRoles(ctx context.Context, org string) ([]*github.CustomOrgRoles, error) {
+	roles, _, err := c.gh.Organizations.ListRoles(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	return roles.CustomRepoRoles, nil
+}
+
+// ListOrgInstallations lists the GitHub App installations of an organization using paginatedFetch.
+func (c *Client) ListOrgInstallations(ctx context.Context, org string) ([]*github.Installation, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Installation, *github.Response, error) {
+		installations, resp, err := c.gh.Organizations.ListInstallations(ctx, org, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		return installations.Installations, resp, nil
+	})
+}
+
+// ListOrgAuditLog lists the audit log events of an organization matching a search
+// phrase, e.g. created:>=2024-01-01, git events included, oldest first. The audit log
+// needs GitHub Enterprise Cloud or Server.
+func (c *Client) ListOrgAuditLog(ctx context.Context, org, phrase string) ([]*github.AuditEntry, error) {
+	opts := &github.GetAuditLogOptions{
+		Phrase:            github.Ptr(phrase),
+		Include:           github.Ptr("all"),
+		Order:             github.Ptr("asc"),
+		ListCursorOptions: github.ListCursorOptions{PerPage: c.perPage},
+	}
+	var entries []*github.AuditEntry
+	for {
+		list, resp, err := c.gh.Organizations.GetAuditLog(ctx, org, opts)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, list...)
+		// the audit log is paginated with cursors
+		if resp == nil || resp.After == "" {
+			return entries, nil
+		}
+		opts.After = resp.After
+	}
+}
+
+// ListOrgRoleTeams lists the teams assigned to an organization role using paginatedFetch
+func (c *Client) ListOrgRoleTeams(ctx context.Context, org string, roleID int64) ([]*github.Team, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Team, *github.Response, error) {
+		return c.gh.Organizations.ListTeamsAssignedToOrgRole(ctx, org, roleID, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+	})
+}
+
+// ListOrgRoleUsers lists the users assigned to an organization role using paginatedFetch
+func (c *Client) ListOrgRoleUsers(ctx context.Context, org string, roleID int64) ([]*github.User, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.User, *github.Response, error) {
+		return c.gh.Organizations.ListUsersAssignedToOrgRole(ctx, org, roleID, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+	})
+}
+
+// ListSecurityManagerTeams lists the security manager teams of an organization,
+// for servers without organization roles.
+func (c *Client) ListSecurityManagerTeams(ctx context.Context, org string) ([]*github.Team, error) {
+	teams, _, err := c.gh.Organizations.ListSecurityManagerTeams(ctx, org)
+	return teams, err
+}
+
+// ListOrgVariables lists the Actions variables of an organization using paginatedFetch
+func (c *Client) ListOrgVariables(ctx context.Context, org string) ([]*github.ActionsVariable, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.ActionsVariable, *github.Response, error) {
+		variables, resp, err := c.gh.Actions.ListOrgVariables(ctx, org, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		return variables.Variables, resp, nil
+	})
+}
+
+// ListOrgSecrets lists the Actions secrets of an organization using paginatedFetch.
+// GitHub only returns the secret names and visibility, never their values.
+func (c *Client) ListOrgSecrets(ctx context.Context, org string) ([]*github.Secret, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Secret, *github.Response, error) {
+		secrets, resp, err := c.gh.Actions.ListOrgSecrets(ctx, org, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		return secrets.Secrets, resp, nil
+	})
+}
+
+// ListOrgSecretRepos lists the repositories selected for an organization secret using paginatedFetch
+func (c *Client) ListOrgSecretRepos(ctx context.Context, org, name string) ([]*github.Repository, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Repository, *github.Response, error) {
+		repos, resp, err := c.gh.Actions.ListSelectedReposForOrgSecret(ctx, org, name, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		return repos.Repositories, resp, nil
+	})
+}
+
+// ListOrgVariableRepos lists the repositories selected for an organization variable using paginatedFetch
+func (c *Client) ListOrgVariableRepos(ctx context.Context, org, name string) ([]*github.Repository, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.Repository, *github.Response, error) {
+		repos, resp, err := c.gh.Actions.ListSelectedReposForOrgVariable(ctx, org, name, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		return repos.Repositories, resp, nil
+	})
+}
+
 // ListUserKeys lists all public keys for a user using paginatedFetch
 func (c *Client) ListUserKeys(ctx context.Context, username string) ([]*github.Key, error) {
 	return paginatedFetch(ctx, func(page int) ([]*github.Key, *github.Response, error) {
 		return c.gh.Users.ListKeys(ctx, username, &github.ListOptions{
 			Page:    page,
-			PerPage: 100,
+			PerPage: c.perPage,
 		})
 	})
 }
+
+// ListUserGPGKeys lists the public GPG keys of a user, with their armored key.
+func (c *Client) ListUserGPGKeys(ctx context.Context, username string) ([]*github.GPGKey, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.GPGKey, *github.Response, error) {
+		return c.gh.Users.ListGPGKeys(ctx, username, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+	})
+}
+
+// ListUserSSHSigningKeys lists the public SSH keys a user signs commits with.
+func (c *Client) ListUserSSHSigningKeys(ctx context.Context, username string) ([]*github.SSHSigningKey, error) {
+	return paginatedFetch(ctx, func(page int) ([]*github.SSHSigningKey, *github.Response, error) {
+		return c.gh.Users.ListSSHSigningKeys(ctx, username, &github.ListOptions{
+			Page:    page,
+			PerPage: c.perPage,
+		})
+	})
+}
+
+// ListRecentCommits lists up to limit commits of the default branch of a repository,
+// the most recent first, with their signature verification.
+func (c *Client) ListRecentCommits(ctx context.Context, owner, repo string, limit int) ([]*github.RepositoryCommit, error) {
+	var commits []*github.RepositoryCommit
+	opts := &github.CommitsListOptions{ListOptions: github.ListOptions{PerPage: c.perPage}}
+	for len(commits) < limit {
+		page, resp, err := c.gh.Repositories.ListCommits(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, page...)
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	if len(commits) > limit {
+		commits = commits[:limit]
+	}
+	return commits, nil
+}
+
+// maxAvatarSize limits the size of a downloaded avatar image.
+const maxAvatarSize = 5 << 20
+
+// DownloadAvatar downloads the avatar image of a user
+func (c *Client) DownloadAvatar(ctx context.Context, user *github.User) ([]byte, error) {
+	if user.GetAvatarURL() == "" {
+		return nil, errors.New("user has no avatar")
+	}
+	return c.downloadAvatar(ctx, user.GetAvatarURL())
+}
+
+// DownloadOrgAvatar downloads the avatar image of an organization
+func (c *Client) DownloadOrgAvatar(ctx context.Context, org *github.Organization) ([]byte, error) {
+	if org.GetAvatarURL() == "" {
+		return nil, errors.New("organization has no avatar")
+	}
+	return c.downloadAvatar(ctx, org.GetAvatarURL())
+}
+
+func (c *Client) downloadAvatar(ctx context.Context, avatarURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, avatarURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.gh.Client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download avatar: unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxAvatarSize))
+}
@@ -0,0 +1,470 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v71/github"
+)
+
+const orgMembersQuery = `query($org: String!, $cursor: String) {
+  organization(login: $org) {
+    membersWithRole(first: 100, after: $cursor) {
+      pageInfo { hasNextPage endCursor }
+      edges { role node { databaseId login name email bio location websiteUrl avatarUrl } }
+    }
+  }
+}`
+
+const orgReposQuery = `query($org: String!, $cursor: String) {
+  organization(login: $org) {
+    repositories(first: 100, after: $cursor) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        databaseId name nameWithOwner description url homepageUrl
+        isPrivate isArchived isFork stargazerCount forkCount pushedAt updatedAt
+        diskUsage visibility hasWikiEnabled hasIssuesEnabled hasProjectsEnabled
+        defaultBranchRef { name }
+        primaryLanguage { name }
+        licenseInfo { key spdxId name }
+        owner { login }
+      }
+    }
+  }
+}`
+
+const orgTeamsQuery = `query($org: String!, $cursor: String) {
+  organization(login: $org) {
+    teams(first: 50, after: $cursor) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        databaseId name slug description privacy
+        members(first: 100, membership: IMMEDIATE) {
+          pageInfo { hasNextPage }
+          nodes { login }
+        }
+        repositories(first: 100) {
+          pageInfo { hasNextPage }
+          edges { permission node { name } }
+        }
+      }
+    }
+  }
+}`
+
+const orgMemberEmailsQuery = `query($org: String!, $cursor: String) {
+  organization(login: $org) {
+    membersWithRole(first: 100, after: $cursor) {
+      pageInfo { hasNextPage endCursor }
+      nodes { login organizationVerifiedDomainEmails(login: $org) }
+    }
+  }
+}`
+
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type gqlUser struct {
+	DatabaseID int64  `json:"databaseId"`
+	Login      string `json:"login"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	Bio        string `json:"bio"`
+	Location   string `json:"location"`
+	WebsiteURL string `json:"websiteUrl"`
+	AvatarURL  string `json:"avatarUrl"`
+}
+
+type gqlRepo struct {
+	DatabaseID     int64     `json:"databaseId"`
+	Name           string    `json:"name"`
+	NameWithOwner  string    `json:"nameWithOwner"`
+	Description    string    `json:"description"`
+	URL            string    `json:"url"`
+	HomepageURL    string    `json:"homepageUrl"`
+	IsPrivate      bool      `json:"isPrivate"`
+	IsArchived     bool      `json:"isArchived"`
+	IsFork         bool      `json:"isFork"`
+	StargazerCount int       `json:"stargazerCount"`
+	ForkCount      int       `json:"forkCount"`
+	PushedAt       time.Time `json:"pushedAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	// DiskUsage is the size in kilobytes, like the REST size.
+	DiskUsage          int    `json:"diskUsage"`
+	Visibility         string `json:"visibility"`
+	HasWikiEnabled     bool   `json:"hasWikiEnabled"`
+	HasIssuesEnabled   bool   `json:"hasIssuesEnabled"`
+	HasProjectsEnabled bool   `json:"hasProjectsEnabled"`
+	DefaultBranchRef   *struct {
+		Name string `json:"name"`
+	} `json:"defaultBranchRef"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	LicenseInfo *struct {
+		Key    string `json:"key"`
+		SPDXID string `json:"spdxId"`
+		Name   string `json:"name"`
+	} `json:"licenseInfo"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+// restRepo converts a GraphQL repository into the REST representation.
+func (node gqlRepo) restRepo() *github.Repository {
+	repo := &github.Repository{
+		ID:              github.Ptr(node.DatabaseID),
+		Name:            github.Ptr(node.Name),
+		FullName:        github.Ptr(node.NameWithOwner),
+		Description:     github.Ptr(node.Description),
+		HTMLURL:         github.Ptr(node.URL),
+		CloneURL:        github.Ptr(node.URL + ".git"),
+		Homepage:        github.Ptr(node.HomepageURL),
+		Private:         github.Ptr(node.IsPrivate),
+		Archived:        github.Ptr(node.IsArchived),
+		Fork:            github.Ptr(node.IsFork),
+		StargazersCount: github.Ptr(node.StargazerCount),
+		ForksCount:      github.Ptr(node.ForkCount),
+		PushedAt:        &github.Timestamp{Time: node.PushedAt},
+		UpdatedAt:       &github.Timestamp{Time: node.UpdatedAt},
+		Size:            github.Ptr(node.DiskUsage),
+		Visibility:      github.Ptr(strings.ToLower(node.Visibility)),
+		HasWiki:         github.Ptr(node.HasWikiEnabled),
+		HasIssues:       github.Ptr(node.HasIssuesEnabled),
+		HasProjects:     github.Ptr(node.HasProjectsEnabled),
+		Owner:           &github.User{Login: github.Ptr(node.Owner.Login)},
+	}
+	if node.DefaultBranchRef != nil {
+		repo.DefaultBranch = github.Ptr(node.DefaultBranchRef.Name)
+	}
+	if node.PrimaryLanguage != nil {
+		repo.Language = github.Ptr(node.PrimaryLanguage.Name)
+	}
+	if node.LicenseInfo != nil {
+		repo.License = &github.License{
+			Key:    github.Ptr(node.LicenseInfo.Key),
+			SPDXID: github.Ptr(node.LicenseInfo.SPDXID),
+			Name:   github.Ptr(node.LicenseInfo.Name),
+		}
+	}
+	return repo
+}
+
+type gqlTeam struct {
+	DatabaseID  int64  `json:"databaseId"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	Privacy     string `json:"privacy"`
+	Members     struct {
+		PageInfo pageInfo `json:"pageInfo"`
+		Nodes    []struct {
+			Login string `json:"login"`
+		} `json:"nodes"`
+	} `json:"members"`
+	Repositories struct {
+		PageInfo pageInfo `json:"pageInfo"`
+		Edges    []struct {
+			Permission string `json:"permission"`
+			Node       struct {
+				Name string `json:"name"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"repositories"`
+}
+
+// orgTree is the organization structure fetched through the GraphQL API.
+type orgTree struct {
+	repos       []*github.Repository
+	teams       []*github.Team
+	members     []*github.User
+	users       map[string]*github.User
+	roles       map[string]string
+	teamMembers map[string][]*github.User
+	teamRepos   map[string][]*github.Repository
+	// incomplete holds the slugs of teams with more nested members or repositories
+	// than a single GraphQL page, which are listed through the REST API instead.
+	incomplete map[string]bool
+}
+
+// graphqlURL returns the GraphQL endpoint matching the REST base URL.
+// GitHub Enterprise Server serves GraphQL under /api/graphql instead of /api/v3.
+func (c *Client) graphqlURL() string {
+	base := c.gh.BaseURL.String()
+	if strings.HasSuffix(base, "/api/v3/") {
+		return strings.TrimSuffix(base, "v3/") + "graphql"
+	}
+	return base + "graphql"
+}
+
+// graphql executes a GraphQL query and decodes the data field into out.
+func (c *Client) graphql(ctx context.Context, query string, variables map[string]any, out any) error {
+	req, err := c.gh.NewRequest(http.MethodPost, c.graphqlURL(), map[string]any{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+	// queries are read-only, mark the POST as safe to retry without sending a header
+	req.Header["Idempotency-Key"] = nil
+
+	var resp struct {
+		Data   any `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	resp.Data = out
+	if _, err := c.gh.Do(ctx, req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return errors.New("github graphql: " + resp.Errors[0].Message)
+	}
+	return nil
+}
+
+// fetchOrgTree loads the repositories, teams and members of an organization
+// with a handful of paginated GraphQL queries.
+func (c *Client) fetchOrgTree(ctx context.Context, org string) (*orgTree, error) {
+	tree := &orgTree{
+		users:       make(map[string]*github.User),
+		roles:       make(map[string]string),
+		teamMembers: make(map[string][]*github.User),
+		teamRepos:   make(map[string][]*github.Repository),
+		incomplete:  make(map[string]bool),
+	}
+
+	// members with their organization role
+	var cursor *string
+	for {
+		var data struct {
+			Organization struct {
+				MembersWithRole struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Edges    []struct {
+						Role string  `json:"role"`
+						Node gqlUser `json:"node"`
+					} `json:"edges"`
+				} `json:"membersWithRole"`
+			} `json:"organization"`
+		}
+		if err := c.graphql(ctx, orgMembersQuery, map[string]any{"org": org, "cursor": cursor}, &data); err != nil {
+			return nil, err
+		}
+		for _, edge := range data.Organization.MembersWithRole.Edges {
+			user := &github.User{
+				ID:        github.Ptr(edge.Node.DatabaseID),
+				Login:     github.Ptr(edge.Node.Login),
+				Name:      github.Ptr(edge.Node.Name),
+				Email:     github.Ptr(edge.Node.Email),
+				Bio:       github.Ptr(edge.Node.Bio),
+				Location:  github.Ptr(edge.Node.Location),
+				Blog:      github.Ptr(edge.Node.WebsiteURL),
+				AvatarURL: github.Ptr(edge.Node.AvatarURL),
+				Type:      github.Ptr("User"),
+			}
+			tree.members = append(tree.members, user)
+			tree.users[strings.ToLower(edge.Node.Login)] = user
+			tree.roles[strings.ToLower(edge.Node.Login)] = strings.ToLower(edge.Role)
+		}
+		page := data.Organization.MembersWithRole.PageInfo
+		if !page.HasNextPage {
+			break
+		}
+		cursor = github.Ptr(page.EndCursor)
+	}
+
+	// repositories
+	cursor = nil
+	for {
+		var data struct {
+			Organization struct {
+				Repositories struct {
+					PageInfo pageInfo  `json:"pageInfo"`
+					Nodes    []gqlRepo `json:"nodes"`
+				} `json:"repositories"`
+			} `json:"organization"`
+		}
+		if err := c.graphql(ctx, orgReposQuery, map[string]any{"org": org, "cursor": cursor}, &data); err != nil {
+			return nil, err
+		}
+		for _, node := range data.Organization.Repositories.Nodes {
+			tree.repos = append(tree.repos, node.restRepo())
+		}
+		page := data.Organization.Repositories.PageInfo
+		if !page.HasNextPage {
+			break
+		}
+		cursor = github.Ptr(page.EndCursor)
+	}
+
+	// teams with their members and repositories
+	cursor = nil
+	for {
+		var data struct {
+			Organization struct {
+				Teams struct {
+					PageInfo pageInfo  `json:"pageInfo"`
+					Nodes    []gqlTeam `json:"nodes"`
+				} `json:"teams"`
+			} `json:"organization"`
+		}
+		if err := c.graphql(ctx, orgTeamsQuery, map[string]any{"org": org, "cursor": cursor}, &data); err != nil {
+			return nil, err
+		}
+		for _, node := range data.Organization.Teams.Nodes {
+			// the permission of the team on each repository, like the REST listing
+			for _, edge := range node.Repositories.Edges {
+				tree.teamRepos[node.Slug] = append(tree.teamRepos[node.Slug], &github.Repository{
+					Name:        github.Ptr(edge.Node.Name),
+					Permissions: restPermissions(edge.Permission),
+				})
+			}
+			for _, member := range node.Members.Nodes {
+				tree.teamMembers[node.Slug] = append(tree.teamMembers[node.Slug], &github.User{
+					Login: github.Ptr(member.Login),
+				})
+			}
+			if node.Members.PageInfo.HasNextPage || node.Repositories.PageInfo.HasNextPage {
+				tree.incomplete[node.Slug] = true
+			}
+			tree.teams = append(tree.teams, &github.Team{
+				ID:          github.Ptr(node.DatabaseID),
+				Name:        github.Ptr(node.Name),
+				Slug:        github.Ptr(node.Slug),
+				Description: github.Ptr(node.Description),
+				Privacy:     github.Ptr(strings.ToLower(node.Privacy)),
+			})
+		}
+		page := data.Organization.Teams.PageInfo
+		if !page.HasNextPage {
+			break
+		}
+		cursor = github.Ptr(page.EndCursor)
+	}
+
+	return tree, nil
+}
+
+// cachedOrgTree returns the GraphQL organization tree when GraphQL enumeration is enabled.
+// The tree is fetched once per organization; on failure the REST API is used instead.
+func (c *Client) cachedOrgTree(ctx context.Context, org string) (*orgTree, bool) {
+	if !c.graphQL {
+		return nil, false
+	}
+
+	c.treeMu.Lock()
+	defer c.treeMu.Unlock()
+
+	key := strings.ToLower(org)
+	if tree, ok := c.trees[key]; ok {
+		return tree, tree != nil
+	}
+
+	tree, err := c.fetchOrgTree(ctx, org)
+	if err != nil {
+		if c.logger != nil {
+			c.logger.Warn("github graphql enumeration failed, fallback to rest api", "org", org, "error", err)
+		}
+		c.trees[key] = nil
+		return nil, false
+	}
+	if c.logger != nil {
+		c.logger.Debug("github graphql enumeration",
+			"org", org,
+			"repos", len(tree.repos),
+			"teams", len(tree.teams),
+			"members", len(tree.members),
+		)
+	}
+	c.trees[key] = tree
+	return tree, true
+}
+
+// cachedUser returns a user loaded by a previous GraphQL enumeration.
+func (c *Client) cachedUser(username string) (*github.User, bool) {
+	c.treeMu.Lock()
+	defer c.treeMu.Unlock()
+	for _, tree := range c.trees {
+		if tree == nil {
+			continue
+		}
+		if user, ok := tree.users[strings.ToLower(username)]; ok {
+			return user, true
+		}
+	}
+	return nil, false
+}
+
+// restPermission converts a GraphQL repository permission into the REST team permission name.
+func restPermission(permission string) string {
+	switch permission {
+	case "ADMIN":
+		return "admin"
+	case "MAINTAIN":
+		return "maintain"
+	case "WRITE":
+		return "push"
+	case "TRIAGE":
+		return "triage"
+	default:
+		return "pull"
+	}
+}
+
+// restLevels are the REST permission names from the least to the most privileged.
+var restLevels = []string{"pull", "triage", "push", "maintain", "admin"}
+
+// restPermissions returns the permissions map of the REST API for a GraphQL
+// repository permission, which holds the permission and the lower ones.
+func restPermissions(permission string) map[string]bool {
+	level := slices.Index(restLevels, restPermission(permission))
+	permissions := make(map[string]bool, len(restLevels))
+	for i, name := range restLevels {
+		permissions[name] = i <= level
+	}
+	return permissions
+}
+
+// ListOrgMemberEmails returns the emails of the organization members in the domains
+// verified by the organization, keyed by lowercase login. Only organization owners
+// can read them.
+func (c *Client) ListOrgMemberEmails(ctx context.Context, org string) (map[string][]string, error) {
+	emails := make(map[string][]string)
+	var cursor *string
+	for {
+		var data struct {
+			Organization struct {
+				MembersWithRole struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []struct {
+						Login  string   `json:"login"`
+						Emails []string `json:"organizationVerifiedDomainEmails"`
+					} `json:"nodes"`
+				} `json:"membersWithRole"`
+			} `json:"organization"`
+		}
+		if err := c.graphql(ctx, orgMemberEmailsQuery, map[string]any{"org": org, "cursor": cursor}, &data); err != nil {
+			return nil, err
+		}
+		for _, node := range data.Organization.MembersWithRole.Nodes {
+			if len(node.Emails) > 0 {
+				emails[strings.ToLower(node.Login)] = node.Emails
+			}
+		}
+		page := data.Organization.MembersWithRole.PageInfo
+		if !page.HasNextPage {
+			return emails, nil
+		}
+		cursor = github.Ptr(page.EndCursor)
+	}
+}
@@ -0,0 +1,22 @@
+package github
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestRestPermissions(t *testing.T) {
+	tests := []struct {
+		permission string
+		want       map[string]bool
+	}{
+		{"READ", map[string]bool{"pull": true, "triage": false, "push": false, "maintain": false, "admin": false}},
+		{"WRITE", map[string]bool{"pull": true, "triage": true, "push": true, "maintain": false, "admin": false}},
+		{"ADMIN", map[string]bool{"pull": true, "triage": true, "push": true, "maintain": true, "admin": true}},
+	}
+	for _, tt := range tests {
+		if got := restPermissions(tt.permission); !maps.Equal(got, tt.want) {
+			t.Errorf("restPermissions(%q) = %v, want %v", tt.permission, got, tt.want)
+		}
+	}
+}
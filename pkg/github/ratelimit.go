@@ -0,0 +1,158 @@
+package github
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultRateLimitThreshold is the remaining quota at which requests are paused until the reset time.
+const defaultRateLimitThreshold = 100
+
+// resourceCore is the rate limit resource of the REST API, the others being graphql
+// and the search endpoints.
+const resourceCore = "core"
+
+// rateLimitTransport is an http.RoundTripper that inspects the GitHub rate limit
+// headers and throttles outgoing requests when the quota is nearly exhausted. GitHub
+// counts the REST, GraphQL and search requests in separate resources, each request is
+// throttled by the quota of its own.
+type rateLimitTransport struct {
+	base      http.RoundTripper
+	logger    *slog.Logger
+	threshold int
+
+	mu      sync.Mutex
+	windows map[string]*rateLimitWindow
+}
+
+// rateLimitWindow is the quota of a rate limit resource, remaining being -1 when unknown.
+type rateLimitWindow struct {
+	remaining int
+	reset     time.Time
+}
+
+func newRateLimitTransport(base http.RoundTripper, threshold int, logger *slog.Logger) *rateLimitTransport {
+	if threshold <= 0 {
+		threshold = defaultRateLimitThreshold
+	}
+	return &rateLimitTransport{
+		base:      base,
+		logger:    logger,
+		threshold: threshold,
+		windows:   make(map[string]*rateLimitWindow),
+	}
+}
+
+// requestResource returns the rate limit resource a request counts against.
+func requestResource(req *http.Request) string {
+	path := req.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/graphql"):
+		return "graphql"
+	case strings.Contains(path, "/search/code"):
+		return "code_search"
+	case strings.Contains(path, "/search/"):
+		return "search"
+	default:
+		return resourceCore
+	}
+}
+
+// RoundTrip waits for the rate limit window if needed, then sends the request
+// and records the quota returned by GitHub.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := requestResource(req)
+	if delay := t.delay(resource, time.Now()); delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.update(resource, resp.Header)
+	return resp, nil
+}
+
+// delay returns how long the next request of the resource should wait.
+// Requests pause until the reset time once the remaining quota drops to the threshold,
+// and are spread evenly over the rest of the window when it drops below ten times the threshold.
+func (t *rateLimitTransport) delay(resource string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.windows[resource]
+	if !ok || w.remaining < 0 || w.reset.IsZero() || !now.Before(w.reset) {
+		return 0
+	}
+
+	window := w.reset.Sub(now)
+	switch {
+	case w.remaining <= t.threshold:
+		t.log(slog.LevelWarn, "github rate limit nearly exhausted, pausing requests", "resource", resource, "wait", window.Round(time.Second))
+		// the counter is refreshed by the first response after the reset
+		w.remaining = -1
+		return window + time.Second
+	case w.remaining <= t.threshold*10:
+		return window / time.Duration(w.remaining)
+	default:
+		return 0
+	}
+}
+
+// update records the rate limit headers of a GitHub response, under the resource
+// GitHub names or else the one of the request.
+func (t *rateLimitTransport) update(resource string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if name := header.Get("X-RateLimit-Resource"); name != "" {
+		resource = name
+	}
+
+	t.mu.Lock()
+	t.windows[resource] = &rateLimitWindow{remaining: remaining, reset: time.Unix(reset, 0)}
+	t.mu.Unlock()
+
+	t.log(slog.LevelDebug, "github rate limit",
+		"resource", resource,
+		"limit", limit,
+		"remaining", remaining,
+		"reset", time.Unix(reset, 0).Format(time.RFC3339),
+	)
+}
+
+// status returns the last recorded quota of the REST API.
+func (t *rateLimitTransport) status() (int, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w, ok := t.windows[resourceCore]
+	if !ok {
+		return -1, time.Time{}
+	}
+	return w.remaining, w.reset
+}
+
+func (t *rateLimitTransport) log(level slog.Level, msg string, args ...any) {
+	if t.logger == nil {
+		return
+	}
+	t.logger.Log(context.Background(), level, msg, args...)
+}
@@ -0,0 +1,104 @@
+package github
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// quotaResponse answers with the rate limit headers of a resource.
+func quotaResponse(resource string, remaining int, reset time.Time) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		header := quotaHeader(remaining, reset)
+		if resource != "" {
+			header.Set("X-RateLimit-Resource", resource)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+	}
+}
+
+func TestRateLimitResources(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+	transport := newRateLimitTransport(quotaResponse("graphql", 10, reset), 100, nil)
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// the exhausted graphql quota neither pauses nor shows in the REST quota
+	if remaining, _ := transport.status(); remaining != -1 {
+		t.Errorf("rest remaining = %d, want unknown", remaining)
+	}
+	if delay := transport.delay(resourceCore, time.Now()); delay != 0 {
+		t.Errorf("rest delay = %s, want none", delay)
+	}
+	if delay := transport.delay("graphql", time.Now()); delay < time.Hour-time.Minute {
+		t.Errorf("graphql delay = %s, want until the reset", delay)
+	}
+	// paused once, the next response refreshes the quota
+	if delay := transport.delay("graphql", time.Now()); delay != 0 {
+		t.Errorf("graphql delay after the pause = %s, want none", delay)
+	}
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	// the reset header has a one second resolution
+	now := time.Unix(time.Now().Unix(), 0)
+	reset := now.Add(100 * time.Second)
+	tests := []struct {
+		remaining int
+		want      time.Duration
+	}{
+		{5000, 0},
+		{500, 100 * time.Second / 500},
+		{100, 101 * time.Second},
+	}
+	for _, tt := range tests {
+		transport := newRateLimitTransport(nil, 100, nil)
+		transport.update(resourceCore, quotaHeader(tt.remaining, reset))
+		if got := transport.delay(resourceCore, now); got != tt.want {
+			t.Errorf("remaining %d: delay = %s, want %s", tt.remaining, got, tt.want)
+		}
+	}
+
+	// the window is over
+	transport := newRateLimitTransport(nil, 100, nil)
+	transport.update(resourceCore, quotaHeader(0, now.Add(-time.Second)))
+	if got := transport.delay(resourceCore, now); got != 0 {
+		t.Errorf("after the reset: delay = %s, want none", got)
+	}
+}
+
+func TestRequestResource(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com/orgs/acme/repos":          resourceCore,
+		"https://api.github.com/graphql":                  "graphql",
+		"https://github.example.com/api/graphql":          "graphql",
+		"https://api.github.com/search/issues?q=x":        "search",
+		"https://api.github.com/search/code?q=x":          "code_search",
+		"https://github.example.com/api/v3/repos/acme/ap": resourceCore,
+	}
+	for url, want := range tests {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if got := requestResource(req); got != want {
+			t.Errorf("requestResource(%s) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func quotaHeader(remaining int, reset time.Time) http.Header {
+	header := make(http.Header)
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	return header
+}
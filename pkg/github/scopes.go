@@ -0,0 +1,188 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxScopeEndpoints is the number of failed endpoints kept as examples per missing scope.
+const maxScopeEndpoints = 3
+
+// scopeRules map the API paths to the classic token scope and fine-grained token
+// permission they need, most specific first. They are used when GitHub does not
+// say which scopes a denied request accepts.
+var scopeRules = []struct {
+	path       *regexp.Regexp
+	scope      string
+	permission string
+}{
+	{regexp.MustCompile(`^/orgs/[^/]+/hooks`), "admin:org_hook", "organization_hooks=read"},
+	{regexp.MustCompile(`^/orgs/[^/]+/actions/secrets`), "admin:org", "organization_secrets=read"},
+	{regexp.MustCompile(`^/orgs/[^/]+/actions/variables`), "admin:org", "organization_actions_variables=read"},
+	{regexp.MustCompile(`^/orgs/[^/]+/audit-log`), "read:audit_log", "organization_administration=read"},
+	{regexp.MustCompile(`^/orgs/[^/]+/(organization-roles|security-managers|installations)`), "admin:org", "organization_administration=read"},
+	{regexp.MustCompile(`^/orgs/[^/]+/(members|memberships|teams|outside_collaborators)`), "read:org", "members=read"},
+	{regexp.MustCompile(`^/repos/[^/]+/[^/]+/hooks`), "admin:repo_hook", "repository_hooks=read"},
+	{regexp.MustCompile(`^/repos/[^/]+/[^/]+/actions/secrets`), "repo", "secrets=read"},
+	{regexp.MustCompile(`^/repos/[^/]+/[^/]+/actions/variables`), "repo", "actions_variables=read"},
+	{regexp.MustCompile(`^/repos/[^/]+/[^/]+/collaborators`), "repo", "administration=read"},
+	{regexp.MustCompile(`^/repos/`), "repo", "contents=read"},
+	{regexp.MustCompile(`^/user/keys`), "read:public_key", "git_ssh_keys=read"},
+	{regexp.MustCompile(`^/user/gpg_keys`), "read:gpg_key", "gpg_keys=read"},
+	{regexp.MustCompile(`^/user/emails`), "user:email", "email_addresses=read"},
+	{regexp.MustCompile(`^/(users/[^/]+/)?gists`), "gist", "gists=read"},
+}
+
+// MissingScope is a token scope or permission some denied requests needed.
+type MissingScope struct {
+	// Scope is the classic token scope, or the fine-grained token permission such as members=read.
+	Scope string
+	// Endpoints are examples of the denied requests, e.g. GET /orgs/acme/members.
+	Endpoints []string
+	// Count is the number of denied requests.
+	Count int
+}
+
+// scopeTransport records the requests denied because the token lacks a scope.
+type scopeTransport struct {
+	base http.RoundTripper
+
+	mu      sync.Mutex
+	missing map[string]*MissingScope
+}
+
+func newScopeTransport(base http.RoundTripper) *scopeTransport {
+	return &scopeTransport{base: base, missing: make(map[string]*MissingScope)}
+}
+
+// RoundTrip sends the request and records the scope a 403 or 404 response points to.
+// GitHub answers 404 instead of 403 for private resources the token cannot see.
+func (t *scopeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusNotFound) {
+		return resp, err
+	}
+	if scope := missingScope(req.URL.Path, resp); scope != "" {
+		t.record(scope, req.Method+" "+req.URL.Path)
+	}
+	return resp, nil
+}
+
+// missingScope returns the scope a denied request needs, empty when the token has it
+// and the resource simply does not exist or is not accessible to the user.
+func missingScope(path string, resp *http.Response) string {
+	// the GitHub Enterprise Server API lives under /api/v3
+	path = strings.TrimPrefix(path, "/api/v3")
+
+	// classic tokens list their scopes, and GitHub the scopes the request accepts
+	if granted, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; ok {
+		accepted := splitScopes(resp.Header.Get("X-Accepted-OAuth-Scopes"))
+		if len(accepted) == 0 {
+			return ""
+		}
+		has := make(map[string]bool)
+		for _, scope := range splitScopes(strings.Join(granted, ",")) {
+			has[scope] = true
+		}
+		for _, scope := range accepted {
+			if has[scope] {
+				return ""
+			}
+		}
+		return strings.Join(accepted, " or ")
+	}
+
+	// fine-grained tokens get the permissions the request needs
+	permissions := resp.Header.Get("X-Accepted-GitHub-Permissions")
+	if permissions != "" && resp.StatusCode == http.StatusForbidden {
+		return strings.ReplaceAll(permissions, ";", " or ")
+	}
+
+	// a 404 without scope headers is a missing resource
+	if resp.StatusCode != http.StatusForbidden || resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return ""
+	}
+	for _, rule := range scopeRules {
+		if rule.path.MatchString(path) {
+			return rule.scope + " (fine-grained: " + rule.permission + ")"
+		}
+	}
+	return ""
+}
+
+func splitScopes(s string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+func (t *scopeTransport) record(scope, endpoint string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m, ok := t.missing[scope]
+	if !ok {
+		m = &MissingScope{Scope: scope}
+		t.missing[scope] = m
+	}
+	m.Count++
+	if len(m.Endpoints) < maxScopeEndpoints {
+		m.Endpoints = append(m.Endpoints, endpoint)
+	}
+}
+
+// MissingScopes returns the token scopes the denied GitHub requests needed, sorted by scope.
+func (c *Client) MissingScopes() []MissingScope {
+	t := c.scopes
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	scopes := make([]MissingScope, 0, len(t.missing))
+	for _, m := range t.missing {
+		scopes = append(scopes, *m)
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i].Scope < scopes[j].Scope })
+	return scopes
+}
+
+// expirationLayouts are the formats of the GitHub-Authentication-Token-Expiration header.
+var expirationLayouts = []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"}
+
+// TokenInfo describes the GitHub token.
+type TokenInfo struct {
+	// Login is the user the token belongs to.
+	Login string
+	// Scopes are the scopes of a classic token, nil for a fine-grained token which has
+	// permissions instead.
+	Scopes []string
+	// Expiration is zero when the token does not expire.
+	Expiration time.Time
+}
+
+// TokenInfo returns the user, scopes and expiration of the token.
+func (c *Client) TokenInfo(ctx context.Context) (*TokenInfo, error) {
+	user, resp, err := c.gh.Users.Get(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	info := &TokenInfo{Login: user.GetLogin()}
+	if granted, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; ok {
+		info.Scopes = append([]string{}, splitScopes(strings.Join(granted, ","))...)
+	}
+	if expiration := resp.Header.Get("GitHub-Authentication-Token-Expiration"); expiration != "" {
+		for _, layout := range expirationLayouts {
+			if t, err := time.Parse(layout, expiration); err == nil {
+				info.Expiration = t
+				break
+			}
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,111 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Supported languages.
+const (
+	English            = "en"
+	TraditionalChinese = "zh-TW"
+	SimplifiedChinese  = "zh-CN"
+)
+
+// Message keys are the English format strings, so an untranslated key still prints sensibly.
+const (
+	HintInvalidConfig = "Invalid configuration: %s. Run with -h to list all flags."
+	HintTimeout       = "Invalid timeout %q, use a duration such as 30s, 10m or 1h."
+	HintClients       = "Unable to connect: %s. Check the server URLs and access tokens."
+	HintMigration     = "Migration stopped: %s. Run again with --debug for more details."
+	MsgSummary        = "Migration finished: %d repositories migrated, %d failed."
+	MsgBots           = "Skipped %d bot accounts: %s"
+	MsgReportWritten  = "Migration report written to %s"
+	HintScopes        = "The GitHub token lacks scopes or permissions, grant them and run again:"
+	HintScope         = "  %s: %d requests denied, e.g. %s"
+)
+
+var catalog = map[string]map[string]string{
+	TraditionalChinese: {
+		HintInvalidConfig: "設定無效：%s。請加上 -h 參數查看所有選項。",
+		HintTimeout:       "逾時設定 %q 無效，請使用如 30s、10m 或 1h 的時間格式。",
+		HintClients:       "無法連線：%s。請檢查伺服器網址與存取權杖。",
+		HintMigration:     "遷移中止：%s。請加上 --debug 參數重新執行以取得更多資訊。",
+		MsgSummary:        "遷移完成：成功 %d 個儲存庫，失敗 %d 個。",
+		MsgBots:           "略過 %d 個機器人帳號：%s",
+		MsgReportWritten:  "遷移報告已寫入 %s",
+		HintScopes:        "GitHub 權杖缺少以下範圍或權限，請授予後重新執行：",
+		HintScope:         "  %s：%d 個請求遭拒，例如 %s",
+	},
+	SimplifiedChinese: {
+		HintInvalidConfig: "配置无效：%s。请加上 -h 参数查看所有选项。",
+		HintTimeout:       "超时设置 %q 无效，请使用如 30s、10m 或 1h 的时间格式。",
+		HintClients:       "无法连接：%s。请检查服务器地址与访问令牌。",
+		HintMigration:     "迁移中止：%s。请加上 --debug 参数重新运行以获取更多信息。",
+		MsgSummary:        "迁移完成：成功 %d 个仓库，失败 %d 个。",
+		MsgBots:           "跳过 %d 个机器人账号：%s",
+		MsgReportWritten:  "迁移报告已写入 %s",
+		HintScopes:        "GitHub 令牌缺少以下范围或权限，请授予后重新运行：",
+		HintScope:         "  %s：%d 个请求被拒绝，例如 %s",
+	},
+}
+
+// Printer formats messages in a single language.
+type Printer struct {
+	lang string
+}
+
+// New creates a Printer for the given language, falling back to English.
+func New(lang string) *Printer {
+	if _, ok := catalog[lang]; !ok {
+		lang = English
+	}
+	return &Printer{lang: lang}
+}
+
+// Lang returns the language used by the printer.
+func (p *Printer) Lang() string {
+	return p.lang
+}
+
+// Sprintf formats the translated message for key.
+func (p *Printer) Sprintf(key string, args ...any) string {
+	format := key
+	if msg, ok := catalog[p.lang][key]; ok {
+		format = msg
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Detect returns the language selected by the --lang flag value,
+// or derived from the LC_ALL, LC_MESSAGES and LANG environment variables.
+func Detect(lang string) string {
+	if lang != "" {
+		return normalize(lang)
+	}
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			return normalize(v)
+		}
+	}
+	return English
+}
+
+// normalize maps locale names such as zh_TW.UTF-8 to a supported language.
+func normalize(locale string) string {
+	locale = strings.ToLower(locale)
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.ReplaceAll(locale, "_", "-")
+
+	switch locale {
+	case "zh-tw", "zh-hk", "zh-mo", "zh-hant":
+		return TraditionalChinese
+	case "zh", "zh-cn", "zh-sg", "zh-hans":
+		return SimplifiedChinese
+	default:
+		return English
+	}
+}
@@ -0,0 +1,395 @@
+package migrate
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/redact"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// SecretValues holds Actions secret values keyed by lowercase GitHub repository
+// full name, or organization name for organization secrets, and secret name.
+type SecretValues map[string]map[string]string
+
+// LoadSecretValues reads a CSV file with a repo,name,value header, where repo is the
+// full name of the GitHub repository, or the organization name for organization secrets.
+func LoadSecretValues(path string) (SecretValues, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseSecretValues(f)
+}
+
+func parseSecretValues(r io.Reader) (SecretValues, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(SecretValues)
+	for index, rec := range records {
+		// Skip the header row
+		if index == 0 {
+			continue
+		}
+		if len(rec) < 3 {
+			return nil, fmt.Errorf("secrets line %d: expected repo,name,value", index+1)
+		}
+		repo := strings.ToLower(strings.TrimSpace(rec[0]))
+		name := strings.ToUpper(strings.TrimSpace(rec[1]))
+		if repo == "" || name == "" {
+			return nil, fmt.Errorf("secrets line %d: repo and name are required", index+1)
+		}
+		if values[repo] == nil {
+			values[repo] = make(map[string]string)
+		}
+		values[repo][name] = rec[2]
+		redact.Add(rec[2])
+	}
+	return values, nil
+}
+
+// Lookup returns the value of a secret of a GitHub repository or organization.
+func (v SecretValues) Lookup(repo, name string) (string, bool) {
+	value, ok := v[strings.ToLower(repo)][strings.ToUpper(name)]
+	return value, ok
+}
+
+// ActionsOption selects the repository whose Actions variables and secrets are copied to Gitea.
+type ActionsOption struct {
+	// SourceOwner, SourceName and SourceID identify the GitHub repository.
+	SourceOwner string
+	SourceName  string
+	SourceID    int64
+	// Owner and Name identify the migrated Gitea repository.
+	Owner string
+	Name  string
+	// Secrets provides the secret values, SecretPlaceholder is used for the others.
+	Secrets SecretValues
+	// Environments copies the secrets and variables of these deployment environments
+	// to the repository, with their names prefixed, optional.
+	Environments EnvironmentPrefixes
+	// Report records every variable and secret when set.
+	Report *report.Report
+}
+
+// MigrateRepoActions copies the Actions variables of a GitHub repository to the
+// migrated Gitea repository, and creates its secrets with the values of the
+// secrets file or SecretPlaceholder. Existing variables get the GitHub value when
+// it differs, so the workflows see the same configuration, and existing secrets
+// are only replaced by a value of the secrets file. The secrets and variables of the
+// deployment environments with a prefix are created with the prefixed names.
+func (m *Migrator) MigrateRepoActions(ctx context.Context, opts ActionsOption) error {
+	ctx, span := trace.Start(ctx, "migrate.MigrateRepoActions",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+
+	source := opts.SourceOwner + "/" + opts.SourceName
+	target := opts.Owner + "/" + opts.Name
+
+	variables, err := m.ghClient.ListRepoVariables(ctx, opts.SourceOwner, opts.SourceName)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	envs, err := m.repoEnvironments(ctx, opts.SourceOwner, opts.SourceName, opts.SourceID, opts.Environments)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	for _, env := range envs {
+		for _, variable := range env.variables {
+			prefixed := *variable
+			prefixed.Name = env.prefix + variable.Name
+			variables = append(variables, &prefixed)
+		}
+	}
+	if !m.gtClient.Supports(gitea.FeatureActionsVariables) {
+		variables = nil
+	}
+	repoVars := m.repoVariables(opts.Owner, opts.Name)
+	for _, variable := range variables {
+		m.setVariable(opts.Report, target, variable.Name, variable.Value, repoVars)
+	}
+
+	secrets, err := m.ghClient.ListRepoSecrets(ctx, opts.SourceOwner, opts.SourceName)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	for _, env := range envs {
+		for _, name := range env.secrets {
+			secrets = append(secrets, &github.Secret{Name: env.prefix + name})
+		}
+	}
+	if len(secrets) == 0 || !m.gtClient.Supports(gitea.FeatureActionsSecrets) {
+		return nil
+	}
+	existing, err := m.repoSecretNames(opts.Owner, opts.Name)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	var placeholders []string
+	for _, secret := range secrets {
+		value, ok := opts.Secrets.Lookup(source, secret.Name)
+		if m.setSecret(opts.Report, target, secret.Name, value, ok, existing[strings.ToUpper(secret.Name)], func(value string) error {
+			return m.gtClient.SetRepoActionSecret(opts.Owner, opts.Name, secret.Name, value)
+		}) {
+			placeholders = append(placeholders, secret.Name)
+		}
+	}
+	if len(placeholders) > 0 {
+		m.logger.Warn("actions secrets set to a placeholder, update them in gitea",
+			"repo", target,
+			"secrets", strings.Join(placeholders, ","),
+		)
+	}
+	return nil
+}
+
+// OrgActionsOption selects the organization whose Actions variables and secrets are copied to Gitea.
+type OrgActionsOption struct {
+	// SourceOrg is the GitHub organization.
+	SourceOrg string
+	// Org is the Gitea organization holding the migrated repositories.
+	Org string
+	// Secrets provides the secret values keyed by organization name, SecretPlaceholder
+	// is used for the others.
+	Secrets SecretValues
+	// Report records every variable and secret when set.
+	Report *report.Report
+}
+
+// MigrateOrgActions copies the Actions variables and secrets of a GitHub organization.
+// Gitea organization variables and secrets are visible to all repositories, so the ones
+// limited to selected repositories on GitHub are created in each of these repositories
+// instead. Run it after the repositories are migrated.
+func (m *Migrator) MigrateOrgActions(ctx context.Context, opts OrgActionsOption) error {
+	ctx, span := trace.Start(ctx, "migrate.MigrateOrgActions",
+		trace.String("github.org", opts.SourceOrg),
+		trace.String("gitea.org", opts.Org),
+	)
+	defer span.End()
+
+	variables, err := m.ghClient.ListOrgVariables(ctx, opts.SourceOrg)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if !m.gtClient.Supports(gitea.FeatureActionsVariables) {
+		variables = nil
+	}
+	orgVars := m.orgVariables(opts.Org)
+	for _, variable := range variables {
+		if variable.GetVisibility() != visibilitySelected {
+			m.setVariable(opts.Report, opts.Org, variable.Name, variable.Value, orgVars)
+			continue
+		}
+		repos, err := m.ghClient.ListOrgVariableRepos(ctx, opts.SourceOrg, variable.Name)
+		if err != nil {
+			m.logger.Error("failed to list repositories of org variable", "org", opts.SourceOrg, "variable", variable.Name, "error", err)
+			continue
+		}
+		for _, repo := range repos {
+			m.setVariable(opts.Report, opts.Org+"/"+repo.GetName(), variable.Name, variable.Value, m.repoVariables(opts.Org, repo.GetName()))
+		}
+	}
+
+	secrets, err := m.ghClient.ListOrgSecrets(ctx, opts.SourceOrg)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if len(secrets) == 0 || !m.gtClient.Supports(gitea.FeatureActionsSecrets) {
+		return nil
+	}
+	orgNames, err := m.gtClient.ListOrgActionSecrets(opts.Org)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	existing := make(map[string]bool, len(orgNames))
+	for _, name := range orgNames {
+		existing[strings.ToUpper(name)] = true
+	}
+	// secret names of the repositories selected by some secrets, loaded on first use
+	repoSecrets := make(map[string]map[string]bool)
+
+	var placeholders []string
+	for _, secret := range secrets {
+		value, ok := opts.Secrets.Lookup(opts.SourceOrg, secret.Name)
+		if secret.Visibility != visibilitySelected {
+			if m.setSecret(opts.Report, opts.Org, secret.Name, value, ok, existing[strings.ToUpper(secret.Name)], func(value string) error {
+				return m.gtClient.SetOrgActionSecret(opts.Org, secret.Name, value)
+			}) {
+				placeholders = append(placeholders, secret.Name)
+			}
+			continue
+		}
+
+		repos, err := m.ghClient.ListOrgSecretRepos(ctx, opts.SourceOrg, secret.Name)
+		if err != nil {
+			m.logger.Error("failed to list repositories of org secret", "org", opts.SourceOrg, "secret", secret.Name, "error", err)
+			continue
+		}
+		for _, repo := range repos {
+			names, loaded := repoSecrets[repo.GetName()]
+			if !loaded {
+				names, err = m.repoSecretNames(opts.Org, repo.GetName())
+				if notFound(err) {
+					opts.Report.Add(report.Item{
+						Kind:   report.KindSecret,
+						Name:   opts.Org + "/" + repo.GetName() + " " + secret.Name,
+						Status: report.StatusSkipped,
+						Error:  "repository not migrated",
+					})
+					continue
+				}
+				if err != nil {
+					m.logger.Error("failed to list repo secrets", "repo", opts.Org+"/"+repo.GetName(), "error", err)
+					continue
+				}
+				repoSecrets[repo.GetName()] = names
+			}
+			if m.setSecret(opts.Report, opts.Org+"/"+repo.GetName(), secret.Name, value, ok, names[strings.ToUpper(secret.Name)], func(value string) error {
+				return m.gtClient.SetRepoActionSecret(opts.Org, repo.GetName(), secret.Name, value)
+			}) {
+				placeholders = append(placeholders, repo.GetName()+":"+secret.Name)
+			}
+		}
+	}
+	if len(placeholders) > 0 {
+		m.logger.Warn("actions secrets set to a placeholder, update them in gitea",
+			"org", opts.Org,
+			"secrets", strings.Join(placeholders, ","),
+		)
+	}
+	return nil
+}
+
+// visibilitySelected is the visibility of GitHub organization variables and secrets
+// limited to selected repositories.
+const visibilitySelected = "selected"
+
+// actionVariables creates, reads and updates the Actions variables of a Gitea
+// repository or organization.
+type actionVariables struct {
+	create func(name, value string) error
+	get    func(name string) (string, error)
+	update func(name, value string) error
+}
+
+// repoVariables returns the Actions variables of a Gitea repository.
+func (m *Migrator) repoVariables(owner, repo string) actionVariables {
+	return actionVariables{
+		create: func(name, value string) error {
+			return m.gtClient.CreateRepoActionVariable(owner, repo, name, value)
+		},
+		get: func(name string) (string, error) {
+			return m.gtClient.GetRepoActionVariable(owner, repo, name)
+		},
+		update: func(name, value string) error {
+			return m.gtClient.UpdateRepoActionVariable(owner, repo, name, value)
+		},
+	}
+}
+
+// orgVariables returns the Actions variables of a Gitea organization.
+func (m *Migrator) orgVariables(org string) actionVariables {
+	return actionVariables{
+		create: func(name, value string) error {
+			return m.gtClient.CreateOrgActionVariable(org, name, value)
+		},
+		get: func(name string) (string, error) {
+			return m.gtClient.GetOrgActionVariable(org, name)
+		},
+		update: func(name, value string) error {
+			return m.gtClient.UpdateOrgActionVariable(org, name, value)
+		},
+	}
+}
+
+// setVariable creates an Actions variable of target, a repository full name or an
+// organization. An existing variable gets value when it differs, and is reported as
+// skipped otherwise.
+func (m *Migrator) setVariable(rpt *report.Report, target, name, value string, vars actionVariables) {
+	item := target + " " + name
+	start := time.Now()
+	err := vars.create(name, value)
+	var giteaErr *gitea.GiteaError
+	if errors.As(err, &giteaErr) && giteaErr.Code == http.StatusConflict {
+		var current string
+		current, err = vars.get(name)
+		if err == nil && current == value {
+			rpt.Add(report.Item{Kind: report.KindVariable, Name: item, Status: report.StatusSkipped, Error: "already exists"})
+			return
+		}
+		if err == nil {
+			err = vars.update(name, value)
+		}
+	}
+	if notFound(err) {
+		rpt.Add(report.Item{Kind: report.KindVariable, Name: item, Status: report.StatusSkipped, Error: "not migrated"})
+		return
+	}
+	record(rpt, report.KindVariable, item, start, err)
+	if err != nil {
+		m.logger.Error("failed to create actions variable", "target", target, "variable", name, "error", err)
+	}
+}
+
+// setSecret sets an Actions secret of target, a repository full name or an organization,
+// to value when hasValue, or to SecretPlaceholder unless the secret already exists.
+// It reports whether the placeholder was set.
+func (m *Migrator) setSecret(rpt *report.Report, target, name, value string, hasValue, exists bool, set func(value string) error) bool {
+	item := target + " " + name
+	if !hasValue {
+		if exists {
+			rpt.Add(report.Item{Kind: report.KindSecret, Name: item, Status: report.StatusSkipped, Error: "already exists"})
+			return false
+		}
+		value = SecretPlaceholder
+	}
+	start := time.Now()
+	err := set(value)
+	record(rpt, report.KindSecret, item, start, err)
+	if err != nil {
+		m.logger.Error("failed to create actions secret", "target", target, "secret", name, "error", err)
+		return false
+	}
+	return !hasValue
+}
+
+// repoSecretNames returns the uppercase names of the Actions secrets of a Gitea repository.
+func (m *Migrator) repoSecretNames(owner, name string) (map[string]bool, error) {
+	names, err := m.gtClient.ListRepoActionSecrets(owner, name)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(names))
+	for _, name := range names {
+		existing[strings.ToUpper(name)] = true
+	}
+	return existing, nil
+}
@@ -0,0 +1,111 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+)
+
+// appScopes maps the GitHub App permissions to the Gitea access token scope category
+// granting the same access. Permissions without Gitea counterpart, such as checks or
+// codespaces, are left out.
+var appScopes = map[string]string{
+	"actions":                    "repository",
+	"administration":             "repository",
+	"contents":                   "repository",
+	"deployments":                "repository",
+	"environments":               "repository",
+	"metadata":                   "repository",
+	"pages":                      "repository",
+	"pull_requests":              "repository",
+	"repository_hooks":           "repository",
+	"secrets":                    "repository",
+	"statuses":                   "repository",
+	"actions_variables":          "repository",
+	"issues":                     "issue",
+	"members":                    "organization",
+	"organization_hooks":         "organization",
+	"organization_secrets":       "organization",
+	"organization_projects":      "organization",
+	"organization_packages":      "package",
+	"packages":                   "package",
+	"email_addresses":            "user",
+	"followers":                  "user",
+	"git_ssh_keys":               "user",
+	"gpg_keys":                   "user",
+	"profile":                    "user",
+	"organization_plan":          "organization",
+	"organization_user_blocking": "organization",
+}
+
+// orgApps lists the GitHub Apps installed on an organization with their permissions,
+// and the Gitea token scopes needed by their replacement. GitHub does not expose the
+// OAuth apps an organization approved.
+func (r *run) orgApps(ctx context.Context, org string) ([]report.App, error) {
+	installations, err := r.ghClient.ListOrgInstallations(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]report.App, 0, len(installations))
+	for _, installation := range installations {
+		permissions := make(map[string]string)
+		if installation.Permissions != nil {
+			// the permissions are a struct of one optional field per permission
+			data, err := json.Marshal(installation.Permissions)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(data, &permissions); err != nil {
+				return nil, err
+			}
+		}
+		apps = append(apps, report.App{
+			Org:          org,
+			Slug:         installation.GetAppSlug(),
+			Permissions:  permissions,
+			Events:       installation.Events,
+			Repositories: installation.GetRepositorySelection(),
+			Suspended:    installation.SuspendedAt != nil,
+			Scopes:       giteaScopes(permissions),
+			Template:     matchOAuth2Template(installation.GetAppSlug()),
+		})
+	}
+	return apps, nil
+}
+
+// giteaScopes returns the sorted Gitea token scopes, e.g. write:repository, covering
+// the GitHub App permissions. Write and admin permissions need the write scope.
+func giteaScopes(permissions map[string]string) []string {
+	levels := make(map[string]string)
+	for permission, level := range permissions {
+		category, ok := appScopes[permission]
+		if !ok {
+			continue
+		}
+		if level == "write" || level == "admin" {
+			levels[category] = "write"
+		} else if levels[category] == "" {
+			levels[category] = "read"
+		}
+	}
+	scopes := make([]string, 0, len(levels))
+	for category, level := range levels {
+		scopes = append(scopes, level+":"+category)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// appChecklist returns the replacement hint of an app for the logs.
+func appChecklist(app report.App) string {
+	if app.Template != "" {
+		return "create its oauth2 application with --oauth2-apps " + app.Template + "=URL"
+	}
+	if len(app.Scopes) == 0 {
+		return "no gitea counterpart"
+	}
+	return "replace with an oauth2 application or bot token with " + strings.Join(app.Scopes, ",")
+}
@@ -0,0 +1,265 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/state"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// attachmentPattern matches the URLs of files attached to GitHub issues, pull
+// requests and comments on host, github.com or a GitHub Enterprise Server, which
+// the Gitea importer leaves pointing at GitHub.
+func attachmentPattern(host string) *regexp.Regexp {
+	host = regexp.QuoteMeta(host)
+	return regexp.MustCompile(`https?://(?:` +
+		host + `/user-attachments/(?:assets|files)/|` +
+		`(?:private-)?user-images\.githubusercontent\.com/|` +
+		host + `/[\w.-]+/[\w.-]+/(?:assets|files)/|` +
+		// GitHub Enterprise Server storage, with and without subdomain isolation
+		`media\.` + host + `/user/|` +
+		host + `/storage/user/` +
+		`)[^\s()<>"'\]]+`)
+}
+
+// AttachmentsOption selects the migrated repository whose issue attachments are copied.
+type AttachmentsOption struct {
+	// Owner and Name identify the migrated Gitea repository.
+	Owner string
+	Name  string
+	// Private is set for repositories not readable by everyone, whose attachments
+	// are not linked from other repositories.
+	Private bool
+	// State deduplicates the attachments across repositories and runs when set.
+	State *state.Store
+	// Report records every issue and comment whose attachments were copied when set.
+	Report *report.Report
+}
+
+// MigrateRepoAttachments downloads the GitHub attachments referenced in the issue,
+// pull request and comment bodies of a migrated repository, uploads them to Gitea
+// and rewrites the links. An attachment referenced several times is uploaded once,
+// and a link which cannot be copied keeps pointing at GitHub. With a state store,
+// files already uploaded to this repository or a public one, found by the hash of
+// their content, are linked instead of uploaded again.
+func (m *Migrator) MigrateRepoAttachments(ctx context.Context, opts AttachmentsOption) error {
+	ctx, span := trace.Start(ctx, "migrate.MigrateRepoAttachments",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+	if opts.State != nil {
+		defer m.saveAttachments(opts.State)
+	}
+
+	issues, err := m.gtClient.ListIssues(opts.Owner, opts.Name)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	comments, err := m.gtClient.ListIssueComments(opts.Owner, opts.Name)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	target := opts.Owner + "/" + opts.Name
+	pattern := attachmentPattern(m.ghClient.Host())
+	copied := make(map[string]string)
+	for _, issue := range issues {
+		item := target + " #" + strconv.FormatInt(issue.Index, 10)
+		m.rewriteAttachments(ctx, opts, pattern, item, issue.Body, copied,
+			func(name, file string) (*gsdk.Attachment, error) {
+				return m.gtClient.UploadIssueAttachment(opts.Owner, opts.Name, issue.Index, name, file)
+			},
+			func(body string) error {
+				return m.gtClient.EditIssueBody(opts.Owner, opts.Name, issue.Index, body)
+			})
+	}
+	for _, comment := range comments {
+		item := target + " comment " + strconv.FormatInt(comment.ID, 10)
+		m.rewriteAttachments(ctx, opts, pattern, item, comment.Body, copied,
+			func(name, file string) (*gsdk.Attachment, error) {
+				return m.gtClient.UploadCommentAttachment(opts.Owner, opts.Name, comment.ID, name, file)
+			},
+			func(body string) error {
+				return m.gtClient.EditIssueComment(opts.Owner, opts.Name, comment.ID, body)
+			})
+	}
+	return nil
+}
+
+// rewriteAttachments copies the GitHub attachments of body with upload, unless
+// already copied, and saves the body with the new links. Bodies without GitHub
+// attachments are not reported.
+func (m *Migrator) rewriteAttachments(
+	ctx context.Context,
+	opts AttachmentsOption,
+	pattern *regexp.Regexp,
+	item, body string,
+	copied map[string]string,
+	upload func(name, file string) (*gsdk.Attachment, error),
+	save func(body string) error,
+) {
+	links := pattern.FindAllString(body, -1)
+	if len(links) == 0 {
+		return
+	}
+
+	start := time.Now()
+	var failed []string
+	rewritten := body
+	for _, link := range links {
+		to, ok := copied[link]
+		if !ok {
+			var err error
+			to, err = m.copyAttachment(ctx, opts, link, upload)
+			if err != nil {
+				m.logger.Warn("failed to copy attachment", "item", item, "url", link, "error", err)
+				failed = append(failed, link+": "+err.Error())
+				continue
+			}
+			copied[link] = to
+		}
+		rewritten = strings.ReplaceAll(rewritten, link, to)
+	}
+
+	var err error
+	if rewritten != body {
+		err = save(rewritten)
+	}
+	if err == nil && len(failed) > 0 {
+		opts.Report.Add(report.Item{Kind: report.KindAttachment, Name: item, Status: report.StatusFailed, Duration: report.Since(start), Error: strings.Join(failed, "; ")})
+		return
+	}
+	record(opts.Report, report.KindAttachment, item, start, err)
+}
+
+// copyAttachment downloads a GitHub attachment to a temporary file, uploads it with
+// upload and returns its Gitea URL. Files already uploaded are linked again, without
+// downloading the links known to the state store.
+func (m *Migrator) copyAttachment(ctx context.Context, opts AttachmentsOption, link string, upload func(name, file string) (*gsdk.Attachment, error)) (string, error) {
+	target := opts.Owner + "/" + opts.Name
+	if opts.State != nil {
+		if to, ok := m.uploadedAttachment(opts.State, link, target); ok {
+			return to, nil
+		}
+	}
+
+	f, err := os.CreateTemp("", "github2gitea-attachment-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	contentType, err := m.ghClient.DownloadAttachment(ctx, link, f)
+	if err != nil {
+		return "", err
+	}
+	if opts.State == nil {
+		attachment, err := upload(attachmentName(link, contentType), f.Name())
+		if err != nil {
+			return "", err
+		}
+		return attachment.DownloadURL, nil
+	}
+
+	hash, err := fileHash(f)
+	if err != nil {
+		return "", err
+	}
+	if to, ok := m.reusableAttachment(opts.State, hash, target); ok {
+		m.logger.Debug("link already uploaded attachment", "repo", target, "url", link, "to", to)
+		opts.State.SetAttachment(link, hash, state.Attachment{})
+		return to, nil
+	}
+
+	attachment, err := upload(attachmentName(link, contentType), f.Name())
+	if err != nil {
+		return "", err
+	}
+	opts.State.SetAttachment(link, hash, state.Attachment{URL: attachment.DownloadURL, Repo: target, Public: !opts.Private})
+	return attachment.DownloadURL, nil
+}
+
+// uploadedAttachment returns the URL of the uploaded attachment with the content of
+// a GitHub URL already downloaded, when it can be reused for the target repository.
+func (m *Migrator) uploadedAttachment(store *state.Store, link, target string) (string, bool) {
+	hash, ok := store.AttachmentLink(link)
+	if !ok {
+		return "", false
+	}
+	return m.reusableAttachment(store, hash, target)
+}
+
+// reusableAttachment returns the URL of the uploaded attachment with the given content
+// hash, when the readers of the target repository can read it. Attachments no longer
+// on Gitea, deleted with their repository, are forgotten.
+func (m *Migrator) reusableAttachment(store *state.Store, hash, target string) (string, bool) {
+	attachment, ok := store.Attachment(hash)
+	if !ok || (!attachment.Public && !strings.EqualFold(attachment.Repo, target)) {
+		return "", false
+	}
+	exists, err := m.gtClient.AttachmentExists(attachment.URL)
+	if err != nil {
+		m.logger.Warn("failed to check uploaded attachment", "url", attachment.URL, "error", err)
+		return "", false
+	}
+	if !exists {
+		store.DropAttachment(hash)
+		return "", false
+	}
+	return attachment.URL, true
+}
+
+// saveAttachments writes the attachments recorded for a repository to the state file.
+func (m *Migrator) saveAttachments(store *state.Store) {
+	if err := store.Save(); err != nil {
+		m.logger.Warn("failed to record attachments", "error", err)
+	}
+}
+
+// fileHash returns the hex SHA-256 of the content of f.
+func fileHash(f *os.File) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// attachmentName returns the file name of an attachment URL. Gitea checks the
+// type of uploads by extension, so names without one, such as the asset UUIDs,
+// get the extension of the content type.
+func attachmentName(link, contentType string) string {
+	name := "attachment"
+	if u, err := url.Parse(link); err == nil && path.Base(u.Path) != "/" {
+		name = path.Base(u.Path)
+	}
+	if path.Ext(name) != "" {
+		return name
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if extensions, _ := mime.ExtensionsByType(mediaType); len(extensions) > 0 {
+		return name + extensions[0]
+	}
+	return name
+}
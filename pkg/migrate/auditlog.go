@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/redact"
+	"github.com/appleboy/github2gitea/pkg/trace"
+)
+
+// auditPush is the audit log action of a git push.
+const auditPush = "git.push"
+
+// exportAuditLog writes the audit log events of the source organization since the
+// given time, the code freeze or the start of the run, as JSON lines to the audit log
+// file, and logs who pushed to GitHub meanwhile. The audit log needs GitHub
+// Enterprise Cloud or Server and a token with the read:audit_log scope.
+func (r *run) exportAuditLog(ctx context.Context, since time.Time) {
+	ctx, span := trace.Start(ctx, "migrate.exportAuditLog",
+		trace.String("github.org", r.plan.SourceOrg),
+	)
+	defer span.End()
+
+	since = since.UTC().Truncate(time.Second)
+	entries, err := r.ghClient.ListOrgAuditLog(ctx, r.plan.SourceOrg, "created:>="+since.Format(time.RFC3339))
+	if err != nil {
+		span.RecordError(err)
+		r.logger.Error("failed to get github audit log", "org", r.plan.SourceOrg, "error", err)
+		return
+	}
+
+	f, err := os.OpenFile(r.plan.AuditLogFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		span.RecordError(err)
+		r.logger.Error("failed to write audit log", "file", r.plan.AuditLogFile, "error", err)
+		return
+	}
+	// an event may quote a URL or command line with credentials
+	enc := json.NewEncoder(redact.NewWriter(f))
+	type pusher struct{ actor, repo string }
+	pushes := make(map[pusher]int)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			f.Close()
+			r.logger.Error("failed to write audit log", "file", r.plan.AuditLogFile, "error", err)
+			return
+		}
+		if entry.GetAction() == auditPush {
+			repo, _ := entry.AdditionalFields["repo"].(string)
+			pushes[pusher{entry.GetActor(), repo}]++
+		}
+	}
+	if err := f.Close(); err != nil {
+		r.logger.Error("failed to write audit log", "file", r.plan.AuditLogFile, "error", err)
+		return
+	}
+	r.logger.Info("github audit log written", "org", r.plan.SourceOrg, "file", r.plan.AuditLogFile, "since", since, "events", len(entries))
+
+	pushers := make([]pusher, 0, len(pushes))
+	for p := range pushes {
+		pushers = append(pushers, p)
+	}
+	sort.Slice(pushers, func(i, j int) bool {
+		if pushers[i].actor != pushers[j].actor {
+			return pushers[i].actor < pushers[j].actor
+		}
+		return pushers[i].repo < pushers[j].repo
+	})
+	for _, p := range pushers {
+		r.logger.Warn("pushed to github since the freeze", "actor", p.actor, "repo", p.repo, "pushes", pushes[p], "since", since)
+	}
+}
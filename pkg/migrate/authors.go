@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+)
+
+// AuthorsOption selects the migrated repository whose issue authors are reported.
+type AuthorsOption struct {
+	// Owner and Name identify the migrated Gitea repository.
+	Owner string
+	Name  string
+	// Report records every GitHub author still shown instead of a Gitea user.
+	Report *report.Report
+}
+
+// ReportRepoAuthors counts, per GitHub author, the migrated issues, pull requests and
+// comments of a repository still attributed to the GitHub account instead of a Gitea
+// user. Gitea has no API, not even through Sudo, to change who posted them: it
+// reassigns them itself once the Gitea user links the GitHub account, by signing in
+// through a GitHub OAuth2 authentication source. Authors with a Gitea account are
+// reported as waiting for that link, the others as without account.
+func (m *Migrator) ReportRepoAuthors(ctx context.Context, opts AuthorsOption) error {
+	_, span := trace.Start(ctx, "migrate.ReportRepoAuthors",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+
+	issues, err := m.gtClient.ListIssues(opts.Owner, opts.Name)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	comments, err := m.gtClient.ListIssueComments(opts.Owner, opts.Name)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	// original authors are only set on the items Gitea could not map to a user
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		if issue.OriginalAuthor != "" {
+			counts[issue.OriginalAuthor]++
+		}
+	}
+	for _, comment := range comments {
+		if comment.OriginalAuthor != "" {
+			counts[comment.OriginalAuthor]++
+		}
+	}
+	authors := make([]string, 0, len(counts))
+	for author := range counts {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+
+	target := opts.Owner + "/" + opts.Name
+	for _, author := range authors {
+		item := report.Item{Kind: report.KindAuthor, Name: target + " " + author, Status: report.StatusSkipped}
+		username := m.gtClient.Username(author)
+		if _, err := m.gtClient.GetUser(username); err != nil {
+			item.Error = fmt.Sprintf("%d items, no gitea account", counts[author])
+		} else {
+			item.Error = fmt.Sprintf("%d items, reassigned once %s links the github account", counts[author], username)
+		}
+		opts.Report.Add(item)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package migrate
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	gsdk "code.gitea.io/sdk/gitea"
+	"github.com/google/go-github/v71/github"
+)
+
+// BackfillOption selects the migrated repositories whose metadata is backfilled.
+type BackfillOption struct {
+	// SourceOrg or SourceUser is the GitHub owner of the repositories.
+	SourceOrg  string
+	SourceUser string
+	// Owner is the Gitea user or organization holding the migrated repositories.
+	Owner string
+	// Report records every repository when set.
+	Report *report.Report
+}
+
+// BackfillMetadata reads the GitHub repositories again and updates the description,
+// website, topics and archived flag of the repositories migrated to Gitea by earlier
+// versions of the tool, which did not copy them. Repositories missing in Gitea are
+// skipped, nothing is migrated.
+func (m *Migrator) BackfillMetadata(ctx context.Context, opts BackfillOption) error {
+	ctx, span := trace.Start(ctx, "migrate.BackfillMetadata",
+		trace.String("gitea.owner", opts.Owner),
+	)
+	defer span.End()
+
+	var ghRepos []*github.Repository
+	var err error
+	if opts.SourceOrg != "" {
+		ghRepos, err = m.ghClient.ListOrgRepos(ctx, opts.SourceOrg)
+	} else {
+		ghRepos, err = m.ghClient.ListAccessibleUserRepos(ctx, opts.SourceUser)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	sortRepos(ghRepos)
+
+	for _, ghRepo := range ghRepos {
+		name := opts.Owner + "/" + ghRepo.GetName()
+		start := time.Now()
+		changed, err := m.backfillRepo(opts.Owner, ghRepo)
+		switch {
+		case notFound(err):
+			opts.Report.Add(report.Item{Kind: report.KindMetadata, Name: name, Status: report.StatusSkipped, Error: "not migrated"})
+		case err == nil && len(changed) == 0:
+			opts.Report.Add(report.Item{Kind: report.KindMetadata, Name: name, Status: report.StatusSkipped})
+		default:
+			record(opts.Report, report.KindMetadata, name, start, err)
+			if err != nil {
+				m.logger.Error("failed to backfill repo metadata", "repo", name, "error", err)
+				continue
+			}
+			m.logger.Info("backfill repo metadata", "repo", name, "fields", strings.Join(changed, ","))
+		}
+	}
+	return nil
+}
+
+// backfillRepo updates the metadata of a Gitea repository which differs from
+// GitHub, and returns the names of the updated fields.
+func (m *Migrator) backfillRepo(owner string, ghRepo *github.Repository) ([]string, error) {
+	repo, err := m.gtClient.GetRepo(owner, ghRepo.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	// topics are left alone on a gitea too old for them
+	if m.gtClient.Supports(gitea.FeatureTopics) {
+		topics, err := m.gtClient.ListRepoTopics(owner, repo.Name)
+		if err != nil {
+			return nil, err
+		}
+		want := slices.Clone(ghRepo.Topics)
+		// the license topic is set by --license-topics, not GitHub
+		for _, topic := range topics {
+			if strings.HasPrefix(topic, licenseTopicPrefix) && !slices.Contains(want, topic) {
+				want = append(want, topic)
+			}
+		}
+		slices.Sort(want)
+		slices.Sort(topics)
+		// topics cannot be changed once the repository is archived
+		if !slices.Equal(want, topics) {
+			if repo.Archived {
+				if _, err := m.gtClient.EditRepo(owner, repo.Name, gsdk.EditRepoOption{Archived: gsdk.OptionalBool(false)}); err != nil {
+					return nil, err
+				}
+				repo.Archived = false
+			}
+			if err := m.gtClient.SetRepoTopics(owner, repo.Name, want); err != nil {
+				return nil, err
+			}
+			changed = append(changed, "topics")
+		}
+	}
+
+	var edit gsdk.EditRepoOption
+	if description := ghRepo.GetDescription(); description != repo.Description {
+		edit.Description = &description
+		changed = append(changed, "description")
+	}
+	if website := ghRepo.GetHomepage(); website != repo.Website {
+		edit.Website = &website
+		changed = append(changed, "website")
+	}
+	if archived := ghRepo.GetArchived(); archived != repo.Archived {
+		edit.Archived = &archived
+		changed = append(changed, "archived")
+	}
+	if edit.Description != nil || edit.Website != nil || edit.Archived != nil {
+		if _, err := m.gtClient.EditRepo(owner, repo.Name, edit); err != nil {
+			return nil, err
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,50 @@
+package migrate
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/google/go-github/v71/github"
+)
+
+// BotLogins are lowercase glob patterns, e.g. *-ci or dependabot, of the GitHub
+// logins of machine accounts. No Gitea user or team membership is created for them,
+// nor for the accounts of type Bot.
+type BotLogins []string
+
+// ParseBotLogins parses a comma separated list of login patterns.
+func ParseBotLogins(s string) (BotLogins, error) {
+	var patterns BotLogins
+	for pattern := range strings.SplitSeq(s, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid bot login pattern %q", pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// Match reports whether a GitHub user is a bot or machine account.
+func (b BotLogins) Match(user *github.User) bool {
+	if user.GetType() == "Bot" {
+		return true
+	}
+	login := strings.ToLower(user.GetLogin())
+	for _, pattern := range b {
+		if ok, _ := path.Match(pattern, login); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// skipBot records a bot account left out of the migration.
+func skipBot(rpt *report.Report, login string) {
+	rpt.Add(report.Item{Kind: report.KindBot, Name: login, Status: report.StatusSkipped, Error: "bot account"})
+}
@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// ErrCanaryStopped is returned when the migration was not confirmed after the canary repositories.
+var ErrCanaryStopped = errors.New("migration stopped after the canary repositories")
+
+// rampRepos calls fn for the repositories like forEachRepo. With plan.Canary, a few
+// representative repositories go first and are verified, and the others only
+// follow once plan.ConfirmCanary agrees. They are recorded as skipped otherwise.
+func (r *run) rampRepos(ctx context.Context, repos []*github.Repository, owner string, fn func(repo *github.Repository)) error {
+	if r.plan.Canary <= 0 || len(repos) <= r.plan.Canary {
+		r.forEachRepo(ctx, repos, owner, fn)
+		return nil
+	}
+
+	canary, rest := canaryRepos(repos, r.plan.Canary)
+	names := make([]string, 0, len(canary))
+	for _, repo := range canary {
+		names = append(names, repo.GetName())
+	}
+	r.logger.Info("migrate canary repositories first", "owner", owner, "repos", strings.Join(names, ","))
+	r.forEachRepo(ctx, canary, owner, fn)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	failed := r.verifyCanary(ctx, canary, owner)
+	question := fmt.Sprintf("%d of %d canary repositories failed the verification, migrate the remaining %d repositories?", failed, len(canary), len(rest))
+	if r.plan.ConfirmCanary == nil || !r.plan.ConfirmCanary(question) {
+		r.logger.Warn("migration stopped after the canary repositories", "owner", owner, "failed", failed, "remaining", len(rest))
+		for _, repo := range rest {
+			name := r.plan.RepoOverrides.Lookup(repo).target(repo)
+			result := report.Repo{Owner: owner, Name: name, Source: repo.GetFullName(), Status: report.StatusSkipped, Error: "stopped after the canary repositories"}
+			r.rpt.AddRepo(result)
+			r.progress(Event{Type: EventRepoFinished, Owner: owner, Name: name, Source: repo.GetFullName(), Result: &result})
+		}
+		return ErrCanaryStopped
+	}
+	r.logger.Info("canary confirmed, migrate the remaining repositories", "owner", owner, "total", len(rest))
+	r.forEachRepo(ctx, rest, owner, fn)
+	return nil
+}
+
+// verifyCanary compares the migrated canary repositories with GitHub, records the
+// results and returns the number of failed ones.
+func (r *run) verifyCanary(ctx context.Context, canary []*github.Repository, owner string) int {
+	failed := 0
+	for _, repo := range canary {
+		override := r.plan.RepoOverrides.Lookup(repo)
+		name := override.target(repo)
+		start := time.Now()
+		result := r.verifyRepo(ctx, override.apply(repo), owner, name)
+		var err error
+		if !result.OK {
+			failed++
+			err = errors.New(result.Failure())
+			r.logger.Warn("canary repository failed the verification", "repo", result.Target, "error", err)
+		}
+		record(r.rpt, report.KindCanary, result.Target, start, err)
+	}
+	return failed
+}
+
+// canaryRepos picks n representative repositories, spread over the sizes from the
+// smallest to the largest so quick and slow migrations are both tried, and returns
+// them with the other repositories.
+func canaryRepos(repos []*github.Repository, n int) (canary, rest []*github.Repository) {
+	bySize := slices.Clone(repos)
+	slices.SortStableFunc(bySize, func(a, b *github.Repository) int {
+		return cmp.Or(cmp.Compare(a.GetSize(), b.GetSize()), strings.Compare(a.GetFullName(), b.GetFullName()))
+	})
+	picked := make(map[int]bool, n)
+	for i := range n {
+		index := len(bySize) / 2
+		if n > 1 {
+			index = i * (len(bySize) - 1) / (n - 1)
+		}
+		picked[index] = true
+	}
+	for i, repo := range bySize {
+		if picked[i] {
+			canary = append(canary, repo)
+		} else {
+			rest = append(rest, repo)
+		}
+	}
+	return canary, rest
+}
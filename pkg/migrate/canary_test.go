@@ -0,0 +1,44 @@
+package migrate
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/go-github/v71/github"
+)
+
+func TestCanaryRepos(t *testing.T) {
+	var repos []*github.Repository
+	for _, size := range []int{50, 10, 40, 20, 30} {
+		repos = append(repos, &github.Repository{
+			FullName: github.Ptr("acme/repo" + string(rune('0'+size/10))),
+			Size:     github.Ptr(size),
+		})
+	}
+
+	sizes := func(repos []*github.Repository) []int {
+		var sizes []int
+		for _, repo := range repos {
+			sizes = append(sizes, repo.GetSize())
+		}
+		return sizes
+	}
+	tests := []struct {
+		n      int
+		canary []int
+		rest   []int
+	}{
+		{1, []int{30}, []int{10, 20, 40, 50}},
+		{2, []int{10, 50}, []int{20, 30, 40}},
+		{3, []int{10, 30, 50}, []int{20, 40}},
+	}
+	for _, tt := range tests {
+		canary, rest := canaryRepos(repos, tt.n)
+		if got := sizes(canary); !slices.Equal(got, tt.canary) {
+			t.Errorf("n=%d: canary sizes = %v, want %v", tt.n, got, tt.canary)
+		}
+		if got := sizes(rest); !slices.Equal(got, tt.rest) {
+			t.Errorf("n=%d: rest sizes = %v, want %v", tt.n, got, tt.rest)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package migrate
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+
+	"github.com/appleboy/com/convert"
+	"github.com/google/go-github/v71/github"
+)
+
+// migrateCollaborators adds the outside collaborators of a GitHub repository, who are
+// not members of its organization, to the migrated repository with their GitHub
+// permission. Their Gitea users are created like the organization members.
+func (r *run) migrateCollaborators(ctx context.Context, repo *github.Repository, owner, name string) {
+	collaborators, err := r.ghClient.ListOutsideCollaborators(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+	if err != nil {
+		r.logger.Error("failed to get github outside collaborators", "repo", repo.GetFullName(), "error", err)
+		return
+	}
+
+	target := owner + "/" + name
+	for _, collaborator := range collaborators {
+		username := r.outsideUser(ctx, collaborator.GetLogin())
+		if username == "" {
+			continue
+		}
+		start := time.Now()
+		_, err := r.gtClient.AddCollaborator(owner, name, username, collaborator.Permissions)
+		record(r.rpt, report.KindCollaborator, target+" "+username, start, err)
+		if err != nil {
+			r.logger.Error("failed to add gitea collaborator", "repo", target, "user", username, "error", err)
+			continue
+		}
+		r.logger.Info("add outside collaborator", "repo", target, "user", username, "login", collaborator.GetLogin())
+	}
+}
+
+// outsideAccount is the Gitea user of an outside collaborator, created once per run.
+type outsideAccount struct {
+	once     sync.Once
+	username string
+}
+
+// outsideUser returns the Gitea username of an outside collaborator, creating the user
+// once per run, or empty when the user is left out or could not be created.
+func (r *run) outsideUser(ctx context.Context, login string) string {
+	key := strings.ToLower(login)
+	r.outsideMu.Lock()
+	if r.outside == nil {
+		r.outside = make(map[string]*outsideAccount)
+	}
+	account, ok := r.outside[key]
+	if !ok {
+		account = &outsideAccount{}
+		r.outside[key] = account
+	}
+	r.outsideMu.Unlock()
+
+	// concurrent repositories only wait for the creation of the same user
+	account.once.Do(func() {
+		account.username = r.createOutsideUser(ctx, login)
+	})
+	return account.username
+}
+
+// createOutsideUser creates the Gitea user of an outside collaborator and returns
+// its username, empty when the user is left out or could not be created.
+func (r *run) createOutsideUser(ctx context.Context, login string) string {
+	ghUser, err := r.ghClient.GetUser(ctx, login)
+	if err != nil {
+		r.logger.Error("failed to get github user", "login", login, "error", err)
+		return ""
+	}
+	if r.plan.BotLogins.Match(ghUser) {
+		r.logger.Info("skip github bot account", "login", login)
+		skipBot(r.rpt, login)
+		return ""
+	}
+	if r.plan.Suspended.skip(ghUser) {
+		r.logger.Info("skip github user suspended", "login", login)
+		skipSuspended(r.rpt, login)
+		return ""
+	}
+
+	password, generated, err := newPassword(r.plan.Passwords, "")
+	if err != nil {
+		r.logger.Error("failed to generate password", "login", login, "error", err)
+		return ""
+	}
+	inactive := r.plan.Suspended.inactive(ghUser)
+	opt := gitea.CreateUserOption{
+		SourceID:  r.plan.SourceID,
+		LoginName: login,
+		Username:  login,
+		Email:     rewriteEmail(ghUser.GetEmail(), r.plan.EmailRewrites),
+		Password:  password,
+		Inactive:  inactive,
+	}
+	if !r.plan.SkipUserProfile {
+		opt.FullName = convert.FromPtr(ghUser.Name)
+	}
+	start := time.Now()
+	gtUser, created, err := r.gtClient.CreateOrGetUser(opt)
+	if created && generated {
+		r.savePassword(r.plan.PasswordsFile, login, gtUser.UserName, password)
+	}
+	record(r.rpt, report.KindUser, login, start, err)
+	if err != nil {
+		r.logger.Error("failed to create gitea user", "login", login, "error", err)
+		return ""
+	}
+	if !r.plan.SkipUserProfile {
+		r.migrateUserProfile(ghUser, gtUser)
+	}
+	if created && !inactive {
+		r.sendWelcome(r.plan.Welcome, r.rpt, welcome{
+			Login:    login,
+			Username: gtUser.UserName,
+			Email:    gtUser.Email,
+			Source:   opt.SourceID,
+			Password: password != "",
+			Keys:     -1,
+		})
+	}
+	return gtUser.UserName
+}
@@ -0,0 +1,132 @@
+package migrate
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/appleboy/github2gitea/pkg/state"
+)
+
+// Controls pause the dispatch of the users of the user list and the repository
+// migrations, and skip queued repositories while a run is going on, e.g. from the
+// terminal dashboard or signals. The work in flight is finished. Methods are safe for
+// concurrent use and no-ops on nil Controls.
+type Controls struct {
+	mu      sync.Mutex
+	paused  bool
+	resumed chan struct{}
+	skip    map[string]bool
+	// store records the paused state, optional.
+	store *state.Store
+}
+
+// NewControls returns Controls that are not paused.
+func NewControls() *Controls {
+	return &Controls{skip: make(map[string]bool)}
+}
+
+// Persist records the paused state in store from now on. The controls start paused
+// when an earlier run was left paused.
+func (c *Controls) Persist(store *state.Store) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = store
+	if !store.PausedSince().IsZero() {
+		c.pause()
+	}
+}
+
+// Pause stops starting work until Resume is called. The controls are paused even when
+// the state cannot be recorded.
+func (c *Controls) Pause() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pause()
+	if c.store == nil {
+		return nil
+	}
+	return c.store.SetPaused(true)
+}
+
+// pause marks the controls paused. The caller holds mu.
+func (c *Controls) pause() {
+	if !c.paused {
+		c.paused = true
+		c.resumed = make(chan struct{})
+	}
+}
+
+// Resume starts the work again after Pause.
+func (c *Controls) Resume() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		c.paused = false
+		close(c.resumed)
+	}
+	if c.store == nil {
+		return nil
+	}
+	return c.store.SetPaused(false)
+}
+
+// Paused reports whether the work is paused.
+func (c *Controls) Paused() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// Skip leaves out a queued repository, given by its GitHub full name. It is recorded
+// as skipped in the report; a repository already migrating is not affected.
+func (c *Controls) Skip(repo string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skip[strings.ToLower(repo)] = true
+}
+
+// skipped reports whether a repository was skipped with Skip.
+func (c *Controls) skipped(repo string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skip[strings.ToLower(repo)]
+}
+
+// wait blocks while the controls are paused, or until the context is done.
+func (c *Controls) wait(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	if !c.paused {
+		c.mu.Unlock()
+		return nil
+	}
+	resumed := c.resumed
+	c.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resumed:
+		return nil
+	}
+}
@@ -0,0 +1,108 @@
+package migrate
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	gsdk "code.gitea.io/sdk/gitea"
+	"github.com/google/go-github/v71/github"
+)
+
+// mirrorSuffix is appended to the name of a pull mirror while the cutover migrates
+// the regular repository, so the mirror can be restored when the migration fails.
+const mirrorSuffix = "-github2gitea-mirror"
+
+// parkMirror renames the Gitea pull mirror of a GitHub repository out of the way of
+// the cutover migration and returns its new name. Gitea cannot turn a pull mirror
+// into a regular repository over the API, so the repository is migrated again from
+// GitHub, which is also the final sync, and brings the issues and pull requests the
+// mirror lacks. It returns why the repository is skipped when it is not a pull
+// mirror of the GitHub repository.
+func (r *run) parkMirror(ctx context.Context, repo *github.Repository, owner, name string) (string, string, error) {
+	_, span := trace.Start(ctx, "migrate.parkMirror",
+		trace.String("gitea.owner", owner),
+		trace.String("gitea.repo", name),
+	)
+	defer span.End()
+
+	existing, err := r.gtClient.GetRepo(owner, name)
+	if notFound(err) {
+		return "", "no mirror", nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		return "", "", err
+	}
+	if !existing.Mirror {
+		return "", "not a mirror", nil
+	}
+	if !sameRepoURL(existing.OriginalURL, repo.GetCloneURL()) {
+		return "", "mirror of " + existing.OriginalURL, nil
+	}
+
+	parked := name + mirrorSuffix
+	if _, err := r.gtClient.EditRepo(owner, name, gsdk.EditRepoOption{Name: &parked}); err != nil {
+		span.RecordError(err)
+		return "", "", err
+	}
+	return parked, "", nil
+}
+
+// finishCutover copies the webhooks, collaborators, teams, branch protections and
+// stars of the parked mirror to the regular repository once it is migrated and
+// deletes the mirror, or gives the mirror its name back when the migration failed,
+// and archives the GitHub repository with ArchiveSource.
+func (r *run) finishCutover(ctx context.Context, repo *github.Repository, owner, name, parked string, migrateErr error) {
+	fullName := owner + "/" + name
+	start := time.Now()
+	if migrateErr != nil {
+		// a failed migration may leave the repository behind, Gitea redirects the
+		// name to the parked mirror otherwise
+		if existing, err := r.gtClient.GetRepo(owner, name); err == nil && strings.EqualFold(existing.Name, name) {
+			if err := r.gtClient.DeleteRepository(gitea.DeleteRepoOption{Owner: owner, Repo: name}); err != nil {
+				r.logger.Error("failed to delete failed cutover repo", "repo", fullName, "error", err)
+			}
+		}
+		_, err := r.gtClient.EditRepo(owner, parked, gsdk.EditRepoOption{Name: gsdk.OptionalString(name)})
+		if err != nil {
+			r.logger.Error("failed to restore mirror, rename it back", "repo", owner+"/"+parked, "error", err)
+		}
+		r.rpt.Add(report.Item{Kind: report.KindCutover, Name: fullName, Status: report.StatusFailed, Duration: report.Since(start), Error: "mirror kept: " + migrateErr.Error()})
+		return
+	}
+
+	if err := r.gtClient.CopyRepoAccess(owner, parked, name); err != nil {
+		r.logger.Error("failed to copy mirror settings, mirror kept", "repo", owner+"/"+parked, "error", err)
+		r.rpt.Add(report.Item{Kind: report.KindCutover, Name: fullName, Status: report.StatusFailed, Duration: report.Since(start), Error: "mirror " + parked + " kept: " + err.Error()})
+		return
+	}
+	err := r.gtClient.DeleteRepository(gitea.DeleteRepoOption{Owner: owner, Repo: parked})
+	if err == nil && r.plan.ArchiveSource && !repo.GetArchived() {
+		err = r.ghClient.ArchiveRepo(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+	}
+	record(r.rpt, report.KindCutover, fullName, start, err)
+	if err != nil {
+		r.logger.Error("failed to complete cutover", "repo", fullName, "error", err)
+		return
+	}
+	r.logger.Info("cutover repo", "repo", fullName, "source", repo.GetFullName(), "archived", r.plan.ArchiveSource)
+}
+
+// sameRepoURL reports whether two clone URLs point to the same repository,
+// ignoring credentials, letter case and the .git suffix.
+func sameRepoURL(a, b string) bool {
+	normalize := func(s string) string {
+		u, err := url.Parse(strings.TrimSpace(s))
+		if err != nil {
+			return strings.ToLower(strings.TrimSuffix(s, ".git"))
+		}
+		return strings.ToLower(u.Host + strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git"))
+	}
+	return normalize(a) == normalize(b)
+}
@@ -0,0 +1,257 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/state"
+
+	gsdk "code.gitea.io/sdk/gitea"
+	"github.com/google/go-github/v71/github"
+)
+
+// DriftPolicy decides what happens to teams and repository settings changed in Gitea
+// since the tool last applied them.
+type DriftPolicy string
+
+const (
+	// DriftAsk asks for every changed object whether to overwrite it.
+	DriftAsk DriftPolicy = "ask"
+	// DriftOverwrite applies the migrated settings again.
+	DriftOverwrite DriftPolicy = "overwrite"
+	// DriftPreserve keeps the manual changes.
+	DriftPreserve DriftPolicy = "preserve"
+)
+
+// ValidDriftPolicy reports whether policy is a known DriftPolicy.
+func ValidDriftPolicy(policy string) bool {
+	switch DriftPolicy(policy) {
+	case DriftAsk, DriftOverwrite, DriftPreserve:
+		return true
+	}
+	return false
+}
+
+// Drift detects manual changes of the target objects created by the tool. The
+// settings applied to each team and repository are fingerprinted in the state
+// store, a later run comparing a different fingerprint has found a manual change.
+// A nil Drift detects nothing.
+type Drift struct {
+	State  *state.Store
+	Policy DriftPolicy
+	// Confirm asks whether to overwrite a changed object with DriftAsk.
+	// Changes are preserved when it is nil.
+	Confirm func(question string) bool
+	// Report records every detected change when set.
+	Report *report.Report
+
+	// mu serializes the questions of concurrent repository migrations.
+	mu sync.Mutex
+}
+
+// fingerprint hashes the JSON encoding of v, whose field order is fixed by its type.
+func fingerprint(v any) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// check compares the settings of an object with the fingerprint recorded on the
+// last run, and reports whether they should be overwritten.
+func (d *Drift) check(kind, name string, current any) bool {
+	if d == nil || d.State == nil {
+		return false
+	}
+	recorded, ok := d.State.Fingerprint(kind, name)
+	if !ok || recorded == fingerprint(current) {
+		return false
+	}
+
+	overwrite := d.Policy == DriftOverwrite
+	if d.Policy == DriftAsk && d.Confirm != nil {
+		d.mu.Lock()
+		overwrite = d.Confirm(fmt.Sprintf("%s %s was changed in Gitea since the last run, overwrite it?", kind, name))
+		d.mu.Unlock()
+	}
+
+	item := report.Item{Kind: report.KindDrift, Name: kind + " " + name, Status: report.StatusSkipped, Error: "manual change preserved"}
+	if overwrite {
+		item.Status = report.StatusSuccess
+		item.Error = "manual change overwritten"
+	}
+	d.Report.Add(item)
+	return overwrite
+}
+
+// record stores the fingerprint of the settings of an object as last applied.
+func (d *Drift) record(kind, name string, current any) error {
+	if d == nil || d.State == nil {
+		return nil
+	}
+	return d.State.SetFingerprint(kind, name, fingerprint(current))
+}
+
+// teamSettings are the team settings applied by CreateOrGetTeam.
+type teamSettings struct {
+	Permission       gsdk.AccessMode     `json:"permission"`
+	CanCreateOrgRepo bool                `json:"can_create_org_repo"`
+	Units            []gsdk.RepoUnitType `json:"units"`
+	// IncludesAllRepositories is left out when false, keeping the fingerprints
+	// recorded before it was applied.
+	IncludesAllRepositories bool `json:"includes_all_repositories,omitempty"`
+}
+
+func newTeamSettings(team *gsdk.Team) teamSettings {
+	units := slices.Clone(team.Units)
+	slices.Sort(units)
+	return teamSettings{
+		Permission:              team.Permission,
+		CanCreateOrgRepo:        team.CanCreateOrgRepo,
+		Units:                   units,
+		IncludesAllRepositories: team.IncludesAllRepositories,
+	}
+}
+
+// reconcileTeam checks an existing team for manual changes, resets it when the drift
+// policy says so, and records the fingerprint of its settings.
+func (m *Migrator) reconcileTeam(d *Drift, org string, team *gsdk.Team, opts gitea.CreateTeamOption) *gsdk.Team {
+	if d == nil {
+		return team
+	}
+	name := org + "/" + team.Name
+	if d.check(report.KindTeam, name, newTeamSettings(team)) {
+		if err := m.gtClient.EditTeam(team.ID, opts); err != nil {
+			m.logger.Error("failed to overwrite team", "team", name, "error", err)
+			return team
+		}
+		updated, err := m.gtClient.GetTeam(team.ID)
+		if err != nil {
+			m.logger.Error("failed to get team", "team", name, "error", err)
+			return team
+		}
+		m.logger.Info("overwrote manual change of team", "team", name)
+		team = updated
+	}
+	if err := d.record(report.KindTeam, name, newTeamSettings(team)); err != nil {
+		m.logger.Warn("failed to record team fingerprint", "team", name, "error", err)
+	}
+	return team
+}
+
+// repoSettings are the repository settings applied by the migration.
+type repoSettings struct {
+	Description         string `json:"description"`
+	Private             bool   `json:"private"`
+	DefaultBranch       string `json:"default_branch"`
+	AllowMerge          bool   `json:"allow_merge"`
+	AllowRebase         bool   `json:"allow_rebase"`
+	AllowSquash         bool   `json:"allow_squash"`
+	DeleteBranchOnMerge bool   `json:"delete_branch_on_merge"`
+	HasIssues           bool   `json:"has_issues"`
+	HasWiki             bool   `json:"has_wiki"`
+	HasProjects         bool   `json:"has_projects"`
+}
+
+func newRepoSettings(repo *gsdk.Repository) repoSettings {
+	return repoSettings{
+		Description:         repo.Description,
+		Private:             repo.Private,
+		DefaultBranch:       repo.DefaultBranch,
+		AllowMerge:          repo.AllowMerge,
+		AllowRebase:         repo.AllowRebase,
+		AllowSquash:         repo.AllowSquash,
+		DeleteBranchOnMerge: repo.DefaultDeleteBranchAfterMerge,
+		HasIssues:           repo.HasIssues,
+		HasWiki:             repo.HasWiki,
+		HasProjects:         repo.HasProjects,
+	}
+}
+
+// githubRepoSettings returns the settings of a GitHub repository as applied to Gitea.
+// The merge options are only part of a single repository response.
+func githubRepoSettings(repo *github.Repository) repoSettings {
+	return repoSettings{
+		Description:         repo.GetDescription(),
+		Private:             repo.GetPrivate(),
+		DefaultBranch:       repo.GetDefaultBranch(),
+		AllowMerge:          repo.GetAllowMergeCommit(),
+		AllowRebase:         repo.GetAllowRebaseMerge(),
+		AllowSquash:         repo.GetAllowSquashMerge(),
+		DeleteBranchOnMerge: repo.GetDeleteBranchOnMerge(),
+		HasIssues:           repo.GetHasIssues(),
+		HasWiki:             repo.GetHasWiki(),
+		HasProjects:         repo.GetHasProjects(),
+	}
+}
+
+// editOption returns the changes applying the settings to a Gitea repository.
+func (s repoSettings) editOption(repo *gsdk.Repository) gsdk.EditRepoOption {
+	opts := gsdk.EditRepoOption{
+		Description: &s.Description,
+		Private:     &s.Private,
+		HasIssues:   &s.HasIssues,
+		HasWiki:     &s.HasWiki,
+		HasProjects: &s.HasProjects,
+	}
+	// the branch does not exist in an empty repository
+	if s.DefaultBranch != "" && !repo.Empty {
+		opts.DefaultBranch = &s.DefaultBranch
+	}
+	// GitHub requires a merge option, none means the token could not read them
+	if s.AllowMerge || s.AllowRebase || s.AllowSquash {
+		opts.AllowMerge = &s.AllowMerge
+		opts.AllowRebase = &s.AllowRebase
+		opts.AllowSquash = &s.AllowSquash
+		opts.DefaultDeleteBranchAfterMerge = &s.DeleteBranchOnMerge
+	}
+	return opts
+}
+
+// applyRepoSettings applies the settings of a GitHub repository to a Gitea repository.
+func (m *Migrator) applyRepoSettings(owner, name string, source *github.Repository) (*gsdk.Repository, error) {
+	repo, err := m.gtClient.GetRepo(owner, name)
+	if err != nil {
+		return nil, err
+	}
+	return m.gtClient.EditRepo(owner, name, githubRepoSettings(source).editOption(repo))
+}
+
+// reconcileRepo checks an existing repository for manual changes of the migrated
+// settings, applies the settings of the GitHub repository again when the drift policy
+// says so, and records their fingerprint. A repository migrated by this run only has
+// its fingerprint recorded. The override replaces settings of the GitHub repository.
+func (m *Migrator) reconcileRepo(ctx context.Context, d *Drift, owner, name string, source *github.Repository, override RepoOverride, migrated bool) {
+	if d == nil {
+		return
+	}
+	fullName := owner + "/" + name
+	repo, err := m.gtClient.GetRepo(owner, name)
+	if err != nil {
+		m.logger.Warn("failed to get repo settings", "repo", fullName, "error", err)
+		return
+	}
+	if !migrated && d.check(report.KindRepo, fullName, newRepoSettings(repo)) {
+		// listed repositories miss the merge options
+		source, err = m.ghClient.GetRepo(ctx, source.GetOwner().GetLogin(), source.GetName())
+		if err != nil {
+			m.logger.Error("failed to get github repo", "repo", fullName, "error", err)
+			return
+		}
+		repo, err = m.applyRepoSettings(owner, name, override.apply(source))
+		if err != nil {
+			m.logger.Error("failed to overwrite repo settings", "repo", fullName, "error", err)
+			return
+		}
+		m.logger.Info("overwrote manual change of repo settings", "repo", fullName)
+	}
+	if err := d.record(report.KindRepo, fullName, newRepoSettings(repo)); err != nil {
+		m.logger.Warn("failed to record repo fingerprint", "repo", fullName, "error", err)
+	}
+}
@@ -0,0 +1,59 @@
+package migrate
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// noreplyDomain is the domain of the GitHub noreply addresses, id+login@users.noreply.github.com.
+const noreplyDomain = "users.noreply.github.com"
+
+// noreplyID is the numeric account id prefixed to the login in newer noreply addresses.
+var noreplyID = regexp.MustCompile(`^[0-9]+\+`)
+
+// EmailRewrite replaces the From domain of user emails with To.
+type EmailRewrite struct {
+	From string
+	To   string
+}
+
+// ParseEmailRewrites parses comma separated from=to email domain rewrites.
+func ParseEmailRewrites(value string) ([]EmailRewrite, error) {
+	var rewrites []EmailRewrite
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(item, "=")
+		from = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(from), "@"))
+		to = strings.TrimPrefix(strings.TrimSpace(to), "@")
+		if !ok || from == "" || to == "" {
+			return nil, errors.New("invalid email rewrite, expected from=to: " + item)
+		}
+		rewrites = append(rewrites, EmailRewrite{From: from, To: to})
+	}
+	return rewrites, nil
+}
+
+// rewriteEmail applies the first rewrite whose domain matches the email domain or
+// one of its parents, so github.com also matches users.noreply.github.com. The
+// account id of GitHub noreply addresses is dropped, keeping login@to.
+func rewriteEmail(email string, rewrites []EmailRewrite) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return email
+	}
+	domain = strings.ToLower(domain)
+	for _, rewrite := range rewrites {
+		if domain != rewrite.From && !strings.HasSuffix(domain, "."+rewrite.From) {
+			continue
+		}
+		if domain == noreplyDomain {
+			local = noreplyID.ReplaceAllString(local, "")
+		}
+		return local + "@" + rewrite.To
+	}
+	return email
+}
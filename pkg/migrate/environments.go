@@ -0,0 +1,103 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// EnvironmentPrefixes maps the lowercase names of GitHub deployment environments to the
+// prefix of their Actions secrets and variables in Gitea, which has no environments,
+// e.g. production to PROD_.
+type EnvironmentPrefixes map[string]string
+
+// secretPrefixPattern matches the prefixes starting a valid Gitea secret name.
+var secretPrefixPattern = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// ParseEnvironmentPrefixes parses a comma separated list of environment=PREFIX mappings.
+func ParseEnvironmentPrefixes(s string) (EnvironmentPrefixes, error) {
+	prefixes := make(EnvironmentPrefixes)
+	for pair := range strings.SplitSeq(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		env, prefix, ok := strings.Cut(pair, "=")
+		env, prefix = strings.ToLower(strings.TrimSpace(env)), strings.ToUpper(strings.TrimSpace(prefix))
+		if !ok || env == "" || prefix == "" {
+			return nil, fmt.Errorf("invalid environment prefix %q, expected environment=PREFIX", pair)
+		}
+		if !secretPrefixPattern.MatchString(prefix) || strings.HasPrefix(prefix, "GITEA_") || strings.HasPrefix(prefix, "GITHUB_") {
+			return nil, fmt.Errorf("invalid environment prefix %q, expected letters, digits and underscores, not GITEA_ or GITHUB_", prefix)
+		}
+		prefixes[env] = prefix
+	}
+	return prefixes, nil
+}
+
+// environment holds the Actions secret names and variables of a GitHub deployment
+// environment with a prefix.
+type environment struct {
+	name      string
+	prefix    string
+	secrets   []string
+	variables []*github.ActionsVariable
+}
+
+// has reports whether the environment holds the secret, with context secrets, or the
+// variable, with context vars, of the given name.
+func (e environment) has(context, name string) bool {
+	if context == "secrets" {
+		for _, secret := range e.secrets {
+			if strings.EqualFold(secret, name) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, variable := range e.variables {
+		if strings.EqualFold(variable.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// repoEnvironments lists the deployment environments of a GitHub repository with a
+// prefix, and their secrets and variables. The environments without prefix are
+// logged, their secrets and variables are not migrated.
+func (m *Migrator) repoEnvironments(ctx context.Context, owner, repo string, id int64, prefixes EnvironmentPrefixes) ([]environment, error) {
+	if len(prefixes) == 0 {
+		return nil, nil
+	}
+	envs, err := m.ghClient.ListRepoEnvironments(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var result []environment
+	for _, env := range envs {
+		prefix, ok := prefixes[strings.ToLower(env.GetName())]
+		if !ok {
+			m.logger.Warn("github environment without prefix, its secrets and variables are not migrated",
+				"repo", owner+"/"+repo, "environment", env.GetName())
+			continue
+		}
+		secrets, err := m.ghClient.ListEnvSecrets(ctx, id, env.GetName())
+		if err != nil {
+			return nil, err
+		}
+		variables, err := m.ghClient.ListEnvVariables(ctx, owner, repo, env.GetName())
+		if err != nil {
+			return nil, err
+		}
+		e := environment{name: env.GetName(), prefix: prefix, variables: variables}
+		for _, secret := range secrets {
+			e.secrets = append(e.secrets, secret.Name)
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package migrate
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+)
+
+// ExportUsersOption selects the organization whose members are exported.
+type ExportUsersOption struct {
+	SourceOrg string
+	// Path is the user list CSV file to create, it must not exist.
+	Path string
+	// Report records every member when set.
+	Report *report.Report
+}
+
+// ExportUsers writes the members of a GitHub organization to a new user list CSV
+// file, in the format of --user-list, with the role admin for the owners and user
+// for the other members. The email is the first one in a domain verified by the
+// organization, or the public profile email. Members without known email are
+// written with an empty email, to be completed by hand.
+func (m *Migrator) ExportUsers(ctx context.Context, opts ExportUsersOption) error {
+	ctx, span := trace.Start(ctx, "migrate.ExportUsers",
+		trace.String("github.org", opts.SourceOrg),
+	)
+	defer span.End()
+
+	ghUsers, err := m.ghClient.ListOrgUsers(ctx, opts.SourceOrg)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	sortUsers(ghUsers)
+	admins, err := m.ghClient.ListOrgAdmins(ctx, opts.SourceOrg)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	owners := make(map[string]bool, len(admins))
+	for _, admin := range admins {
+		owners[strings.ToLower(admin.GetLogin())] = true
+	}
+	verified, err := m.ghClient.ListOrgMemberEmails(ctx, opts.SourceOrg)
+	if err != nil {
+		m.logger.Warn("verified domain emails not available, use public emails", "org", opts.SourceOrg, "error", err)
+	}
+
+	// never overwrite a user list completed by hand
+	f, err := os.OpenFile(opts.Path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"created_at", "id", "login", "email", "role"}); err != nil {
+		f.Close()
+		return err
+	}
+
+	for _, ghUser := range ghUsers {
+		login := ghUser.GetLogin()
+		role := "user"
+		if owners[strings.ToLower(login)] {
+			role = "admin"
+		}
+		// the member list has no emails, the profile has the public one
+		var email string
+		if emails := verified[strings.ToLower(login)]; len(emails) > 0 {
+			email = emails[0]
+		} else if profile, err := m.ghClient.GetUser(ctx, login); err != nil {
+			m.logger.Warn("failed to get github user", "login", login, "error", err)
+		} else {
+			email = profile.GetEmail()
+		}
+
+		item := report.Item{Kind: report.KindUser, Name: login, Status: report.StatusSuccess}
+		if email == "" {
+			item.Status = report.StatusSkipped
+			item.Error = "no known email"
+			m.logger.Warn("no known email for github user, complete the user list", "login", login)
+		}
+		if err := w.Write([]string{"", strconv.FormatInt(ghUser.GetID(), 10), login, email, role}); err != nil {
+			f.Close()
+			return err
+		}
+		opts.Report.Add(item)
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	m.logger.Info("user list exported", "org", opts.SourceOrg, "file", opts.Path, "users", len(ghUsers))
+	return nil
+}
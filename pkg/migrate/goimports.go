@@ -0,0 +1,223 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// GoImportsBranch is the branch the rewritten Go import paths are committed to.
+const GoImportsBranch = "github2gitea/go-imports"
+
+// GoImportsOption selects the migrated repository whose Go import paths are rewritten.
+type GoImportsOption struct {
+	// Paths maps the GitHub paths of the repositories migrated by the run, e.g.
+	// github.com/acme/api, to their Gitea path, e.g. gitea.example.com/acme/api.
+	// Imports of other repositories are left alone.
+	Paths map[string]string
+	// Owner and Name identify the migrated Gitea repository.
+	Owner string
+	Name  string
+	// Report records the repository when set.
+	Report *report.Report
+}
+
+// RewriteGoImports rewrites the module paths of the go.mod files and the import paths
+// of the Go files of a migrated repository with Paths, and opens a pull request
+// from GoImportsBranch with the changes in a single commit. Vendored and testdata
+// files are left alone. Nothing is committed when no path changes, or when
+// GoImportsBranch exists from an earlier run.
+func (m *Migrator) RewriteGoImports(ctx context.Context, opts GoImportsOption) error {
+	_, span := trace.Start(ctx, "migrate.RewriteGoImports",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+
+	fullName := opts.Owner + "/" + opts.Name
+	repo, err := m.gtClient.GetRepo(opts.Owner, opts.Name)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if repo.Empty {
+		return nil
+	}
+	entries, err := m.gtClient.ListTree(opts.Owner, opts.Name, repo.DefaultBranch)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	start := time.Now()
+	var changes []gitea.FileChange
+	isModule := false
+	for _, entry := range entries {
+		name := path.Base(entry.Path)
+		if entry.Type != "blob" || (name != "go.mod" && path.Ext(name) != ".go") || skipGoPath(entry.Path) {
+			continue
+		}
+		isModule = isModule || name == "go.mod"
+		data, err := m.gtClient.GetFile(opts.Owner, opts.Name, entry.Path)
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+		var content string
+		var changed bool
+		if name == "go.mod" {
+			content, changed = rewriteGoMod(string(data), opts.Paths)
+		} else {
+			content, changed = rewriteGoFile(entry.Path, data, opts.Paths)
+		}
+		if changed {
+			changes = append(changes, gitea.FileChange{Path: entry.Path, Content: []byte(content), SHA: entry.SHA})
+		}
+	}
+	if !isModule {
+		return nil
+	}
+	if len(changes) == 0 {
+		opts.Report.Add(report.Item{Kind: report.KindImports, Name: fullName, Status: report.StatusSkipped})
+		return nil
+	}
+
+	err = m.gtClient.CreateBranch(opts.Owner, opts.Name, GoImportsBranch, repo.DefaultBranch)
+	var giteaErr *gitea.GiteaError
+	if errors.As(err, &giteaErr) && giteaErr.Code == http.StatusConflict {
+		m.logger.Info("go imports branch already exists, skip rewrite", "repo", fullName, "branch", GoImportsBranch)
+		return nil
+	}
+	if err == nil {
+		err = m.gtClient.ChangeFiles(gitea.ChangeFilesOption{
+			Owner:   opts.Owner,
+			Repo:    opts.Name,
+			Files:   changes,
+			Branch:  GoImportsBranch,
+			Message: "Rewrite Go import paths of the repositories migrated to Gitea",
+		})
+	}
+	if err == nil {
+		var body strings.Builder
+		body.WriteString("Rewrites the Go module and import paths of the repositories migrated to Gitea.\n\n")
+		for _, change := range changes {
+			fmt.Fprintf(&body, "- `%s`\n", change.Path)
+		}
+		body.WriteString("\n- [ ] run `go mod tidy` to update `go.sum` for the renamed dependencies\n")
+		var pr *gsdk.PullRequest
+		pr, err = m.gtClient.CreatePullRequest(gitea.CreatePullRequestOption{
+			Owner: opts.Owner,
+			Repo:  opts.Name,
+			Head:  GoImportsBranch,
+			Base:  repo.DefaultBranch,
+			Title: "Rewrite Go import paths for Gitea",
+			Body:  body.String(),
+		})
+		if err == nil {
+			m.logger.Info("open go imports pull request", "repo", fullName, "files", len(changes), "url", pr.HTMLURL)
+		}
+	}
+	record(opts.Report, report.KindImports, fullName, start, err)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// skipGoPath reports whether a file belongs to vendored code or test data, whose
+// import paths are not the repository's own.
+func skipGoPath(file string) bool {
+	for _, dir := range strings.Split(path.Dir(file), "/") {
+		if dir == "vendor" || dir == "testdata" {
+			return true
+		}
+	}
+	return false
+}
+
+// goModField matches the words of a go.mod line.
+var goModField = regexp.MustCompile(`[^\s]+`)
+
+// rewriteGoMod rewrites the module paths of the module, require, replace, exclude
+// and retract lines of a go.mod file, keeping its formatting and comments.
+func rewriteGoMod(content string, paths map[string]string) (string, bool) {
+	changed := false
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		code, comment, hasComment := strings.Cut(line, "//")
+		code = goModField.ReplaceAllStringFunc(code, func(field string) string {
+			modPath := strings.Trim(field, `"`)
+			newPath, ok := rewriteImportPath(modPath, paths)
+			if !ok {
+				return field
+			}
+			changed = true
+			return strings.Replace(field, modPath, newPath, 1)
+		})
+		if hasComment {
+			code += "//" + comment
+		}
+		lines[i] = code
+	}
+	return strings.Join(lines, "\n"), changed
+}
+
+// rewriteGoFile rewrites the import paths of a Go file, leaving the rest of the file
+// untouched. Files which do not parse are left alone.
+func rewriteGoFile(name string, data []byte, paths map[string]string) (string, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, data, parser.ImportsOnly)
+	if err != nil {
+		return string(data), false
+	}
+	content := string(data)
+	changed := false
+	// from the last import, so the earlier offsets stay valid
+	for i := len(file.Imports) - 1; i >= 0; i-- {
+		lit := file.Imports[i].Path
+		importPath, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			continue
+		}
+		newPath, ok := rewriteImportPath(importPath, paths)
+		if !ok {
+			continue
+		}
+		start := fset.Position(lit.Pos()).Offset
+		end := fset.Position(lit.End()).Offset
+		content = content[:start] + strconv.Quote(newPath) + content[end:]
+		changed = true
+	}
+	return content, changed
+}
+
+// rewriteImportPath rewrites a module or import path inside one of the repositories of
+// paths, keyed by lowercase GitHub path.
+func rewriteImportPath(importPath string, paths map[string]string) (string, bool) {
+	// a repository path has three elements, host/owner/name
+	parts := strings.SplitN(importPath, "/", 4)
+	if len(parts) < 3 {
+		return importPath, false
+	}
+	from := strings.Join(parts[:3], "/")
+	to, ok := paths[strings.ToLower(from)]
+	if !ok {
+		return importPath, false
+	}
+	return rewriteModulePath(importPath, from, to)
+}
@@ -0,0 +1,24 @@
+package migrate
+
+import "testing"
+
+func TestRewriteImportPath(t *testing.T) {
+	paths := map[string]string{"github.com/acme/api": "gitea.example.com/acme/api"}
+	tests := []struct {
+		in      string
+		want    string
+		changed bool
+	}{
+		{"github.com/acme/api", "gitea.example.com/acme/api", true},
+		{"github.com/Acme/API/v2/client", "gitea.example.com/acme/api/v2/client", true},
+		{"github.com/acme/apiserver", "github.com/acme/apiserver", false},
+		{"github.com/acme/web", "github.com/acme/web", false},
+		{"fmt", "fmt", false},
+	}
+	for _, tt := range tests {
+		got, changed := rewriteImportPath(tt.in, paths)
+		if got != tt.want || changed != tt.changed {
+			t.Errorf("rewriteImportPath(%q) = %q, %v, want %q, %v", tt.in, got, changed, tt.want, tt.changed)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+)
+
+// GoModulesOption selects the migrated repository whose Go module path is recorded.
+type GoModulesOption struct {
+	// SourceURL is the GitHub web URL of the repository, e.g. https://github.com/acme/api.
+	SourceURL string
+	// RenamedFromURL is the GitHub web URL of the repository before it was renamed or
+	// moved, optional. Dependents may still import the old module path, which is listed too.
+	RenamedFromURL string
+	// Owner and Name identify the migrated Gitea repository.
+	Owner string
+	Name  string
+	// Host is the Gitea host, with its path prefix, the module paths move to.
+	Host string
+	// Report receives the module path change.
+	Report *report.Report
+}
+
+// RecordGoModule reads the go.mod file at the root of a migrated repository and
+// records the module path it gets in Gitea. Repositories without go.mod and modules
+// outside the GitHub repository path, such as vanity import paths, are ignored.
+func (m *Migrator) RecordGoModule(ctx context.Context, opts GoModulesOption) error {
+	_, span := trace.Start(ctx, "migrate.RecordGoModule",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+
+	data, err := m.gtClient.GetFile(opts.Owner, opts.Name, "go.mod")
+	if notFound(err) {
+		return nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	path := modulePath(data)
+	source, err := url.Parse(opts.SourceURL)
+	if path == "" || err != nil {
+		return nil
+	}
+	target := opts.Host + "/" + opts.Owner + "/" + opts.Name
+	var renamedFrom string
+	if old, err := url.Parse(opts.RenamedFromURL); err == nil && opts.RenamedFromURL != "" {
+		renamedFrom = old.Host + old.Path
+	}
+	newPath, ok := rewriteModulePath(path, source.Host+source.Path, target)
+	if !ok && renamedFrom != "" {
+		// go.mod not updated since the rename
+		newPath, ok = rewriteModulePath(path, renamedFrom, target)
+	}
+	if !ok {
+		m.logger.Info("go module path outside the github repository, not listed", "repo", opts.Owner+"/"+opts.Name, "module", path)
+		return nil
+	}
+	modules := []report.GoModule{{
+		Repo: opts.Owner + "/" + opts.Name,
+		Old:  path,
+		New:  newPath,
+	}}
+	if renamedFrom != "" {
+		// GitHub redirects the old module path, which keeps working until it moves
+		if oldPath, ok := rewriteModulePath(newPath, target, renamedFrom); ok && !strings.EqualFold(oldPath, path) {
+			modules = append(modules, report.GoModule{Repo: modules[0].Repo, Old: oldPath, New: newPath})
+		}
+	}
+	opts.Report.AddGoModules(modules...)
+	return nil
+}
+
+// modulePath returns the module path declared by a go.mod file, empty when there is none.
+func modulePath(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = strings.TrimSpace(line[:comment])
+		}
+		rest, ok := strings.CutPrefix(line, "module")
+		if !ok || rest == "" || (rest[0] != ' ' && rest[0] != '\t' && rest[0] != '"') {
+			continue
+		}
+		rest = strings.TrimSpace(rest)
+		if unquoted, err := strconv.Unquote(rest); err == nil {
+			return unquoted
+		}
+		return rest
+	}
+	return ""
+}
+
+// rewriteModulePath replaces the from repository prefix of a module or import path
+// with to, keeping subdirectories and major version suffixes. GitHub paths are case
+// insensitive. It reports false when path is not under from.
+func rewriteModulePath(path, from, to string) (string, bool) {
+	from = strings.TrimSuffix(from, "/")
+	if len(path) < len(from) || !strings.EqualFold(path[:len(from)], from) {
+		return path, false
+	}
+	rest := path[len(from):]
+	if rest != "" && rest[0] != '/' {
+		return path, false
+	}
+	return to + rest, true
+}
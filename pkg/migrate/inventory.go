@@ -0,0 +1,64 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// InventoryOption selects the GitHub repositories listed in the inventory.
+type InventoryOption struct {
+	// SourceOrg or SourceUser is the GitHub owner of the repositories.
+	SourceOrg  string
+	SourceUser string
+	// Report receives the inventory.
+	Report *report.Report
+}
+
+// ExportInventory lists the GitHub repositories with their size, language byte
+// breakdown and detected license, to plan the migration waves by technology and
+// review the licenses before migrating anything. A repository whose languages
+// cannot be read is listed without them.
+func (m *Migrator) ExportInventory(ctx context.Context, opts InventoryOption) error {
+	ctx, span := trace.Start(ctx, "migrate.ExportInventory",
+		trace.String("github.source", opts.SourceOrg+opts.SourceUser),
+	)
+	defer span.End()
+
+	var ghRepos []*github.Repository
+	var err error
+	if opts.SourceOrg != "" {
+		ghRepos, err = m.ghClient.ListOrgRepos(ctx, opts.SourceOrg)
+	} else {
+		ghRepos, err = m.ghClient.ListAccessibleUserRepos(ctx, opts.SourceUser)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	sortRepos(ghRepos)
+
+	for _, ghRepo := range ghRepos {
+		languages, err := m.ghClient.ListRepoLanguages(ctx, ghRepo.GetOwner().GetLogin(), ghRepo.GetName())
+		if err != nil {
+			m.logger.Warn("failed to list github repo languages", "repo", ghRepo.GetFullName(), "error", err)
+		}
+		license := repoLicense(ghRepo)
+		opts.Report.AddLicenses(license)
+		opts.Report.AddInventory(report.InventoryRepo{
+			Name:          ghRepo.GetFullName(),
+			Visibility:    ghRepo.GetVisibility(),
+			Archived:      ghRepo.GetArchived(),
+			Fork:          ghRepo.GetFork(),
+			SizeKB:        ghRepo.GetSize(),
+			DefaultBranch: ghRepo.GetDefaultBranch(),
+			Language:      ghRepo.GetLanguage(),
+			Languages:     languages,
+			License:       license.SPDX,
+		})
+	}
+	return nil
+}
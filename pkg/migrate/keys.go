@@ -0,0 +1,54 @@
+package migrate
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// KeyPolicy limits the SSH keys migrated per user. The zero value migrates every key.
+type KeyPolicy struct {
+	// Max is the number of keys kept per user, the most recent ones, unlimited when zero.
+	Max int
+}
+
+// deprecatedKeyTypes are refused by current OpenSSH versions.
+var deprecatedKeyTypes = map[string]bool{
+	"ssh-dss": true,
+}
+
+// selectKeys applies the policy to the SSH keys of a user. It returns the keys to
+// migrate and why each of the other ones is skipped. Duplicate keys, which only
+// differ by their comment, and keys of deprecated types are always skipped.
+func selectKeys(keys []*github.Key, policy KeyPolicy) ([]*github.Key, map[*github.Key]string) {
+	skipped := make(map[*github.Key]string)
+	seen := make(map[string]bool)
+	var keep []*github.Key
+	for _, key := range keys {
+		fields := strings.Fields(key.GetKey())
+		switch {
+		case len(fields) < 2:
+			skipped[key] = "invalid key"
+		case deprecatedKeyTypes[fields[0]]:
+			skipped[key] = "deprecated key type " + fields[0]
+		case seen[fields[0]+" "+fields[1]]:
+			skipped[key] = "duplicate key"
+		default:
+			seen[fields[0]+" "+fields[1]] = true
+			keep = append(keep, key)
+		}
+	}
+
+	if policy.Max > 0 && len(keep) > policy.Max {
+		// most recent first, the public key API only returns the IDs, which grow
+		sort.SliceStable(keep, func(i, j int) bool {
+			return keep[i].GetID() > keep[j].GetID()
+		})
+		for _, key := range keep[policy.Max:] {
+			skipped[key] = "over the per-user key limit"
+		}
+		keep = keep[:policy.Max]
+	}
+	return keep, skipped
+}
@@ -0,0 +1,40 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v71/github"
+)
+
+func TestSelectKeys(t *testing.T) {
+	key := func(id int64, value string) *github.Key {
+		return &github.Key{ID: github.Ptr(id), Key: github.Ptr(value)}
+	}
+	keys := []*github.Key{
+		key(1, "ssh-ed25519 AAAAone laptop"),
+		key(2, "ssh-ed25519 AAAAone desktop"),
+		key(3, "ssh-dss AAAAtwo"),
+		key(4, "invalid"),
+		key(5, "ssh-rsa AAAAthree"),
+		key(6, "ssh-ed25519 AAAAfour"),
+	}
+
+	keep, skipped := selectKeys(keys, KeyPolicy{})
+	if len(keep) != 3 {
+		t.Errorf("kept %d keys, want 3", len(keep))
+	}
+	for id, reason := range map[int]string{1: "duplicate key", 2: "deprecated key type ssh-dss", 3: "invalid key"} {
+		if got := skipped[keys[id]]; got != reason {
+			t.Errorf("key %d skipped as %q, want %q", keys[id].GetID(), got, reason)
+		}
+	}
+
+	// the most recent keys are kept
+	keep, skipped = selectKeys(keys, KeyPolicy{Max: 2})
+	if len(keep) != 2 || keep[0].GetID() != 6 || keep[1].GetID() != 5 {
+		t.Errorf("kept %v, want keys 6 and 5", keep)
+	}
+	if got := skipped[keys[0]]; got != "over the per-user key limit" {
+		t.Errorf("key 1 skipped as %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+package migrate
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// LabelMode decides what happens to migrated labels differing from GitHub.
+type LabelMode string
+
+const (
+	// LabelCheck reports the labels whose color or description differs, and the missing ones.
+	LabelCheck LabelMode = "check"
+	// LabelFix applies the GitHub color and description again, and creates the missing labels.
+	LabelFix LabelMode = "fix"
+)
+
+// ValidLabelMode reports whether mode is a known LabelMode.
+func ValidLabelMode(mode string) bool {
+	switch LabelMode(mode) {
+	case LabelCheck, LabelFix:
+		return true
+	}
+	return false
+}
+
+// LabelsOption selects the repository whose labels are compared with GitHub.
+type LabelsOption struct {
+	// SourceOwner and SourceName identify the GitHub repository.
+	SourceOwner string
+	SourceName  string
+	// Owner and Name identify the migrated Gitea repository.
+	Owner string
+	Name  string
+	Mode  LabelMode
+	// Report records every differing label when set.
+	Report *report.Report
+}
+
+// CheckRepoLabels compares the issue labels of a migrated repository with GitHub, as
+// the Gitea importer dropped label descriptions in some versions. Matching labels are
+// not reported.
+func (m *Migrator) CheckRepoLabels(ctx context.Context, opts LabelsOption) error {
+	ctx, span := trace.Start(ctx, "migrate.CheckRepoLabels",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+
+	sources, err := m.ghClient.ListRepoLabels(ctx, opts.SourceOwner, opts.SourceName)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	labels, err := m.gtClient.ListRepoLabels(opts.Owner, opts.Name)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	// GitHub label names are case insensitive
+	existing := make(map[string]*gsdk.Label, len(labels))
+	for _, label := range labels {
+		existing[strings.ToLower(label.Name)] = label
+	}
+
+	target := opts.Owner + "/" + opts.Name
+	for _, source := range sources {
+		color := labelColor(source.GetColor())
+		description := source.GetDescription()
+		item := report.Item{Kind: report.KindLabel, Name: target + " " + source.GetName(), Status: report.StatusFailed}
+
+		label, ok := existing[strings.ToLower(source.GetName())]
+		if !ok {
+			item.Error = "missing"
+			if opts.Mode == LabelFix {
+				start := time.Now()
+				err := m.gtClient.CreateLabel(opts.Owner, opts.Name, gsdk.CreateLabelOption{
+					Name:        source.GetName(),
+					Color:       "#" + color,
+					Description: description,
+				})
+				item = fixedLabel(item, start, err)
+			}
+			opts.Report.Add(item)
+			continue
+		}
+
+		var diffs []string
+		edit := gsdk.EditLabelOption{}
+		if labelColor(label.Color) != color {
+			diffs = append(diffs, "color "+labelColor(label.Color)+" instead of "+color)
+			value := "#" + color
+			edit.Color = &value
+		}
+		if strings.TrimSpace(label.Description) != strings.TrimSpace(description) {
+			if label.Description == "" {
+				diffs = append(diffs, "description missing")
+			} else {
+				diffs = append(diffs, "description differs")
+			}
+			edit.Description = &description
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+		item.Error = strings.Join(diffs, "; ")
+		if opts.Mode == LabelFix {
+			start := time.Now()
+			item = fixedLabel(item, start, m.gtClient.EditLabel(opts.Owner, opts.Name, label.ID, edit))
+		}
+		opts.Report.Add(item)
+	}
+	return nil
+}
+
+// fixedLabel marks a differing label item as fixed, keeping the difference as a note.
+func fixedLabel(item report.Item, start time.Time, err error) report.Item {
+	item.Duration = report.Since(start)
+	if err != nil {
+		item.Error += ": " + err.Error()
+		return item
+	}
+	item.Status = report.StatusSuccess
+	item.Error = "fixed " + item.Error
+	return item
+}
+
+// labelColor normalizes a label color to lowercase hex without #.
+func labelColor(color string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(color), "#"))
+}
@@ -0,0 +1,121 @@
+package migrate
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// licenseTopicPrefix starts the Gitea topic tagging a repository with its license.
+const licenseTopicPrefix = "license-"
+
+// maxTopicLength is the longest topic Gitea accepts.
+const maxTopicLength = 35
+
+// licenseFamilies maps the lowercase SPDX identifier prefixes to their category,
+// the longest prefixes first so LGPL and AGPL do not count as GPL.
+var licenseFamilies = []struct {
+	prefix   string
+	category string
+}{
+	{"agpl-", report.LicenseCopyleft},
+	{"lgpl-", report.LicenseWeakCopyleft},
+	{"gpl-", report.LicenseCopyleft},
+	{"eupl-", report.LicenseCopyleft},
+	{"osl-", report.LicenseCopyleft},
+	{"cc-by-sa-", report.LicenseCopyleft},
+	{"mpl-", report.LicenseWeakCopyleft},
+	{"epl-", report.LicenseWeakCopyleft},
+	{"cddl-", report.LicenseWeakCopyleft},
+	{"mit", report.LicensePermissive},
+	{"apache-", report.LicensePermissive},
+	{"bsd-", report.LicensePermissive},
+	{"0bsd", report.LicensePermissive},
+	{"isc", report.LicensePermissive},
+	{"zlib", report.LicensePermissive},
+	{"bsl-", report.LicensePermissive},
+	{"unlicense", report.LicensePermissive},
+	{"cc0-", report.LicensePermissive},
+	{"wtfpl", report.LicensePermissive},
+	{"postgresql", report.LicensePermissive},
+	{"ncsa", report.LicensePermissive},
+	{"artistic-", report.LicensePermissive},
+}
+
+// repoLicense returns the license GitHub detected in a repository, as listed.
+func repoLicense(repo *github.Repository) report.License {
+	license := report.License{
+		Repo:     repo.GetFullName(),
+		Category: report.LicenseNone,
+	}
+	if repo.License == nil {
+		return license
+	}
+	license.SPDX = repo.GetLicense().GetSPDXID()
+	license.Name = repo.GetLicense().GetName()
+	license.Category = licenseCategory(license.SPDX)
+	return license
+}
+
+// licenseCategory classifies an SPDX identifier as permissive, weak copyleft or
+// copyleft. NOASSERTION, GitHub's answer for a license file it cannot identify,
+// and unknown identifiers are other.
+func licenseCategory(spdx string) string {
+	id := strings.ToLower(spdx)
+	for _, family := range licenseFamilies {
+		if strings.HasPrefix(id, family.prefix) {
+			return family.category
+		}
+	}
+	return report.LicenseOther
+}
+
+// licenseTopic returns the Gitea topic tagging a repository with its license, e.g.
+// license-gpl3 for GPL-3.0, or an empty string when there is no identified license.
+func licenseTopic(spdx string) string {
+	if spdx == "" || spdx == "NOASSERTION" {
+		return ""
+	}
+	id := strings.ReplaceAll(strings.ToLower(spdx), ".0", "")
+	var b strings.Builder
+	b.WriteString(licenseTopicPrefix)
+	for _, c := range id {
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' {
+			b.WriteRune(c)
+		}
+	}
+	topic := b.String()
+	if len(topic) > maxTopicLength {
+		topic = topic[:maxTopicLength]
+	}
+	return topic
+}
+
+// tagLicense adds the license topic to a Gitea repository, replacing the license
+// topic of a previous run, and returns the topic.
+func (m *Migrator) tagLicense(owner, name string, license report.License) (string, error) {
+	topic := licenseTopic(license.SPDX)
+	topics, err := m.gtClient.ListRepoTopics(owner, name)
+	if err != nil {
+		return "", err
+	}
+	want := slices.DeleteFunc(slices.Clone(topics), func(t string) bool {
+		return strings.HasPrefix(t, licenseTopicPrefix)
+	})
+	if topic != "" {
+		want = append(want, topic)
+	}
+	slices.Sort(want)
+	slices.Sort(topics)
+	if slices.Equal(want, topics) {
+		return topic, nil
+	}
+	if err := m.gtClient.SetRepoTopics(owner, name, want); err != nil {
+		return "", err
+	}
+	m.logger.Info("tag repo license", "repo", owner+"/"+name, "topic", topic)
+	return topic, nil
+}
@@ -0,0 +1,25 @@
+package migrate
+
+import "testing"
+
+func TestLicenseTopic(t *testing.T) {
+	tests := map[string]string{
+		"":             "",
+		"NOASSERTION":  "",
+		"MIT":          "license-mit",
+		"GPL-3.0":      "license-gpl3",
+		"Apache-2.0":   "license-apache2",
+		"LGPL-2.1":     "license-lgpl2.1",
+		"BSD-3-Clause": "license-bsd3clause",
+	}
+	for spdx, want := range tests {
+		if got := licenseTopic(spdx); got != want {
+			t.Errorf("licenseTopic(%q) = %q, want %q", spdx, got, want)
+		}
+	}
+
+	// topics are limited to 35 characters
+	if got := licenseTopic("LicenseRef-a-very-long-custom-license-name"); len(got) != maxTopicLength {
+		t.Errorf("licenseTopic = %q, want %d characters", got, maxTopicLength)
+	}
+}
@@ -2,24 +2,42 @@ package migrate
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/appleboy/com/convert"
+	"github.com/appleboy/github2gitea/pkg/core"
 	"github.com/appleboy/github2gitea/pkg/gitea"
 	"github.com/appleboy/github2gitea/pkg/github"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/state"
+	"github.com/appleboy/github2gitea/pkg/trace"
 
 	gsdk "code.gitea.io/sdk/gitea"
 )
 
-type migrate struct {
+// Migrator is the migration engine, moving organizations, teams, users and
+// repositories from GitHub to Gitea. Use Run to execute a whole Plan, or the
+// single steps such as CreateNewOrg and MigrateNewRepo.
+type Migrator struct {
 	ghClient *github.Client
 	gtClient *gitea.Client
 	logger   *slog.Logger
+
+	// derived caches the teams created for overrides, keyed by team ID and permission.
+	derivedMu sync.Mutex
+	derived   map[string]*derivedEntry
 }
 
-func New(ghClient *github.Client, gtClient *gitea.Client, logger *slog.Logger) *migrate {
-	return &migrate{
+// New creates a Migrator using the given GitHub and Gitea clients.
+func New(ghClient *github.Client, gtClient *gitea.Client, logger *slog.Logger) *Migrator {
+	return &Migrator{
 		ghClient: ghClient,
 		gtClient: gtClient,
 		logger:   logger,
@@ -30,34 +48,119 @@ func New(ghClient *github.Client, gtClient *gitea.Client, logger *slog.Logger) *
 type CreateNewOrgOption struct {
 	OldName     string
 	NewName     string
+	FullName    string
 	Description string
-	Public      bool
-	Permission  map[string][]string
-	SourceID    int64
+	// Website, Location and Avatar copy the GitHub organization profile when the
+	// organization is created, existing organizations are not changed.
+	Website    string
+	Location   string
+	Avatar     []byte
+	Public     bool
+	Permission map[string][]string
+	SourceID   int64
+	// DefaultRepoPermission is the base permission of the GitHub organization members on
+	// every repository: none, read, write or admin, granted through the members team.
+	// Empty when the token cannot read it, the members team then gets no repository.
+	DefaultRepoPermission string
+	// MembersCanCreateRepos lets the members team create repositories.
+	MembersCanCreateRepos bool
+	// Emails rewrites the email domains of the created users.
+	Emails []EmailRewrite
+	// SkipProfile leaves out the bio, website and location of the created users.
+	SkipProfile bool
+	// Bots are the logins of machine accounts left out, with the accounts of type Bot.
+	Bots BotLogins
+	// Suspended decides how the users suspended on GitHub are created.
+	Suspended SuspendedPolicy
+	// Passwords decides the local password of the created users, recorded in
+	// PasswordsFile when generated.
+	Passwords     PasswordPolicy
+	PasswordsFile *Passwords
+	// Welcome emails the created users when set.
+	Welcome *Mailer
+	// Report records the organization, users and teams when set.
+	Report *report.Report
+	// Drift detects manual changes of existing teams when set.
+	Drift *Drift
+	// TeamUnits sets the per-unit permissions of the teams by their permission.
+	TeamUnits TeamUnits
+	// Team is the slug of a GitHub team limiting the created users to its members
+	// and the organization owners, for staged migrations. All users when empty.
+	Team string
 }
 
 // CreateNewOrgResult create new organization result
 type CreateNewOrgResult struct {
 	Org       *gsdk.Organization
 	Admins    []*gsdk.User
+	Members   []*gsdk.User
 	RepoTeams map[string][]*gsdk.Team
+	// RepoPermissions are the permissions of the teams, by repository and Gitea
+	// team ID, differing from the permission the team was created with.
+	RepoPermissions map[string]map[int64]string
+}
+
+// membersTeamName is the team granting organization membership to non-admin members.
+// Gitea has no direct membership API, users belong to an organization through its teams.
+const membersTeamName = "Members"
+
+var (
+	invalidCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9\-_\.]`)
+	repeatCharsRegex  = regexp.MustCompile(`[\-_\.]{2,}`)
+)
+
+// SanitizeOrgName converts a name into a valid Gitea organization name.
+// Gitea only allows alphanumeric, dash, underscore and dot characters,
+// and the name cannot start or end with, or repeat, a special character.
+func SanitizeOrgName(name string) string {
+	name = invalidCharsRegex.ReplaceAllString(name, "-")
+	name = repeatCharsRegex.ReplaceAllString(name, "-")
+	return strings.Trim(name, "-_.")
 }
 
 // CreateNewOrg create new organization
-var invalidCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9\-_\.]`)
+func (m *Migrator) CreateNewOrg(ctx context.Context, opts CreateNewOrgOption) (*CreateNewOrgResult, error) {
+	ctx, span := trace.Start(ctx, "migrate.CreateNewOrg",
+		trace.String("github.org", opts.OldName),
+		trace.String("gitea.org", opts.NewName),
+	)
+	defer span.End()
+
+	result, err := m.createNewOrg(ctx, opts)
+	span.RecordError(err)
+	return result, err
+}
 
-func (m *migrate) CreateNewOrg(ctx context.Context, opts CreateNewOrgOption) (*CreateNewOrgResult, error) {
+func (m *Migrator) createNewOrg(ctx context.Context, opts CreateNewOrgOption) (*CreateNewOrgResult, error) {
 	visibility := gsdk.VisibleTypePrivate
 	if opts.Public {
 		visibility = gsdk.VisibleTypePublic
 	}
 
-	m.logger.Info("start create organization", "name", opts.NewName)
+	name := SanitizeOrgName(opts.NewName)
+	if name == "" {
+		return nil, errors.New("invalid organization name: " + opts.NewName)
+	}
+	if name != opts.NewName {
+		m.logger.Warn("organization name sanitized", "name", opts.NewName, "sanitized", name)
+	}
+	fullName := opts.FullName
+	if fullName == "" {
+		fullName = opts.NewName
+	}
+
+	m.logger.Info("start create organization", "name", name, "full_name", fullName)
+	start := time.Now()
 	org, err := m.gtClient.CreateAndGetOrg(gitea.CreateOrgOption{
-		Name:        opts.NewName,
+		Name:        name,
+		FullName:    fullName,
 		Description: opts.Description,
+		Website:     opts.Website,
+		Location:    opts.Location,
 		Visibility:  visibility,
+		Avatar:      opts.Avatar,
 	})
+	record(opts.Report, report.KindOrg, name, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -70,15 +173,78 @@ func (m *migrate) CreateNewOrg(ctx context.Context, opts CreateNewOrgOption) (*C
 	}
 	ownerTeam := owners[0]
 
+	memberTeamOption := membersTeamOption(opts)
+	memberTeam, err := m.gtClient.CreateOrGetTeam(org.UserName, memberTeamOption)
+	if err != nil {
+		return nil, err
+	}
+	memberTeam = m.reconcileTeam(opts.Drift, org.UserName, memberTeam, memberTeamOption)
+
 	// get github organization members
 	ghUsers, err := m.ghClient.ListOrgUsers(ctx, opts.OldName)
 	if err != nil {
 		return nil, err
 	}
+	sortUsers(ghUsers)
+
+	// public memberships keyed by lowercase login, nil when unknown
+	var public map[string]bool
+	if ghPublic, err := m.ghClient.ListOrgPublicMembers(ctx, opts.OldName); err != nil {
+		m.logger.Warn("failed to list github org public members, membership visibility not migrated", "org", opts.OldName, "error", err)
+	} else {
+		public = make(map[string]bool, len(ghPublic))
+		for _, ghUser := range ghPublic {
+			public[strings.ToLower(ghUser.GetLogin())] = true
+		}
+	}
+
+	// members of the team limiting the created users, keyed by lowercase login
+	var teamMembers map[string]bool
+	// organization owners, created outside of the team too
+	var orgOwners map[string]bool
+	if opts.Team != "" {
+		ghTeamUsers, err := m.ghClient.ListOrgTeamsMembers(ctx, opts.OldName, opts.Team)
+		if err != nil {
+			return nil, err
+		}
+		teamMembers = make(map[string]bool, len(ghTeamUsers))
+		for _, ghUser := range ghTeamUsers {
+			teamMembers[strings.ToLower(ghUser.GetLogin())] = true
+		}
+		ghAdmins, err := m.ghClient.ListOrgAdmins(ctx, opts.OldName)
+		if err != nil {
+			return nil, err
+		}
+		orgOwners = make(map[string]bool, len(ghAdmins))
+		for _, ghUser := range ghAdmins {
+			orgOwners[strings.ToLower(ghUser.GetLogin())] = true
+		}
+	}
 
 	admins := make([]*gsdk.User, 0)
+	members := make([]*gsdk.User, 0)
+	// created users, keyed by lowercase login
+	created := make(map[string]bool, len(ghUsers))
+	// suspended users left out, keyed by lowercase login
+	suspended := make(map[string]bool)
+	skipped := 0
 	// create gitea organization members
 	for _, ghUser := range ghUsers {
+		if opts.Bots.Match(ghUser) {
+			m.logger.Info("skip github bot account", "login", ghUser.GetLogin())
+			skipBot(opts.Report, ghUser.GetLogin())
+			continue
+		}
+		// outside the team, only the organization owners are needed
+		var role string
+		if teamMembers != nil && !teamMembers[strings.ToLower(ghUser.GetLogin())] {
+			if !orgOwners[strings.ToLower(ghUser.GetLogin())] {
+				skipped++
+				continue
+			}
+			role = "admin"
+		}
+
 		// get github user
 		ghUser, err := m.ghClient.GetUser(ctx, convert.FromPtr(ghUser.Login))
 		if err != nil {
@@ -89,15 +255,33 @@ func (m *migrate) CreateNewOrg(ctx context.Context, opts CreateNewOrgOption) (*C
 			)
 			continue
 		}
+		if opts.Suspended.skip(ghUser) {
+			m.logger.Info("skip github user suspended", "login", ghUser.GetLogin())
+			skipSuspended(opts.Report, ghUser.GetLogin())
+			suspended[strings.ToLower(ghUser.GetLogin())] = true
+			continue
+		}
 
 		// create gitea user
-		gtUser, err := m.gtClient.CreateOrGetUser(gitea.CreateUserOption{
+		password, generated, err := newPassword(opts.Passwords, "")
+		if err != nil {
+			m.logger.Error("failed to generate password", "name", ghUser.GetLogin(), "error", err)
+			continue
+		}
+		start := time.Now()
+		gtUser, isNew, err := m.gtClient.CreateOrGetUser(gitea.CreateUserOption{
 			LoginName: convert.FromPtr(ghUser.Login),
 			Username:  convert.FromPtr(ghUser.Login),
 			FullName:  convert.FromPtr(ghUser.Name),
-			Email:     convert.FromPtr(ghUser.Email),
+			Email:     rewriteEmail(convert.FromPtr(ghUser.Email), opts.Emails),
 			SourceID:  opts.SourceID,
+			Password:  password,
+			Inactive:  opts.Suspended.inactive(ghUser),
 		})
+		if isNew && generated {
+			m.savePassword(opts.PasswordsFile, ghUser.GetLogin(), gtUser.UserName, password)
+		}
+		record(opts.Report, report.KindUser, convert.FromPtr(ghUser.Login), start, err)
 		if err != nil {
 			m.logger.Error(
 				"failed to create gitea user",
@@ -106,6 +290,20 @@ func (m *migrate) CreateNewOrg(ctx context.Context, opts CreateNewOrgOption) (*C
 			)
 			continue
 		}
+		if !opts.SkipProfile {
+			m.migrateUserProfile(ghUser, gtUser)
+		}
+		// SSH keys are only migrated for the users of the user list
+		if isNew && !opts.Suspended.inactive(ghUser) {
+			m.sendWelcome(opts.Welcome, opts.Report, welcome{
+				Login:    ghUser.GetLogin(),
+				Username: gtUser.UserName,
+				Email:    gtUser.Email,
+				Source:   opts.SourceID,
+				Password: password != "",
+				Keys:     -1,
+			})
+		}
 
 		// Role identifies the user's role within the organization or team.
 		// Possible values for organization membership:
@@ -117,14 +315,17 @@ func (m *migrate) CreateNewOrg(ctx context.Context, opts CreateNewOrgOption) (*C
 		//     maintainer - a team maintainer. Able to add/remove other team
 		//                  members, promote other team members to team
 		//                  maintainer, and edit the team’s name and description
-		role, err := m.ghClient.GetUserPermissionFromOrg(ctx, opts.OldName, gtUser.LoginName)
-		if err != nil {
-			m.logger.Error(
-				"failed to get github user permission",
-				"name", convert.FromPtr(ghUser.Login),
-				"error", err,
-			)
-			continue
+		created[strings.ToLower(ghUser.GetLogin())] = true
+		if role == "" {
+			role, err = m.ghClient.GetUserPermissionFromOrg(ctx, opts.OldName, convert.FromPtr(ghUser.Login))
+			if err != nil {
+				m.logger.Error(
+					"failed to get github user permission",
+					"name", convert.FromPtr(ghUser.Login),
+					"error", err,
+				)
+				continue
+			}
 		}
 
 		if role == "admin" {
@@ -139,15 +340,38 @@ func (m *migrate) CreateNewOrg(ctx context.Context, opts CreateNewOrgOption) (*C
 				)
 				continue
 			}
+			m.migrateMembershipVisibility(org.UserName, ghUser.GetLogin(), gtUser, public)
+			continue
+		}
+
+		members = append(members, gtUser)
+		if err := m.gtClient.AddTeamMember(memberTeam.ID, gtUser.UserName); err != nil {
+			m.logger.Error(
+				"failed to add gitea team member (member)",
+				"name", memberTeam.Name,
+				"user", gtUser.UserName,
+				"error", err,
+			)
+			continue
 		}
+		m.migrateMembershipVisibility(org.UserName, ghUser.GetLogin(), gtUser, public)
 	}
 
-	repoTeams := make(map[string][]*gsdk.Team)
+	if skipped > 0 {
+		m.logger.Info("skip github org members outside the team", "team", opts.Team, "total", skipped)
+	}
+
+	resp := &CreateNewOrgResult{
+		Org:     org,
+		Admins:  admins,
+		Members: members,
+	}
 	// get github organization teams
 	ghTeams, err := m.ghClient.ListOrgTeams(ctx, opts.OldName)
 	if err != nil {
 		return nil, err
 	}
+	sortTeams(ghTeams)
 	// create gitea organization teams
 	for _, ghTeam := range ghTeams {
 		// get github team repositories
@@ -163,11 +387,15 @@ func (m *migrate) CreateNewOrg(ctx context.Context, opts CreateNewOrgOption) (*C
 
 		// Sanitize the team name
 		sanitizedTeamName := invalidCharsRegex.ReplaceAllString(convert.FromPtr(ghTeam.Name), "_")
-		team, err := m.gtClient.CreateOrGetTeam(opts.NewName, gitea.CreateTeamOption{
+		permission := teamPermission(ghRepos)
+		start := time.Now()
+		teamOption := opts.TeamUnits.apply(gitea.CreateTeamOption{
 			Name:        sanitizedTeamName,
 			Description: convert.FromPtr(ghTeam.Description),
-			Permission:  convert.FromPtr(ghTeam.Permission),
+			Permission:  overridePermissions[permission],
 		})
+		team, err := m.gtClient.CreateOrGetTeam(org.UserName, teamOption)
+		record(opts.Report, report.KindTeam, org.UserName+"/"+sanitizedTeamName, start, err)
 		if err != nil {
 			m.logger.Error(
 				"failed to create gitea team",
@@ -176,13 +404,12 @@ func (m *migrate) CreateNewOrg(ctx context.Context, opts CreateNewOrgOption) (*C
 			)
 			continue
 		}
+		team = m.reconcileTeam(opts.Drift, org.UserName, team, teamOption)
 
-		for _, ghRepo := range ghRepos {
-			repoTeams[convert.FromPtr(ghRepo.Name)] = append(repoTeams[convert.FromPtr(ghRepo.Name)], team)
-		}
+		resp.addTeamRepos(team, permission, ghRepos)
 
 		m.logger.Info("create gitea team",
-			"org", opts.NewName,
+			"org", org.UserName,
 			"name", team.Name,
 			"permission", team.Permission,
 		)
@@ -199,8 +426,16 @@ func (m *migrate) CreateNewOrg(ctx context.Context, opts CreateNewOrgOption) (*C
 		}
 
 		// add gitea team members
+		sortUsers(ghUsers)
 		for _, ghUser := range ghUsers {
-			err := m.gtClient.AddTeamMember(team.ID, convert.FromPtr(ghUser.Login))
+			if opts.Team != "" && !created[strings.ToLower(ghUser.GetLogin())] {
+				continue
+			}
+			// reported with the organization members
+			if opts.Bots.Match(ghUser) || suspended[strings.ToLower(ghUser.GetLogin())] {
+				continue
+			}
+			err := m.gtClient.AddTeamMember(team.ID, m.gtClient.Username(convert.FromPtr(ghUser.Login)))
 			if err != nil {
 				m.logger.Error(
 					"failed to add gitea team member",
@@ -213,13 +448,89 @@ func (m *migrate) CreateNewOrg(ctx context.Context, opts CreateNewOrgOption) (*C
 		}
 	}
 
-	resp := &CreateNewOrgResult{
-		Org:       org,
-		Admins:    admins,
-		RepoTeams: repoTeams,
+	return resp, nil
+}
+
+// membersTeamOption returns the members team of an organization, with the default
+// repository permission of the GitHub organization on all its repositories.
+func membersTeamOption(opts CreateNewOrgOption) gitea.CreateTeamOption {
+	option := gitea.CreateTeamOption{
+		Name:             membersTeamName,
+		Description:      "Members of the " + opts.OldName + " GitHub organization",
+		Permission:       core.GitHubTeamPull,
+		CanCreateOrgRepo: opts.MembersCanCreateRepos,
 	}
+	switch opts.DefaultRepoPermission {
+	case "read":
+		option.IncludesAllRepositories = true
+	case "write":
+		option.Permission = core.GitHubTeamPush
+		option.IncludesAllRepositories = true
+	case "admin":
+		option.Permission = core.GitHubTeamAdmin
+		option.IncludesAllRepositories = true
+	}
+	return opts.TeamUnits.apply(option)
+}
 
-	return resp, nil
+// migrateMembershipVisibility makes the organization membership of a user public or
+// private like on GitHub, unless the public members are unknown.
+func (m *Migrator) migrateMembershipVisibility(org, login string, gtUser *gsdk.User, public map[string]bool) {
+	if public == nil {
+		return
+	}
+	isPublic := public[strings.ToLower(login)]
+	if err := m.gtClient.SetOrgMembershipPublic(org, gtUser.UserName, isPublic); err != nil {
+		m.logger.Warn("failed to set gitea org membership visibility",
+			"org", org,
+			"user", gtUser.UserName,
+			"public", isPublic,
+			"error", err,
+		)
+	}
+}
+
+// record adds the result of a single operation to the report.
+func record(rpt *report.Report, kind, name string, start time.Time, err error) {
+	item := report.Item{
+		Kind:     kind,
+		Name:     name,
+		Status:   report.StatusSuccess,
+		Duration: report.Since(start),
+	}
+	if err != nil {
+		item.Status = report.StatusFailed
+		item.Error = err.Error()
+	}
+	rpt.Add(item)
+}
+
+// EnsureOwnerOption target namespace option
+type EnsureOwnerOption struct {
+	Name        string
+	IsOrg       bool
+	Description string
+	Public      bool
+}
+
+// EnsureOwner make sure the target namespace exists in Gitea.
+// Organizations are created when missing, user namespaces must already exist.
+func (m *Migrator) EnsureOwner(opts EnsureOwnerOption) error {
+	if !opts.IsOrg {
+		_, err := m.gtClient.GetUser(opts.Name)
+		return err
+	}
+
+	visibility := gsdk.VisibleTypePrivate
+	if opts.Public {
+		visibility = gsdk.VisibleTypePublic
+	}
+	_, err := m.gtClient.CreateAndGetOrg(gitea.CreateOrgOption{
+		Name:        opts.Name,
+		Description: opts.Description,
+		Visibility:  visibility,
+	})
+	return err
 }
 
 // MigrateNewRepoOption migrate repository option
@@ -232,24 +543,112 @@ type MigrateNewRepoOption struct {
 	Permission   map[string][]string
 	AuthUsername string
 	AuthToken    string
+	// Timeout limits how long to wait for the Gitea migration task, no limit when zero.
+	Timeout time.Duration
+	// StallTimeout abandons the Gitea migration task without progress for this long, disabled when zero.
+	StallTimeout time.Duration
+	// Sync re-migrates a repository already present in Gitea only when it changed on GitHub.
+	Sync bool
+	// OnDrift decides whether Sync deletes and migrates again a regular repository changed
+	// on GitHub, losing what was created in Gitea since: DriftOverwrite does, DriftAsk asks
+	// ConfirmDrift and the others keep the Gitea repository. DriftPreserve when empty.
+	OnDrift      DriftPolicy
+	ConfirmDrift func(question string) bool
+	// State records the GitHub timestamps of migrated repositories when set.
+	State *state.Store
+	// Source is the full name of the GitHub repository and SourceRepoID its id.
+	Source       string
+	SourceRepoID int64
+	// PushedAt and UpdatedAt are the GitHub timestamps of the repository.
+	PushedAt  time.Time
+	UpdatedAt time.Time
+	// LFS migrates the Git LFS objects, from LFSEndpoint when set.
+	LFS         bool
+	LFSEndpoint string
+	// Mirror creates a Gitea pull mirror synced every MirrorInterval instead of a one-shot migration.
+	Mirror         bool
+	MirrorInterval string
+	// SkipWiki leaves out the wiki.
+	SkipWiki bool
+	// ForkOwner and ForkName create the repository as a Gitea fork of this repository,
+	// pushing the git content of CloneAddr instead of migrating it.
+	ForkOwner string
+	ForkName  string
+	// Windows delays the migration until one of them is open.
+	Windows Windows
 }
 
+// ErrUnchanged is returned in sync mode for repositories without changes since the last run.
+var ErrUnchanged = errors.New("repository unchanged since last migration")
+
+// ErrPreserved is returned in sync mode for a repository changed on GitHub whose Gitea
+// repository the drift policy keeps. It matches ErrUnchanged, the Gitea repository
+// being left as it is.
+var ErrPreserved error = preservedError{}
+
+type preservedError struct{}
+
+func (preservedError) Error() string {
+	return "repository changed on github, gitea repository kept by the drift policy"
+}
+
+func (preservedError) Is(target error) bool { return target == ErrUnchanged }
+
 // MigrateNewRepo migrate repository
-func (m *migrate) MigrateNewRepo(ctx context.Context, opts MigrateNewRepoOption) error {
+func (m *Migrator) MigrateNewRepo(ctx context.Context, opts MigrateNewRepoOption) error {
+	_, span := trace.Start(ctx, "migrate.MigrateNewRepo",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+
+	if opts.Sync {
+		synced, err := m.syncExisting(opts)
+		if err != nil {
+			return err
+		}
+		if synced {
+			m.recordState(opts)
+			return nil
+		}
+	}
+
+	if !opts.Windows.Open(time.Now()) {
+		m.logger.Info("wait for the migration window",
+			"owner", opts.Owner,
+			"name", opts.Name,
+			"opens", opts.Windows.Next(time.Now()).Format(time.DateTime),
+		)
+		if err := opts.Windows.wait(ctx); err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+
 	m.logger.Info("start migrate repo",
 		"owner", opts.Owner,
 		"name", opts.Name,
 	)
 	_, err := m.gtClient.MigrateRepo(gitea.MigrateRepoOption{
-		RepoName:     opts.Name,
-		RepoOwner:    opts.Owner,
-		CloneAddr:    opts.CloneAddr,
-		Private:      opts.Private,
-		Description:  opts.Description,
-		AuthUsername: opts.AuthUsername,
-		AuthToken:    opts.AuthToken,
+		RepoName:       opts.Name,
+		RepoOwner:      opts.Owner,
+		CloneAddr:      opts.CloneAddr,
+		Private:        opts.Private,
+		Description:    opts.Description,
+		AuthUsername:   opts.AuthUsername,
+		AuthToken:      opts.AuthToken,
+		Timeout:        opts.Timeout,
+		StallTimeout:   opts.StallTimeout,
+		LFS:            opts.LFS,
+		LFSEndpoint:    opts.LFSEndpoint,
+		Mirror:         opts.Mirror,
+		MirrorInterval: opts.MirrorInterval,
+		SkipWiki:       opts.SkipWiki,
+		ForkOwner:      opts.ForkOwner,
+		ForkName:       opts.ForkName,
 	})
 	if err != nil {
+		span.RecordError(err)
 		return err
 	}
 
@@ -258,5 +657,72 @@ func (m *migrate) MigrateNewRepo(ctx context.Context, opts MigrateNewRepoOption)
 		"name", opts.Name,
 	)
 
+	m.recordState(opts)
 	return nil
 }
+
+// syncExisting compares an existing Gitea repository with its GitHub source.
+// Unchanged repositories return ErrUnchanged, changed pull mirrors are synced in place
+// and reported as synced. Other changed repositories are deleted to be migrated again
+// when the drift policy allows it, else ErrPreserved is returned.
+// The last migration time comes from the state store, or the Gitea repository update time.
+func (m *Migrator) syncExisting(opts MigrateNewRepoOption) (bool, error) {
+	existing, err := m.gtClient.GetRepo(opts.Owner, opts.Name)
+	if err != nil {
+		var giteaErr *gitea.GiteaError
+		if errors.As(err, &giteaErr) && giteaErr.Code == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	changed := opts.PushedAt.After(existing.Updated)
+	if opts.State != nil {
+		if last, ok := opts.State.Repo(opts.Owner, opts.Name); ok {
+			changed = last.Changed(opts.PushedAt, opts.UpdatedAt)
+		}
+	}
+	if !changed {
+		m.logger.Info("skip unchanged repo", "owner", opts.Owner, "name", opts.Name)
+		return false, ErrUnchanged
+	}
+
+	if existing.Mirror {
+		m.logger.Info("sync mirror repo", "owner", opts.Owner, "name", opts.Name)
+		return true, m.gtClient.MirrorSync(opts.Owner, opts.Name)
+	}
+
+	// deleting loses the issues, pull requests, stars and settings created in Gitea since
+	replace := opts.OnDrift == DriftOverwrite
+	if opts.OnDrift == DriftAsk && opts.ConfirmDrift != nil {
+		replace = opts.ConfirmDrift(fmt.Sprintf("repo %s/%s changed on GitHub since the last run, delete it in Gitea with"+
+			" everything created there since and migrate it again?", opts.Owner, opts.Name))
+	}
+	if !replace {
+		m.logger.Warn("repo changed since last migration, keep the gitea repo", "owner", opts.Owner, "name", opts.Name)
+		return false, ErrPreserved
+	}
+
+	m.logger.Warn("repo changed since last migration, migrate again", "owner", opts.Owner, "name", opts.Name)
+	return false, m.gtClient.DeleteRepository(gitea.DeleteRepoOption{
+		Owner: opts.Owner,
+		Repo:  opts.Name,
+	})
+}
+
+// recordState saves the GitHub timestamps of a migrated repository. A failure is only
+// logged, the repository is migrated all the same.
+func (m *Migrator) recordState(opts MigrateNewRepoOption) {
+	if opts.State == nil {
+		return
+	}
+	if err := opts.State.SetRepo(opts.Owner, opts.Name, state.Repo{
+		Source:     opts.Source,
+		ID:         opts.SourceRepoID,
+		PushedAt:   opts.PushedAt,
+		UpdatedAt:  opts.UpdatedAt,
+		MigratedAt: time.Now(),
+	}); err != nil {
+		m.logger.Warn("failed to record repo state", "owner", opts.Owner, "name", opts.Name, "error", err)
+	}
+}
@@ -0,0 +1,148 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+)
+
+// oauth2Template describes the Gitea OAuth2 application an integration signs in with.
+type oauth2Template struct {
+	// keywords match the slugs of the GitHub Apps of the integration.
+	keywords []string
+	// callback is the redirect path of the integration.
+	callback string
+}
+
+// oauth2Templates are the known integrations, by name.
+var oauth2Templates = map[string]oauth2Template{
+	"argocd":     {keywords: []string{"argo"}, callback: "/api/dex/callback"},
+	"drone":      {keywords: []string{"drone"}, callback: "/login"},
+	"jenkins":    {keywords: []string{"jenkins"}, callback: "/securityRealm/finishLogin"},
+	"woodpecker": {keywords: []string{"woodpecker"}, callback: "/authorize"},
+}
+
+// matchOAuth2Template returns the name of the template matching a GitHub App slug, empty when none does.
+func matchOAuth2Template(slug string) string {
+	names := make([]string, 0, len(oauth2Templates))
+	for name := range oauth2Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	slug = strings.ToLower(slug)
+	for _, name := range names {
+		for _, keyword := range oauth2Templates[name].keywords {
+			if strings.Contains(slug, keyword) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// OAuth2App is a Gitea OAuth2 application created from a template for an integration.
+type OAuth2App struct {
+	// Template is the integration, e.g. jenkins.
+	Template string
+	// URL is the base URL of the integration, e.g. https://jenkins.example.com.
+	URL string
+}
+
+// RedirectURI returns the callback URL of the integration.
+func (a OAuth2App) RedirectURI() string {
+	return strings.TrimSuffix(a.URL, "/") + oauth2Templates[a.Template].callback
+}
+
+// ParseOAuth2Apps parses a comma separated list of template=url integrations.
+func ParseOAuth2Apps(s string) ([]OAuth2App, error) {
+	var apps []OAuth2App
+	for pair := range strings.SplitSeq(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, base, ok := strings.Cut(pair, "=")
+		name, base = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(base)
+		if !ok || name == "" || base == "" {
+			return nil, fmt.Errorf("invalid oauth2 app %q, expected template=url", pair)
+		}
+		if _, ok := oauth2Templates[name]; !ok {
+			templates := make([]string, 0, len(oauth2Templates))
+			for template := range oauth2Templates {
+				templates = append(templates, template)
+			}
+			sort.Strings(templates)
+			return nil, fmt.Errorf("unknown oauth2 app template %q, expected one of %s", name, strings.Join(templates, ", "))
+		}
+		u, err := url.Parse(base)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid oauth2 app url %q", base)
+		}
+		apps = append(apps, OAuth2App{Template: name, URL: base})
+	}
+	return apps, nil
+}
+
+// OAuth2Secrets records the client credentials of the created OAuth2 applications in
+// a name,client_id,client_secret,redirect_uri CSV file only readable by its owner.
+type OAuth2Secrets struct {
+	*secretsFile
+}
+
+// CreateOAuth2Secrets opens the OAuth2 secrets file at path, creating it with its header.
+func CreateOAuth2Secrets(path string) (*OAuth2Secrets, error) {
+	f, err := openSecretsFile(path, "name", "client_id", "client_secret", "redirect_uri")
+	if err != nil {
+		return nil, err
+	}
+	return &OAuth2Secrets{f}, nil
+}
+
+// provisionOAuth2Apps creates the OAuth2 applications of the integrations, owned by
+// the Gitea token user, and records their credentials. Applications existing from an
+// earlier run are skipped, as their secret cannot be read again.
+func (r *run) provisionOAuth2Apps(ctx context.Context) {
+	_, span := trace.Start(ctx, "migrate.provisionOAuth2Apps")
+	defer span.End()
+
+	existing, err := r.gtClient.ListOAuth2Apps()
+	if err != nil {
+		span.RecordError(err)
+		r.logger.Error("failed to list gitea oauth2 apps", "error", err)
+		return
+	}
+	names := make([]string, 0, len(existing))
+	for _, app := range existing {
+		names = append(names, strings.ToLower(app.Name))
+	}
+
+	for _, app := range r.plan.OAuth2Apps {
+		if slices.Contains(names, app.Template) {
+			r.rpt.Add(report.Item{Kind: report.KindOAuth2, Name: app.Template, Status: report.StatusSkipped, Error: "exists, client secret not regenerated"})
+			continue
+		}
+		start := time.Now()
+		created, err := r.gtClient.CreateOAuth2App(app.Template, []string{app.RedirectURI()})
+		if err == nil {
+			if saveErr := r.plan.OAuth2Secrets.write(app.Template, created.ClientID, created.ClientSecret, app.RedirectURI()); saveErr != nil {
+				// the application exists with a secret nobody knows
+				err = fmt.Errorf("client secret not saved: %w", saveErr)
+			}
+		}
+		record(r.rpt, report.KindOAuth2, app.Template, start, err)
+		if err != nil {
+			span.RecordError(err)
+			r.logger.Error("failed to create gitea oauth2 app", "name", app.Template, "error", err)
+			continue
+		}
+		names = append(names, app.Template)
+		r.logger.Info("create gitea oauth2 app", "name", app.Template, "redirect_uri", app.RedirectURI(), "client_id", created.ClientID)
+	}
+}
@@ -0,0 +1,24 @@
+package migrate
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// sortByKey sorts a GitHub listing by a case insensitive key, so two runs against the
+// same source process it in the same order whatever order the API returned.
+func sortByKey[T any](items []T, key func(T) string) {
+	slices.SortStableFunc(items, func(a, b T) int {
+		return strings.Compare(strings.ToLower(key(a)), strings.ToLower(key(b)))
+	})
+}
+
+func sortRepos(repos []*github.Repository) { sortByKey(repos, (*github.Repository).GetFullName) }
+
+func sortUsers(users []*github.User) { sortByKey(users, (*github.User).GetLogin) }
+
+func sortTeams(teams []*github.Team) { sortByKey(teams, (*github.Team).GetSlug) }
+
+func sortGists(gists []*github.Gist) { sortByKey(gists, (*github.Gist).GetID) }
@@ -0,0 +1,264 @@
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// OrgSettings are the options of an organization in an OrgMapping. Unset fields of an
+// organization are inherited from the defaults, and unset defaults keep the options of
+// the command line.
+type OrgSettings struct {
+	// Repos and ExcludeRepos are glob patterns, e.g. service-*, of the GitHub repository
+	// names migrated and left out; all repositories when Repos is empty.
+	Repos        []string `json:"repos,omitempty"`
+	ExcludeRepos []string `json:"exclude_repos,omitempty"`
+	// ByTeam limits the organization to the repositories of a GitHub team, see Plan.
+	ByTeam *string `json:"by_team,omitempty"`
+	// Concurrency is the number of repositories of the organization migrated at once.
+	Concurrency *int  `json:"concurrency,omitempty"`
+	Mirror      *bool `json:"mirror,omitempty"`
+	// OnDrift, SuspendedUsers, PasswordPolicy and BotLogins take the values of the
+	// on-drift, suspended-users, password-policy and bot-logins options.
+	OnDrift        *string `json:"on_drift,omitempty"`
+	SuspendedUsers *string `json:"suspended_users,omitempty"`
+	PasswordPolicy *string `json:"password_policy,omitempty"`
+	BotLogins      *string `json:"bot_logins,omitempty"`
+}
+
+// OrgEntry is a GitHub organization migrated into a Gitea organization, Source when
+// Target is empty.
+type OrgEntry struct {
+	Source string `json:"source"`
+	Target string `json:"target,omitempty"`
+	OrgSettings
+}
+
+// OrgMapping migrates several organizations in one run, in the order of Orgs, each
+// with the Defaults overridden by its own settings.
+type OrgMapping struct {
+	Defaults OrgSettings `json:"defaults"`
+	Orgs     []OrgEntry  `json:"orgs"`
+}
+
+// LoadOrgMapping reads a JSON organization mapping:
+//
+//	{
+//	  "defaults": {"concurrency": 4, "exclude_repos": ["*-archive"]},
+//	  "orgs": [
+//	    {"source": "acme", "target": "acme-git"},
+//	    {"source": "acme-labs", "concurrency": 1, "mirror": true}
+//	  ]
+//	}
+func LoadOrgMapping(file string) (*OrgMapping, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	var m OrgMapping
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("orgs file: %w", err)
+	}
+	if len(m.Orgs) == 0 {
+		return nil, errors.New("orgs file: no organization")
+	}
+	if err := m.Defaults.validate(); err != nil {
+		return nil, fmt.Errorf("orgs file: defaults: %w", err)
+	}
+	targets := make(map[string]string)
+	for i, entry := range m.Orgs {
+		if entry.Source == "" {
+			return nil, fmt.Errorf("orgs file: organization %d: source is required", i+1)
+		}
+		// targets differing only by the characters Gitea refuses are the same organization
+		target := strings.ToLower(SanitizeOrgName(entry.target()))
+		if target == "" {
+			return nil, fmt.Errorf("orgs file: %s: invalid target organization name %q", entry.Source, entry.target())
+		}
+		if source, ok := targets[target]; ok {
+			return nil, fmt.Errorf("orgs file: %s and %s have the same target %s", source, entry.Source, entry.target())
+		}
+		targets[target] = entry.Source
+		if err := entry.validate(); err != nil {
+			return nil, fmt.Errorf("orgs file: %s: %w", entry.Source, err)
+		}
+	}
+	return &m, nil
+}
+
+// target returns the Gitea organization of the entry.
+func (e OrgEntry) target() string {
+	if e.Target != "" {
+		return e.Target
+	}
+	return e.Source
+}
+
+// validate checks the values which are set.
+func (s OrgSettings) validate() error {
+	for _, pattern := range append(append([]string{}, s.Repos...), s.ExcludeRepos...) {
+		if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+			return fmt.Errorf("invalid repository pattern %q", pattern)
+		}
+	}
+	if s.Concurrency != nil && *s.Concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+	if s.OnDrift != nil && !ValidDriftPolicy(*s.OnDrift) {
+		return errors.New("on_drift must be ask, overwrite or preserve")
+	}
+	if s.SuspendedUsers != nil && !ValidSuspendedPolicy(*s.SuspendedUsers) {
+		return errors.New("suspended_users must be active, inactive or skip")
+	}
+	if s.PasswordPolicy != nil && !ValidPasswordPolicy(*s.PasswordPolicy) {
+		return errors.New("password_policy must be none, random or mapping")
+	}
+	if s.BotLogins != nil {
+		if _, err := ParseBotLogins(*s.BotLogins); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inherit returns the settings with the unset fields taken from defaults.
+func (s OrgSettings) inherit(defaults OrgSettings) OrgSettings {
+	if s.Repos == nil {
+		s.Repos = defaults.Repos
+	}
+	if s.ExcludeRepos == nil {
+		s.ExcludeRepos = defaults.ExcludeRepos
+	}
+	if s.ByTeam == nil {
+		s.ByTeam = defaults.ByTeam
+	}
+	if s.Concurrency == nil {
+		s.Concurrency = defaults.Concurrency
+	}
+	if s.Mirror == nil {
+		s.Mirror = defaults.Mirror
+	}
+	if s.OnDrift == nil {
+		s.OnDrift = defaults.OnDrift
+	}
+	if s.SuspendedUsers == nil {
+		s.SuspendedUsers = defaults.SuspendedUsers
+	}
+	if s.PasswordPolicy == nil {
+		s.PasswordPolicy = defaults.PasswordPolicy
+	}
+	if s.BotLogins == nil {
+		s.BotLogins = defaults.BotLogins
+	}
+	return s
+}
+
+// Plans returns a plan per organization of the mapping, derived from base with the
+// settings of the organization. The users of base are created, and the OAuth2
+// applications provisioned, by the first plan only, and the personal data of the
+// users migrated by the last plan.
+func (m *OrgMapping) Plans(base Plan) ([]Plan, error) {
+	plans := make([]Plan, 0, len(m.Orgs))
+	for i, entry := range m.Orgs {
+		s := entry.OrgSettings.inherit(m.Defaults)
+		plan := base
+		plan.SourceOrg = entry.Source
+		plan.TargetOrg = SanitizeOrgName(entry.target())
+		plan.TargetOrgFullName = entry.target()
+		plan.SourceUser = ""
+		plan.TargetUser = ""
+		plan.Repos = s.Repos
+		plan.ExcludeRepos = s.ExcludeRepos
+		if s.ByTeam != nil {
+			plan.ByTeam = *s.ByTeam
+		}
+		if s.Concurrency != nil {
+			plan.Concurrency = *s.Concurrency
+		}
+		if s.Mirror != nil {
+			plan.Mirror = *s.Mirror
+		}
+		if s.OnDrift != nil {
+			plan.OnDrift = DriftPolicy(*s.OnDrift)
+		}
+		if s.SuspendedUsers != nil {
+			plan.Suspended = SuspendedPolicy(*s.SuspendedUsers)
+		}
+		if s.PasswordPolicy != nil {
+			plan.Passwords = PasswordPolicy(*s.PasswordPolicy)
+		}
+		if plan.Passwords == PasswordRandom && plan.PasswordsFile == nil {
+			return nil, fmt.Errorf("%s: the random password policy requires passwords-file", entry.Source)
+		}
+		if s.BotLogins != nil {
+			// validated by LoadOrgMapping
+			plan.BotLogins, _ = ParseBotLogins(*s.BotLogins)
+		}
+		// the users are created with the first organization, their personal data
+		// migrated after the last one, which is when all repositories exist
+		last := len(m.Orgs) - 1
+		switch {
+		case i == 0 && last > 0:
+			plan.UsersDeferred = true
+		case i > 0 && i < last:
+			plan.Users = nil
+		case i > 0:
+			plan.UsersCreated = true
+		}
+		if i > 0 {
+			plan.OAuth2Apps = nil
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// selectRepos keeps the repositories matching the Repos and ExcludeRepos patterns of
+// the plan. A Repos pattern matching no repository, such as a mistyped name, is
+// recorded as failed.
+func (r *run) selectRepos(repos []*github.Repository) []*github.Repository {
+	if len(r.plan.Repos) == 0 && len(r.plan.ExcludeRepos) == 0 {
+		return repos
+	}
+	used := make(map[string]bool, len(r.plan.Repos))
+	match := func(patterns []string, name string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+				used[pattern] = true
+				return true
+			}
+		}
+		return false
+	}
+	selected := make([]*github.Repository, 0, len(repos))
+	for _, repo := range repos {
+		name := strings.ToLower(repo.GetName())
+		if len(r.plan.Repos) > 0 && !match(r.plan.Repos, name) {
+			continue
+		}
+		if match(r.plan.ExcludeRepos, name) {
+			r.logger.Info("skip github repo by filter", "repo", repo.GetFullName())
+			continue
+		}
+		selected = append(selected, repo)
+	}
+	for _, pattern := range r.plan.Repos {
+		if !used[pattern] {
+			r.logger.Error("no github repo matches", "repo", pattern)
+			r.rpt.Add(report.Item{Kind: report.KindRepo, Name: pattern, Status: report.StatusFailed, Error: "no github repository matches"})
+		}
+	}
+	return selected
+}
@@ -0,0 +1,121 @@
+package migrate
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/appleboy/github2gitea/pkg/core"
+	"github.com/appleboy/github2gitea/pkg/gitea"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// PermissionNone removes the team from the repository.
+const PermissionNone = "none"
+
+// overridePermissions maps the permissions of the overrides file to GitHub team permissions.
+var overridePermissions = map[string]string{
+	core.GiteaRepoRead:    core.GitHubTeamPull,
+	core.GitHubTeamTriage: core.GitHubTeamTriage,
+	core.GiteaRepoWrite:   core.GitHubTeamPush,
+	core.GiteaRepoAdmin:   core.GitHubTeamAdmin,
+	PermissionNone:        "",
+}
+
+// PermissionOverrides holds team permissions on single repositories, keyed by
+// lowercase Gitea team name and GitHub repository name.
+type PermissionOverrides map[string]map[string]string
+
+// LoadPermissionOverrides reads a CSV file with a team,repo,permission header,
+// where permission is read, triage, write, admin or none.
+func LoadPermissionOverrides(path string) (PermissionOverrides, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(PermissionOverrides)
+	for index, rec := range records {
+		// Skip the header row
+		if index == 0 {
+			continue
+		}
+		if len(rec) < 3 {
+			return nil, fmt.Errorf("overrides line %d: expected team,repo,permission", index+1)
+		}
+		team := strings.ToLower(strings.TrimSpace(rec[0]))
+		repo := strings.ToLower(strings.TrimSpace(rec[1]))
+		permission := strings.ToLower(strings.TrimSpace(rec[2]))
+		if team == "" || repo == "" {
+			return nil, fmt.Errorf("overrides line %d: team and repo are required", index+1)
+		}
+		if _, ok := overridePermissions[permission]; !ok {
+			return nil, fmt.Errorf("overrides line %d: invalid permission %q", index+1, permission)
+		}
+		if overrides[team] == nil {
+			overrides[team] = make(map[string]string)
+		}
+		overrides[team][repo] = permission
+	}
+	return overrides, nil
+}
+
+// Lookup returns the overridden permission of a team on a repository.
+func (o PermissionOverrides) Lookup(team, repo string) (string, bool) {
+	permission, ok := o[strings.ToLower(team)][strings.ToLower(repo)]
+	return permission, ok
+}
+
+// OverrideTeam returns a team holding the members of team with the given permission,
+// and the per-unit permissions units sets for it.
+// Gitea grants a single permission per team, so the override is applied through
+// a derived team named after the original one and the permission.
+func (m *Migrator) OverrideTeam(org string, team *gsdk.Team, permission string, units TeamUnits) (*gsdk.Team, error) {
+	ghPermission, ok := overridePermissions[permission]
+	if !ok || permission == PermissionNone {
+		return nil, errors.New("invalid override permission: " + permission)
+	}
+
+	derived, err := m.gtClient.CreateOrGetTeam(org, units.apply(gitea.CreateTeamOption{
+		Name:        team.Name + "-" + permission,
+		Description: team.Description,
+		Permission:  ghPermission,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := m.gtClient.ListTeamMembers(team.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if err := m.gtClient.AddTeamMember(derived.ID, member.UserName); err != nil {
+			m.logger.Error("failed to add gitea team member",
+				"name", derived.Name,
+				"user", member.UserName,
+				"error", err,
+			)
+		}
+	}
+
+	m.logger.Info("create gitea override team",
+		"org", org,
+		"name", derived.Name,
+		"team", team.Name,
+		"permission", permission,
+	)
+	return derived, nil
+}
@@ -0,0 +1,111 @@
+package migrate
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// PasswordPolicy decides the local password of the users created in Gitea.
+type PasswordPolicy string
+
+const (
+	// PasswordNone creates users without local password, signing in through the
+	// external authentication source.
+	PasswordNone PasswordPolicy = "none"
+	// PasswordRandom generates a random password to be changed at the first sign-in,
+	// recorded in the passwords file.
+	PasswordRandom PasswordPolicy = "random"
+	// PasswordMapping takes the password of the user list, to be changed at the first
+	// sign-in. Users without password in the list get none.
+	PasswordMapping PasswordPolicy = "mapping"
+)
+
+// ValidPasswordPolicy reports whether policy is a known PasswordPolicy.
+func ValidPasswordPolicy(policy string) bool {
+	switch PasswordPolicy(policy) {
+	case PasswordNone, PasswordRandom, PasswordMapping:
+		return true
+	}
+	return false
+}
+
+// Passwords records the passwords generated for the created users in a
+// login,username,password CSV file only readable by its owner, to hand them out.
+type Passwords struct {
+	*secretsFile
+}
+
+// CreatePasswords opens the passwords file at path, creating it with its header.
+func CreatePasswords(path string) (*Passwords, error) {
+	f, err := openSecretsFile(path, "login", "username", "password")
+	if err != nil {
+		return nil, err
+	}
+	return &Passwords{f}, nil
+}
+
+// savePassword records the generated password of a created user.
+func (m *Migrator) savePassword(passwords *Passwords, login, username, password string) {
+	if passwords == nil {
+		m.logger.Error("no passwords file, reset the password of the created user in gitea", "login", login, "username", username)
+		return
+	}
+	if err := passwords.write(login, username, password); err != nil {
+		m.logger.Error("failed to save generated password, reset it in gitea", "login", login, "username", username, "error", err)
+	}
+}
+
+// passwordClasses are the character classes of a generated password, one of each is
+// included for the Gitea password complexity settings.
+var passwordClasses = []string{
+	"ABCDEFGHJKLMNPQRSTUVWXYZ",
+	"abcdefghijkmnopqrstuvwxyz",
+	"23456789",
+	"!#%+-=?@_",
+}
+
+// passwordLength is the length of a generated password.
+const passwordLength = 20
+
+// newPassword returns the password of a user created under the policy, empty for
+// none, and whether it was generated. mapped is the password of the user list.
+func newPassword(policy PasswordPolicy, mapped string) (string, bool, error) {
+	switch policy {
+	case PasswordRandom:
+		password, err := generatePassword()
+		return password, true, err
+	case PasswordMapping:
+		return mapped, false, nil
+	}
+	return "", false, nil
+}
+
+// generatePassword returns a random password with characters of every class.
+func generatePassword() (string, error) {
+	var all string
+	for _, class := range passwordClasses {
+		all += class
+	}
+	password := make([]byte, passwordLength)
+	for i := range password {
+		// the first characters cover every class, shuffled below
+		class := all
+		if i < len(passwordClasses) {
+			class = passwordClasses[i]
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(class))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = class[n.Int64()]
+	}
+	for i := len(password) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		j := n.Int64()
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
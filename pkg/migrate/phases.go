@@ -0,0 +1,71 @@
+package migrate
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	gsdk "code.gitea.io/sdk/gitea"
+	"github.com/appleboy/com/convert"
+)
+
+// Phase is a part of a run, which the subcommands run, retry and script on their own.
+type Phase string
+
+// Phases of a run, in the order they run.
+const (
+	// PhaseUsers creates the users of the plan with their keys, profile, personal
+	// repositories and personal data.
+	PhaseUsers Phase = "users"
+	// PhaseOrg creates the target organization with its members and teams, or the
+	// target user namespace, with the organization webhooks, roles, apps, Actions
+	// and OAuth2 applications.
+	PhaseOrg Phase = "org"
+	// PhaseRepos migrates the repositories of the source and adds them to the Gitea
+	// teams created by the organization phase.
+	PhaseRepos Phase = "repos"
+)
+
+// Phases selects the phases of a run, all of them when empty.
+type Phases []Phase
+
+// Has reports whether the phase runs.
+func (p Phases) Has(phase Phase) bool {
+	return len(p) == 0 || slices.Contains(p, phase)
+}
+
+// existingOrg looks up the Gitea organization and teams created by an earlier
+// organization phase, and maps the GitHub repositories to their teams without
+// changing anything. A GitHub team missing in Gitea is skipped.
+func (r *run) existingOrg(ctx context.Context, sourceOrg, targetOrg string) (*CreateNewOrgResult, error) {
+	org, err := r.gtClient.GetOrg(targetOrg)
+	if err != nil {
+		return nil, err
+	}
+	ghTeams, err := r.ghClient.ListOrgTeams(ctx, sourceOrg)
+	if err != nil {
+		return nil, err
+	}
+	sortTeams(ghTeams)
+
+	resp := &CreateNewOrgResult{Org: org}
+	for _, ghTeam := range ghTeams {
+		name := invalidCharsRegex.ReplaceAllString(convert.FromPtr(ghTeam.Name), "_")
+		teams, err := r.gtClient.SearchOrgTeams(org.UserName, &gsdk.SearchTeamsOptions{Query: name})
+		if err != nil {
+			return nil, err
+		}
+		i := slices.IndexFunc(teams, func(team *gsdk.Team) bool { return strings.EqualFold(team.Name, name) })
+		if i < 0 {
+			r.logger.Warn("gitea team not found, run the org phase first", "org", org.UserName, "team", name)
+			continue
+		}
+		ghRepos, err := r.ghClient.ListTeamReposBySlug(ctx, sourceOrg, ghTeam.GetSlug())
+		if err != nil {
+			r.logger.Error("failed to get github team repositories", "name", ghTeam.GetName(), "error", err)
+			continue
+		}
+		resp.addTeamRepos(teams[i], teamPermission(ghRepos), ghRepos)
+	}
+	return resp, nil
+}
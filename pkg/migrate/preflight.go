@@ -0,0 +1,277 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// Statuses of a preflight check.
+const (
+	PreflightOK      = "ok"
+	PreflightWarning = "warning"
+	PreflightFailed  = "failed"
+)
+
+// tokenExpiryWarning is how long before its expiration a GitHub token is reported,
+// as a large migration may outlive it.
+const tokenExpiryWarning = 7 * 24 * time.Hour
+
+// PreflightCheck is the result of one check made before a migration.
+type PreflightCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Detail is what was found, Hint how to fix a warning or failure.
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+// PreflightOption describes the migration to check.
+type PreflightOption struct {
+	SourceOrg  string
+	SourceUser string
+	TargetOrg  string
+	TargetUser string
+	// Users are the users of the user list, looked up in Gitea.
+	Users []User
+	// Report receives every check with the check kind, failed for failures.
+	Report *report.Report
+}
+
+// Preflight checks the tokens, the connection to Gitea and the source and target of
+// a migration without changing anything, so the problems a migration would run into
+// are fixed before it starts.
+func (m *Migrator) Preflight(ctx context.Context, opts PreflightOption) []PreflightCheck {
+	var checks []PreflightCheck
+	add := func(check PreflightCheck) {
+		checks = append(checks, check)
+		if opts.Report != nil {
+			item := report.Item{Kind: report.KindCheck, Name: check.Name, Status: report.StatusSuccess}
+			if check.Status == PreflightFailed {
+				item.Status = report.StatusFailed
+				item.Error = strings.TrimSuffix(check.Detail+": "+check.Hint, ": ")
+			}
+			opts.Report.Add(item)
+		}
+	}
+
+	check, login := m.checkGitHubToken(ctx, opts)
+	add(check)
+	if login != "" {
+		add(m.checkSource(ctx, opts, login))
+	}
+
+	gtUser, err := m.gtClient.GetCurrentUser()
+	if err != nil {
+		add(PreflightCheck{
+			Name:   "gitea token",
+			Status: PreflightFailed,
+			Detail: err.Error(),
+			Hint:   "check --gt-server and --gt-token, the token needs the write:admin, write:organization, write:repository and write:user scopes",
+		})
+		return checks
+	}
+	add(PreflightCheck{Name: "gitea token", Status: PreflightOK, Detail: "@" + gtUser.UserName})
+	add(m.checkAdmin(opts, gtUser.IsAdmin))
+	add(m.checkGiteaVersion())
+	if opts.TargetOrg != "" || opts.TargetUser != "" {
+		add(m.checkTarget(opts))
+	}
+	if len(opts.Users) > 0 {
+		add(m.checkUsers(opts.Users))
+	}
+	return checks
+}
+
+// checkGitHubToken checks the GitHub token works, has the scopes a migration needs
+// and does not expire soon, and returns the login of the token user, empty when the
+// token does not work.
+func (m *Migrator) checkGitHubToken(ctx context.Context, opts PreflightOption) (PreflightCheck, string) {
+	check := PreflightCheck{Name: "github token", Status: PreflightOK}
+	info, err := m.ghClient.TokenInfo(ctx)
+	if err != nil {
+		check.Status = PreflightFailed
+		check.Detail = err.Error()
+		check.Hint = "check --gh-token and --gh-server, the token may be revoked or expired"
+		return check, ""
+	}
+	details := []string{"@" + info.Login}
+	var hints []string
+
+	if info.Scopes == nil {
+		details = append(details, "fine-grained token, its permissions are checked as they are used")
+	} else {
+		details = append(details, "scopes "+strings.Join(info.Scopes, ","))
+		var missing []string
+		if !slices.Contains(info.Scopes, "repo") {
+			missing = append(missing, "repo")
+		}
+		if opts.SourceOrg != "" && !slices.Contains(info.Scopes, "admin:org") {
+			missing = append(missing, "admin:org")
+		}
+		if len(missing) > 0 {
+			check.Status = PreflightFailed
+			hints = append(hints, "grant the "+strings.Join(missing, " and ")+" scopes to the token")
+		}
+	}
+
+	if !info.Expiration.IsZero() {
+		details = append(details, "expires "+info.Expiration.Format(time.DateTime))
+		if time.Until(info.Expiration) < tokenExpiryWarning {
+			if check.Status == PreflightOK {
+				check.Status = PreflightWarning
+			}
+			hints = append(hints, "the token expires within a week, regenerate it before a long migration")
+		}
+	}
+	check.Detail = strings.Join(details, ", ")
+	check.Hint = strings.Join(hints, "; ")
+	return check, info.Login
+}
+
+// checkSource checks the source organization or user exists, and that the token
+// user owns the organization, as its teams, webhooks and secrets need an owner.
+func (m *Migrator) checkSource(ctx context.Context, opts PreflightOption, login string) PreflightCheck {
+	if opts.SourceOrg == "" {
+		check := PreflightCheck{Name: "github user " + opts.SourceUser, Status: PreflightOK}
+		user, err := m.ghClient.GetUser(ctx, opts.SourceUser)
+		if err != nil {
+			check.Status = PreflightFailed
+			check.Detail = err.Error()
+			check.Hint = "check --source-user"
+			if githubNotFound(err) {
+				check.Detail = "not found"
+			}
+			return check
+		}
+		check.Detail = fmt.Sprintf("%d public repositories", user.GetPublicRepos())
+		return check
+	}
+
+	check := PreflightCheck{Name: "github org " + opts.SourceOrg, Status: PreflightOK}
+	if _, err := m.ghClient.GetOrg(ctx, opts.SourceOrg); err != nil {
+		check.Status = PreflightFailed
+		check.Detail = err.Error()
+		check.Hint = "check --source-org, the token user must be a member of a private organization"
+		if githubNotFound(err) {
+			check.Detail = "not found"
+		}
+		return check
+	}
+	check.Detail = "@" + login + " is an owner"
+	role, err := m.ghClient.GetUserPermissionFromOrg(ctx, opts.SourceOrg, login)
+	if err != nil || role != "admin" {
+		check.Status = PreflightWarning
+		check.Detail = "@" + login + " is not an owner"
+		check.Hint = "use a token of an organization owner, teams, webhooks and secrets are only readable by owners"
+	}
+	return check
+}
+
+// checkAdmin checks the Gitea token belongs to a site administrator, which creating
+// users and organizations and acting on behalf of users needs.
+func (m *Migrator) checkAdmin(opts PreflightOption, admin bool) PreflightCheck {
+	check := PreflightCheck{Name: "gitea admin", Status: PreflightOK, Detail: "site administrator"}
+	if admin {
+		return check
+	}
+	check.Detail = "not a site administrator"
+	check.Hint = "use a token of a site administrator"
+	check.Status = PreflightWarning
+	if opts.SourceOrg != "" || len(opts.Users) > 0 {
+		// the members and the user list are created as users
+		check.Status = PreflightFailed
+		check.Hint += ", creating users needs one"
+	}
+	return check
+}
+
+// checkGiteaVersion reports the version of Gitea and the features it is too old for,
+// which the migration skips.
+func (m *Migrator) checkGiteaVersion() PreflightCheck {
+	check := PreflightCheck{Name: "gitea version", Status: PreflightOK, Detail: m.gtClient.Version()}
+	var skipped []string
+	for _, missing := range m.gtClient.Unsupported() {
+		skipped = append(skipped, fmt.Sprintf("%s (gitea %s)", missing.Feature, missing.Needs))
+	}
+	if len(skipped) > 0 {
+		check.Status = PreflightWarning
+		check.Detail += ", skipping " + strings.Join(skipped, ", ")
+		check.Hint = "upgrade gitea to migrate them"
+	}
+	return check
+}
+
+// checkTarget reports whether the target organization or user already exists, and
+// how many repositories it has, as the migration reuses them.
+func (m *Migrator) checkTarget(opts PreflightOption) PreflightCheck {
+	if opts.TargetOrg == "" {
+		check := PreflightCheck{Name: "gitea user " + opts.TargetUser, Status: PreflightOK, Detail: "exists"}
+		if _, err := m.gtClient.GetUser(opts.TargetUser); err != nil {
+			if !notFound(err) {
+				check.Status = PreflightFailed
+				check.Detail = err.Error()
+				check.Hint = "check --target-user"
+				return check
+			}
+			check.Detail = "does not exist, it is created"
+		}
+		return check
+	}
+
+	name := SanitizeOrgName(opts.TargetOrg)
+	check := PreflightCheck{Name: "gitea org " + name, Status: PreflightOK}
+	if _, err := m.gtClient.GetOrg(name); err != nil {
+		if !notFound(err) {
+			check.Status = PreflightFailed
+			check.Detail = err.Error()
+			check.Hint = "check --target-org"
+			return check
+		}
+		check.Detail = "does not exist, it is created"
+		return check
+	}
+	repos, _, err := m.gtClient.ListOrgRepos(name, gsdk.ListOrgReposOptions{ListOptions: gsdk.ListOptions{PageSize: 1}})
+	if err != nil || len(repos) == 0 {
+		check.Detail = "exists, its teams are reused"
+		return check
+	}
+	check.Status = PreflightWarning
+	check.Detail = "exists with repositories"
+	check.Hint = "existing repositories are skipped, run cleanup or pass --rm-org to start over"
+	return check
+}
+
+// checkUsers reports the users of the user list already existing in Gitea, which are
+// reused instead of created.
+func (m *Migrator) checkUsers(users []User) PreflightCheck {
+	check := PreflightCheck{Name: "gitea users", Status: PreflightOK}
+	var existing []string
+	for _, u := range users {
+		name := u.Username
+		if name == "" {
+			name = m.gtClient.Username(u.Login)
+		}
+		if _, err := m.gtClient.GetUser(name); err != nil {
+			if !notFound(err) {
+				check.Status = PreflightFailed
+				check.Detail = err.Error()
+				check.Hint = "check the gitea connection"
+				return check
+			}
+			continue
+		}
+		existing = append(existing, name)
+	}
+	check.Detail = fmt.Sprintf("%d of %d users exist", len(existing), len(users))
+	if len(existing) > 0 {
+		check.Detail += " and are reused: " + strings.Join(existing, ",")
+	}
+	return check
+}
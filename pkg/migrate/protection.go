@@ -0,0 +1,151 @@
+package migrate
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+)
+
+// Who may push to the branches of a protection rule.
+const (
+	// PushOwners limits pushes to the Owners team of an organization, or to the
+	// owner of a personal repository.
+	PushOwners = "owners"
+	// PushWriters allows pushes to everybody with write access.
+	PushWriters = "writers"
+	// PushNone disables pushes, changes are merged through pull requests.
+	PushNone = "none"
+)
+
+// ownersTeam is the Gitea team holding the owners of an organization.
+const ownersTeam = "Owners"
+
+// ProtectionRule is a branch protection rule applied to every migrated repository.
+type ProtectionRule struct {
+	// Branch is a branch name or glob pattern, e.g. release/*.
+	Branch string
+	// Push is PushOwners, PushWriters or PushNone.
+	Push string
+	// Approvals is the number of approvals needed to merge a pull request.
+	Approvals int64
+}
+
+// ProtectionPolicy is the list of branch protection rules of the migrated repositories.
+type ProtectionPolicy []ProtectionRule
+
+// LoadProtectionPolicy reads a CSV file with a branch,push,approvals header, where push
+// is owners, writers or none, and approvals a number, 0 when empty.
+func LoadProtectionPolicy(path string) (ProtectionPolicy, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var policy ProtectionPolicy
+	branches := make(map[string]bool)
+	for index, rec := range records {
+		// Skip the header row
+		if index == 0 {
+			continue
+		}
+		if len(rec) < 3 {
+			return nil, fmt.Errorf("protection policy line %d: expected branch,push,approvals", index+1)
+		}
+		rule := ProtectionRule{
+			Branch: strings.TrimSpace(rec[0]),
+			Push:   strings.ToLower(strings.TrimSpace(rec[1])),
+		}
+		if rule.Branch == "" {
+			return nil, fmt.Errorf("protection policy line %d: branch is required", index+1)
+		}
+		if branches[rule.Branch] {
+			return nil, fmt.Errorf("protection policy line %d: %s is listed twice", index+1, rule.Branch)
+		}
+		branches[rule.Branch] = true
+		switch rule.Push {
+		case PushOwners, PushWriters, PushNone:
+		default:
+			return nil, fmt.Errorf("protection policy line %d: invalid push %q, expected owners, writers or none", index+1, rule.Push)
+		}
+		if approvals := strings.TrimSpace(rec[2]); approvals != "" {
+			rule.Approvals, err = strconv.ParseInt(approvals, 10, 64)
+			if err != nil || rule.Approvals < 0 {
+				return nil, fmt.Errorf("protection policy line %d: invalid approvals %q", index+1, approvals)
+			}
+		}
+		policy = append(policy, rule)
+	}
+	return policy, nil
+}
+
+// ProtectionOption selects the repository the protection policy is applied to.
+type ProtectionOption struct {
+	// Owner and Name identify the migrated Gitea repository.
+	Owner string
+	Name  string
+	// Org tells whether Owner is an organization, whose Owners team may push.
+	Org    bool
+	Policy ProtectionPolicy
+	// Report records every rule when set.
+	Report *report.Report
+}
+
+// ApplyProtectionPolicy creates the branch protection rules of the policy on a migrated
+// repository. Rules with the same branch pattern, e.g. from an earlier run, are updated
+// to match the policy.
+func (m *Migrator) ApplyProtectionPolicy(ctx context.Context, opts ProtectionOption) error {
+	_, span := trace.Start(ctx, "migrate.ApplyProtectionPolicy",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+
+	target := opts.Owner + "/" + opts.Name
+	for _, rule := range opts.Policy {
+		protection := gitea.BranchProtectionOption{
+			RuleName:          rule.Branch,
+			EnablePush:        rule.Push != PushNone,
+			RequiredApprovals: rule.Approvals,
+		}
+		if rule.Push == PushOwners {
+			if opts.Org {
+				protection.PushTeams = []string{ownersTeam}
+			} else {
+				protection.PushUsers = []string{opts.Owner}
+			}
+		}
+		start := time.Now()
+		created, err := m.gtClient.ProtectBranch(opts.Owner, opts.Name, protection)
+		record(opts.Report, report.KindProtection, target+" "+rule.Branch, start, err)
+		if err != nil {
+			span.RecordError(err)
+			m.logger.Error("failed to protect branch", "repo", target, "branch", rule.Branch, "error", err)
+			continue
+		}
+		m.logger.Info("protect branch",
+			"repo", target,
+			"branch", rule.Branch,
+			"push", rule.Push,
+			"approvals", rule.Approvals,
+			"created", created,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/state"
+
+	gsdk "code.gitea.io/sdk/gitea"
+	"github.com/google/go-github/v71/github"
+)
+
+// renameCandidate is a Gitea repository migrated from GitHub, with its source full
+// name and GitHub id when recorded.
+type renameCandidate struct {
+	source   string
+	id       int64
+	recorded *state.Repo
+}
+
+// detectRenames finds the GitHub repositories renamed or moved since they were
+// migrated. GitHub lists repositories under their new name only, so each Gitea
+// repository of the owner migrated from a source missing from the listing all is
+// matched again: by the GitHub id recorded in the state store, or else through the
+// GitHub redirect of the old name. Sources still listed cost no request, and renamed
+// repositories outside selected are left alone. The Gitea repository is renamed to
+// the target of the new name, Gitea redirecting the old name, and its state moves
+// along. It returns the old full names keyed by the lowercase new full name.
+func (r *run) detectRenames(ctx context.Context, owner string, isOrg bool, all, selected []*github.Repository) map[string]string {
+	listed := make(map[string]bool, len(all))
+	byID := make(map[int64]*github.Repository, len(all))
+	for _, repo := range all {
+		listed[strings.ToLower(repo.GetFullName())] = true
+		byID[repo.GetID()] = repo
+	}
+	chosen := make(map[string]*github.Repository, len(selected))
+	for _, repo := range selected {
+		chosen[strings.ToLower(repo.GetFullName())] = repo
+	}
+
+	renames := make(map[string]string)
+	for name, candidate := range r.renameCandidates(owner, isOrg) {
+		if listed[strings.ToLower(candidate.source)] {
+			continue
+		}
+		var current *github.Repository
+		if candidate.id != 0 {
+			if current = byID[candidate.id]; current == nil {
+				// moved out of the source or deleted
+				continue
+			}
+		} else {
+			sourceOwner, sourceName, _ := strings.Cut(candidate.source, "/")
+			var err error
+			current, err = r.ghClient.GetRepo(ctx, sourceOwner, sourceName)
+			if err != nil {
+				if !githubNotFound(err) {
+					r.logger.Warn("failed to check github repo redirect", "repo", candidate.source, "error", err)
+				}
+				continue
+			}
+		}
+		if strings.EqualFold(current.GetFullName(), candidate.source) {
+			// not renamed, only left out of the listing
+			continue
+		}
+		repo, ok := chosen[strings.ToLower(current.GetFullName())]
+		if !ok {
+			if !listed[strings.ToLower(current.GetFullName())] {
+				r.logger.Info("github repo moved out of the source", "old", candidate.source, "new", current.GetFullName())
+			}
+			continue
+		}
+
+		start := time.Now()
+		old := candidate.source
+		renames[strings.ToLower(repo.GetFullName())] = old
+		target := r.plan.RepoOverrides.Lookup(repo).target(repo)
+		r.logger.Warn("github repo renamed since its migration", "old", old, "new", repo.GetFullName())
+		err := r.renameTarget(owner, name, target)
+		if err == nil && candidate.recorded != nil {
+			recorded := *candidate.recorded
+			recorded.Source = repo.GetFullName()
+			recorded.ID = repo.GetID()
+			err = r.plan.State.RenameRepo(owner, name, target, recorded)
+		}
+		record(r.rpt, report.KindRedirect, old+" -> "+repo.GetFullName(), start, err)
+	}
+	return renames
+}
+
+// renameCandidates returns the Gitea repositories of the owner migrated from a GitHub
+// repository keyed by lowercase name: those recorded in the state store, and those
+// whose original URL points to the GitHub host, so a first run finds them as well.
+func (r *run) renameCandidates(owner string, isOrg bool) map[string]renameCandidate {
+	candidates := make(map[string]renameCandidate)
+	if r.plan.State != nil {
+		for name, recorded := range r.plan.State.OwnerRepos(owner) {
+			// gists record their URL
+			if strings.Count(recorded.Source, "/") != 1 {
+				continue
+			}
+			candidates[name] = renameCandidate{source: recorded.Source, id: recorded.ID, recorded: &recorded}
+		}
+	}
+
+	repos, err := r.gtClient.ListOwnerRepos(owner, isOrg)
+	if err != nil {
+		if !notFound(err) {
+			r.logger.Warn("failed to list gitea repos", "owner", owner, "error", err)
+		}
+		return candidates
+	}
+	for _, repo := range repos {
+		name := strings.ToLower(repo.Name)
+		if _, ok := candidates[name]; ok {
+			continue
+		}
+		if source := githubSource(repo.OriginalURL, r.ghClient.Host()); source != "" {
+			candidates[name] = renameCandidate{source: source}
+		}
+	}
+	return candidates
+}
+
+// githubSource returns the full name of the GitHub repository a Gitea repository was
+// migrated from, or an empty string when its original URL is not on host.
+func githubSource(originalURL, host string) string {
+	u, err := url.Parse(originalURL)
+	if err != nil || !strings.EqualFold(u.Host, host) {
+		return ""
+	}
+	source := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if strings.Count(source, "/") != 1 {
+		return ""
+	}
+	return source
+}
+
+// renameTarget renames a Gitea repository, unless it already has the name. Gitea
+// redirects the old name to the new one.
+func (r *run) renameTarget(owner, oldName, newName string) error {
+	if strings.EqualFold(oldName, newName) {
+		return nil
+	}
+	if _, err := r.gtClient.GetRepo(owner, newName); !notFound(err) {
+		if err != nil {
+			return err
+		}
+		return errors.New("gitea repository " + owner + "/" + newName + " already exists")
+	}
+	if _, err := r.gtClient.EditRepo(owner, oldName, gsdk.EditRepoOption{Name: &newName}); err != nil {
+		return err
+	}
+	r.logger.Info("renamed gitea repo", "owner", owner, "old", oldName, "new", newName)
+	return nil
+}
+
+// githubNotFound reports whether a GitHub API call failed with 404.
+func githubNotFound(err error) bool {
+	var ghErr *github.ErrorResponse
+	return errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusNotFound
+}
+
+// renamedURL returns the GitHub web URL of a repository under its old full name.
+func renamedURL(repo *github.Repository, old string) string {
+	return strings.TrimSuffix(repo.GetHTMLURL(), repo.GetFullName()) + old
+}
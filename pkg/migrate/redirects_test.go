@@ -0,0 +1,20 @@
+package migrate
+
+import "testing"
+
+func TestGithubSource(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/acme/api.git":              "acme/api",
+		"https://github.com/acme/api":                  "acme/api",
+		"https://GitHub.com/acme/api/":                 "acme/api",
+		"https://gitlab.com/acme/api.git":              "",
+		"https://github.com/acme":                      "",
+		"https://gist.github.com/0123456789abcdef.git": "",
+		"": "",
+	}
+	for originalURL, want := range tests {
+		if got := githubSource(originalURL, "github.com"); got != want {
+			t.Errorf("githubSource(%q) = %q, want %q", originalURL, got, want)
+		}
+	}
+}
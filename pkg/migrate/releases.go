@@ -0,0 +1,180 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/state"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	gsdk "code.gitea.io/sdk/gitea"
+	"github.com/google/go-github/v71/github"
+)
+
+// ReleaseAssetsOption selects the migrated repository whose release assets are completed.
+type ReleaseAssetsOption struct {
+	// SourceOwner and SourceName identify the GitHub repository.
+	SourceOwner string
+	SourceName  string
+	// Owner and Name identify the migrated Gitea repository.
+	Owner string
+	Name  string
+	// Private is set for repositories not readable by everyone, whose assets are
+	// not linked from other releases.
+	Private bool
+	// State links the assets already uploaded instead of uploading them again when
+	// set and Gitea supports external release attachments.
+	State *state.Store
+	// Report records every transferred asset when set.
+	Report *report.Report
+}
+
+// MigrateReleaseAssets uploads the GitHub release assets missing in the migrated Gitea
+// releases, which the Gitea importer often drops for large files. An attachment whose
+// size differs from GitHub, left by an interrupted upload, is replaced. Complete
+// attachments are not reported. With a state store, assets with the content of an
+// attachment already uploaded are added as a link to it.
+func (m *Migrator) MigrateReleaseAssets(ctx context.Context, opts ReleaseAssetsOption) error {
+	ctx, span := trace.Start(ctx, "migrate.MigrateReleaseAssets",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+	if opts.State != nil {
+		defer m.saveAttachments(opts.State)
+	}
+	dedup := opts.State != nil && m.gtClient.Supports(gitea.FeatureExternalAssets)
+
+	sources, err := m.ghClient.ListReleases(ctx, opts.SourceOwner, opts.SourceName)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+	releases, err := m.gtClient.ListReleases(opts.Owner, opts.Name)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	byTag := make(map[string]*gsdk.Release, len(releases))
+	for _, release := range releases {
+		byTag[release.TagName] = release
+	}
+
+	target := opts.Owner + "/" + opts.Name
+	for _, source := range sources {
+		release, migrated := byTag[source.GetTagName()]
+		for _, asset := range source.Assets {
+			item := target + " " + source.GetTagName() + "/" + asset.GetName()
+			if !migrated {
+				opts.Report.Add(report.Item{Kind: report.KindAsset, Name: item, Status: report.StatusSkipped, Error: "release not migrated"})
+				continue
+			}
+			var existing *gsdk.Attachment
+			for _, attachment := range release.Attachments {
+				if attachment.Name == asset.GetName() {
+					existing = attachment
+				}
+			}
+			var linked string
+			if dedup {
+				linked, _ = m.uploadedAttachment(opts.State, asset.GetBrowserDownloadURL(), target)
+			}
+			if existing != nil && (existing.Size == int64(asset.GetSize()) || linked != "" && existing.DownloadURL == linked) {
+				continue
+			}
+
+			start := time.Now()
+			err := m.transferAsset(ctx, opts, release.ID, existing, asset, linked, dedup)
+			record(opts.Report, report.KindAsset, item, start, err)
+			if err != nil {
+				span.RecordError(err)
+				m.logger.Error("failed to transfer release asset", "repo", target, "asset", item, "error", err)
+				continue
+			}
+			m.logger.Info("transfer release asset", "repo", target, "asset", item, "size", asset.GetSize())
+		}
+	}
+	return nil
+}
+
+// transferAsset downloads a GitHub release asset to a temporary file, resuming
+// interrupted downloads, then replaces the existing attachment, if any, and streams
+// the file to the Gitea release. An asset whose content is already uploaded, at
+// linked when known before the download, is added as a link when dedup is set.
+func (m *Migrator) transferAsset(
+	ctx context.Context,
+	opts ReleaseAssetsOption,
+	releaseID int64,
+	existing *gsdk.Attachment,
+	asset *github.ReleaseAsset,
+	linked string,
+	dedup bool,
+) error {
+	if linked != "" {
+		return m.replaceAsset(opts, releaseID, existing, func() error {
+			return m.gtClient.LinkReleaseAttachment(opts.Owner, opts.Name, releaseID, asset.GetName(), linked)
+		})
+	}
+
+	f, err := os.CreateTemp("", "github2gitea-asset-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if err := m.ghClient.DownloadReleaseAsset(ctx, opts.SourceOwner, opts.SourceName, asset, f); err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != int64(asset.GetSize()) {
+		return fmt.Errorf("downloaded %d bytes of %d", info.Size(), asset.GetSize())
+	}
+
+	var hash string
+	if opts.State != nil {
+		if hash, err = fileHash(f); err != nil {
+			return err
+		}
+	}
+	link := asset.GetBrowserDownloadURL()
+	target := opts.Owner + "/" + opts.Name
+	if dedup {
+		if to, ok := m.reusableAttachment(opts.State, hash, target); ok {
+			m.logger.Debug("link already uploaded release asset", "repo", target, "asset", asset.GetName(), "to", to)
+			opts.State.SetAttachment(link, hash, state.Attachment{})
+			return m.replaceAsset(opts, releaseID, existing, func() error {
+				return m.gtClient.LinkReleaseAttachment(opts.Owner, opts.Name, releaseID, asset.GetName(), to)
+			})
+		}
+	}
+
+	return m.replaceAsset(opts, releaseID, existing, func() error {
+		attachment, err := m.gtClient.UploadReleaseAttachment(opts.Owner, opts.Name, releaseID, asset.GetName(), f.Name())
+		if err == nil && opts.State != nil {
+			opts.State.SetAttachment(link, hash, state.Attachment{URL: attachment.DownloadURL, Repo: target, Public: !opts.Private})
+		}
+		return err
+	})
+}
+
+// replaceAsset deletes the existing attachment of a release, if any, then adds the
+// new one with add.
+func (m *Migrator) replaceAsset(opts ReleaseAssetsOption, releaseID int64, existing *gsdk.Attachment, add func() error) error {
+	if existing != nil {
+		if err := m.gtClient.DeleteReleaseAttachment(opts.Owner, opts.Name, releaseID, existing.ID); err != nil {
+			return err
+		}
+	}
+	return add()
+}
@@ -0,0 +1,166 @@
+package migrate
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v71/github"
+)
+
+// RepoOverride changes the migration options of a single repository. Unset fields
+// keep the options of the run.
+type RepoOverride struct {
+	// Name is the Gitea repository name, instead of the GitHub one.
+	Name string
+	// Private and Mirror replace the GitHub visibility and the --mirror option.
+	Private *bool
+	Mirror  *bool
+	// SkipWiki leaves out the wiki and disables the wiki unit.
+	SkipWiki bool
+}
+
+// RepoOverrides holds the repository overrides, keyed by lowercase GitHub repository
+// name or full name.
+type RepoOverrides map[string]RepoOverride
+
+// repoNamePattern matches the repository names Gitea accepts.
+var repoNamePattern = regexp.MustCompile(`^[-.\w]+$`)
+
+// repoOverrideColumns are the header names of the repository overrides file.
+var repoOverrideColumns = []string{"repo", "name", "private", "wiki", "mirror"}
+
+// LoadRepoOverrides reads a CSV file with a repo header and any of the name, private,
+// wiki and mirror headers. Repo is the GitHub repository name, or owner/name for the
+// runs migrating several owners. Empty cells keep the options of the run; private,
+// wiki and mirror are true or false.
+func LoadRepoOverrides(path string) (RepoOverrides, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for column, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(repoOverrideColumns, name) {
+			return nil, fmt.Errorf("repo overrides: unknown column %q, expected %s", name, strings.Join(repoOverrideColumns, ","))
+		}
+		columns[name] = column
+	}
+	if _, ok := columns["repo"]; !ok {
+		return nil, errors.New("repo overrides: the repo column is required")
+	}
+
+	overrides := make(RepoOverrides)
+	targets := make(map[string]string)
+	for index, rec := range records[1:] {
+		line := index + 2
+		value := func(name string) string {
+			column, ok := columns[name]
+			if !ok || column >= len(rec) {
+				return ""
+			}
+			return strings.TrimSpace(rec[column])
+		}
+		repo := strings.ToLower(value("repo"))
+		if repo == "" {
+			return nil, fmt.Errorf("repo overrides line %d: repo is required", line)
+		}
+		if _, ok := overrides[repo]; ok {
+			return nil, fmt.Errorf("repo overrides line %d: %s is listed twice", line, repo)
+		}
+
+		var override RepoOverride
+		if name := value("name"); name != "" {
+			if !repoNamePattern.MatchString(name) || name == "." || name == ".." {
+				return nil, fmt.Errorf("repo overrides line %d: %q is not a valid gitea repository name", line, name)
+			}
+			// two repositories of the same owner cannot share a name
+			if other, ok := targets[strings.ToLower(name)]; ok {
+				return nil, fmt.Errorf("repo overrides line %d: %s is already the name of %s", line, name, other)
+			}
+			targets[strings.ToLower(name)] = repo
+			override.Name = name
+		}
+		if override.Private, err = parseOverrideBool(value("private")); err != nil {
+			return nil, fmt.Errorf("repo overrides line %d: private: %w", line, err)
+		}
+		if override.Mirror, err = parseOverrideBool(value("mirror")); err != nil {
+			return nil, fmt.Errorf("repo overrides line %d: mirror: %w", line, err)
+		}
+		wiki, err := parseOverrideBool(value("wiki"))
+		if err != nil {
+			return nil, fmt.Errorf("repo overrides line %d: wiki: %w", line, err)
+		}
+		override.SkipWiki = wiki != nil && !*wiki
+		overrides[repo] = override
+	}
+	return overrides, nil
+}
+
+// parseOverrideBool parses a true or false cell, nil when empty.
+func parseOverrideBool(s string) (*bool, error) {
+	var value bool
+	switch strings.ToLower(s) {
+	case "":
+		return nil, nil
+	case "true", "yes", "1":
+		value = true
+	case "false", "no", "0":
+		value = false
+	default:
+		return nil, fmt.Errorf("invalid value %q, expected true or false", s)
+	}
+	return &value, nil
+}
+
+// Lookup returns the override of a GitHub repository, by full name first.
+func (o RepoOverrides) Lookup(repo *github.Repository) RepoOverride {
+	if override, ok := o[strings.ToLower(repo.GetFullName())]; ok {
+		return override
+	}
+	return o[strings.ToLower(repo.GetName())]
+}
+
+// target returns the Gitea name of a GitHub repository.
+func (o RepoOverride) target(repo *github.Repository) string {
+	if o.Name != "" {
+		return o.Name
+	}
+	return repo.GetName()
+}
+
+// apply returns a copy of a GitHub repository with the overridden settings, so the
+// settings applied after the migration, and their drift fingerprint, keep them.
+func (o RepoOverride) apply(repo *github.Repository) *github.Repository {
+	if o.Private == nil && !o.SkipWiki {
+		return repo
+	}
+	overridden := *repo
+	if o.Private != nil {
+		overridden.Private = o.Private
+	}
+	if o.SkipWiki {
+		overridden.HasWiki = github.Ptr(false)
+	}
+	return &overridden
+}
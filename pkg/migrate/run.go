@@ -0,0 +1,1401 @@
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/state"
+
+	gsdk "code.gitea.io/sdk/gitea"
+	"github.com/appleboy/com/convert"
+	"github.com/google/go-github/v71/github"
+)
+
+// Plan describes a migration run. Either SourceOrg or SourceUser selects the
+// repositories to migrate; Users are created first in both cases.
+type Plan struct {
+	// SourceOrg is the GitHub organization migrated with its teams and repositories into TargetOrg.
+	SourceOrg string
+	// TargetOrg is the Gitea organization receiving the repositories, a name returned
+	// by SanitizeOrgName, and TargetOrgFullName the name it was given, which becomes the
+	// full name of a new organization when GitHub has none.
+	TargetOrg         string
+	TargetOrgFullName string
+	// SourceUser is the GitHub user whose repositories are migrated into TargetUser,
+	// or into TargetOrg when it is set.
+	SourceUser string
+	// TargetUser is the Gitea user namespace receiving the repositories of SourceUser.
+	TargetUser string
+
+	// Users are created in Gitea, with their avatars and SSH keys, before any repository.
+	Users []User
+	// UserRepos also migrates the personal repositories of Users into their Gitea accounts.
+	UserRepos bool
+	// UsersCreated leaves out the creation of Users, done by an earlier plan of the run.
+	UsersCreated bool
+	// UsersDeferred leaves the personal repositories, gists, follows, stars and watches
+	// of Users to a later plan of the run, migrating the other repositories they refer to.
+	UsersDeferred bool
+	// SourceID is the Gitea authentication source of the created users.
+	SourceID int64
+	// EmailRewrites replaces the email domains of the created users, e.g. GitHub noreply addresses.
+	EmailRewrites []EmailRewrite
+	// SkipUserKeys, SkipUserGPG, SkipUserAvatars and SkipUserProfile leave out these parts of the users.
+	SkipUserKeys    bool
+	SkipUserGPG     bool
+	SkipUserAvatars bool
+	SkipUserProfile bool
+	// UserKeys limits the SSH keys migrated per user.
+	UserKeys KeyPolicy
+	// BotLogins are the machine accounts not created, with the accounts of type Bot.
+	BotLogins BotLogins
+	// Suspended decides how the users suspended on GitHub Enterprise Server are created,
+	// as active accounts when empty.
+	Suspended SuspendedPolicy
+	// Passwords decides the local password of the created users, none when empty, and
+	// PasswordsFile records the generated ones.
+	Passwords     PasswordPolicy
+	PasswordsFile *Passwords
+	// Welcome emails the created users, optional.
+	Welcome *Mailer
+	// OutsideCollaborators adds the outside collaborators of the GitHub repositories to
+	// the migrated ones, creating their users.
+	OutsideCollaborators bool
+
+	// AuthToken is the GitHub token Gitea clones the repositories with.
+	AuthToken string
+	// Timeout limits how long to wait for each Gitea migration task, no limit when zero.
+	Timeout time.Duration
+	// StallTimeout abandons a Gitea migration task without progress for this long, disabled when zero.
+	StallTimeout time.Duration
+	// Sync only migrates repositories again when they changed on GitHub since the last run.
+	Sync bool
+	// State records the migrated repositories between runs, optional.
+	State *state.Store
+	// TeamOverrides adjusts team permissions on single repositories, optional.
+	TeamOverrides PermissionOverrides
+	// TeamUnits translates the GitHub team permissions to Gitea per-unit permissions,
+	// instead of a single access mode, optional.
+	TeamUnits TeamUnits
+	// AuditLogFile receives the GitHub audit log events of the source organization since
+	// AuditLogSince, the start of the run when zero, as JSON lines once the run is done.
+	AuditLogFile  string
+	AuditLogSince time.Time
+	// Protection is the branch protection policy of the migrated repositories, optional.
+	Protection ProtectionPolicy
+	// RepoOverrides changes the name, visibility, wiki and mirror mode of single repositories, optional.
+	RepoOverrides RepoOverrides
+	// ReportForks adds the GitHub fork network of every repository to the report.
+	ReportForks bool
+	// ReportLicenses adds the license GitHub detected in every repository to the
+	// compliance section of the report.
+	ReportLicenses bool
+	// LicenseTopics tags the migrated repositories with a topic naming their license,
+	// e.g. license-gpl3, for policy filtering in Gitea.
+	LicenseTopics bool
+	// GoModulesHost is the Gitea host, with its path prefix, of the migrated Go module paths.
+	GoModulesHost string
+	// GoModules adds the changed Go module paths to the report.
+	GoModules bool
+	// GoImports opens a pull request rewriting the Go module and import paths of the
+	// migrated repositories from GitHub to GoModulesHost.
+	GoImports bool
+	// ReportSigningKeys adds the GPG and SSH keys which signed the recent verified
+	// commits of the migrated repositories to the report, grouped by user.
+	ReportSigningKeys bool
+	// ReportAuthors adds the GitHub authors of the migrated issues and comments which
+	// are not attributed to a Gitea user to the report.
+	ReportAuthors bool
+	// ReportOrgRoles adds the role assignments of the source organization, such as
+	// security managers, to the report.
+	ReportOrgRoles bool
+	// ReportApps adds the GitHub Apps installed on the source organization to the report,
+	// with the Gitea token scopes their replacement needs.
+	ReportApps bool
+	// OAuth2Apps are the integrations whose Gitea OAuth2 applications are created, with
+	// their credentials written to OAuth2Secrets.
+	OAuth2Apps    []OAuth2App
+	OAuth2Secrets *OAuth2Secrets
+	// MergeMessageTemplates commits Gitea merge message templates matching the GitHub settings.
+	MergeMessageTemplates bool
+	// Concurrency is the number of repositories migrated at once, one when zero.
+	Concurrency int
+	// MaxInflightBytes limits the total GitHub size of the repositories migrated at once,
+	// unlimited when zero. A bigger repository is migrated alone.
+	MaxInflightBytes int64
+	// Windows are the daily time ranges the repository migrations may start in, any time when empty.
+	Windows Windows
+	// Webhooks copies the organization and repository webhooks, rewriting their URLs with WebhookRewrites.
+	Webhooks        bool
+	WebhookRewrites []URLRewrite
+	// WebhooksInactive creates the webhooks disabled, to be reviewed before they fire.
+	WebhooksInactive bool
+	// WebhookSecrets records the new secrets of the webhooks, instead of SecretPlaceholder.
+	WebhookSecrets *WebhookSecrets
+	// Actions copies the organization and repository Actions variables and creates their
+	// secrets, with the values of ActionsSecrets or SecretPlaceholder.
+	Actions        bool
+	ActionsSecrets SecretValues
+	// Environments maps GitHub deployment environments to the prefix of their secrets
+	// and variables, created by Actions and renamed in the workflows by Workflows.
+	Environments EnvironmentPrefixes
+	// Workflows converts the GitHub Actions workflows of the migrated repositories for
+	// Gitea Actions on a branch and pull request, mapping runner labels with RunnerLabels
+	// and downgrading the v4 artifact actions with DowngradeArtifacts.
+	Workflows          bool
+	RunnerLabels       map[string]string
+	DowngradeArtifacts bool
+	// EnableActions enables the Actions unit of the migrated repositories, and
+	// RunnerTokens records the runner registration tokens of the migrated organization
+	// and repositories, optional.
+	EnableActions bool
+	RunnerTokens  *RunnerTokens
+	// SecretsFile is set on the Gitea organizations and repositories in bulk at the end
+	// of the run, replacing the placeholders of the names-only secrets migration.
+	SecretsFile SecretValues
+	// LFS migrates the Git LFS objects of the repositories, from LFSEndpoint when set
+	// instead of the GitHub LFS endpoint of each repository.
+	LFS         bool
+	LFSEndpoint string
+	// Mirror creates Gitea pull mirrors synced every MirrorInterval instead of one-shot
+	// migrations, to run GitHub and Gitea side by side. Mirrors are never forks.
+	Mirror         bool
+	MirrorInterval string
+	// Cutover replaces the pull mirrors created with Mirror by regular repositories
+	// migrated from GitHub, archiving the GitHub repositories with ArchiveSource.
+	// Repositories which are not mirrored are skipped.
+	Cutover       bool
+	ArchiveSource bool
+	// ReleaseAssets uploads the GitHub release assets missing in the migrated releases.
+	ReleaseAssets bool
+	// Attachments copies the files attached to issues and comments on GitHub and rewrites their links.
+	Attachments bool
+	// SocialRate limits the star, watch and follow calls to this many per second on
+	// average, unlimited when zero, run in batches of SocialBatch calls.
+	SocialRate  float64
+	SocialBatch int
+	// Labels compares the issue labels of the migrated repositories with GitHub, fixing
+	// them with LabelFix. Disabled when empty.
+	Labels LabelMode
+	// Forks recreates the fork relationship of GitHub forks whose parent is migrated
+	// to another Gitea owner, forking the parent in Gitea and pushing the git content.
+	Forks bool
+	// Archive archives the migrated repositories which are archived on GitHub.
+	Archive bool
+	// Repos and ExcludeRepos are glob patterns of the GitHub repository names of the
+	// source migrated and left out, all repositories when Repos is empty.
+	Repos        []string
+	ExcludeRepos []string
+	// ByTeam limits the organization migration to the repositories of a GitHub team,
+	// given by slug, and the created users to its members and the organization owners.
+	ByTeam string
+	// OnDrift decides what happens to teams and repository settings changed in Gitea since
+	// the last run, detected with fingerprints recorded in State. DriftPreserve when empty.
+	OnDrift DriftPolicy
+	// ConfirmDrift asks whether to overwrite a changed object with DriftAsk, optional.
+	ConfirmDrift func(question string) bool
+	// Controls pause the user creation and the repository migrations and skip queued
+	// repositories during the run, optional.
+	Controls *Controls
+	// Canary migrates this many representative repositories of the source first and
+	// verifies them, then asks ConfirmCanary whether to migrate the others. The run
+	// stops with ErrCanaryStopped without confirmation. Disabled when zero.
+	Canary        int
+	ConfirmCanary func(question string) bool
+	// Phases limits the run to some of its phases, all of them when empty. The
+	// repositories phase alone expects the organization and teams of an earlier run.
+	Phases Phases
+
+	// Report collects the results, a new report is created when nil.
+	Report *report.Report
+}
+
+// User is a GitHub user to create in Gitea.
+type User struct {
+	Login string
+	Email string
+	Role  string
+	// Username, SourceID and Admin are the per-user overrides of a JSON or YAML user
+	// list: the Gitea username, the authentication source instead of the plan one, 0
+	// for a local account, and the site administrator flag.
+	Username string
+	SourceID *int64
+	Admin    bool
+	// Password is the local password of the user with the mapping password policy.
+	Password string
+	// MigrateGists, MigrateStars, MigrateWatches and MigrateFollows opt the user in
+	// to the migration of this personal data. Follows are replayed once the users and
+	// organization members exist, the others after all repositories are migrated.
+	MigrateGists   bool
+	MigrateStars   bool
+	MigrateWatches bool
+	MigrateFollows bool
+}
+
+// Types of progress events.
+const (
+	// EventTotal adds Total repositories to the number expected in the run.
+	EventTotal = "total"
+	// EventRepoQueued is sent for every repository of the Source GitHub full name
+	// waiting to be migrated.
+	EventRepoQueued = "repo_queued"
+	// EventRepoStarted is sent before the migration of the repository Owner/Name.
+	EventRepoStarted = "repo_started"
+	// EventRepoFinished is sent with the Result of the repository Owner/Name.
+	EventRepoFinished = "repo_finished"
+	// EventItem is sent with the Item of every organization, team, user or key result.
+	EventItem = "item"
+)
+
+// Event reports the progress of a run.
+type Event struct {
+	Type  string
+	Owner string
+	Name  string
+	// Source is the GitHub full name of the repository of the repo events.
+	Source string
+	Total  int
+	Result *report.Repo
+	Item   *report.Item
+}
+
+// ProgressFunc receives the progress events of a run. It must not block, and is
+// called concurrently when the plan migrates several repositories at once.
+type ProgressFunc func(Event)
+
+// run holds the state of a single Migrator.Run call.
+type run struct {
+	*Migrator
+	plan     Plan
+	rpt      *report.Report
+	progress ProgressFunc
+	// ghUser is the authenticated GitHub user Gitea clones the repositories as.
+	ghUser *github.User
+	// drift is nil without a state store.
+	drift *Drift
+	// confirmMu serializes the drift questions of sync mode between concurrent repositories.
+	confirmMu sync.Mutex
+	// social replays the stars, watches and follows of the users.
+	social *socialQueue
+	// signers collects the keys of the verified commits when ReportSigningKeys is set.
+	signers signers
+	// outside maps the lowercase logins of the outside collaborators to their Gitea
+	// users, outsideMu only guards the map.
+	outsideMu sync.Mutex
+	outside   map[string]*outsideAccount
+	// renames maps the lowercase full names of the GitHub repositories renamed since
+	// the last run to their old full name.
+	renames map[string]string
+	// goImports maps the lowercase GitHub paths of the repositories of the run to their
+	// Gitea path when GoImports is set.
+	goImportsMu sync.Mutex
+	goImports   map[string]string
+}
+
+// confirmDrift asks ConfirmDrift whether to replace a repository changed on GitHub,
+// one question at a time. Without ConfirmDrift the repository is kept.
+func (r *run) confirmDrift(question string) bool {
+	if r.plan.ConfirmDrift == nil {
+		return false
+	}
+	r.confirmMu.Lock()
+	defer r.confirmMu.Unlock()
+	return r.plan.ConfirmDrift(question)
+}
+
+// Run executes the plan and returns the report of the migrated resources.
+// Failures of single users, teams or repositories are recorded in the report
+// and do not stop the run; the returned error means the run could not complete.
+// progress may be nil.
+func (m *Migrator) Run(ctx context.Context, plan Plan, progress ProgressFunc) (*report.Report, error) {
+	start := time.Now()
+	rpt := plan.Report
+	if rpt == nil {
+		rpt = report.New()
+	}
+	usersOnly := !plan.Phases.Has(PhaseOrg) && !plan.Phases.Has(PhaseRepos)
+	if plan.SourceOrg == "" && plan.SourceUser == "" && !usersOnly {
+		return rpt, errors.New("plan requires a source organization or user")
+	}
+	if progress == nil {
+		progress = func(Event) {}
+	}
+	rpt.Observe(func(item report.Item) {
+		if item.Kind != report.KindRepo {
+			progress(Event{Type: EventItem, Item: &item})
+		}
+	})
+
+	ghUser, err := m.ghClient.GetCurrentUser(ctx)
+	if err != nil {
+		m.logger.Error("failed to get current github user", "error", err)
+		return rpt, err
+	}
+	gtUser, err := m.gtClient.GetCurrentUser()
+	if err != nil {
+		m.logger.Error("failed to get current gitea user", "error", err)
+		return rpt, err
+	}
+	m.logger.Info("github user",
+		"login", convert.FromPtr(ghUser.Login),
+		"name", convert.FromPtr(ghUser.Name),
+		"email", convert.FromPtr(ghUser.Email),
+	)
+	m.logger.Info("gitea user",
+		"login", gtUser.UserName,
+		"name", gtUser.FullName,
+		"email", gtUser.Email,
+	)
+
+	r := &run{
+		Migrator: m,
+		plan:     plan,
+		rpt:      rpt,
+		progress: progress,
+		ghUser:   ghUser,
+	}
+	if plan.State != nil {
+		policy := plan.OnDrift
+		if policy == "" {
+			policy = DriftPreserve
+		}
+		r.drift = &Drift{
+			State:   plan.State,
+			Policy:  policy,
+			Confirm: r.confirmDrift,
+			Report:  rpt,
+		}
+	}
+
+	// also after failed runs, which need it most
+	if plan.AuditLogFile != "" && plan.SourceOrg != "" {
+		since := plan.AuditLogSince
+		if since.IsZero() {
+			since = start
+		}
+		defer r.exportAuditLog(ctx, since)
+	}
+
+	users := plan.Phases.Has(PhaseUsers)
+	if len(plan.Users) > 0 && users && !plan.UsersCreated {
+		r.createUsers(ctx)
+	}
+	r.social = newSocialQueue(ctx, plan.SocialRate, plan.SocialBatch)
+	defer r.social.wait()
+
+	switch {
+	case usersOnly:
+	case plan.SourceUser != "":
+		err = r.migrateUserRepos(ctx)
+	default:
+		err = r.migrateOrgAndRepos(ctx)
+	}
+	if err != nil {
+		return rpt, err
+	}
+
+	userData := users && !plan.UsersDeferred
+	// once the organization members exist, replayed while the personal repositories migrate
+	if userData {
+		r.queueUsersFollows(ctx)
+	}
+
+	// after the organization, so personal forks of its repositories find their parent
+	if len(plan.Users) > 0 && plan.UserRepos && userData {
+		r.migrateUsersRepos(ctx)
+	}
+
+	// stars and watches need the repositories of the whole run
+	if userData {
+		r.migrateUsersData(ctx)
+	}
+	if len(plan.SecretsFile) > 0 && !usersOnly {
+		r.applySecrets(ctx)
+	}
+	if plan.ReportSigningKeys {
+		r.reportSigningKeys(ctx)
+	}
+	if len(plan.OAuth2Apps) > 0 && plan.Phases.Has(PhaseOrg) {
+		r.provisionOAuth2Apps(ctx)
+	}
+	return rpt, nil
+}
+
+// migrateOrgAndRepos migrates the source organization, its teams and repositories.
+func (r *run) migrateOrgAndRepos(ctx context.Context) error {
+	// get github organization
+	ghOrg, err := r.ghClient.GetOrg(ctx, r.plan.SourceOrg)
+	if err != nil {
+		r.logger.Error("failed to get github org", "error", err)
+		return err
+	}
+
+	var org *CreateNewOrgResult
+	if r.plan.Phases.Has(PhaseOrg) {
+		org, err = r.migrateOrg(ctx, ghOrg)
+		if err != nil {
+			r.logger.Error("failed to create gitea org", "error", err)
+			return err
+		}
+	} else {
+		org, err = r.existingOrg(ctx, r.plan.SourceOrg, r.plan.TargetOrg)
+		if err != nil {
+			r.logger.Error("failed to get gitea org, run the org phase first", "org", r.plan.TargetOrg, "error", err)
+			return err
+		}
+	}
+
+	if r.plan.Phases.Has(PhaseRepos) {
+		if err := r.migrateOrgRepos(ctx, ghOrg, org); err != nil {
+			return err
+		}
+	}
+
+	// organization secrets may be limited to repositories, which exist by now
+	if r.plan.Actions && r.plan.Phases.Has(PhaseOrg) {
+		if err := r.MigrateOrgActions(ctx, OrgActionsOption{
+			SourceOrg: r.plan.SourceOrg,
+			Org:       org.Org.UserName,
+			Secrets:   r.plan.ActionsSecrets,
+			Report:    r.rpt,
+		}); err != nil {
+			r.logger.Warn("failed to migrate org actions", "org", org.Org.UserName, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// migrateOrg creates the Gitea organization with its members and teams, and migrates
+// the organization webhooks, runner token, roles and apps.
+func (r *run) migrateOrg(ctx context.Context, ghOrg *github.Organization) (*CreateNewOrgResult, error) {
+	avatar, err := r.ghClient.DownloadOrgAvatar(ctx, ghOrg)
+	if err != nil {
+		r.logger.Warn("failed to download github org avatar", "org", r.plan.SourceOrg, "error", err)
+	}
+
+	// create new gitea organization
+	org, err := r.CreateNewOrg(ctx, CreateNewOrgOption{
+		OldName:               r.plan.SourceOrg,
+		NewName:               r.plan.TargetOrg,
+		FullName:              cmp.Or(ghOrg.GetName(), r.plan.TargetOrgFullName),
+		Description:           convert.FromPtr(ghOrg.Description),
+		Website:               ghOrg.GetBlog(),
+		Location:              ghOrg.GetLocation(),
+		Avatar:                avatar,
+		Public:                false,
+		SourceID:              r.plan.SourceID,
+		Emails:                r.plan.EmailRewrites,
+		SkipProfile:           r.plan.SkipUserProfile,
+		Bots:                  r.plan.BotLogins,
+		Suspended:             r.plan.Suspended,
+		Passwords:             r.plan.Passwords,
+		PasswordsFile:         r.plan.PasswordsFile,
+		Welcome:               r.plan.Welcome,
+		Report:                r.rpt,
+		Drift:                 r.drift,
+		TeamUnits:             r.plan.TeamUnits,
+		Team:                  r.plan.ByTeam,
+		DefaultRepoPermission: ghOrg.GetDefaultRepoPermission(),
+		MembersCanCreateRepos: ghOrg.GetMembersCanCreateRepos(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if r.plan.Webhooks {
+		if err := r.MigrateOrgWebhooks(ctx, WebhooksOption{
+			SourceOwner: r.plan.SourceOrg,
+			Owner:       org.Org.UserName,
+			Rewrites:    r.plan.WebhookRewrites,
+			Inactive:    r.plan.WebhooksInactive,
+			Secrets:     r.plan.WebhookSecrets,
+			Report:      r.rpt,
+		}); err != nil {
+			r.logger.Warn("failed to migrate org webhooks", "org", org.Org.UserName, "error", err)
+		}
+	}
+
+	if r.plan.RunnerTokens != nil && r.gtClient.Supports(gitea.FeatureRunnerTokens) {
+		start := time.Now()
+		err := r.saveRunnerToken(org.Org.UserName, "")
+		record(r.rpt, report.KindRunner, org.Org.UserName, start, err)
+	}
+
+	if r.plan.ReportOrgRoles {
+		roles, err := r.orgRoles(ctx, r.plan.SourceOrg)
+		if err != nil {
+			r.logger.Warn("failed to list github org roles", "org", r.plan.SourceOrg, "error", err)
+		}
+		r.rpt.AddRoles(roles...)
+	}
+
+	if r.plan.ReportApps {
+		apps, err := r.orgApps(ctx, r.plan.SourceOrg)
+		if err != nil {
+			r.logger.Warn("failed to list github org apps", "org", r.plan.SourceOrg, "error", err)
+		}
+		for _, app := range apps {
+			r.logger.Info("github app needs a replacement", "org", app.Org, "app", app.Slug, "repositories", app.Repositories, "hint", appChecklist(app))
+		}
+		r.rpt.AddApps(apps...)
+	}
+	return org, nil
+}
+
+// migrateOrgRepos migrates the selected repositories of the organization and adds
+// them to their teams.
+func (r *run) migrateOrgRepos(ctx context.Context, ghOrg *github.Organization, org *CreateNewOrgResult) error {
+	// get github repo list from organization, or from the team of a staged migration
+	var ghRepos []*github.Repository
+	var err error
+	if r.plan.ByTeam != "" {
+		ghRepos, err = r.ghClient.ListTeamReposBySlug(ctx, *ghOrg.Login, r.plan.ByTeam)
+	} else {
+		ghRepos, err = r.ghClient.ListOrgRepos(ctx, *ghOrg.Login)
+	}
+	if err != nil {
+		r.logger.Error("failed to get github org repos", "error", err)
+		return err
+	}
+	all := ghRepos
+	ghRepos = r.selectRepos(ghRepos)
+	r.renames = r.detectRenames(ctx, org.Org.UserName, true, all, ghRepos)
+	r.addGoImports(ghRepos, org.Org.UserName)
+
+	r.progress(Event{Type: EventTotal, Total: len(ghRepos)})
+	return r.rampRepos(ctx, ghRepos, org.Org.UserName, func(repo *github.Repository) {
+		// create new gitea repository
+		r.migrateRepo(ctx, repo, org.Org.UserName)
+
+		if err := r.AssignTeamsToRepos(ctx, AssignTeamsOption{
+			Org:             org.Org.UserName,
+			RepoTeams:       org.RepoTeams,
+			RepoPermissions: org.RepoPermissions,
+			Repos:           map[string]string{repo.GetName(): r.plan.RepoOverrides.Lookup(repo).target(repo)},
+			Overrides:       r.plan.TeamOverrides,
+			TeamUnits:       r.plan.TeamUnits,
+		}); err != nil {
+			r.logger.Error("failed to add teams to repo", "repo", repo.GetFullName(), "error", err)
+		}
+	})
+}
+
+// migrateUserRepos migrates all repositories owned by the source GitHub user
+// into a Gitea user namespace or organization.
+func (r *run) migrateUserRepos(ctx context.Context) error {
+	owner := r.plan.TargetUser
+	if r.plan.TargetOrg != "" {
+		owner = r.plan.TargetOrg
+	}
+	if r.plan.Phases.Has(PhaseOrg) {
+		if err := r.EnsureOwner(EnsureOwnerOption{
+			Name:  owner,
+			IsOrg: r.plan.TargetOrg != "",
+		}); err != nil {
+			r.logger.Error("failed to prepare gitea owner", "owner", owner, "error", err)
+			return err
+		}
+	}
+	if !r.plan.Phases.Has(PhaseRepos) {
+		return nil
+	}
+
+	ghRepos, err := r.ghClient.ListAccessibleUserRepos(ctx, r.plan.SourceUser)
+	if err != nil {
+		r.logger.Error("failed to get github user repos", "user", r.plan.SourceUser, "error", err)
+		return err
+	}
+	all := ghRepos
+	ghRepos = r.selectRepos(ghRepos)
+	r.renames = r.detectRenames(ctx, owner, r.plan.TargetOrg != "", all, ghRepos)
+	r.addGoImports(ghRepos, owner)
+
+	r.progress(Event{Type: EventTotal, Total: len(ghRepos)})
+	return r.rampRepos(ctx, ghRepos, owner, func(repo *github.Repository) {
+		r.migrateRepo(ctx, repo, owner)
+	})
+}
+
+// migrateUsersRepos migrates the personal repositories of each user of the plan
+// into the matching Gitea user account.
+func (r *run) migrateUsersRepos(ctx context.Context) {
+	for _, u := range r.plan.Users {
+		owner := r.gtClient.Username(u.Login)
+		if _, err := r.gtClient.GetUser(owner); err != nil {
+			r.logger.Error("gitea user not found, skip personal repositories", "login", u.Login, "error", err)
+			continue
+		}
+
+		ghRepos, err := r.ghClient.ListAccessibleUserRepos(ctx, u.Login)
+		if err != nil {
+			r.logger.Error("failed to get github user repos", "login", u.Login, "error", err)
+			continue
+		}
+		r.logger.Info("migrate personal repositories", "login", u.Login, "total", len(ghRepos))
+		r.addGoImports(ghRepos, owner)
+		r.progress(Event{Type: EventTotal, Total: len(ghRepos)})
+
+		r.forEachRepo(ctx, ghRepos, owner, func(repo *github.Repository) {
+			r.migrateRepo(ctx, repo, owner)
+		})
+	}
+}
+
+// addGoImports records the Gitea paths of repositories migrated into owner for the
+// import rewrite of GoImports, before any of them is migrated.
+func (r *run) addGoImports(repos []*github.Repository, owner string) {
+	if !r.plan.GoImports {
+		return
+	}
+	r.goImportsMu.Lock()
+	defer r.goImportsMu.Unlock()
+	if r.goImports == nil {
+		r.goImports = make(map[string]string)
+	}
+	for _, repo := range repos {
+		source, err := url.Parse(repo.GetHTMLURL())
+		if err != nil {
+			continue
+		}
+		target := r.plan.RepoOverrides.Lookup(repo).target(repo)
+		r.goImports[strings.ToLower(source.Host+source.Path)] = r.plan.GoModulesHost + "/" + owner + "/" + target
+	}
+}
+
+// goImportPaths returns a copy of the Gitea paths recorded by addGoImports.
+func (r *run) goImportPaths() map[string]string {
+	r.goImportsMu.Lock()
+	defer r.goImportsMu.Unlock()
+	paths := make(map[string]string, len(r.goImports))
+	for from, to := range r.goImports {
+		paths[from] = to
+	}
+	return paths
+}
+
+// migrateRepo migrates a single GitHub repository into the given Gitea owner
+// and records the result in the report.
+func (r *run) migrateRepo(ctx context.Context, repo *github.Repository, owner string) {
+	override := r.plan.RepoOverrides.Lookup(repo)
+	repo = override.apply(repo)
+	name := override.target(repo)
+	mirror := r.plan.Mirror
+	if override.Mirror != nil {
+		mirror = *override.Mirror
+	}
+	r.progress(Event{Type: EventRepoStarted, Owner: owner, Name: name, Source: repo.GetFullName()})
+
+	var parked string
+	if r.plan.Cutover {
+		var skip string
+		var err error
+		parked, skip, err = r.parkMirror(ctx, repo, owner, name)
+		if err != nil || skip != "" {
+			result := report.Repo{Owner: owner, Name: name, Source: repo.GetFullName(), Status: report.StatusSkipped, Error: skip}
+			if err != nil {
+				r.logger.Error("failed to prepare mirror cutover", "repo", repo.GetFullName(), "error", err)
+				result.Status = report.StatusFailed
+				result.Error = err.Error()
+			}
+			r.rpt.AddRepo(result)
+			r.progress(Event{Type: EventRepoFinished, Owner: owner, Name: name, Source: repo.GetFullName(), Result: &result})
+			return
+		}
+	}
+
+	// the listed repository misses the parent and the watchers, fetched once when needed
+	full := sync.OnceValues(func() (*github.Repository, error) {
+		return r.ghClient.GetRepo(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+	})
+
+	var forkOwner, forkName string
+	if r.plan.Forks && !mirror && repo.GetFork() {
+		forkOwner, forkName = r.forkParent(repo, owner, full)
+	}
+
+	// the parked mirror is found under its old name through the Gitea redirect,
+	// the cutover always migrates the repository again
+	sync := r.plan.Sync && parked == ""
+
+	start := time.Now()
+	err := r.MigrateNewRepo(ctx, MigrateNewRepoOption{
+		ForkOwner:      forkOwner,
+		ForkName:       forkName,
+		LFS:            r.plan.LFS,
+		LFSEndpoint:    r.plan.LFSEndpoint,
+		Mirror:         mirror,
+		SkipWiki:       override.SkipWiki,
+		MirrorInterval: r.plan.MirrorInterval,
+		Windows:        r.plan.Windows,
+		Owner:          owner,
+		Name:           name,
+		CloneAddr:      convert.FromPtr(repo.CloneURL),
+		Description:    convert.FromPtr(repo.Description),
+		Private:        convert.FromPtr(repo.Private),
+		AuthUsername:   convert.FromPtr(r.ghUser.Login),
+		AuthToken:      r.plan.AuthToken,
+		Timeout:        r.plan.Timeout,
+		StallTimeout:   r.plan.StallTimeout,
+		Sync:           sync,
+		OnDrift:        r.plan.OnDrift,
+		ConfirmDrift:   r.confirmDrift,
+		State:          r.plan.State,
+		Source:         repo.GetFullName(),
+		SourceRepoID:   repo.GetID(),
+		PushedAt:       repo.GetPushedAt().Time,
+		UpdatedAt:      repo.GetUpdatedAt().Time,
+	})
+	result := report.Repo{
+		Owner:    owner,
+		Name:     name,
+		Source:   convert.FromPtr(repo.FullName),
+		Status:   report.StatusSuccess,
+		Duration: report.Since(start),
+		Stats:    repoStats(repo, full, errors.Is(err, ErrUnchanged) || errors.Is(err, ErrPreserved)),
+		License:  repo.GetLicense().GetSPDXID(),
+	}
+	switch {
+	case errors.Is(err, ErrPreserved):
+		result.Status = report.StatusSkipped
+		result.Error = err.Error()
+	case errors.Is(err, ErrUnchanged):
+		result.Status = report.StatusSkipped
+	case errors.Is(err, gitea.ErrMigrationStuck):
+		r.logger.Error("migration repository stuck, continue with the next one", "error", err)
+		result.Status = report.StatusStuck
+		result.Error = err.Error()
+	case err != nil:
+		r.logger.Error("migration repository error", "error", err)
+		result.Status = report.StatusFailed
+		result.Error = err.Error()
+	case r.gtClient.GitOnly(owner, name):
+		result.Error = "gitea refuses repository migrations, only the git content was pushed:" +
+			" issues, pull requests, releases and wiki are not migrated"
+	}
+	r.rpt.AddRepo(result)
+	r.progress(Event{Type: EventRepoFinished, Owner: owner, Name: name, Source: repo.GetFullName(), Result: &result})
+
+	if parked != "" {
+		r.finishCutover(ctx, repo, owner, name, parked, err)
+	}
+
+	if err == nil {
+		if err := r.MigrateRepoSettings(ctx, RepoSettingsOption{
+			SourceOwner:           repo.GetOwner().GetLogin(),
+			SourceName:            repo.GetName(),
+			Owner:                 owner,
+			Name:                  name,
+			Settings:              true,
+			MergeMessageTemplates: r.plan.MergeMessageTemplates,
+			Override:              override,
+		}); err != nil {
+			r.logger.Warn("failed to migrate repo settings", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	// fingerprinted once the settings are applied
+	if err == nil || errors.Is(err, ErrUnchanged) {
+		r.reconcileRepo(ctx, r.drift, owner, name, repo, override, err == nil)
+	}
+
+	if err == nil && r.plan.OutsideCollaborators {
+		r.migrateCollaborators(ctx, repo, owner, name)
+	}
+
+	if err == nil && (r.plan.EnableActions || r.plan.RunnerTokens != nil) {
+		r.setupActions(owner, name)
+	}
+
+	if err == nil && r.plan.Labels != "" {
+		if err := r.CheckRepoLabels(ctx, LabelsOption{
+			SourceOwner: repo.GetOwner().GetLogin(),
+			SourceName:  repo.GetName(),
+			Owner:       owner,
+			Name:        name,
+			Mode:        r.plan.Labels,
+			Report:      r.rpt,
+		}); err != nil {
+			r.logger.Warn("failed to check repo labels", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	if err == nil && r.plan.ReleaseAssets {
+		if err := r.MigrateReleaseAssets(ctx, ReleaseAssetsOption{
+			SourceOwner: repo.GetOwner().GetLogin(),
+			SourceName:  repo.GetName(),
+			Owner:       owner,
+			Name:        name,
+			Private:     repo.GetPrivate(),
+			State:       r.plan.State,
+			Report:      r.rpt,
+		}); err != nil {
+			r.logger.Warn("failed to migrate release assets", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	if err == nil && r.plan.Attachments {
+		if err := r.MigrateRepoAttachments(ctx, AttachmentsOption{
+			Owner:   owner,
+			Name:    name,
+			Private: repo.GetPrivate(),
+			State:   r.plan.State,
+			Report:  r.rpt,
+		}); err != nil {
+			r.logger.Warn("failed to migrate issue attachments", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	if err == nil && r.plan.ReportAuthors {
+		if err := r.ReportRepoAuthors(ctx, AuthorsOption{
+			Owner:  owner,
+			Name:   name,
+			Report: r.rpt,
+		}); err != nil {
+			r.logger.Warn("failed to report issue authors", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	if (err == nil || errors.Is(err, ErrUnchanged)) && r.plan.ReportSigningKeys {
+		if err := r.collectSigners(ctx, repo.GetOwner().GetLogin(), repo.GetName()); err != nil {
+			r.logger.Warn("failed to read commit signatures", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	// unchanged repositories keep their module in the list
+	if (err == nil || errors.Is(err, ErrUnchanged)) && r.plan.GoModules {
+		opts := GoModulesOption{
+			SourceURL: repo.GetHTMLURL(),
+			Owner:     owner,
+			Name:      name,
+			Host:      r.plan.GoModulesHost,
+			Report:    r.rpt,
+		}
+		if old, ok := r.renames[strings.ToLower(repo.GetFullName())]; ok {
+			opts.RenamedFromURL = renamedURL(repo, old)
+		}
+		if err := r.RecordGoModule(ctx, opts); err != nil {
+			r.logger.Warn("failed to read go module path", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	if err == nil && r.plan.GoImports && r.gtClient.Supports(gitea.FeatureChangeFiles) {
+		if err := r.RewriteGoImports(ctx, GoImportsOption{
+			Paths:  r.goImportPaths(),
+			Owner:  owner,
+			Name:   name,
+			Report: r.rpt,
+		}); err != nil {
+			r.logger.Warn("failed to rewrite go imports", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	if err == nil && r.plan.Webhooks {
+		if err := r.MigrateRepoWebhooks(ctx, WebhooksOption{
+			SourceOwner: repo.GetOwner().GetLogin(),
+			SourceName:  repo.GetName(),
+			Owner:       owner,
+			Name:        name,
+			Rewrites:    r.plan.WebhookRewrites,
+			Inactive:    r.plan.WebhooksInactive,
+			Secrets:     r.plan.WebhookSecrets,
+			Report:      r.rpt,
+		}); err != nil {
+			r.logger.Warn("failed to migrate repo webhooks", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	if err == nil && r.plan.Actions {
+		if err := r.MigrateRepoActions(ctx, ActionsOption{
+			SourceOwner:  repo.GetOwner().GetLogin(),
+			SourceName:   repo.GetName(),
+			SourceID:     repo.GetID(),
+			Owner:        owner,
+			Name:         name,
+			Secrets:      r.plan.ActionsSecrets,
+			Environments: r.plan.Environments,
+			Report:       r.rpt,
+		}); err != nil {
+			r.logger.Warn("failed to migrate repo actions", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	if err == nil && r.plan.Workflows {
+		if err := r.ConvertRepoWorkflows(ctx, WorkflowsOption{
+			SourceOwner:        repo.GetOwner().GetLogin(),
+			SourceName:         repo.GetName(),
+			SourceID:           repo.GetID(),
+			Owner:              owner,
+			Name:               name,
+			RunnerLabels:       r.plan.RunnerLabels,
+			DowngradeArtifacts: r.plan.DowngradeArtifacts,
+			Environments:       r.plan.Environments,
+			Report:             r.rpt,
+		}); err != nil {
+			r.logger.Warn("failed to convert repo workflows", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	// mirrors refuse pushes anyway
+	if err == nil && !mirror && len(r.plan.Protection) > 0 {
+		if err := r.ApplyProtectionPolicy(ctx, ProtectionOption{
+			Owner:  owner,
+			Name:   name,
+			Org:    strings.EqualFold(owner, r.plan.TargetOrg),
+			Policy: r.plan.Protection,
+			Report: r.rpt,
+		}); err != nil {
+			r.logger.Warn("failed to apply protection policy", "repo", repo.GetFullName(), "error", err)
+		}
+	}
+
+	if r.plan.ReportLicenses || r.plan.LicenseTopics {
+		license := repoLicense(repo)
+		if err == nil && r.plan.LicenseTopics && r.gtClient.Supports(gitea.FeatureTopics) {
+			topic, err := r.tagLicense(owner, name, license)
+			if err != nil {
+				r.logger.Warn("failed to tag repo license", "repo", repo.GetFullName(), "error", err)
+			}
+			license.Topic = topic
+		}
+		if r.plan.ReportLicenses {
+			r.rpt.AddLicenses(license)
+		}
+	}
+
+	// last, an archived repository refuses the changes of the steps above
+	if err == nil && r.plan.Archive && repo.GetArchived() {
+		archived := true
+		if _, err := r.gtClient.EditRepo(owner, name, gsdk.EditRepoOption{Archived: &archived}); err != nil {
+			r.logger.Error("failed to archive repo", "repo", owner+"/"+name, "error", err)
+		} else {
+			r.logger.Info("archive repo archived on github", "repo", owner+"/"+name)
+		}
+	}
+
+	if r.plan.ReportForks && repo.GetForksCount() > 0 {
+		forks, err := r.forkNetwork(ctx, repo, repo.GetOwner().GetLogin())
+		if err != nil {
+			r.logger.Warn("failed to list github forks", "repo", repo.GetFullName(), "error", err)
+			return
+		}
+		r.rpt.AddForks(forks...)
+	}
+}
+
+// repoStats takes a snapshot of the GitHub repository counters from the listed
+// repository. The list endpoints omit subscribers_count, read from the full repository
+// unless the repository was skipped.
+func repoStats(repo *github.Repository, full func() (*github.Repository, error), skipped bool) report.RepoStats {
+	stats := report.RepoStats{
+		Stars:      repo.GetStargazersCount(),
+		Forks:      repo.GetForksCount(),
+		OpenIssues: repo.GetOpenIssuesCount(),
+	}
+	// skipped repositories cost no request
+	if !skipped {
+		if repo, err := full(); err == nil {
+			stats.Watchers = repo.GetSubscribersCount()
+		}
+	}
+	return stats
+}
+
+// forkParent returns the Gitea repository a GitHub fork is forked from in Gitea: its
+// parent, when migrated to another owner since Gitea forks live in another owner too.
+// The relationship is recorded in the report either way. fetch returns the full
+// repository, the listed one misses the parent.
+func (r *run) forkParent(repo *github.Repository, owner string, fetch func() (*github.Repository, error)) (string, string) {
+	full, err := fetch()
+	if err != nil {
+		r.logger.Warn("failed to get github fork parent", "repo", repo.GetFullName(), "error", err)
+		return "", ""
+	}
+	parent := full.GetParent()
+	if parent == nil {
+		return "", ""
+	}
+	fork := report.Fork{
+		Upstream: parent.GetFullName(),
+		Name:     repo.GetFullName(),
+		Internal: true,
+	}
+	defer func() { r.rpt.AddForks(fork) }()
+
+	parentOwner := r.giteaOwner(parent.GetOwner().GetLogin())
+	parentName := r.plan.RepoOverrides.Lookup(parent).target(parent)
+	if strings.EqualFold(parentOwner, owner) {
+		r.logger.Info("gitea cannot fork into the owner of the parent, migrate fork as a copy", "repo", repo.GetFullName(), "parent", parent.GetFullName())
+		return "", ""
+	}
+	if _, err := r.gtClient.GetRepo(parentOwner, parentName); err != nil {
+		fork.Internal = false
+		r.logger.Info("fork parent not migrated, migrate fork as a copy", "repo", repo.GetFullName(), "parent", parent.GetFullName())
+		return "", ""
+	}
+	fork.Recreated = true
+	r.logger.Info("recreate fork of migrated parent", "repo", repo.GetFullName(), "parent", parentOwner+"/"+parentName)
+	return parentOwner, parentName
+}
+
+// forkNetwork walks the forks of a repository, including forks of forks, and marks
+// the ones owned by the source owner as internal.
+func (r *run) forkNetwork(ctx context.Context, repo *github.Repository, owner string) ([]report.Fork, error) {
+	forks, err := r.ghClient.ListRepoForks(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+	if err != nil {
+		return nil, err
+	}
+	network := make([]report.Fork, 0, len(forks))
+	for _, fork := range forks {
+		network = append(network, report.Fork{
+			Upstream: repo.GetFullName(),
+			Name:     fork.GetFullName(),
+			Internal: strings.EqualFold(fork.GetOwner().GetLogin(), owner),
+		})
+		if fork.GetForksCount() > 0 {
+			nested, err := r.forkNetwork(ctx, fork, owner)
+			if err != nil {
+				return nil, err
+			}
+			network = append(network, nested...)
+		}
+	}
+	return network, nil
+}
+
+// orgRoles lists the organization role assignments of a GitHub organization. Servers
+// without organization roles only report the security manager teams. Billing
+// managers are not exposed by the GitHub API.
+func (r *run) orgRoles(ctx context.Context, org string) ([]report.RoleAssignment, error) {
+	var assignments []report.RoleAssignment
+	roles, err := r.ghClient.ListOrgRoles(ctx, org)
+	if err != nil {
+		r.logger.Debug("organization roles not available, list security managers", "org", org, "error", err)
+		teams, err := r.ghClient.ListSecurityManagerTeams(ctx, org)
+		if err != nil {
+			return nil, err
+		}
+		for _, team := range teams {
+			assignments = append(assignments, report.RoleAssignment{Org: org, Role: "security_manager", Assignee: team.GetSlug(), Type: "team"})
+		}
+		return assignments, nil
+	}
+
+	for _, role := range roles {
+		teams, err := r.ghClient.ListOrgRoleTeams(ctx, org, role.GetID())
+		if err != nil {
+			return assignments, err
+		}
+		for _, team := range teams {
+			assignments = append(assignments, report.RoleAssignment{Org: org, Role: role.GetName(), Assignee: team.GetSlug(), Type: "team"})
+		}
+		users, err := r.ghClient.ListOrgRoleUsers(ctx, org, role.GetID())
+		if err != nil {
+			return assignments, err
+		}
+		for _, user := range users {
+			assignments = append(assignments, report.RoleAssignment{Org: org, Role: role.GetName(), Assignee: user.GetLogin(), Type: "user"})
+		}
+	}
+	return assignments, nil
+}
+
+// createUsers creates the users of the plan in Gitea and migrates their
+// avatars and SSH keys.
+func (r *run) createUsers(ctx context.Context) {
+	for _, u := range r.plan.Users {
+		if r.plan.Controls.Paused() {
+			r.logger.Info("user creation paused, waiting to resume", "next", u.Login)
+		}
+		if err := r.plan.Controls.wait(ctx); err != nil {
+			return
+		}
+		// Get user information from GitHub
+		ghUser, err := r.ghClient.GetUser(ctx, u.Login)
+		if err != nil {
+			r.logger.Error("failed to get github user", "login", u.Login, "error", err)
+			continue
+		}
+		if r.plan.BotLogins.Match(ghUser) {
+			r.logger.Info("skip github bot account", "login", u.Login)
+			skipBot(r.rpt, u.Login)
+			continue
+		}
+		if r.plan.Suspended.skip(ghUser) {
+			r.logger.Info("skip github user suspended", "login", u.Login)
+			skipSuspended(r.rpt, u.Login)
+			continue
+		}
+
+		// Create or get the user in Gitea
+		opt := gitea.CreateUserOption{
+			SourceID:  r.plan.SourceID,
+			LoginName: u.Login,
+			Username:  u.Login,
+			Email:     rewriteEmail(u.Email, r.plan.EmailRewrites),
+			Inactive:  r.plan.Suspended.inactive(ghUser),
+		}
+		if u.SourceID != nil {
+			opt.SourceID = *u.SourceID
+		}
+		if !r.plan.SkipUserProfile {
+			opt.FullName = convert.FromPtr(ghUser.Name)
+		}
+		password, generated, err := newPassword(r.plan.Passwords, u.Password)
+		if err != nil {
+			r.logger.Error("failed to generate password", "login", u.Login, "error", err)
+			continue
+		}
+		opt.Password = password
+		start := time.Now()
+		gtUser, created, err := r.gtClient.CreateOrGetUser(opt)
+		if created && generated {
+			r.savePassword(r.plan.PasswordsFile, u.Login, gtUser.UserName, password)
+		}
+		if err != nil {
+			r.logger.Error("failed to create user", "login", u.Login, "email", u.Email, "err", err)
+			r.rpt.Add(report.Item{
+				Kind:     report.KindUser,
+				Name:     u.Login,
+				Status:   report.StatusFailed,
+				Duration: report.Since(start),
+				Error:    err.Error(),
+			})
+			continue
+		}
+		r.rpt.Add(report.Item{
+			Kind:     report.KindUser,
+			Name:     u.Login,
+			Status:   report.StatusSuccess,
+			Duration: report.Since(start),
+		})
+		r.logger.Info("user created or exists",
+			"login", u.Login,
+			"username", gtUser.UserName,
+			"role", u.Role,
+			"fullName", opt.FullName,
+		)
+
+		if u.Admin && !gtUser.IsAdmin {
+			if err := r.gtClient.SetUserAdmin(gtUser); err != nil {
+				r.logger.Error("failed to make user site administrator", "login", u.Login, "username", gtUser.UserName, "error", err)
+			} else {
+				r.logger.Info("make user site administrator", "login", u.Login, "username", gtUser.UserName)
+			}
+		}
+
+		if !r.plan.SkipUserProfile {
+			r.migrateUserProfile(ghUser, gtUser)
+		}
+
+		if !r.plan.SkipUserAvatars {
+			r.migrateUserAvatar(ctx, ghUser, gtUser.UserName)
+		}
+
+		if !r.plan.SkipUserGPG {
+			r.migrateUserGPGKeys(ctx, u.Login, gtUser.UserName)
+		}
+
+		keys := -1
+		if r.plan.SkipUserKeys {
+			r.logger.Info("skip ssh key migration", "login", u.Login)
+		} else {
+			keys = r.migrateUserKeys(ctx, u.Login, gtUser.UserName)
+		}
+
+		// deactivated accounts cannot sign in yet
+		if created && !opt.Inactive {
+			r.sendWelcome(r.plan.Welcome, r.rpt, welcome{
+				Login:    u.Login,
+				Username: gtUser.UserName,
+				Email:    gtUser.Email,
+				Source:   opt.SourceID,
+				Password: password != "",
+				Keys:     keys,
+			})
+		}
+	}
+}
+
+// migrateUserAvatar copies the GitHub avatar of a user to the Gitea account.
+func (r *run) migrateUserAvatar(ctx context.Context, ghUser *github.User, login string) {
+	image, err := r.ghClient.DownloadAvatar(ctx, ghUser)
+	if err != nil {
+		r.logger.Warn("failed to download github avatar", "login", login, "error", err)
+		return
+	}
+	if err := r.gtClient.UpdateUserAvatar(login, image); err != nil {
+		r.logger.Warn("failed to migrate avatar", "login", login, "error", err)
+		return
+	}
+	r.logger.Info("successfully migrated avatar", "login", login)
+}
+
+// maxLocationLength is the longest location Gitea accepts.
+const maxLocationLength = 50
+
+// migrateUserProfile copies the GitHub bio, blog and location of a user to the Gitea
+// profile fields which are still empty.
+func (m *Migrator) migrateUserProfile(ghUser *github.User, gtUser *gsdk.User) {
+	website := ghUser.GetBlog()
+	// GitHub accepts a blog without scheme, Gitea needs a URL
+	if website != "" && !strings.Contains(website, "://") {
+		website = "https://" + website
+	}
+	location := ghUser.GetLocation()
+	if runes := []rune(location); len(runes) > maxLocationLength {
+		location = string(runes[:maxLocationLength])
+	}
+	filled, err := m.gtClient.FillUserProfile(gtUser, gitea.UserProfileOption{
+		Description: ghUser.GetBio(),
+		Website:     website,
+		Location:    location,
+	})
+	if err != nil {
+		m.logger.Warn("failed to migrate user profile", "login", gtUser.UserName, "error", err)
+		return
+	}
+	if len(filled) > 0 {
+		m.logger.Info("successfully migrated user profile", "login", gtUser.UserName, "fields", strings.Join(filled, ","))
+	}
+}
+
+// migrateUserKeys copies the SSH keys of a GitHub user to the Gitea account, and
+// returns how many of them the account has, -1 when they could not be listed.
+func (r *run) migrateUserKeys(ctx context.Context, login, username string) int {
+	// Retrieve the user's SSH keys from GitHub
+	sshKeys, err := r.ghClient.ListUserKeys(ctx, login)
+	if err != nil {
+		r.logger.Error("failed to get user ssh keys", "login", login, "error", err)
+		return -1
+	}
+
+	var (
+		successCount  int            // Number of successfully migrated keys
+		existCount    int            // Number of keys that already exist in Gitea
+		failedCount   int            // Number of failed key migrations
+		skippedCount  int            // Number of keys left out by the key policy
+		totalKeyCount = len(sshKeys) // Total number of keys to migrate
+	)
+
+	keep, skipped := selectKeys(sshKeys, r.plan.UserKeys)
+	for index, key := range sshKeys {
+		keyTitle := key.GetTitle()
+		if keyTitle == "" {
+			keyTitle = fmt.Sprintf("Migrate key-%d from %s", index, login)
+		}
+		keyItem := report.Item{Kind: report.KindKey, Name: login + "/" + keyTitle}
+		if reason, ok := skipped[key]; ok {
+			skippedCount++
+			r.logger.Info("skip ssh key", "login", login, "title", keyTitle, "reason", reason)
+			keyItem.Status = report.StatusSkipped
+			keyItem.Error = reason
+			r.rpt.Add(keyItem)
+			continue
+		}
+		keyStart := time.Now()
+		// Attempt to create the SSH key in Gitea
+		_, err := r.gtClient.CreateUserPublicKey(
+			username,
+			gitea.CreatePublicKeyOption{
+				Title: keyTitle,
+				Key:   key.GetKey(),
+			})
+		keyItem.Duration = report.Since(keyStart)
+		if err != nil {
+			// Check if the key already exists in Gitea
+			var giteaErr *gitea.GiteaError
+			if errors.As(err, &giteaErr) && giteaErr.Code == http.StatusUnprocessableEntity && keyUsed(giteaErr.Message) {
+				existCount++
+				r.logger.Info("ssh key already exists in gitea",
+					"login", login,
+					"title", keyTitle,
+				)
+				keyItem.Status = report.StatusSkipped
+				r.rpt.Add(keyItem)
+				continue
+			}
+			failedCount++
+			r.logger.Warn("failed to migrate ssh key",
+				"login", login,
+				"title", keyTitle,
+				"error", err,
+			)
+			keyItem.Status = report.StatusFailed
+			keyItem.Error = err.Error()
+			r.rpt.Add(keyItem)
+			continue
+		}
+		successCount++
+		keyItem.Status = report.StatusSuccess
+		r.rpt.Add(keyItem)
+		r.logger.Info("successfully migrated ssh key",
+			"login", login,
+			"title", keyTitle,
+		)
+	}
+
+	// Log the migration summary for this user
+	r.logger.Info("ssh key migration summary",
+		"login", login,
+		"total", totalKeyCount,
+		"success", successCount,
+		"exists", existCount,
+		"failed", failedCount,
+		"skipped", skippedCount,
+		"kept", len(keep),
+	)
+	return successCount + existCount
+}
+
+// migrateUserGPGKeys copies the GPG keys of a GitHub user to the Gitea account, so
+// the signatures of the migrated commits keep being verified. Gitea only verifies a
+// signature whose key holds an activated email of the user.
+func (r *run) migrateUserGPGKeys(ctx context.Context, login, username string) {
+	keys, err := r.ghClient.ListUserGPGKeys(ctx, login)
+	if err != nil {
+		r.logger.Error("failed to get user gpg keys", "login", login, "error", err)
+		return
+	}
+	for _, key := range keys {
+		item := report.Item{Kind: report.KindGPG, Name: login + "/" + key.GetKeyID(), Status: report.StatusSkipped}
+		if key.GetRawKey() == "" {
+			item.Error = "no armored key"
+			r.rpt.Add(item)
+			continue
+		}
+		start := time.Now()
+		err := r.gtClient.CreateUserGPGKey(username, key.GetRawKey())
+		var giteaErr *gitea.GiteaError
+		if errors.As(err, &giteaErr) && giteaErr.Code == http.StatusUnprocessableEntity && gpgKeyUsed(giteaErr.Message) {
+			item.Duration = report.Since(start)
+			r.rpt.Add(item)
+			continue
+		}
+		record(r.rpt, report.KindGPG, item.Name, start, err)
+		if err != nil {
+			r.logger.Warn("failed to migrate gpg key", "login", login, "key_id", key.GetKeyID(), "error", err)
+			continue
+		}
+		r.logger.Info("successfully migrated gpg key", "login", login, "key_id", key.GetKeyID())
+	}
+}
+
+// gpgKeyUsed checks if the Gitea error message indicates that the GPG key already exists.
+func gpgKeyUsed(msg string) bool {
+	return strings.Contains(strings.ToLower(msg), "already")
+}
+
+// keyUsed checks if the Gitea error message indicates that the SSH key already exists.
+func keyUsed(msg string) bool {
+	return strings.Contains(strings.ToLower(msg), "key content has been used")
+}
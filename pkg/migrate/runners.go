@@ -0,0 +1,64 @@
+package migrate
+
+import (
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// RunnerTokens records the Gitea Actions runner registration tokens of the migrated
+// organizations and repositories in an owner,repo,token CSV file only readable by its
+// owner, the repo being empty for an organization token.
+type RunnerTokens struct {
+	*secretsFile
+}
+
+// CreateRunnerTokens opens the runner tokens file at path, creating it with its header.
+func CreateRunnerTokens(path string) (*RunnerTokens, error) {
+	f, err := openSecretsFile(path, "owner", "repo", "token")
+	if err != nil {
+		return nil, err
+	}
+	return &RunnerTokens{f}, nil
+}
+
+// setupActions enables the Actions unit of a migrated repository with EnableActions
+// and records its runner registration token with RunnerTokens.
+func (r *run) setupActions(owner, name string) {
+	start := time.Now()
+	target := owner + "/" + name
+	if r.plan.EnableActions {
+		if _, err := r.gtClient.EditRepo(owner, name, gsdk.EditRepoOption{HasActions: gsdk.OptionalBool(true)}); err != nil {
+			record(r.rpt, report.KindRunner, target, start, err)
+			r.logger.Error("failed to enable gitea actions", "repo", target, "error", err)
+			return
+		}
+		r.logger.Info("gitea actions enabled", "repo", target)
+	}
+	if r.plan.RunnerTokens != nil && r.gtClient.Supports(gitea.FeatureRunnerTokens) {
+		if err := r.saveRunnerToken(owner, name); err != nil {
+			record(r.rpt, report.KindRunner, target, start, err)
+			return
+		}
+	}
+	record(r.rpt, report.KindRunner, target, start, nil)
+}
+
+// saveRunnerToken records the runner registration token of an organization, or of a
+// repository when name is set.
+func (r *run) saveRunnerToken(owner, name string) error {
+	token, err := r.gtClient.RunnerRegistrationToken(owner, name)
+	if err != nil {
+		r.logger.Error("failed to get gitea runner registration token", "owner", owner, "repo", name, "error", err)
+		return err
+	}
+	if err := r.plan.RunnerTokens.write(owner, name, token); err != nil {
+		r.logger.Error("failed to save runner registration token", "owner", owner, "repo", name, "error", err)
+		return err
+	}
+	r.logger.Info("runner registration token saved", "owner", owner, "repo", name)
+	return nil
+}
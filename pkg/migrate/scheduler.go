@@ -0,0 +1,98 @@
+package migrate
+
+import (
+	"context"
+	"sync"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/google/go-github/v71/github"
+)
+
+// scheduler limits the repository migrations running at once, both by count and
+// by the total size of the repositories in flight, so a few big repositories do
+// not saturate the disk and IO of the Gitea server.
+type scheduler struct {
+	workers  int
+	maxBytes int64
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	running  int
+	inflight int64
+}
+
+// newScheduler creates a scheduler running up to workers tasks whose sizes add up
+// to at most maxBytes, unlimited when zero. A task bigger than maxBytes runs alone.
+func newScheduler(workers int, maxBytes int64) *scheduler {
+	if workers < 1 {
+		workers = 1
+	}
+	s := &scheduler{workers: workers, maxBytes: maxBytes}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// acquire blocks until a task of the given size may start.
+func (s *scheduler) acquire(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.running >= s.workers ||
+		(s.running > 0 && s.maxBytes > 0 && s.inflight+size > s.maxBytes) {
+		s.cond.Wait()
+	}
+	s.running++
+	s.inflight += size
+}
+
+// release marks a task of the given size as finished.
+func (s *scheduler) release(size int64) {
+	s.mu.Lock()
+	s.running--
+	s.inflight -= size
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// forEachRepo calls fn for every repository in name order, running up to plan.Concurrency
+// calls at once while the size of the repositories in flight stays under plan.MaxInflightBytes.
+// No call starts while plan.Controls are paused, and the repositories skipped with them
+// are recorded for the Gitea owner instead.
+func (r *run) forEachRepo(ctx context.Context, repos []*github.Repository, owner string, fn func(repo *github.Repository)) {
+	sortRepos(repos)
+	for _, repo := range repos {
+		r.progress(Event{Type: EventRepoQueued, Source: repo.GetFullName()})
+	}
+	sched := newScheduler(r.plan.Concurrency, r.plan.MaxInflightBytes)
+	var wg sync.WaitGroup
+	for _, repo := range repos {
+		if r.plan.Controls.Paused() {
+			r.logger.Info("repository migrations paused, waiting to resume", "next", repo.GetFullName())
+		}
+		if err := r.plan.Controls.wait(ctx); err != nil {
+			break
+		}
+		if r.plan.Controls.skipped(repo.GetFullName()) {
+			r.skipRepo(repo, owner)
+			continue
+		}
+		// GitHub reports the repository size in kilobytes
+		size := int64(repo.GetSize()) * 1024
+		sched.acquire(size)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer sched.release(size)
+			fn(repo)
+		}()
+	}
+	wg.Wait()
+}
+
+// skipRepo records a repository skipped with the plan controls.
+func (r *run) skipRepo(repo *github.Repository, owner string) {
+	name := r.plan.RepoOverrides.Lookup(repo).target(repo)
+	r.logger.Info("skip repository", "repo", repo.GetFullName())
+	result := report.Repo{Owner: owner, Name: name, Source: repo.GetFullName(), Status: report.StatusSkipped, Error: "skipped during the run"}
+	r.rpt.AddRepo(result)
+	r.progress(Event{Type: EventRepoFinished, Owner: owner, Name: name, Source: repo.GetFullName(), Result: &result})
+}
@@ -0,0 +1,131 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+)
+
+// opensslIterations is the PBKDF2 iteration count of openssl enc -pbkdf2.
+const opensslIterations = 10000
+
+// errSecretsPassphrase is returned when a secrets file cannot be decrypted.
+var errSecretsPassphrase = errors.New("cannot decrypt secrets file, wrong passphrase or format")
+
+// LoadEncryptedSecretValues reads a secrets CSV file (repo,name,value) encrypted with
+//
+//	openssl enc -aes-256-cbc -pbkdf2 -salt -in secrets.csv -out secrets.csv.enc
+//
+// so secret values never have to be stored in plain text next to the tool.
+func LoadEncryptedSecretValues(path string, passphrase []byte) (SecretValues, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := opensslDecrypt(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return parseSecretValues(bytes.NewReader(plain))
+}
+
+// opensslDecrypt decrypts the salted AES-256-CBC format of openssl enc with a PBKDF2 derived key.
+func opensslDecrypt(data, passphrase []byte) ([]byte, error) {
+	const magic = "Salted__"
+	if len(data) < len(magic)+8+aes.BlockSize || string(data[:len(magic)]) != magic {
+		return nil, errSecretsPassphrase
+	}
+	salt := data[len(magic) : len(magic)+8]
+	encrypted := data[len(magic)+8:]
+	if len(encrypted)%aes.BlockSize != 0 {
+		return nil, errSecretsPassphrase
+	}
+
+	keyIV, err := pbkdf2.Key(sha256.New, string(passphrase), salt, opensslIterations, 32+aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(keyIV[:32])
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, keyIV[32:]).CryptBlocks(plain, encrypted)
+
+	// PKCS#7 padding, a wrong passphrase almost always breaks it
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, errSecretsPassphrase
+	}
+	for _, b := range plain[len(plain)-padding:] {
+		if int(b) != padding {
+			return nil, errSecretsPassphrase
+		}
+	}
+	return plain[:len(plain)-padding], nil
+}
+
+// applySecrets sets every secret of the secrets file on the Gitea organization or
+// repository its GitHub owner was migrated to, replacing existing values.
+func (r *run) applySecrets(ctx context.Context) {
+	_, span := trace.Start(ctx, "migrate.applySecrets")
+	defer span.End()
+	if !r.gtClient.Supports(gitea.FeatureActionsSecrets) {
+		return
+	}
+
+	sources := make([]string, 0, len(r.plan.SecretsFile))
+	for source := range r.plan.SecretsFile {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		owner, name, isRepo := strings.Cut(source, "/")
+		target := r.giteaOwner(owner)
+		if isRepo {
+			target += "/" + name
+		}
+		secrets := r.plan.SecretsFile[source]
+		names := make([]string, 0, len(secrets))
+		for secret := range secrets {
+			names = append(names, secret)
+		}
+		sort.Strings(names)
+		for _, secret := range names {
+			value := secrets[secret]
+			item := target + " " + secret
+			start := time.Now()
+			var err error
+			if isRepo {
+				err = r.gtClient.SetRepoActionSecret(r.giteaOwner(owner), name, secret, value)
+			} else {
+				err = r.gtClient.SetOrgActionSecret(target, secret, value)
+			}
+			if notFound(err) {
+				r.rpt.Add(report.Item{Kind: report.KindSecret, Name: item, Status: report.StatusSkipped, Error: "not migrated"})
+				continue
+			}
+			record(r.rpt, report.KindSecret, item, start, err)
+			if err != nil {
+				span.RecordError(err)
+				r.logger.Error("failed to set actions secret", "target", target, "secret", secret, "error", err)
+			}
+		}
+	}
+}
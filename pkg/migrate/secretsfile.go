@@ -0,0 +1,64 @@
+package migrate
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"os"
+	"sync"
+
+	"github.com/appleboy/github2gitea/pkg/redact"
+)
+
+// secretsFile is a CSV file receiving generated credentials, only readable by its
+// owner. Lines are appended, so the file keeps the credentials of earlier runs.
+type secretsFile struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *csv.Writer
+}
+
+// openSecretsFile opens the secrets file at path, creating it with the header.
+func openSecretsFile(path string, header ...string) (*secretsFile, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	s := &secretsFile{f: f, w: csv.NewWriter(f)}
+	info, err := f.Stat()
+	if err == nil && info.Size() == 0 {
+		err = s.write(header...)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// write appends a line and flushes it, so the credentials already created are never lost.
+func (s *secretsFile) write(fields ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Write(fields); err != nil {
+		return err
+	}
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// Close closes the secrets file.
+func (s *secretsFile) Close() error {
+	return s.f.Close()
+}
+
+// generateSecret returns a new random secret, masked in logs and reports from now on.
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	value := hex.EncodeToString(secret)
+	redact.Add(value)
+	return value, nil
+}
@@ -0,0 +1,134 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/trace"
+)
+
+// mergeMessageDir holds the Gitea default merge message templates of a repository.
+// The first line of a template is the commit title, the rest is the commit body.
+const mergeMessageDir = ".gitea/default_merge_message/"
+
+// Commit title and message settings of GitHub repositories.
+const (
+	githubPRTitle        = "PR_TITLE"
+	githubPRBody         = "PR_BODY"
+	githubMergeMessage   = "MERGE_MESSAGE"
+	githubCommitMessages = "COMMIT_MESSAGES"
+)
+
+// RepoSettingsOption selects the repository whose GitHub settings are applied to Gitea.
+type RepoSettingsOption struct {
+	// SourceOwner and SourceName identify the GitHub repository.
+	SourceOwner string
+	SourceName  string
+	// Owner and Name identify the migrated Gitea repository.
+	Owner string
+	Name  string
+	// Settings applies the default branch, the allowed merge styles, the branch
+	// deletion after merge and the issues, wiki and projects units.
+	Settings bool
+	// MergeMessageTemplates commits Gitea merge message templates matching the
+	// GitHub default merge and squash commit messages.
+	MergeMessageTemplates bool
+	// Override replaces the visibility and wiki unit of the GitHub repository.
+	Override RepoOverride
+}
+
+// MigrateRepoSettings applies the settings of a GitHub repository to the migrated Gitea repository.
+func (m *Migrator) MigrateRepoSettings(ctx context.Context, opts RepoSettingsOption) error {
+	ctx, span := trace.Start(ctx, "migrate.MigrateRepoSettings",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+
+	source, err := m.ghClient.GetRepo(ctx, opts.SourceOwner, opts.SourceName)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	source = opts.Override.apply(source)
+
+	if opts.Settings {
+		if _, err := m.applyRepoSettings(opts.Owner, opts.Name, source); err != nil {
+			span.RecordError(err)
+			return err
+		}
+		m.logger.Info("apply repo settings", "owner", opts.Owner, "name", opts.Name, "default_branch", source.GetDefaultBranch())
+	}
+
+	if opts.MergeMessageTemplates {
+		templates := mergeMessageTemplates(
+			source.GetMergeCommitTitle(), source.GetMergeCommitMessage(),
+			source.GetSquashMergeCommitTitle(), source.GetSquashMergeCommitMessage(),
+		)
+		for _, name := range []string{"MERGE_TEMPLATE.md", "SQUASH_TEMPLATE.md"} {
+			content, ok := templates[name]
+			if !ok {
+				continue
+			}
+			err := m.gtClient.CreateFile(gitea.CreateFileOption{
+				Owner:   opts.Owner,
+				Repo:    opts.Name,
+				Path:    mergeMessageDir + name,
+				Content: []byte(content),
+				Message: "Add default merge message template from GitHub settings",
+			})
+			var giteaErr *gitea.GiteaError
+			if errors.As(err, &giteaErr) && giteaErr.Code == http.StatusUnprocessableEntity {
+				m.logger.Info("merge message template already exists", "owner", opts.Owner, "name", opts.Name, "template", name)
+				continue
+			}
+			if err != nil {
+				span.RecordError(err)
+				return err
+			}
+			m.logger.Info("add merge message template", "owner", opts.Owner, "name", opts.Name, "template", name)
+		}
+	}
+
+	return nil
+}
+
+// mergeMessageTemplates maps the GitHub default commit messages of merge and squash
+// merges to Gitea merge message templates, keyed by file name. Settings matching the
+// Gitea defaults produce no template; empty settings mean the token could not read them.
+func mergeMessageTemplates(mergeTitle, mergeMessage, squashTitle, squashMessage string) map[string]string {
+	const prTitle = "${PullRequestTitle} (${PullRequestReference})"
+	templates := make(map[string]string)
+
+	// GitHub defaults to the merge message and the PR title, as Gitea does
+	if mergeTitle != "" && (mergeTitle != githubMergeMessage || mergeMessage != githubPRTitle) {
+		title := "Merge pull request '${PullRequestTitle}' (${PullRequestReference}) from ${HeadBranch} into ${BaseBranch}"
+		if mergeTitle == githubPRTitle {
+			title = prTitle
+		}
+		templates["MERGE_TEMPLATE.md"] = title + "\n\n" + templateBody(mergeMessage)
+	}
+
+	// Gitea squash commits list the squashed commits, the GitHub default;
+	// a template replaces that list with the PR description or nothing
+	if squashTitle != "" && squashMessage != githubCommitMessages {
+		templates["SQUASH_TEMPLATE.md"] = prTitle + "\n\n" + templateBody(squashMessage)
+	}
+
+	return templates
+}
+
+// templateBody returns the template variables of a GitHub commit message setting.
+func templateBody(message string) string {
+	switch message {
+	case githubPRBody:
+		return "${PullRequestDescription}\n"
+	case githubPRTitle:
+		return "${PullRequestTitle}\n"
+	default:
+		// BLANK
+		return ""
+	}
+}
@@ -0,0 +1,218 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	"golang.org/x/crypto/openpgp/armor"  //nolint:staticcheck
+	"golang.org/x/crypto/openpgp/packet" //nolint:staticcheck
+	"golang.org/x/crypto/ssh"
+)
+
+// signingCommits is the number of recent commits per repository whose signing keys are collected.
+const signingCommits = 300
+
+// signer is a key which signed verified commits, a GPG key id or an SSH key fingerprint.
+type signer struct {
+	login string
+	kind  string
+	id    string
+}
+
+// signers counts the verified commits of the migrated repositories by signing key.
+type signers struct {
+	mu      sync.Mutex
+	commits map[signer]int
+}
+
+// collectSigners reads the signatures of the recent verified commits of a repository.
+func (r *run) collectSigners(ctx context.Context, owner, name string) error {
+	commits, err := r.ghClient.ListRecentCommits(ctx, owner, name, signingCommits)
+	if err != nil {
+		return err
+	}
+	r.signers.mu.Lock()
+	defer r.signers.mu.Unlock()
+	if r.signers.commits == nil {
+		r.signers.commits = make(map[signer]int)
+	}
+	for _, commit := range commits {
+		verification := commit.GetCommit().GetVerification()
+		// the committer of commits made on the web is web-flow, the author signed them
+		login := commit.GetAuthor().GetLogin()
+		if !verification.GetVerified() || login == "" {
+			continue
+		}
+		kind, id, err := signatureKey(verification.GetSignature())
+		if err != nil {
+			r.logger.Debug("cannot read commit signature", "repo", owner+"/"+name, "sha", commit.GetSHA(), "error", err)
+			continue
+		}
+		r.signers.commits[signer{login: strings.ToLower(login), kind: kind, id: id}]++
+	}
+	return nil
+}
+
+// reportSigningKeys adds the keys which signed the verified commits to the report,
+// with the public key from the GitHub account of the signer, so Gitea admins can
+// trust them and the users upload them again. Keys no longer on the account are
+// reported without public key.
+func (r *run) reportSigningKeys(ctx context.Context) {
+	ctx, span := trace.Start(ctx, "migrate.reportSigningKeys")
+	defer span.End()
+
+	r.signers.mu.Lock()
+	byLogin := make(map[string][]signer)
+	counts := make(map[signer]int, len(r.signers.commits))
+	for s, count := range r.signers.commits {
+		byLogin[s.login] = append(byLogin[s.login], s)
+		counts[s] = count
+	}
+	r.signers.mu.Unlock()
+	logins := make([]string, 0, len(byLogin))
+	for login := range byLogin {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+
+	for _, login := range logins {
+		keys, err := r.accountSigningKeys(ctx, login)
+		if err != nil {
+			span.RecordError(err)
+			r.logger.Warn("failed to list github signing keys", "login", login, "error", err)
+		}
+		for _, s := range byLogin[login] {
+			r.rpt.AddSigningKeys(report.SigningKey{
+				User:      login,
+				Type:      s.kind,
+				ID:        s.id,
+				Commits:   counts[s],
+				PublicKey: keys[s.kind+":"+s.id],
+			})
+		}
+	}
+}
+
+// accountSigningKeys returns the public GPG and SSH signing keys of a GitHub user,
+// keyed by gpg:<key id>, subkeys included, and ssh:<fingerprint>.
+func (r *run) accountSigningKeys(ctx context.Context, login string) (map[string]string, error) {
+	keys := make(map[string]string)
+	gpgKeys, err := r.ghClient.ListUserGPGKeys(ctx, login)
+	if err != nil {
+		return keys, err
+	}
+	for _, key := range gpgKeys {
+		keys[report.SigningGPG+":"+strings.ToUpper(key.GetKeyID())] = key.GetRawKey()
+		for _, subkey := range key.Subkeys {
+			keys[report.SigningGPG+":"+strings.ToUpper(subkey.GetKeyID())] = key.GetRawKey()
+		}
+	}
+	sshKeys, err := r.ghClient.ListUserSSHSigningKeys(ctx, login)
+	if err != nil {
+		return keys, err
+	}
+	for _, key := range sshKeys {
+		public, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key.GetKey()))
+		if err != nil {
+			continue
+		}
+		keys[report.SigningSSH+":"+ssh.FingerprintSHA256(public)] = key.GetKey()
+	}
+	return keys, nil
+}
+
+// signatureKey returns the type and identifier of the key of an armored commit
+// signature: the issuer key id of an OpenPGP signature, or the fingerprint of the
+// public key embedded in an SSH signature.
+func signatureKey(signature string) (kind, id string, err error) {
+	switch {
+	case strings.Contains(signature, "BEGIN SSH SIGNATURE"):
+		data, err := dearmor(signature)
+		if err != nil {
+			return "", "", err
+		}
+		public, err := sshSignatureKey(data)
+		if err != nil {
+			return "", "", err
+		}
+		return report.SigningSSH, ssh.FingerprintSHA256(public), nil
+	case strings.Contains(signature, "BEGIN PGP SIGNATURE"):
+		issuer, err := pgpIssuer(signature)
+		if err != nil {
+			return "", "", err
+		}
+		return report.SigningGPG, issuer, nil
+	}
+	return "", "", errors.New("unsupported signature format")
+}
+
+// dearmor decodes the base64 body of an armored SSH signature.
+func dearmor(armored string) ([]byte, error) {
+	var body strings.Builder
+	inBody := false
+	for _, line := range strings.Split(armored, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "-----BEGIN"):
+			inBody = true
+		case strings.HasPrefix(line, "-----END"):
+			inBody = false
+		case !inBody, strings.Contains(line, ": "), strings.HasPrefix(line, "="):
+			// armor headers and checksum
+		default:
+			body.WriteString(line)
+		}
+	}
+	return base64.StdEncoding.DecodeString(body.String())
+}
+
+// sshSignatureKey returns the public key of an SSHSIG blob.
+func sshSignatureKey(data []byte) (ssh.PublicKey, error) {
+	const magic = "SSHSIG"
+	if !bytes.HasPrefix(data, []byte(magic)) || len(data) < len(magic)+8 {
+		return nil, errors.New("not an ssh signature")
+	}
+	// magic, uint32 version, then the public key as an ssh string
+	data = data[len(magic)+4:]
+	size := binary.BigEndian.Uint32(data)
+	if uint64(len(data)-4) < uint64(size) {
+		return nil, errors.New("truncated ssh signature")
+	}
+	return ssh.ParsePublicKey(data[4 : 4+size])
+}
+
+// pgpIssuer returns the issuer key id of an armored OpenPGP signature, in
+// uppercase hex as GitHub lists the key ids.
+func pgpIssuer(armored string) (string, error) {
+	block, err := armor.Decode(strings.NewReader(armored))
+	if err != nil {
+		return "", err
+	}
+	p, err := packet.Read(block.Body)
+	if err != nil {
+		return "", err
+	}
+	var issuer uint64
+	switch sig := p.(type) {
+	case *packet.Signature:
+		if sig.IssuerKeyId == nil {
+			return "", errors.New("signature without issuer")
+		}
+		issuer = *sig.IssuerKeyId
+	case *packet.SignatureV3:
+		issuer = sig.IssuerKeyId
+	default:
+		return "", errors.New("not a signature packet")
+	}
+	return fmt.Sprintf("%016X", issuer), nil
+}
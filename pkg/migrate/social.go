@@ -0,0 +1,65 @@
+package migrate
+
+import (
+	"context"
+	"time"
+)
+
+// socialQueue replays the star, watch and follow calls, made as every user through the
+// Gitea Sudo header, on a dedicated worker. The calls run in batches averaging at most
+// rate calls per second, so the social graph does not take the Gitea API throughput
+// the repository migrations need.
+type socialQueue struct {
+	calls chan func()
+	done  chan struct{}
+}
+
+// newSocialQueue starts the worker of a queue running batch calls at once, at most
+// rate calls per second on average, unlimited when zero.
+func newSocialQueue(ctx context.Context, rate float64, batch int) *socialQueue {
+	if batch < 1 {
+		batch = 1
+	}
+	q := &socialQueue{
+		calls: make(chan func(), 1024),
+		done:  make(chan struct{}),
+	}
+	go q.work(ctx, rate, batch)
+	return q
+}
+
+func (q *socialQueue) work(ctx context.Context, rate float64, batch int) {
+	defer close(q.done)
+	count := 0
+	start := time.Now()
+	for call := range q.calls {
+		// drain the queue once the run is canceled
+		if ctx.Err() != nil {
+			continue
+		}
+		call()
+		count++
+		if rate <= 0 || count < batch {
+			continue
+		}
+		// pause so the batch averages out at rate calls per second
+		if wait := time.Duration(float64(batch)/rate*float64(time.Second)) - time.Since(start); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+			}
+		}
+		count, start = 0, time.Now()
+	}
+}
+
+// add queues a call, blocking while the queue is full.
+func (q *socialQueue) add(call func()) {
+	q.calls <- call
+}
+
+// wait blocks until every queued call ran. No call may be added afterwards.
+func (q *socialQueue) wait() {
+	close(q.calls)
+	<-q.done
+}
@@ -0,0 +1,44 @@
+package migrate
+
+import (
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/google/go-github/v71/github"
+)
+
+// SuspendedPolicy decides how the users suspended on GitHub Enterprise Server are
+// migrated. GitHub.com does not expose suspensions, its users are always active.
+type SuspendedPolicy string
+
+const (
+	// SuspendedActive creates suspended users as regular active accounts.
+	SuspendedActive SuspendedPolicy = "active"
+	// SuspendedInactive creates suspended users as deactivated accounts, keeping their
+	// name, memberships and keys for a later activation.
+	SuspendedInactive SuspendedPolicy = "inactive"
+	// SuspendedSkip creates neither the suspended users nor their team memberships.
+	SuspendedSkip SuspendedPolicy = "skip"
+)
+
+// ValidSuspendedPolicy reports whether policy is a known SuspendedPolicy.
+func ValidSuspendedPolicy(policy string) bool {
+	switch SuspendedPolicy(policy) {
+	case SuspendedActive, SuspendedInactive, SuspendedSkip:
+		return true
+	}
+	return false
+}
+
+// skip reports whether a GitHub user is left out of the migration.
+func (p SuspendedPolicy) skip(user *github.User) bool {
+	return p == SuspendedSkip && user.SuspendedAt != nil
+}
+
+// inactive reports whether a GitHub user is created as a deactivated account.
+func (p SuspendedPolicy) inactive(user *github.User) bool {
+	return p == SuspendedInactive && user.SuspendedAt != nil
+}
+
+// skipSuspended records a suspended user left out of the migration.
+func skipSuspended(rpt *report.Report, login string) {
+	rpt.Add(report.Item{Kind: report.KindUser, Name: login, Status: report.StatusSkipped, Error: "suspended on github"})
+}
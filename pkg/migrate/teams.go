@@ -0,0 +1,199 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/appleboy/github2gitea/pkg/core"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	gsdk "code.gitea.io/sdk/gitea"
+	"github.com/google/go-github/v71/github"
+)
+
+// AssignTeamsOption selects the migrated repositories AssignTeamsToRepos grants the
+// teams access to.
+type AssignTeamsOption struct {
+	// Org is the Gitea organization of the teams and repositories.
+	Org string
+	// RepoTeams are the teams of each GitHub repository, from CreateNewOrgResult.
+	RepoTeams map[string][]*gsdk.Team
+	// RepoPermissions are the permissions of the teams on the repositories differing
+	// from their own, from CreateNewOrgResult.
+	RepoPermissions map[string]map[int64]string
+	// Repos maps the names of the migrated GitHub repositories to their Gitea names.
+	Repos map[string]string
+	// Overrides changes the permission of single teams on single repositories.
+	Overrides PermissionOverrides
+	// TeamUnits sets the per-unit permissions of the teams derived for the overrides.
+	TeamUnits TeamUnits
+}
+
+// AssignTeamsToRepos adds the teams recorded by CreateNewOrg to the migrated
+// repositories, to be called after MigrateNewRepo. Teams granted another permission
+// on a repository, on GitHub or by an override, are added through their derived team,
+// and left out with none. Failures of single teams do not stop the others and are
+// returned together.
+func (m *Migrator) AssignTeamsToRepos(ctx context.Context, opts AssignTeamsOption) error {
+	_, span := trace.Start(ctx, "migrate.AssignTeamsToRepos",
+		trace.String("gitea.org", opts.Org),
+	)
+	defer span.End()
+
+	var errs []error
+	for source, name := range opts.Repos {
+		for _, team := range opts.RepoTeams[source] {
+			// overrides name the GitHub repository, renamed in Gitea or not
+			permission, ok := opts.Overrides.Lookup(team.Name, source)
+			if !ok {
+				permission, ok = opts.RepoPermissions[source][team.ID]
+			}
+			if ok {
+				if permission == PermissionNone {
+					m.logger.Info("skip team on repo by override", "repo", name, "team", team.Name)
+					continue
+				}
+				derived, err := m.derivedTeam(opts.Org, team, permission, opts.TeamUnits)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("override team %s %s: %w", team.Name, permission, err))
+					continue
+				}
+				team = derived
+			}
+
+			if err := m.gtClient.AddTeamRepository(team.ID, opts.Org, name); err != nil {
+				errs = append(errs, fmt.Errorf("team %s on repo %s: %w", team.Name, name, err))
+				continue
+			}
+			m.logger.Info("added team to repo",
+				"org", opts.Org,
+				"repo", name,
+				"team", team.Name,
+			)
+		}
+	}
+	err := errors.Join(errs...)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+// derivedTeam returns the team created by OverrideTeam for a team and permission,
+// creating it once. Only the calls for the same team and permission wait for each
+// other, the Gitea requests are not made under derivedMu.
+func (m *Migrator) derivedTeam(org string, team *gsdk.Team, permission string, units TeamUnits) (*gsdk.Team, error) {
+	key := fmt.Sprintf("%d/%s", team.ID, permission)
+	m.derivedMu.Lock()
+	if m.derived == nil {
+		m.derived = make(map[string]*derivedEntry)
+	}
+	entry, ok := m.derived[key]
+	if !ok {
+		entry = &derivedEntry{}
+		m.derived[key] = entry
+	}
+	m.derivedMu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.team != nil {
+		return entry.team, nil
+	}
+	derived, err := m.OverrideTeam(org, team, permission, units)
+	if err != nil {
+		return nil, err
+	}
+	entry.team = derived
+	return derived, nil
+}
+
+// derivedEntry is a team derived for an override, nil until created.
+type derivedEntry struct {
+	mu   sync.Mutex
+	team *gsdk.Team
+}
+
+// repoAccess maps the GitHub permissions of a team on a repository to the
+// permissions of the overrides file.
+var repoAccess = map[string]string{
+	core.GitHubTeamPull:     core.GiteaRepoRead,
+	core.GitHubTeamTriage:   core.GitHubTeamTriage,
+	core.GitHubTeamPush:     core.GiteaRepoWrite,
+	core.GitHubTeamMaintain: core.GiteaRepoWrite,
+	core.GitHubTeamAdmin:    core.GiteaRepoAdmin,
+}
+
+// accessOrder lists the permissions of repoAccess from the least privileged.
+var accessOrder = []string{core.GiteaRepoRead, core.GitHubTeamTriage, core.GiteaRepoWrite, core.GiteaRepoAdmin}
+
+// roleNames maps the repository role names of GitHub to its team permissions.
+var roleNames = map[string]string{
+	"read":     core.GitHubTeamPull,
+	"triage":   core.GitHubTeamTriage,
+	"write":    core.GitHubTeamPush,
+	"maintain": core.GitHubTeamMaintain,
+	"admin":    core.GitHubTeamAdmin,
+}
+
+// repoPermission returns the permission of a team on a repository listed by
+// ListTeamReposBySlug, as permission of the overrides file. The permission of the
+// team itself is deprecated by GitHub and nearly always pull, the repositories
+// carry the permission actually granted.
+func repoPermission(repo *github.Repository) string {
+	for _, permission := range []string{
+		core.GitHubTeamAdmin,
+		core.GitHubTeamMaintain,
+		core.GitHubTeamPush,
+		core.GitHubTeamTriage,
+		core.GitHubTeamPull,
+	} {
+		if repo.GetPermissions()[permission] {
+			return repoAccess[permission]
+		}
+	}
+	if permission, ok := roleNames[repo.GetRoleName()]; ok {
+		return repoAccess[permission]
+	}
+	return core.GiteaRepoRead
+}
+
+// teamPermission returns the permission the most repositories of a team grant,
+// the lower one on a tie, which the Gitea team is created with. Read without
+// repositories.
+func teamPermission(ghRepos []*github.Repository) string {
+	counts := make(map[string]int, len(accessOrder))
+	for _, repo := range ghRepos {
+		counts[repoPermission(repo)]++
+	}
+	permission := core.GiteaRepoRead
+	for _, access := range accessOrder {
+		if counts[access] > counts[permission] {
+			permission = access
+		}
+	}
+	return permission
+}
+
+// addTeamRepos records the repositories of a team, and its permissions on them
+// differing from the permission the team was created with.
+func (r *CreateNewOrgResult) addTeamRepos(team *gsdk.Team, permission string, ghRepos []*github.Repository) {
+	if r.RepoTeams == nil {
+		r.RepoTeams = make(map[string][]*gsdk.Team)
+	}
+	if r.RepoPermissions == nil {
+		r.RepoPermissions = make(map[string]map[int64]string)
+	}
+	for _, ghRepo := range ghRepos {
+		name := ghRepo.GetName()
+		r.RepoTeams[name] = append(r.RepoTeams[name], team)
+		if access := repoPermission(ghRepo); access != permission {
+			if r.RepoPermissions[name] == nil {
+				r.RepoPermissions[name] = make(map[int64]string)
+			}
+			r.RepoPermissions[name][team.ID] = access
+		}
+	}
+}
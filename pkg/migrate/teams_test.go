@@ -0,0 +1,60 @@
+package migrate
+
+import (
+	"testing"
+
+	gsdk "code.gitea.io/sdk/gitea"
+	"github.com/google/go-github/v71/github"
+)
+
+func TestTeamPermissions(t *testing.T) {
+	repo := func(name, role string, permissions ...string) *github.Repository {
+		r := &github.Repository{Name: github.Ptr(name), RoleName: github.Ptr(role)}
+		if len(permissions) > 0 {
+			r.Permissions = make(map[string]bool)
+			for _, permission := range permissions {
+				r.Permissions[permission] = true
+			}
+		}
+		return r
+	}
+	repos := []*github.Repository{
+		repo("api", "", "pull", "triage", "push"),
+		repo("web", "", "pull", "triage", "push", "maintain"),
+		repo("docs", "", "pull"),
+		repo("ops", "admin"),
+		repo("wiki", "custom-role"),
+	}
+
+	for i, want := range []string{"write", "write", "read", "admin", "read"} {
+		if got := repoPermission(repos[i]); got != want {
+			t.Errorf("repoPermission(%s) = %q, want %q", repos[i].GetName(), got, want)
+		}
+	}
+	if got := teamPermission(repos[:4]); got != "write" {
+		t.Errorf("teamPermission = %q, want write", got)
+	}
+	// ties go to the lower permission
+	if got := teamPermission(repos[1:4]); got != "read" {
+		t.Errorf("teamPermission on a tie = %q, want read", got)
+	}
+	if got := teamPermission(nil); got != "read" {
+		t.Errorf("teamPermission without repos = %q, want read", got)
+	}
+
+	var result CreateNewOrgResult
+	team := &gsdk.Team{ID: 7, Name: "platform"}
+	result.addTeamRepos(team, "write", repos)
+	if len(result.RepoTeams["docs"]) != 1 || result.RepoTeams["docs"][0] != team {
+		t.Errorf("docs teams = %v", result.RepoTeams["docs"])
+	}
+	want := map[string]string{"docs": "read", "ops": "admin", "wiki": "read"}
+	if len(result.RepoPermissions) != len(want) {
+		t.Errorf("permissions recorded for %d repos, want %d", len(result.RepoPermissions), len(want))
+	}
+	for name, permission := range want {
+		if got := result.RepoPermissions[name][team.ID]; got != permission {
+			t.Errorf("permission on %s = %q, want %q", name, got, permission)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package migrate
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/appleboy/github2gitea/pkg/core"
+	"github.com/appleboy/github2gitea/pkg/gitea"
+
+	gsdk "code.gitea.io/sdk/gitea"
+)
+
+// teamUnitRoles maps the roles of the team units file, GitHub team permissions and
+// their names in the GitHub interface, to GitHub team permissions. Admin teams have
+// every unit in Gitea.
+var teamUnitRoles = map[string]string{
+	"read":                  core.GitHubTeamPull,
+	core.GitHubTeamPull:     core.GitHubTeamPull,
+	core.GitHubTeamTriage:   core.GitHubTeamTriage,
+	"write":                 core.GitHubTeamPush,
+	core.GitHubTeamPush:     core.GitHubTeamPush,
+	core.GitHubTeamMaintain: core.GitHubTeamMaintain,
+}
+
+// teamUnitPermissions are the permissions of a unit in the team units file.
+var teamUnitPermissions = []string{PermissionNone, core.GiteaRepoRead, core.GiteaRepoWrite}
+
+// TeamUnits maps GitHub team permissions to the Gitea per-unit permissions of the
+// teams created for them, keyed by GitHub permission and unit such as repo.code.
+type TeamUnits map[string]map[string]string
+
+// LoadTeamUnits reads a CSV file with a role,unit,permission header. Role is a GitHub
+// team permission: read, triage, write or maintain, or pull and push; unit a Gitea
+// repository unit such as code or repo.issues; and permission none, read or write.
+// The units not listed keep the access of the role.
+func LoadTeamUnits(path string) (TeamUnits, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	units := make(TeamUnits)
+	for index, rec := range records {
+		// Skip the header row
+		if index == 0 {
+			continue
+		}
+		if len(rec) < 3 {
+			return nil, fmt.Errorf("team units line %d: expected role,unit,permission", index+1)
+		}
+		role, ok := teamUnitRoles[strings.ToLower(strings.TrimSpace(rec[0]))]
+		if !ok {
+			return nil, fmt.Errorf("team units line %d: invalid role %q", index+1, rec[0])
+		}
+		unit := strings.ToLower(strings.TrimSpace(rec[1]))
+		if !strings.HasPrefix(unit, "repo.") {
+			unit = "repo." + unit
+		}
+		if !slices.Contains(core.DefaultUnits, gsdk.RepoUnitType(unit)) {
+			return nil, fmt.Errorf("team units line %d: invalid unit %q", index+1, rec[1])
+		}
+		permission := strings.ToLower(strings.TrimSpace(rec[2]))
+		if !slices.Contains(teamUnitPermissions, permission) {
+			return nil, fmt.Errorf("team units line %d: invalid permission %q", index+1, rec[2])
+		}
+		if units[role] == nil {
+			units[role] = make(map[string]string)
+		}
+		units[role][unit] = permission
+	}
+	return units, nil
+}
+
+// apply sets the per-unit permissions of the team permission. The read access of the
+// members team and of the read overrides uses the read role.
+func (u TeamUnits) apply(opts gitea.CreateTeamOption) gitea.CreateTeamOption {
+	if units, ok := u[opts.Permission]; ok {
+		opts.Units = units
+	}
+	return opts
+}
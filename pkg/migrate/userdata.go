@@ -0,0 +1,191 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+)
+
+// migrateUsersData migrates the gists of the users who opted in, and queues their
+// stars and watches. They are only created for repositories present in Gitea.
+func (r *run) migrateUsersData(ctx context.Context) {
+	for _, u := range r.plan.Users {
+		if !u.MigrateGists && !u.MigrateStars && !u.MigrateWatches {
+			continue
+		}
+		username := r.gtClient.Username(u.Login)
+		if _, err := r.gtClient.GetUser(username); err != nil {
+			r.logger.Error("gitea user not found, skip personal data", "login", u.Login, "error", err)
+			continue
+		}
+		if u.MigrateGists {
+			r.migrateUserGists(ctx, u.Login, username)
+		}
+		if u.MigrateStars {
+			r.migrateUserStars(ctx, u.Login, username)
+		}
+		if u.MigrateWatches {
+			r.migrateUserWatches(ctx, u.Login, username)
+		}
+	}
+}
+
+// queueUsersFollows queues the follows of the users who opted in. Follows only need
+// the users, so they are replayed while the repositories are migrated.
+func (r *run) queueUsersFollows(ctx context.Context) {
+	for _, u := range r.plan.Users {
+		if !u.MigrateFollows {
+			continue
+		}
+		username := r.gtClient.Username(u.Login)
+		if _, err := r.gtClient.GetUser(username); err != nil {
+			r.logger.Error("gitea user not found, skip follows", "login", u.Login, "error", err)
+			continue
+		}
+		r.migrateUserFollows(ctx, u.Login, username)
+	}
+}
+
+// migrateUserGists migrates every gist of a user as a repository named gist-<id> in the user account.
+func (r *run) migrateUserGists(ctx context.Context, login, username string) {
+	gists, err := r.ghClient.ListUserGists(ctx, login)
+	if err != nil {
+		r.logger.Error("failed to list github gists", "login", login, "error", err)
+		return
+	}
+	sortGists(gists)
+	for _, gist := range gists {
+		name := "gist-" + gist.GetID()
+		start := time.Now()
+		err := r.MigrateNewRepo(ctx, MigrateNewRepoOption{
+			Owner:          username,
+			Name:           name,
+			CloneAddr:      gist.GetGitPullURL(),
+			Description:    gist.GetDescription(),
+			Private:        !gist.GetPublic(),
+			AuthUsername:   r.ghUser.GetLogin(),
+			AuthToken:      r.plan.AuthToken,
+			Timeout:        r.plan.Timeout,
+			StallTimeout:   r.plan.StallTimeout,
+			Mirror:         r.plan.Mirror,
+			MirrorInterval: r.plan.MirrorInterval,
+			Sync:           r.plan.Sync,
+			OnDrift:        r.plan.OnDrift,
+			ConfirmDrift:   r.confirmDrift,
+			State:          r.plan.State,
+			Source:         gist.GetHTMLURL(),
+			UpdatedAt:      gist.GetUpdatedAt().Time,
+		})
+		if errors.Is(err, ErrUnchanged) {
+			r.rpt.Add(report.Item{Kind: report.KindGist, Name: username + "/" + name, Status: report.StatusSkipped})
+			continue
+		}
+		record(r.rpt, report.KindGist, username+"/"+name, start, err)
+		if err != nil {
+			r.logger.Error("failed to migrate gist", "login", login, "gist", gist.GetID(), "error", err)
+		}
+	}
+}
+
+// migrateUserStars stars the migrated repositories the user starred on GitHub.
+func (r *run) migrateUserStars(ctx context.Context, login, username string) {
+	repos, err := r.ghClient.ListStarred(ctx, login)
+	if err != nil {
+		r.logger.Error("failed to list github stars", "login", login, "error", err)
+		return
+	}
+	sortRepos(repos)
+	for _, repo := range repos {
+		owner := r.giteaOwner(repo.GetOwner().GetLogin())
+		name := username + " " + owner + "/" + repo.GetName()
+		r.social.add(func() {
+			start := time.Now()
+			err := r.gtClient.StarRepo(username, owner, repo.GetName())
+			if notFound(err) {
+				r.rpt.Add(report.Item{Kind: report.KindStar, Name: name, Status: report.StatusSkipped, Error: "repository not migrated"})
+				return
+			}
+			record(r.rpt, report.KindStar, name, start, err)
+			if err != nil {
+				r.logger.Error("failed to star repo", "login", login, "repo", owner+"/"+repo.GetName(), "error", err)
+			}
+		})
+	}
+}
+
+// migrateUserWatches watches the migrated repositories the user watches on GitHub.
+func (r *run) migrateUserWatches(ctx context.Context, login, username string) {
+	repos, err := r.ghClient.ListWatched(ctx, login)
+	if err != nil {
+		r.logger.Error("failed to list github watched repos", "login", login, "error", err)
+		return
+	}
+	sortRepos(repos)
+	for _, repo := range repos {
+		owner := r.giteaOwner(repo.GetOwner().GetLogin())
+		name := username + " " + owner + "/" + repo.GetName()
+		r.social.add(func() {
+			start := time.Now()
+			err := r.gtClient.WatchRepo(username, owner, repo.GetName())
+			if notFound(err) {
+				r.rpt.Add(report.Item{Kind: report.KindWatch, Name: name, Status: report.StatusSkipped, Error: "repository not migrated"})
+				return
+			}
+			record(r.rpt, report.KindWatch, name, start, err)
+			if err != nil {
+				r.logger.Error("failed to watch repo", "login", login, "repo", owner+"/"+repo.GetName(), "error", err)
+			}
+		})
+	}
+}
+
+// migrateUserFollows follows the Gitea accounts of the users the user follows on GitHub.
+func (r *run) migrateUserFollows(ctx context.Context, login, username string) {
+	users, err := r.ghClient.ListFollowing(ctx, login)
+	if err != nil {
+		r.logger.Error("failed to list github following", "login", login, "error", err)
+		return
+	}
+	sortUsers(users)
+	for _, user := range users {
+		target := r.gtClient.Username(user.GetLogin())
+		name := username + " " + target
+		r.social.add(func() {
+			start := time.Now()
+			err := r.gtClient.FollowUser(username, target)
+			if notFound(err) {
+				r.rpt.Add(report.Item{Kind: report.KindFollow, Name: name, Status: report.StatusSkipped, Error: "user not in gitea"})
+				return
+			}
+			record(r.rpt, report.KindFollow, name, start, err)
+			if err != nil {
+				r.logger.Error("failed to follow user", "login", login, "target", target, "error", err)
+			}
+		})
+	}
+}
+
+// giteaOwner returns the Gitea owner receiving the repositories of a GitHub owner in this run.
+// Repositories of other owners are expected in the account of the same user.
+func (r *run) giteaOwner(ghOwner string) string {
+	switch {
+	case r.plan.SourceOrg != "" && strings.EqualFold(ghOwner, r.plan.SourceOrg):
+		return r.plan.TargetOrg
+	case r.plan.SourceUser != "" && strings.EqualFold(ghOwner, r.plan.SourceUser):
+		if r.plan.TargetOrg != "" {
+			return r.plan.TargetOrg
+		}
+		return r.plan.TargetUser
+	}
+	return r.gtClient.Username(ghOwner)
+}
+
+func notFound(err error) bool {
+	var giteaErr *gitea.GiteaError
+	return errors.As(err, &giteaErr) && giteaErr.Code == http.StatusNotFound
+}
@@ -0,0 +1,130 @@
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// userEntry is a user of a JSON or YAML user list.
+type userEntry struct {
+	Login          string `json:"login" yaml:"login"`
+	Email          string `json:"email" yaml:"email"`
+	Role           string `json:"role" yaml:"role"`
+	Username       string `json:"username" yaml:"username"`
+	SourceID       *int64 `json:"source_id" yaml:"source_id"`
+	Admin          bool   `json:"admin" yaml:"admin"`
+	Password       string `json:"password" yaml:"password"`
+	MigrateGists   bool   `json:"migrate_gists" yaml:"migrate_gists"`
+	MigrateStars   bool   `json:"migrate_stars" yaml:"migrate_stars"`
+	MigrateWatches bool   `json:"migrate_watches" yaml:"migrate_watches"`
+	MigrateFollows bool   `json:"migrate_follows" yaml:"migrate_follows"`
+}
+
+// userListFile is a user list with the users under a users key.
+type userListFile struct {
+	Users []userEntry `json:"users" yaml:"users"`
+}
+
+// IsStructuredUserList reports whether the user list file at path is read as JSON or
+// YAML, by its extension, instead of CSV.
+func IsStructuredUserList(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// LoadUserList reads a JSON or YAML user list: a list of users, optionally under a
+// users key, with the login, email and role fields of the CSV user list, the
+// migrate_* opt-ins, and the per-user overrides username, source_id, admin and password.
+func LoadUserList(path string) ([]User, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []userEntry
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		entries, err = decodeYAMLUsers(data)
+	} else {
+		entries, err = decodeJSONUsers(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]User, 0, len(entries))
+	usernames := make(map[string]string)
+	for index, entry := range entries {
+		login := strings.TrimSpace(entry.Login)
+		if login == "" {
+			return nil, fmt.Errorf("user list entry %d: login is required", index+1)
+		}
+		username := strings.TrimSpace(entry.Username)
+		if username != "" {
+			if !usernamePattern.MatchString(username) {
+				return nil, fmt.Errorf("user list entry %d: %q is not a valid gitea username", index+1, username)
+			}
+			// two logins sharing an account would merge their memberships
+			if other, ok := usernames[strings.ToLower(username)]; ok && !strings.EqualFold(other, login) {
+				return nil, fmt.Errorf("user list entry %d: %s is already mapped from %s", index+1, username, other)
+			}
+			usernames[strings.ToLower(username)] = login
+		}
+		if entry.SourceID != nil && *entry.SourceID < 0 {
+			return nil, fmt.Errorf("user list entry %d: invalid source_id %d", index+1, *entry.SourceID)
+		}
+		users = append(users, User{
+			Login:          login,
+			Email:          strings.TrimSpace(entry.Email),
+			Role:           strings.TrimSpace(entry.Role),
+			Username:       username,
+			SourceID:       entry.SourceID,
+			Admin:          entry.Admin,
+			Password:       entry.Password,
+			MigrateGists:   entry.MigrateGists,
+			MigrateStars:   entry.MigrateStars,
+			MigrateWatches: entry.MigrateWatches,
+			MigrateFollows: entry.MigrateFollows,
+		})
+	}
+	return users, nil
+}
+
+// decodeJSONUsers decodes a JSON user list, an array or an object with a users key.
+func decodeJSONUsers(data []byte) ([]userEntry, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		var list userListFile
+		err := json.Unmarshal(data, &list)
+		return list.Users, err
+	}
+	var entries []userEntry
+	err := json.Unmarshal(data, &entries)
+	return entries, err
+}
+
+// decodeYAMLUsers decodes a YAML user list, a sequence or a mapping with a users key.
+func decodeYAMLUsers(data []byte) ([]userEntry, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	if doc.Content[0].Kind == yaml.MappingNode {
+		var list userListFile
+		err := doc.Decode(&list)
+		return list.Users, err
+	}
+	var entries []userEntry
+	err := doc.Decode(&entries)
+	return entries, err
+}
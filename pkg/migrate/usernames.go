@@ -0,0 +1,60 @@
+package migrate
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// usernamePattern matches the usernames Gitea accepts: letters, digits, dashes,
+// underscores and dots, starting and ending with a letter or digit, without
+// consecutive dashes, underscores or dots.
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9]+([-_.][a-zA-Z0-9]+)*$`)
+
+// LoadUsernames reads a CSV file with a login,username header mapping GitHub logins
+// to the Gitea usernames they are created as, for logins colliding with existing
+// Gitea accounts or not valid in Gitea. The map is keyed by lowercase login.
+func LoadUsernames(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	usernames := make(map[string]string)
+	logins := make(map[string]string)
+	for index, rec := range records {
+		// Skip the header row
+		if index == 0 {
+			continue
+		}
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("usernames line %d: expected login,username", index+1)
+		}
+		login := strings.ToLower(strings.TrimSpace(rec[0]))
+		username := strings.TrimSpace(rec[1])
+		if login == "" || username == "" {
+			return nil, fmt.Errorf("usernames line %d: login and username are required", index+1)
+		}
+		if !usernamePattern.MatchString(username) {
+			return nil, fmt.Errorf("usernames line %d: %q is not a valid gitea username", index+1, username)
+		}
+		// two logins sharing an account would merge their memberships
+		if other, ok := logins[strings.ToLower(username)]; ok && other != login {
+			return nil, fmt.Errorf("usernames line %d: %s is already mapped from %s", index+1, username, other)
+		}
+		logins[strings.ToLower(username)] = login
+		usernames[login] = username
+	}
+	return usernames, nil
+}
@@ -0,0 +1,199 @@
+package migrate
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	gsdk "code.gitea.io/sdk/gitea"
+	"github.com/appleboy/com/convert"
+	"github.com/google/go-github/v71/github"
+)
+
+// Check compares a value of a migrated repository with its GitHub source.
+type Check struct {
+	Name   string `json:"name"`
+	GitHub any    `json:"github"`
+	Gitea  any    `json:"gitea"`
+	OK     bool   `json:"ok"`
+}
+
+// RepoVerification is the result of VerifyRepo.
+type RepoVerification struct {
+	// Source and Target are the GitHub and Gitea full names.
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// Migrated reports whether the repository exists in Gitea.
+	Migrated bool `json:"migrated"`
+	// OK reports whether the repository is migrated and every check passed.
+	OK     bool    `json:"ok"`
+	Checks []Check `json:"checks,omitempty"`
+	// Error is set when the Gitea side could not be read completely.
+	Error string `json:"error,omitempty"`
+}
+
+// Failure describes why the verification failed, empty when it passed.
+func (v RepoVerification) Failure() string {
+	switch {
+	case v.OK:
+		return ""
+	case v.Error != "":
+		return v.Error
+	case !v.Migrated:
+		return "not migrated"
+	}
+	var failed []string
+	for _, check := range v.Checks {
+		if !check.OK {
+			failed = append(failed, check.Name)
+		}
+	}
+	return "checks failed: " + strings.Join(failed, ", ")
+}
+
+// OrgVerification is the result of VerifyOrg.
+type OrgVerification struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// OK reports whether every repository and team passed.
+	OK    bool               `json:"ok"`
+	Repos []RepoVerification `json:"repos"`
+	// MissingTeams are the GitHub teams without Gitea team.
+	MissingTeams []string `json:"missing_teams,omitempty"`
+}
+
+// VerifyRepo compares a migrated Gitea repository with its GitHub source: visibility,
+// archived flag, default branch, description, topics and the number of releases and
+// labels. Nothing is changed on either side. An error is returned when the GitHub
+// repository cannot be read.
+func (m *Migrator) VerifyRepo(ctx context.Context, sourceOwner, sourceName, owner, name string) (RepoVerification, error) {
+	ctx, span := trace.Start(ctx, "migrate.VerifyRepo",
+		trace.String("gitea.owner", owner),
+		trace.String("gitea.repo", name),
+	)
+	defer span.End()
+
+	ghRepo, err := m.ghClient.GetRepo(ctx, sourceOwner, sourceName)
+	if err != nil {
+		span.RecordError(err)
+		return RepoVerification{}, err
+	}
+	return m.verifyRepo(ctx, ghRepo, owner, name), nil
+}
+
+// verifyRepo compares a Gitea repository with a GitHub repository.
+func (m *Migrator) verifyRepo(ctx context.Context, ghRepo *github.Repository, owner, name string) RepoVerification {
+	result := RepoVerification{Source: ghRepo.GetFullName(), Target: owner + "/" + name}
+	repo, err := m.gtClient.GetRepo(owner, name)
+	if notFound(err) {
+		return result
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Migrated = true
+
+	check := func(field string, source, target any, ok bool) {
+		result.Checks = append(result.Checks, Check{Name: field, GitHub: source, Gitea: target, OK: ok})
+	}
+	check("private", ghRepo.GetPrivate(), repo.Private, ghRepo.GetPrivate() == repo.Private)
+	check("archived", ghRepo.GetArchived(), repo.Archived, ghRepo.GetArchived() == repo.Archived)
+	check("default_branch", ghRepo.GetDefaultBranch(), repo.DefaultBranch, ghRepo.GetDefaultBranch() == repo.DefaultBranch)
+	check("description", ghRepo.GetDescription(), repo.Description, ghRepo.GetDescription() == repo.Description)
+
+	var errs []string
+	// topics are not migrated to a gitea too old for them
+	if !m.gtClient.Supports(gitea.FeatureTopics) {
+		m.logger.Debug("skip topics check", "repo", owner+"/"+name)
+	} else if topics, err := m.gtClient.ListRepoTopics(owner, name); err != nil {
+		errs = append(errs, "topics: "+err.Error())
+	} else {
+		want := slices.Clone(ghRepo.Topics)
+		// the license topic is added by LicenseTopics
+		topics = slices.DeleteFunc(topics, func(topic string) bool {
+			return strings.HasPrefix(topic, licenseTopicPrefix) && !slices.Contains(want, topic)
+		})
+		slices.Sort(want)
+		slices.Sort(topics)
+		check("topics", want, topics, slices.Equal(want, topics))
+	}
+
+	sourceOwner := ghRepo.GetOwner().GetLogin()
+	ghReleases, err := m.ghClient.ListReleases(ctx, sourceOwner, ghRepo.GetName())
+	if err == nil {
+		var releases []*gsdk.Release
+		releases, err = m.gtClient.ListReleases(owner, name)
+		if err == nil {
+			check("releases", len(ghReleases), len(releases), len(ghReleases) == len(releases))
+		}
+	}
+	if err != nil {
+		errs = append(errs, "releases: "+err.Error())
+	}
+	ghLabels, err := m.ghClient.ListRepoLabels(ctx, sourceOwner, ghRepo.GetName())
+	if err == nil {
+		var labels []*gsdk.Label
+		labels, err = m.gtClient.ListRepoLabels(owner, name)
+		if err == nil {
+			check("labels", len(ghLabels), len(labels), len(ghLabels) == len(labels))
+		}
+	}
+	if err != nil {
+		errs = append(errs, "labels: "+err.Error())
+	}
+	result.Error = strings.Join(errs, "; ")
+
+	result.OK = result.Error == ""
+	for _, c := range result.Checks {
+		result.OK = result.OK && c.OK
+	}
+	return result
+}
+
+// VerifyOrg compares the repositories of a GitHub organization with the ones of the
+// same name in a Gitea organization, like VerifyRepo, and lists the GitHub teams
+// missing in Gitea. Repository overrides are not applied.
+func (m *Migrator) VerifyOrg(ctx context.Context, sourceOrg, targetOrg string) (OrgVerification, error) {
+	ctx, span := trace.Start(ctx, "migrate.VerifyOrg",
+		trace.String("github.org", sourceOrg),
+		trace.String("gitea.org", targetOrg),
+	)
+	defer span.End()
+
+	ghRepos, err := m.ghClient.ListOrgRepos(ctx, sourceOrg)
+	if err != nil {
+		span.RecordError(err)
+		return OrgVerification{}, err
+	}
+	ghTeams, err := m.ghClient.ListOrgTeams(ctx, sourceOrg)
+	if err != nil {
+		span.RecordError(err)
+		return OrgVerification{}, err
+	}
+	sortRepos(ghRepos)
+	sortTeams(ghTeams)
+
+	result := OrgVerification{Source: sourceOrg, Target: targetOrg, OK: true}
+	for _, ghRepo := range ghRepos {
+		repo := m.verifyRepo(ctx, ghRepo, targetOrg, ghRepo.GetName())
+		result.OK = result.OK && repo.OK
+		result.Repos = append(result.Repos, repo)
+	}
+	for _, ghTeam := range ghTeams {
+		name := invalidCharsRegex.ReplaceAllString(convert.FromPtr(ghTeam.Name), "_")
+		teams, err := m.gtClient.SearchOrgTeams(targetOrg, &gsdk.SearchTeamsOptions{Query: name})
+		if err != nil {
+			span.RecordError(err)
+			return OrgVerification{}, err
+		}
+		if !slices.ContainsFunc(teams, func(team *gsdk.Team) bool { return strings.EqualFold(team.Name, name) }) {
+			result.MissingTeams = append(result.MissingTeams, name)
+			result.OK = false
+		}
+	}
+	return result, nil
+}
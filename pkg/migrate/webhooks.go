@@ -0,0 +1,294 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+
+	gsdk "code.gitea.io/sdk/gitea"
+	"github.com/google/go-github/v71/github"
+)
+
+// SecretPlaceholder is set as the secret of migrated webhooks which had one on GitHub,
+// and as the value of migrated Actions secrets without a value in the secrets file.
+// GitHub never returns secret values, so the real ones have to be set again in Gitea.
+const SecretPlaceholder = "github2gitea-replace-me"
+
+// WebhookSecrets records the fresh secrets generated for the migrated webhooks in
+// a target,url,secret CSV file only readable by its owner, to configure them on the
+// receiving side. Lines are appended, so the file keeps the secrets of earlier runs.
+type WebhookSecrets struct {
+	*secretsFile
+}
+
+// CreateWebhookSecrets opens the webhook secrets file at path, creating it with its header.
+func CreateWebhookSecrets(path string) (*WebhookSecrets, error) {
+	f, err := openSecretsFile(path, "target", "url", "secret")
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookSecrets{f}, nil
+}
+
+// webhookEvents maps GitHub webhook events to the Gitea events closest to them.
+var webhookEvents = map[string][]string{
+	"create":                      {"create"},
+	"delete":                      {"delete"},
+	"fork":                        {"fork"},
+	"push":                        {"push"},
+	"issues":                      {"issues", "issue_assign", "issue_label", "issue_milestone"},
+	"issue_comment":               {"issue_comment", "pull_request_comment"},
+	"pull_request":                {"pull_request", "pull_request_assign", "pull_request_label", "pull_request_milestone", "pull_request_sync"},
+	"pull_request_review":         {"pull_request_review_approved", "pull_request_review_rejected"},
+	"pull_request_review_comment": {"pull_request_review_comment"},
+	"gollum":                      {"wiki"},
+	"repository":                  {"repository"},
+	"release":                     {"release"},
+}
+
+// URLRewrite replaces the From prefix of webhook URLs with To.
+type URLRewrite struct {
+	From string
+	To   string
+}
+
+// ParseURLRewrites parses comma separated from=to URL prefix rewrites.
+func ParseURLRewrites(value string) ([]URLRewrite, error) {
+	var rewrites []URLRewrite
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(item, "=")
+		if !ok || from == "" {
+			return nil, errors.New("invalid url rewrite, expected from=to: " + item)
+		}
+		rewrites = append(rewrites, URLRewrite{From: from, To: to})
+	}
+	return rewrites, nil
+}
+
+// rewriteURL applies the first rewrite matching the URL.
+func rewriteURL(url string, rewrites []URLRewrite) string {
+	for _, rewrite := range rewrites {
+		if strings.HasPrefix(url, rewrite.From) {
+			return rewrite.To + strings.TrimPrefix(url, rewrite.From)
+		}
+	}
+	return url
+}
+
+// WebhooksOption selects the repository whose GitHub webhooks are copied to Gitea.
+type WebhooksOption struct {
+	// SourceOwner and SourceName identify the GitHub repository.
+	SourceOwner string
+	SourceName  string
+	// Owner and Name identify the migrated Gitea repository.
+	Owner string
+	Name  string
+	// Rewrites replaces URL prefixes of the webhooks, e.g. to point to hosts reachable from Gitea.
+	Rewrites []URLRewrite
+	// Inactive creates the webhooks disabled, so they can be reviewed before they fire.
+	Inactive bool
+	// Secrets generates a fresh secret for the webhooks which had one on GitHub, instead
+	// of SecretPlaceholder, and records it.
+	Secrets *WebhookSecrets
+	// Report records every webhook when set.
+	Report *report.Report
+}
+
+// MigrateRepoWebhooks copies the webhooks of a GitHub repository to the migrated Gitea repository.
+// Webhooks whose URL already exists in Gitea are skipped.
+func (m *Migrator) MigrateRepoWebhooks(ctx context.Context, opts WebhooksOption) error {
+	ctx, span := trace.Start(ctx, "migrate.MigrateRepoWebhooks",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+
+	hooks, err := m.ghClient.ListRepoHooks(ctx, opts.SourceOwner, opts.SourceName)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	existing, err := m.gtClient.ListRepoHooks(opts.Owner, opts.Name)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	m.copyWebhooks(opts.Owner+"/"+opts.Name, hooks, existing, opts, func(hook gitea.CreateHookOption) error {
+		_, err := m.gtClient.CreateRepoHook(opts.Owner, opts.Name, hook)
+		return err
+	})
+	return nil
+}
+
+// MigrateOrgWebhooks copies the webhooks of a GitHub organization to the Gitea organization.
+// Only the SourceOwner, Owner, Rewrites, Inactive and Report options are used.
+// Webhooks whose URL already exists in Gitea are skipped.
+func (m *Migrator) MigrateOrgWebhooks(ctx context.Context, opts WebhooksOption) error {
+	ctx, span := trace.Start(ctx, "migrate.MigrateOrgWebhooks",
+		trace.String("github.org", opts.SourceOwner),
+		trace.String("gitea.org", opts.Owner),
+	)
+	defer span.End()
+
+	hooks, err := m.ghClient.ListOrgHooks(ctx, opts.SourceOwner)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	existing, err := m.gtClient.ListOrgHooks(opts.Owner)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	m.copyWebhooks(opts.Owner, hooks, existing, opts, func(hook gitea.CreateHookOption) error {
+		_, err := m.gtClient.CreateOrgHook(opts.Owner, hook)
+		return err
+	})
+	return nil
+}
+
+// copyWebhooks creates the GitHub webhooks missing from the existing Gitea webhooks
+// of target, a repository full name or an organization, with create.
+func (m *Migrator) copyWebhooks(
+	target string,
+	hooks []*github.Hook,
+	existing []*gsdk.Hook,
+	opts WebhooksOption,
+	create func(gitea.CreateHookOption) error,
+) {
+	urls := make(map[string]bool, len(existing))
+	for _, hook := range existing {
+		urls[hook.Config["url"]] = true
+	}
+
+	for _, hook := range hooks {
+		config := hook.GetConfig()
+		url := rewriteURL(config.GetURL(), opts.Rewrites)
+		name := target + " " + url
+		// only webhooks are copied, not GitHub services or app hooks
+		if url == "" {
+			continue
+		}
+		if urls[url] {
+			m.logger.Info("webhook already exists", "target", target, "url", url)
+			opts.Report.Add(report.Item{Kind: report.KindWebhook, Name: name, Status: report.StatusSkipped})
+			continue
+		}
+
+		events, unsupported := giteaEvents(hook.Events)
+		if len(unsupported) > 0 {
+			m.logger.Warn("webhook events not supported by gitea",
+				"target", target,
+				"url", url,
+				"events", strings.Join(unsupported, ","),
+			)
+		}
+		if len(events) == 0 {
+			opts.Report.Add(report.Item{
+				Kind:   report.KindWebhook,
+				Name:   name,
+				Status: report.StatusSkipped,
+				Error:  "no gitea equivalent for events " + strings.Join(unsupported, ","),
+			})
+			continue
+		}
+
+		start := time.Now()
+		secret := ""
+		var err error
+		if config.GetSecret() != "" {
+			secret = SecretPlaceholder
+			if opts.Secrets != nil {
+				secret, err = generateSecret()
+			}
+		}
+
+		if err == nil {
+			err = create(gitea.CreateHookOption{
+				URL:         url,
+				ContentType: config.GetContentType(),
+				Secret:      secret,
+				Events:      events,
+				Active:      hook.GetActive() && !opts.Inactive,
+			})
+		}
+		var saveErr error
+		if err == nil && secret != SecretPlaceholder && secret != "" {
+			saveErr = opts.Secrets.write(target, url, secret)
+		}
+		item := report.Item{Kind: report.KindWebhook, Name: name, Status: report.StatusSuccess, Duration: report.Since(start)}
+		switch {
+		case err != nil:
+			item.Status = report.StatusFailed
+			item.Error = err.Error()
+		case saveErr != nil:
+			// the webhook exists with a secret nobody knows
+			item.Status = report.StatusFailed
+			item.Error = "secret not saved: " + saveErr.Error()
+		case len(unsupported) > 0:
+			item.Error = "unsupported events dropped: " + strings.Join(unsupported, ",")
+		}
+		opts.Report.Add(item)
+		if err != nil {
+			m.logger.Error("failed to create webhook", "target", target, "url", url, "error", err)
+			continue
+		}
+		urls[url] = true
+		m.logger.Info("migrated webhook", "target", target, "url", url, "events", strings.Join(events, ","))
+		if secret == SecretPlaceholder {
+			m.logger.Warn("webhook secret set to a placeholder, update it in gitea", "target", target, "url", url)
+		}
+	}
+}
+
+// giteaEvents maps GitHub webhook events to Gitea events, returning the GitHub
+// events without a Gitea equivalent. The wildcard event maps to all events.
+func giteaEvents(events []string) (mapped, unsupported []string) {
+	seen := make(map[string]bool)
+	for _, event := range events {
+		targets, ok := webhookEvents[event]
+		if event == "*" {
+			targets, ok = allGiteaEvents(), true
+		}
+		if !ok {
+			unsupported = append(unsupported, event)
+			continue
+		}
+		for _, target := range targets {
+			if !seen[target] {
+				seen[target] = true
+				mapped = append(mapped, target)
+			}
+		}
+	}
+	return mapped, unsupported
+}
+
+func allGiteaEvents() []string {
+	var events []string
+	for _, targets := range webhookEvents {
+		events = append(events, targets...)
+	}
+	sort.Strings(events)
+	return events
+}
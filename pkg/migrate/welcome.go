@@ -0,0 +1,119 @@
+package migrate
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+)
+
+// Mailer sends a welcome email to the users created in Gitea, with their username,
+// the Gitea URL, how to sign in and the state of their SSH keys.
+type Mailer struct {
+	// Addr is the host:port of the SMTP server, upgraded with STARTTLS when offered.
+	Addr string
+	// Username and Password authenticate with the SMTP server when Username is set.
+	Username string
+	Password string
+	// From is the sender address, e.g. Gitea Migration <gitea@example.com>.
+	From string
+	// URL is the Gitea URL given to the users.
+	URL string
+}
+
+// welcome is the content of the welcome email of a created user.
+type welcome struct {
+	Login    string
+	Username string
+	Email    string
+	// Source is the Gitea authentication source the user signs in through, 0 for a
+	// local account.
+	Source int64
+	// Password is set when the local account got an initial password, which the
+	// email leaves out: the administrators hand it out from the passwords file or
+	// user list.
+	Password bool
+	// Keys is the number of SSH keys of the user in Gitea, -1 when not migrated.
+	Keys int
+}
+
+// message renders the email to a user.
+func (m *Mailer) message(w welcome, from, to *mail.Address) []byte {
+	url := strings.TrimSuffix(m.URL, "/")
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello %s,\r\n\r\n", w.Login)
+	fmt.Fprintf(&body, "your GitHub account %s was migrated to Gitea at %s.\r\n\r\n", w.Login, url)
+	fmt.Fprintf(&body, "Username: %s\r\n", w.Username)
+	switch {
+	case w.Source > 0:
+		fmt.Fprintf(&body, "Sign in: with the login %s and the password of your existing account\r\n", w.Login)
+	case w.Password:
+		body.WriteString("Password: ask your Gitea administrator for the initial one, to be changed at your first sign-in\r\n")
+	default:
+		fmt.Fprintf(&body, "Password: choose one at %s/user/forgot_password\r\n", url)
+	}
+	switch {
+	case w.Keys < 0:
+		fmt.Fprintf(&body, "SSH keys: not migrated, add them at %s/user/settings/keys\r\n", url)
+	case w.Keys == 0:
+		fmt.Fprintf(&body, "SSH keys: none found on GitHub, add them at %s/user/settings/keys\r\n", url)
+	default:
+		fmt.Fprintf(&body, "SSH keys: %d copied from GitHub\r\n", w.Keys)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", to.String())
+	msg.WriteString("Subject: Your Gitea account\r\n")
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(body.String())
+	return []byte(msg.String())
+}
+
+// send delivers the welcome email of a user.
+func (m *Mailer) send(w welcome) error {
+	to, err := mail.ParseAddress(w.Email)
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, from.Address, []string{to.Address}, m.message(w, from, to))
+}
+
+// sendWelcome emails a created user with the mailer, nothing when nil, and records the
+// result in the report.
+func (m *Migrator) sendWelcome(mailer *Mailer, rpt *report.Report, w welcome) {
+	if mailer == nil {
+		return
+	}
+	if w.Email == "" {
+		m.logger.Warn("no email address, skip welcome email", "login", w.Login)
+		rpt.Add(report.Item{Kind: report.KindWelcome, Name: w.Login, Status: report.StatusSkipped, Error: "no email address"})
+		return
+	}
+	start := time.Now()
+	err := mailer.send(w)
+	record(rpt, report.KindWelcome, w.Login, start, err)
+	if err != nil {
+		m.logger.Error("failed to send welcome email", "login", w.Login, "email", w.Email, "error", err)
+		return
+	}
+	m.logger.Info("welcome email sent", "login", w.Login, "email", w.Email)
+}
@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a daily time range, in minutes since midnight local time, allowed for the
+// repository migrations. It ends on the next day when To is before From.
+type Window struct {
+	From int
+	To   int
+}
+
+// Windows are the daily time ranges the repository migrations may start in, any time
+// when empty. The other work of a run, such as teams, metadata and unchanged
+// repositories in sync mode, does not wait for them.
+type Windows []Window
+
+// ParseWindows parses a comma separated list of HH:MM-HH:MM windows, e.g. 22:00-06:00.
+func ParseWindows(s string) (Windows, error) {
+	var windows Windows
+	for value := range strings.SplitSeq(s, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(value, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration window %q, expected HH:MM-HH:MM", value)
+		}
+		start, err := time.Parse("15:04", strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration window %q, expected HH:MM-HH:MM", value)
+		}
+		end, err := time.Parse("15:04", strings.TrimSpace(to))
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration window %q, expected HH:MM-HH:MM", value)
+		}
+		window := Window{From: start.Hour()*60 + start.Minute(), To: end.Hour()*60 + end.Minute()}
+		if window.From == window.To {
+			return nil, fmt.Errorf("empty migration window %q", value)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// timeOfDay returns the time minutes after the midnight of the day of t.
+func timeOfDay(t time.Time, minutes int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, minutes, 0, 0, t.Location())
+}
+
+// Open reports whether t is in one of the windows.
+func (w Windows) Open(t time.Time) bool {
+	if len(w) == 0 {
+		return true
+	}
+	minutes := t.Hour()*60 + t.Minute()
+	for _, window := range w {
+		if window.From < window.To {
+			if minutes >= window.From && minutes < window.To {
+				return true
+			}
+		} else if minutes >= window.From || minutes < window.To {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the next time one of the windows opens after t.
+func (w Windows) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, window := range w {
+		start := timeOfDay(t, window.From)
+		if !start.After(t) {
+			start = timeOfDay(t.AddDate(0, 0, 1), window.From)
+		}
+		if next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+	return next
+}
+
+// wait blocks until one of the windows is open, or the context is done.
+func (w Windows) wait(ctx context.Context) error {
+	for now := time.Now(); !w.Open(now); now = time.Now() {
+		timer := time.NewTimer(time.Until(w.Next(now)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
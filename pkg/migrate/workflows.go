@@ -0,0 +1,413 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/gitea"
+	"github.com/appleboy/github2gitea/pkg/report"
+	"github.com/appleboy/github2gitea/pkg/trace"
+)
+
+// workflowDir holds the GitHub Actions workflows, which Gitea Actions also runs.
+const workflowDir = ".github/workflows"
+
+// WorkflowBranch is the branch the converted workflows are committed to.
+const WorkflowBranch = "github2gitea/workflows"
+
+// artifactActions maps the v4 artifact actions to v3, for the Gitea servers
+// without the v4 artifact service.
+var artifactActions = map[string]string{
+	"actions/upload-artifact@v4":   "actions/upload-artifact@v3",
+	"actions/download-artifact@v4": "actions/download-artifact@v3",
+}
+
+// unsupportedActions are the action prefixes depending on GitHub services.
+var unsupportedActions = []string{
+	"github/codeql-action/",
+	"actions/dependency-review-action",
+	"actions/attest-build-provenance",
+	"actions/configure-pages",
+	"actions/upload-pages-artifact",
+	"actions/deploy-pages",
+}
+
+// unsupportedKeys are the workflow keys Gitea Actions ignores.
+var unsupportedKeys = []string{
+	"permissions",
+	"environment",
+	"continue-on-error",
+	"timeout-minutes",
+}
+
+// unsupportedContexts are the expressions which do not work against the Gitea API.
+var unsupportedContexts = [][2]string{
+	{"github.graphql_url", "gitea has no graphql api"},
+	{"api.github.com", "calls the github api"},
+}
+
+var (
+	usesPattern   = regexp.MustCompile(`^(\s*(?:-\s*)?uses:\s*)(["']?)([^"'\s#]+)(["']?)(.*)$`)
+	runsOnPattern = regexp.MustCompile(`^(\s*(?:-\s*)?runs-on:\s*)([^#]*?)(\s*(?:#.*)?)$`)
+	keyPattern    = regexp.MustCompile(`^\s*(?:-\s*)?([a-z-]+):`)
+	// contextPattern matches the secrets and vars contexts of expressions.
+	contextPattern = regexp.MustCompile(`\b(secrets|vars)\.([A-Za-z_][A-Za-z0-9_]*)`)
+	// environmentPattern matches the environment of a job, inline or as a mapping.
+	environmentPattern = regexp.MustCompile(`^\s*environment:\s*(.*?)\s*(?:#.*)?$`)
+	namePattern        = regexp.MustCompile(`^\s*name:\s*(.*?)\s*(?:#.*)?$`)
+)
+
+// ParseRunnerLabels parses a comma separated list of from=to runner label mappings.
+func ParseRunnerLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid runner label mapping %q, expected from=to", pair)
+		}
+		labels[from] = to
+	}
+	return labels, nil
+}
+
+// convertWorkflow rewrites the known incompatibilities of a workflow line by line,
+// keeping its formatting and comments, the actions of the actions map, and the
+// secrets and variables of the jobs deployed to envs to their prefixed names. It
+// returns the converted workflow, the rewrites and what could not be converted.
+func convertWorkflow(content string, actions, labels map[string]string, envs []environment) (string, []string, []string) {
+	var changes, unsupported []string
+	lines := strings.Split(content, "\n")
+	jobEnvs := jobEnvironments(lines)
+	for i, line := range lines {
+		at := fmt.Sprintf("line %d: ", i+1)
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		if m := usesPattern.FindStringSubmatch(line); m != nil {
+			action := m[3]
+			if to, ok := actions[action]; ok {
+				lines[i] = m[1] + m[2] + to + m[4] + m[5]
+				changes = append(changes, at+action+" → "+to)
+			}
+			for _, prefix := range unsupportedActions {
+				if strings.HasPrefix(action, prefix) {
+					unsupported = append(unsupported, at+action+" is not supported")
+				}
+			}
+		}
+
+		if m := runsOnPattern.FindStringSubmatch(line); m != nil && len(labels) > 0 {
+			if value, changed := rewriteRunsOn(m[2], labels); changed {
+				lines[i] = m[1] + value + m[3]
+				changes = append(changes, at+"runs-on "+strings.TrimSpace(m[2])+" → "+value)
+			}
+		}
+
+		if m := keyPattern.FindStringSubmatch(line); m != nil {
+			for _, key := range unsupportedKeys {
+				if m[1] == key {
+					unsupported = append(unsupported, at+key+" is ignored by gitea")
+				}
+			}
+		}
+
+		if env, ok := findEnvironment(envs, jobEnvs[i]); ok {
+			lines[i] = contextPattern.ReplaceAllStringFunc(lines[i], func(ref string) string {
+				m := contextPattern.FindStringSubmatch(ref)
+				if !env.has(m[1], m[2]) {
+					return ref
+				}
+				to := m[1] + "." + env.prefix + m[2]
+				changes = append(changes, at+ref+" → "+to+" (environment "+env.name+")")
+				return to
+			})
+		}
+
+		for _, expr := range unsupportedContexts {
+			if strings.Contains(line, expr[0]) {
+				unsupported = append(unsupported, at+expr[0]+" "+expr[1])
+			}
+		}
+	}
+	return strings.Join(lines, "\n"), changes, unsupported
+}
+
+// jobEnvironments returns the lowercase environment of the job of every line of a
+// workflow, empty outside of jobs and for jobs without environment or with one set by
+// an expression.
+func jobEnvironments(lines []string) []string {
+	envs := make([]string, len(lines))
+	inJobs := false
+	jobIndent, keyIndent, start := -1, -1, -1
+	env := ""
+	finish := func(end int) {
+		for i := start; i >= 0 && i < end; i++ {
+			envs[i] = env
+		}
+		start, keyIndent, env = -1, -1, ""
+	}
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent == 0 {
+			finish(i)
+			inJobs = strings.HasPrefix(trimmed, "jobs:")
+			jobIndent = -1
+			continue
+		}
+		if !inJobs {
+			continue
+		}
+		if jobIndent < 0 {
+			jobIndent = indent
+		}
+		if indent == jobIndent {
+			finish(i)
+			start = i
+			continue
+		}
+		if start < 0 {
+			continue
+		}
+		if keyIndent < 0 {
+			keyIndent = indent
+		}
+		if m := environmentPattern.FindStringSubmatch(line); m != nil && indent == keyIndent && env == "" {
+			value := m[1]
+			if value == "" && i+1 < len(lines) {
+				// environment: followed by name: on the next line
+				if n := namePattern.FindStringSubmatch(lines[i+1]); n != nil {
+					value = n[1]
+				}
+			}
+			if !strings.Contains(value, "${{") {
+				env = strings.ToLower(strings.Trim(value, `"'`))
+			}
+		}
+	}
+	finish(len(lines))
+	return envs
+}
+
+// findEnvironment returns the environment of the given lowercase name.
+func findEnvironment(envs []environment, name string) (environment, bool) {
+	if name == "" {
+		return environment{}, false
+	}
+	for _, env := range envs {
+		if strings.ToLower(env.name) == name {
+			return env, true
+		}
+	}
+	return environment{}, false
+}
+
+// rewriteRunsOn maps the runner labels of a runs-on value, a single label or a
+// flow sequence. Expressions are kept.
+func rewriteRunsOn(value string, labels map[string]string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "${{") {
+		return value, false
+	}
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		items := strings.Split(value[1:len(value)-1], ",")
+		changed := false
+		for i, item := range items {
+			label := strings.Trim(strings.TrimSpace(item), `"'`)
+			if to, ok := labels[label]; ok {
+				items[i] = to
+				changed = true
+			} else {
+				items[i] = strings.TrimSpace(item)
+			}
+		}
+		return "[" + strings.Join(items, ", ") + "]", changed
+	}
+	if to, ok := labels[strings.Trim(value, `"'`)]; ok {
+		return to, true
+	}
+	return value, false
+}
+
+// WorkflowsOption selects the migrated repository whose workflows are converted.
+type WorkflowsOption struct {
+	// SourceOwner, SourceName and SourceID identify the GitHub repository.
+	SourceOwner string
+	SourceName  string
+	SourceID    int64
+	// Owner and Name identify the migrated Gitea repository.
+	Owner string
+	Name  string
+	// RunnerLabels maps the GitHub runner labels to Gitea runner labels.
+	RunnerLabels map[string]string
+	// DowngradeArtifacts rewrites the v4 artifact actions to v3 when the Gitea
+	// server does not support v4.
+	DowngradeArtifacts bool
+	// Environments renames the secrets and variables of the jobs deployed to these
+	// environments like MigrateRepoActions, optional.
+	Environments EnvironmentPrefixes
+	// Report records every workflow when set.
+	Report *report.Report
+}
+
+// ConvertRepoWorkflows scans the GitHub Actions workflows of a migrated repository,
+// commits the rewritten ones to WorkflowBranch and opens a pull request listing the
+// changes and what could not be converted. Nothing is committed when no workflow
+// needs a rewrite, or when WorkflowBranch exists from an earlier run.
+func (m *Migrator) ConvertRepoWorkflows(ctx context.Context, opts WorkflowsOption) error {
+	ctx, span := trace.Start(ctx, "migrate.ConvertRepoWorkflows",
+		trace.String("gitea.owner", opts.Owner),
+		trace.String("gitea.repo", opts.Name),
+	)
+	defer span.End()
+
+	fullName := opts.Owner + "/" + opts.Name
+	entries, err := m.gtClient.ListContents(opts.Owner, opts.Name, workflowDir)
+	if notFound(err) {
+		return nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	envs, err := m.repoEnvironments(ctx, opts.SourceOwner, opts.SourceName, opts.SourceID, opts.Environments)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	var actions map[string]string
+	if opts.DowngradeArtifacts && !m.gtClient.Supports(gitea.FeatureArtifactsV4) {
+		actions = artifactActions
+	}
+
+	type workflow struct {
+		path, sha, content   string
+		changes, unsupported []string
+	}
+	var workflows []workflow
+	for _, entry := range entries {
+		if entry.Type != "file" || (path.Ext(entry.Name) != ".yml" && path.Ext(entry.Name) != ".yaml") {
+			continue
+		}
+		data, err := m.gtClient.GetFile(opts.Owner, opts.Name, entry.Path)
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+		content, changes, unsupported := convertWorkflow(string(data), actions, opts.RunnerLabels, envs)
+		workflows = append(workflows, workflow{entry.Path, entry.SHA, content, changes, unsupported})
+	}
+	sort.Slice(workflows, func(i, j int) bool { return workflows[i].path < workflows[j].path })
+
+	changed := 0
+	for _, w := range workflows {
+		if len(w.changes) > 0 {
+			changed++
+		}
+	}
+	if changed == 0 {
+		for _, w := range workflows {
+			opts.Report.Add(report.Item{
+				Kind:   report.KindWorkflow,
+				Name:   fullName + " " + w.path,
+				Status: report.StatusSkipped,
+				Error:  strings.Join(w.unsupported, "; "),
+			})
+		}
+		return nil
+	}
+
+	repo, err := m.gtClient.GetRepo(opts.Owner, opts.Name)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	err = m.gtClient.CreateBranch(opts.Owner, opts.Name, WorkflowBranch, repo.DefaultBranch)
+	var giteaErr *gitea.GiteaError
+	if errors.As(err, &giteaErr) && giteaErr.Code == http.StatusConflict {
+		m.logger.Info("workflow branch already exists, skip conversion", "repo", fullName, "branch", WorkflowBranch)
+		return nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	var body strings.Builder
+	body.WriteString("Rewrites the GitHub Actions workflows for Gitea Actions.\n")
+	for _, w := range workflows {
+		start := time.Now()
+		if len(w.changes) > 0 {
+			err = m.gtClient.UpdateFile(gitea.UpdateFileOption{
+				Owner:   opts.Owner,
+				Repo:    opts.Name,
+				Path:    w.path,
+				Content: []byte(w.content),
+				SHA:     w.sha,
+				Branch:  WorkflowBranch,
+				Message: "Convert " + w.path + " for Gitea Actions",
+			})
+			if err != nil {
+				m.logger.Error("failed to commit converted workflow", "repo", fullName, "workflow", w.path, "error", err)
+			}
+		}
+		item := report.Item{Kind: report.KindWorkflow, Name: fullName + " " + w.path, Status: report.StatusSuccess, Duration: report.Since(start)}
+		switch {
+		case err != nil:
+			item.Status = report.StatusFailed
+			item.Error = err.Error()
+		case len(w.changes) == 0:
+			item.Status = report.StatusSkipped
+		}
+		if len(w.unsupported) > 0 && err == nil {
+			item.Error = strings.Join(w.unsupported, "; ")
+		}
+		opts.Report.Add(item)
+		err = nil
+
+		if len(w.changes) == 0 && len(w.unsupported) == 0 {
+			continue
+		}
+		fmt.Fprintf(&body, "\n### `%s`\n\n", w.path)
+		for _, change := range w.changes {
+			fmt.Fprintf(&body, "- %s\n", change)
+		}
+		for _, note := range w.unsupported {
+			fmt.Fprintf(&body, "- [ ] %s\n", note)
+		}
+	}
+	body.WriteString("\nUnchecked items could not be converted and need a manual review.\n")
+
+	pr, err := m.gtClient.CreatePullRequest(gitea.CreatePullRequestOption{
+		Owner: opts.Owner,
+		Repo:  opts.Name,
+		Head:  WorkflowBranch,
+		Base:  repo.DefaultBranch,
+		Title: "Convert GitHub Actions workflows for Gitea Actions",
+		Body:  body.String(),
+	})
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	m.logger.Info("open workflow conversion pull request", "repo", fullName, "workflows", changed, "url", pr.HTMLURL)
+	return nil
+}
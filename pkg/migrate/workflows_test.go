@@ -0,0 +1,72 @@
+package migrate
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestJobEnvironments(t *testing.T) {
+	lines := strings.Split(`on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+  deploy:
+    environment: Production
+    runs-on: ubuntu-latest
+  release:
+    environment:
+      name: staging
+      url: https://staging.example.com
+  preview:
+    environment: ${{ inputs.env }}`, "\n")
+
+	want := []string{
+		"", "",
+		"", "", "", "",
+		"production", "production", "production",
+		"staging", "staging", "staging", "staging",
+		"", "",
+	}
+	if got := jobEnvironments(lines); !slices.Equal(got, want) {
+		t.Errorf("jobEnvironments =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestConvertWorkflow(t *testing.T) {
+	content := `jobs:
+  build:
+    runs-on: [self-hosted, "linux"] # runner
+    permissions:
+      contents: read
+    steps:
+      # - uses: actions/upload-artifact@v4
+      - uses: actions/upload-artifact@v4
+      - uses: github/codeql-action/init@v3`
+	got, changes, unsupported := convertWorkflow(content, artifactActions, map[string]string{"linux": "ubuntu-22.04"}, nil)
+
+	want := `jobs:
+  build:
+    runs-on: [self-hosted, ubuntu-22.04] # runner
+    permissions:
+      contents: read
+    steps:
+      # - uses: actions/upload-artifact@v4
+      - uses: actions/upload-artifact@v3
+      - uses: github/codeql-action/init@v3`
+	if got != want {
+		t.Errorf("converted =\n%s\nwant\n%s", got, want)
+	}
+	if len(changes) != 2 {
+		t.Errorf("changes = %q, want the runs-on and the artifact action", changes)
+	}
+	wantUnsupported := []string{
+		"line 4: permissions is ignored by gitea",
+		"line 9: github/codeql-action/init@v3 is not supported",
+	}
+	if !slices.Equal(unsupported, wantUnsupported) {
+		t.Errorf("unsupported = %q, want %q", unsupported, wantUnsupported)
+	}
+}
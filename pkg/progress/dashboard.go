@@ -0,0 +1,416 @@
+package progress
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+	"golang.org/x/term"
+)
+
+// maxLogLines is the number of log lines kept by the dashboard, printed when it stops.
+const maxLogLines = 1000
+
+// dashboardKeys is the help line of the dashboard.
+const dashboardKeys = "p pause/resume  s skip selected  j/k select  b/f scroll errors  q quit"
+
+// Controller pauses the repository migrations and skips queued repositories, see
+// migrate.Controls.
+type Controller interface {
+	Pause() error
+	Resume() error
+	Paused() bool
+	Skip(repo string)
+}
+
+// dashboard is the state of a full-screen Display, see NewDashboard.
+type dashboard struct {
+	in        *os.File
+	fd        int
+	state     *term.State
+	controls  Controller
+	interrupt func()
+	started   time.Time
+
+	// queue holds the GitHub full names of the repositories waiting to be migrated.
+	queue    []string
+	skipped  map[string]bool
+	selected int
+	errors   []string
+	// scroll is the number of errors hidden below the error pane.
+	scroll int
+	logs   []string
+	// limit is the highest remaining GitHub quota seen, the maximum of the gauge.
+	limit int
+
+	question    string
+	answer      chan bool
+	interrupted bool
+}
+
+// NewDashboard creates a Display taking over the whole terminal once started: a pane
+// per phase of the run, the queued repositories, the failures and the latest log lines,
+// with the GitHub rate limit on top. Keys read from in pause and resume the repository
+// migrations and skip the selected queued repository with controls; q and Ctrl-C call
+// interrupt, which should cancel the run, a second time they close the dashboard while
+// the run stops. Log output written meanwhile is printed when the dashboard stops.
+// When in or out is not a terminal, the Display is drawn below the log output instead.
+func NewDashboard(in, out *os.File, controls Controller, interrupt func()) *Display {
+	d := New(out)
+	d.dash = &dashboard{
+		in:        in,
+		fd:        int(out.Fd()),
+		controls:  controls,
+		interrupt: interrupt,
+		skipped:   make(map[string]bool),
+	}
+	return d
+}
+
+// openDashboard switches the terminal to the dashboard, or falls back to the display
+// below the log output. The caller holds mu.
+func (d *Display) openDashboard() {
+	dash := d.dash
+	if !term.IsTerminal(int(dash.in.Fd())) || !term.IsTerminal(dash.fd) {
+		d.dash = nil
+		return
+	}
+	state, err := term.MakeRaw(int(dash.in.Fd()))
+	if err != nil {
+		d.dash = nil
+		return
+	}
+	dash.state = state
+	dash.started = time.Now()
+	// alternate screen, hidden cursor
+	fmt.Fprint(d.out, "\x1b[?1049h\x1b[?25l")
+	d.wg.Add(1)
+	go d.readKeys(dash)
+}
+
+// RepoQueued adds a repository, by GitHub full name, to the queue of the dashboard.
+func (d *Display) RepoQueued(source string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dash == nil {
+		return
+	}
+	d.dash.queue = append(d.dash.queue, source)
+}
+
+// RepoDequeued removes a started or skipped repository from the queue of the dashboard.
+func (d *Display) RepoDequeued(source string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dash == nil {
+		return
+	}
+	dash := d.dash
+	for i, name := range dash.queue {
+		if name == source {
+			dash.queue = append(dash.queue[:i], dash.queue[i+1:]...)
+			if dash.selected > i {
+				dash.selected--
+			}
+			break
+		}
+	}
+	dash.selected = max(min(dash.selected, len(dash.queue)-1), 0)
+}
+
+// Failed adds a failure to the error list of the dashboard.
+func (d *Display) Failed(name, message string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dash == nil {
+		return
+	}
+	if d.dash.scroll > 0 {
+		// keep the scrolled errors in place
+		d.dash.scroll++
+	}
+	d.dash.errors = append(d.dash.errors, name+": "+message)
+}
+
+// Confirm asks a yes or no question in the dashboard and waits for the answer, no when
+// the display is not a running dashboard or stops meanwhile.
+func (d *Display) Confirm(question string) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	if d.stopped || d.dash == nil || d.dash.state == nil {
+		d.mu.Unlock()
+		return false
+	}
+	answer := make(chan bool, 1)
+	d.dash.question = question
+	d.dash.answer = answer
+	d.redraw()
+	d.mu.Unlock()
+
+	select {
+	case ok := <-answer:
+		return ok
+	case <-d.done:
+		return false
+	}
+}
+
+// keyPoll is how long readKeys waits for a key before checking whether the
+// dashboard stopped, so no read is left blocked on the terminal.
+const keyPoll = 100 * time.Millisecond
+
+// readKeys handles the keys pressed until the dashboard stops or is closed.
+func (d *Display) readKeys(dash *dashboard) {
+	defer d.wg.Done()
+	buf := make([]byte, 16)
+	for {
+		ready, err := waitKey(dash.in, keyPoll)
+		if err != nil {
+			return
+		}
+		d.mu.Lock()
+		closed := d.stopped || d.dash != dash
+		d.mu.Unlock()
+		if closed {
+			return
+		}
+		if !ready {
+			continue
+		}
+		n, err := dash.in.Read(buf)
+		if err != nil {
+			return
+		}
+		d.mu.Lock()
+		if d.stopped || d.dash != dash {
+			d.mu.Unlock()
+			return
+		}
+		d.key(string(buf[:n]))
+		d.redraw()
+		d.mu.Unlock()
+	}
+}
+
+// key handles a key press. The caller holds mu.
+func (d *Display) key(key string) {
+	dash := d.dash
+	if key == "\x03" || (key == "q" && dash.answer == nil) {
+		dash.reply(false)
+		if dash.interrupted {
+			// give the terminal back while the run stops, the logs follow below
+			d.closeDashboard()
+			d.dash = nil
+			return
+		}
+		dash.interrupted = true
+		if dash.interrupt != nil {
+			dash.interrupt()
+		}
+		return
+	}
+	if dash.answer != nil {
+		switch key {
+		case "y", "Y":
+			dash.reply(true)
+		case "n", "N", "\r", "\x1b":
+			dash.reply(false)
+		}
+		return
+	}
+
+	switch key {
+	case "p":
+		if dash.controls == nil {
+			return
+		}
+		var err error
+		if dash.controls.Paused() {
+			err = dash.controls.Resume()
+		} else {
+			err = dash.controls.Pause()
+		}
+		if err != nil {
+			dash.errors = append(dash.errors, "failed to record pause: "+err.Error())
+		}
+	case "s":
+		if dash.controls == nil || dash.selected >= len(dash.queue) {
+			return
+		}
+		name := dash.queue[dash.selected]
+		dash.controls.Skip(name)
+		dash.skipped[name] = true
+	case "k", "\x1b[A":
+		dash.selected = max(dash.selected-1, 0)
+	case "j", "\x1b[B":
+		dash.selected = max(min(dash.selected+1, len(dash.queue)-1), 0)
+	case "b", "\x1b[5~":
+		dash.scroll = min(dash.scroll+5, max(len(dash.errors)-1, 0))
+	case "f", "\x1b[6~":
+		dash.scroll = max(dash.scroll-5, 0)
+	}
+}
+
+// reply answers the pending question, if any.
+func (dash *dashboard) reply(ok bool) {
+	if dash.answer == nil {
+		return
+	}
+	dash.answer <- ok
+	dash.answer = nil
+	dash.question = ""
+}
+
+// writeLog keeps log output for the log pane. The caller holds mu.
+func (d *Display) writeLog(p []byte) {
+	dash := d.dash
+	for line := range strings.SplitSeq(strings.TrimRight(string(p), "\n"), "\n") {
+		dash.logs = append(dash.logs, strings.TrimRight(line, "\r"))
+	}
+	if len(dash.logs) > maxLogLines {
+		dash.logs = dash.logs[len(dash.logs)-maxLogLines:]
+	}
+}
+
+// closeDashboard gives the terminal back and prints the kept log lines. The caller holds mu.
+func (d *Display) closeDashboard() {
+	dash := d.dash
+	if dash.state == nil {
+		return
+	}
+	_ = term.Restore(int(dash.in.Fd()), dash.state)
+	fmt.Fprint(d.out, "\x1b[?25h\x1b[?1049l")
+	for _, line := range dash.logs {
+		fmt.Fprintln(d.out, line)
+	}
+	dash.logs = nil
+}
+
+// drawDashboard replaces the screen with a new frame. The caller holds mu.
+func (d *Display) drawDashboard() {
+	dash := d.dash
+	width, height, err := term.GetSize(dash.fd)
+	if err != nil || width < 20 || height < 12 {
+		width, height = 80, 24
+	}
+
+	var lines []string
+	add := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+
+	status := "running"
+	switch {
+	case dash.interrupted:
+		status = "stopping"
+	case dash.controls != nil && dash.controls.Paused():
+		status = "PAUSED, no repository migration starts"
+	}
+	add("github2gitea  %s  %s", status, time.Since(dash.started).Round(time.Second))
+	if d.rateLimit != nil {
+		remaining, reset := d.rateLimit()
+		dash.limit = max(dash.limit, remaining)
+		switch {
+		case remaining < 0 && !reset.IsZero():
+			add("GitHub rate limit %s paused until %s", bar(0, 1), reset.Format(time.TimeOnly))
+		case remaining < 0:
+			add("GitHub rate limit unknown")
+		default:
+			add("GitHub rate limit %s %d/%d, resets in %s", bar(remaining, dash.limit), remaining, dash.limit,
+				time.Until(reset).Round(time.Second))
+		}
+	}
+	add("")
+
+	kinds := map[string]int{}
+	failed := 0
+	if d.counts != nil {
+		kinds, failed = d.counts()
+	}
+	add("Organization  orgs %d  teams %d  users %d  keys %d  bots skipped %d",
+		kinds[report.KindOrg], kinds[report.KindTeam], kinds[report.KindUser], kinds[report.KindKey], kinds[report.KindBot])
+	done := kinds[report.KindRepo]
+	add("Repositories  %s %d/%d  failed %d", bar(done, d.total), done, d.total, failed)
+	names := make([]string, 0, len(d.repos))
+	for name := range d.repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		repo := d.repos[name]
+		add("  %s %s %s (%s)", bar(repo.stage+1, len(stages)), repo.name, repo.state,
+			time.Since(repo.started).Round(time.Second))
+	}
+	add("Personal data  gists %d  stars %d  watches %d  follows %d",
+		kinds[report.KindGist], kinds[report.KindStar], kinds[report.KindWatch], kinds[report.KindFollow])
+
+	// the queue, errors and logs share the rest of the screen
+	rest := max(height-len(lines)-4, 3)
+	queueRows := min(len(dash.queue), max(rest/3, 1))
+	errorRows := (rest - queueRows) / 2
+	logRows := rest - queueRows - errorRows
+
+	add("Queue (%d)", len(dash.queue))
+	first := max(dash.selected-queueRows+1, 0)
+	for i := first; i < first+queueRows && i < len(dash.queue); i++ {
+		marker := "  "
+		if i == dash.selected {
+			marker = "> "
+		}
+		line := marker + dash.queue[i]
+		if dash.skipped[dash.queue[i]] {
+			line += " (skipped)"
+		}
+		lines = append(lines, line)
+	}
+	add("Errors (%d)", len(dash.errors))
+	end := len(dash.errors) - dash.scroll
+	for _, line := range dash.errors[max(end-errorRows, 0):end] {
+		lines = append(lines, "  "+line)
+	}
+	for range errorRows - min(end, errorRows) {
+		lines = append(lines, "")
+	}
+	add("Log")
+	for _, line := range dash.logs[max(len(dash.logs)-logRows, 0):] {
+		lines = append(lines, "  "+line)
+	}
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	if dash.question != "" {
+		add("%s [y/N]", dash.question)
+	} else {
+		add(dashboardKeys)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("\x1b[H")
+	for i, line := range lines[max(len(lines)-height, 0):] {
+		if i > 0 {
+			buf.WriteString("\r\n")
+		}
+		if runes := []rune(line); len(runes) > width {
+			line = string(runes[:width])
+		}
+		buf.WriteString(line)
+		buf.WriteString("\x1b[K")
+	}
+	buf.WriteString("\x1b[J")
+	_, _ = d.out.Write(buf.Bytes())
+}
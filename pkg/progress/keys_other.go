@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package progress
+
+import (
+	"os"
+	"time"
+)
+
+// waitKey reports input as ready, the platform cannot wait for it: the key reader
+// stays blocked in a read after the dashboard stops.
+func waitKey(*os.File, time.Duration) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,17 @@
+package progress
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// waitKey waits up to timeout for input on in and reports whether a read would not block.
+func waitKey(in *os.File, timeout time.Duration) (bool, error) {
+	event, err := windows.WaitForSingleObject(windows.Handle(in.Fd()), uint32(timeout.Milliseconds()))
+	if err != nil {
+		return false, err
+	}
+	return event == windows.WAIT_OBJECT_0, nil
+}
@@ -0,0 +1,281 @@
+// Package progress renders a live terminal view of a migration run: the overall
+// repository progress, a bar per running repository migration, the number of
+// processed users, keys and teams and the GitHub rate limit, below the log output or
+// as a full-screen dashboard.
+package progress
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/report"
+)
+
+// refreshInterval is the delay between two redraws of the display.
+const refreshInterval = 500 * time.Millisecond
+
+const barWidth = 24
+
+// stages are the steps of a Gitea migration task, in order, as named in its status message.
+var stages = []string{
+	"migrating_git",
+	"migrating_topics",
+	"migrating_milestones",
+	"migrating_labels",
+	"migrating_releases",
+	"migrating_issues",
+	"migrating_comments",
+	"migrating_pulls",
+}
+
+// CountsFunc returns the number of processed results by kind and how many failed.
+type CountsFunc func() (kinds map[string]int, failed int)
+
+// RateLimitFunc returns the remaining GitHub quota and its reset time, remaining is -1 when unknown.
+type RateLimitFunc func() (remaining int, reset time.Time)
+
+type repoProgress struct {
+	name    string
+	state   string
+	stage   int
+	started time.Time
+}
+
+// Display draws the progress below the log output of a terminal.
+// All methods are no-ops on a nil Display.
+type Display struct {
+	out io.Writer
+	// dash is set for a full-screen dashboard.
+	dash *dashboard
+
+	mu        sync.Mutex
+	counts    CountsFunc
+	rateLimit RateLimitFunc
+	total     int
+	repos     map[string]*repoProgress
+	lines     int
+	stopped   bool
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New creates a Display writing to out, usually a terminal.
+func New(out io.Writer) *Display {
+	return &Display{
+		out:   out,
+		repos: make(map[string]*repoProgress),
+		done:  make(chan struct{}),
+	}
+}
+
+type displayKey struct{}
+
+// NewContext returns a context carrying the display.
+func NewContext(ctx context.Context, d *Display) context.Context {
+	return context.WithValue(ctx, displayKey{}, d)
+}
+
+// FromContext returns the display of the context, or nil.
+func FromContext(ctx context.Context) *Display {
+	d, _ := ctx.Value(displayKey{}).(*Display)
+	return d
+}
+
+// Start redraws the display periodically until Stop is called.
+func (d *Display) Start(counts CountsFunc, rateLimit RateLimitFunc) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.counts = counts
+	d.rateLimit = rateLimit
+	if d.dash != nil {
+		d.openDashboard()
+	}
+	d.mu.Unlock()
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.done:
+				return
+			case <-ticker.C:
+				d.mu.Lock()
+				d.redraw()
+				d.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Stop draws the display a last time and stops refreshing it.
+// Writes after Stop go straight to the output.
+func (d *Display) Stop() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return
+	}
+	d.stopped = true
+	close(d.done)
+	if d.dash != nil {
+		d.closeDashboard()
+	} else {
+		d.redraw()
+	}
+	d.lines = 0
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+// AddTotal adds n repositories to the number expected in this run.
+func (d *Display) AddTotal(n int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.total += n
+}
+
+// RepoStarted adds a bar for a repository migration.
+func (d *Display) RepoStarted(owner, name string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.repos[owner+"/"+name] = &repoProgress{
+		name:    owner + "/" + name,
+		state:   "starting",
+		stage:   -1,
+		started: time.Now(),
+	}
+}
+
+// MigrationState updates the bar of a repository from the Gitea migration task status.
+func (d *Display) MigrationState(owner, name, state, message string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	repo, ok := d.repos[owner+"/"+name]
+	if !ok {
+		return
+	}
+	repo.state = state
+	for i, stage := range stages {
+		if strings.Contains(message, stage) && i >= repo.stage {
+			repo.stage = i
+			repo.state = strings.ReplaceAll(stage, "_", " ")
+		}
+	}
+}
+
+// RepoFinished removes the bar of a repository.
+func (d *Display) RepoFinished(owner, name string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.repos, owner+"/"+name)
+}
+
+// Write prints log output above the display, so it can be used as the log writer.
+func (d *Display) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return d.out.Write(p)
+	}
+	if d.dash != nil && d.dash.state != nil {
+		d.writeLog(p)
+		return len(p), nil
+	}
+	d.clear()
+	n, err := d.out.Write(p)
+	d.draw()
+	return n, err
+}
+
+// redraw replaces the previous frame with a new one. The caller holds mu.
+func (d *Display) redraw() {
+	if d.dash != nil {
+		d.drawDashboard()
+		return
+	}
+	d.clear()
+	d.draw()
+}
+
+// clear erases the previous frame. The caller holds mu.
+func (d *Display) clear() {
+	if d.lines > 0 {
+		fmt.Fprintf(d.out, "\x1b[%dA\x1b[J", d.lines)
+		d.lines = 0
+	}
+}
+
+// draw writes a frame below the cursor. The caller holds mu.
+func (d *Display) draw() {
+	if d.counts == nil {
+		return
+	}
+	kinds, failed := d.counts()
+	done := kinds[report.KindRepo]
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Repositories %s %d/%d  failed %d\n", bar(done, d.total), done, d.total, failed)
+	fmt.Fprintf(&buf, "Orgs %d  Teams %d  Users %d  Keys %d\n",
+		kinds[report.KindOrg], kinds[report.KindTeam], kinds[report.KindUser], kinds[report.KindKey])
+	if d.rateLimit != nil {
+		remaining, reset := d.rateLimit()
+		switch {
+		case remaining < 0 && !reset.IsZero():
+			fmt.Fprintf(&buf, "GitHub rate limit: paused until %s\n", reset.Format(time.TimeOnly))
+		case remaining < 0:
+			buf.WriteString("GitHub rate limit: unknown\n")
+		default:
+			fmt.Fprintf(&buf, "GitHub rate limit: %d remaining, resets in %s\n",
+				remaining, time.Until(reset).Round(time.Second))
+		}
+	}
+
+	names := make([]string, 0, len(d.repos))
+	for name := range d.repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		repo := d.repos[name]
+		fmt.Fprintf(&buf, "  %s %s %s (%s)\n", bar(repo.stage+1, len(stages)), repo.name, repo.state,
+			time.Since(repo.started).Round(time.Second))
+	}
+
+	d.lines = strings.Count(buf.String(), "\n")
+	_, _ = d.out.Write(buf.Bytes())
+}
+
+// bar renders done out of total as a fixed width bar.
+func bar(done, total int) string {
+	filled := 0
+	if total > 0 {
+		filled = min(done*barWidth/total, barWidth)
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", barWidth-filled) + "]"
+}
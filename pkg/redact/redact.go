@@ -0,0 +1,107 @@
+// Package redact removes secrets, such as the GitHub and Gitea tokens and the webhook
+// secrets, from the text written to logs, reports, audit logs and error messages.
+package redact
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every secret.
+const Mask = "***"
+
+var (
+	mu       sync.RWMutex
+	secrets  []string
+	replacer = strings.NewReplacer()
+
+	// userinfo matches the credentials of URLs, such as clone URLs with embedded tokens.
+	userinfo = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/\s@"']+@`)
+	// query matches the values of credential query parameters, such as webhook URLs
+	// with a ?token= parameter.
+	query = regexp.MustCompile(`(?i)([?&](?:access_token|api_key|apikey|key|password|private_token|secret|sig|signature|token)=)[^&#\s"'\\]+`)
+)
+
+// Add registers secrets to remove from now on, along with their JSON-escaped and
+// URL-escaped forms. Every configured secret is masked whatever its length, only
+// empty values are ignored.
+func Add(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	changed := false
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		for _, form := range forms(value) {
+			if slices.Contains(secrets, form) {
+				continue
+			}
+			secrets = append(secrets, form)
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	// the longest secret wins when one contains another
+	slices.SortFunc(secrets, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, secret := range secrets {
+		pairs = append(pairs, secret, Mask)
+	}
+	replacer = strings.NewReplacer(pairs...)
+}
+
+// forms returns value as written by the JSON encoders and in URLs.
+func forms(value string) []string {
+	escaped := []string{value}
+	if b, err := json.Marshal(value); err == nil {
+		escaped = append(escaped, string(b[1:len(b)-1]))
+	}
+	escaped = append(escaped, url.QueryEscape(value), url.PathEscape(value))
+	return slices.Compact(escaped)
+}
+
+// String masks the registered secrets, the credentials of URLs and the credential
+// query parameters in s.
+func String(s string) string {
+	mu.RLock()
+	r := replacer
+	mu.RUnlock()
+	s = r.Replace(s)
+	if strings.Contains(s, "@") {
+		s = userinfo.ReplaceAllString(s, "${1}"+Mask+"@")
+	}
+	if strings.Contains(s, "=") {
+		s = query.ReplaceAllString(s, "${1}"+Mask)
+	}
+	return s
+}
+
+// Writer masks the secrets of every write to the underlying writer. A secret split
+// over two writes is not found, so each write should hold whole lines or records,
+// like the ones of slog handlers and JSON encoders.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write masks the secrets of p and writes it. It reports len(p) on success, the
+// masked text being shorter or longer.
+func (w *Writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,172 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Snapshot returns the status of every result of the report keyed by kind:name,
+// repositories as repo:owner/name. A failed result wins over other results of the
+// same name. It is kept between runs to compute the next Digest.
+func (r *Report) Snapshot() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	results := make(map[string]string, len(r.Items)+len(r.Repos))
+	set := func(key, status string) {
+		if results[key] != StatusFailed && results[key] != StatusStuck {
+			results[key] = status
+		}
+	}
+	for _, item := range r.Items {
+		set(item.Kind+":"+item.Name, item.Status)
+	}
+	for _, repo := range r.Repos {
+		set(KindRepo+":"+repo.Owner+"/"+repo.Name, repo.Status)
+	}
+	return results
+}
+
+// Digest is the difference between two runs, such as two nights of a scheduled sync.
+type Digest struct {
+	// Previous is the time of the previous run, zero for the first one.
+	Previous time.Time `json:"previous"`
+	// NewRepos are the repositories migrated for the first time.
+	NewRepos []string `json:"new_repos"`
+	// MembersAdded and MembersRemoved are the users appearing in, or gone from, the migrated members.
+	MembersAdded   []string `json:"members_added"`
+	MembersRemoved []string `json:"members_removed"`
+	// Failures are the results failing since this run, Resolved the ones no longer failing.
+	Failures []string `json:"failures"`
+	Resolved []string `json:"resolved"`
+}
+
+// NewDigest compares the snapshot of the previous run with the current report.
+func NewDigest(previous map[string]string, at time.Time, current *Report) Digest {
+	results := current.Snapshot()
+	d := Digest{Previous: at}
+	for key, status := range results {
+		kind, name, _ := strings.Cut(key, ":")
+		before, existed := previous[key]
+		switch {
+		case failedStatus(status) && !failedStatus(before):
+			d.Failures = append(d.Failures, kind+" "+name+current.errorOf(kind, name))
+		case !failedStatus(status) && failedStatus(before):
+			d.Resolved = append(d.Resolved, kind+" "+name)
+		}
+		if existed || failedStatus(status) {
+			continue
+		}
+		switch kind {
+		case KindRepo:
+			d.NewRepos = append(d.NewRepos, name)
+		case KindUser:
+			d.MembersAdded = append(d.MembersAdded, name)
+		}
+	}
+	for key := range previous {
+		if kind, name, _ := strings.Cut(key, ":"); kind == KindUser {
+			if _, ok := results[key]; !ok {
+				d.MembersRemoved = append(d.MembersRemoved, name)
+			}
+		}
+	}
+	for _, list := range [][]string{d.NewRepos, d.MembersAdded, d.MembersRemoved, d.Failures, d.Resolved} {
+		slices.Sort(list)
+	}
+	return d
+}
+
+func failedStatus(status string) bool {
+	return status == StatusFailed || status == StatusStuck
+}
+
+// errorOf returns the error of a failed result as a ": error" suffix.
+func (r *Report) errorOf(kind, name string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if kind == KindRepo {
+		for _, repo := range r.Repos {
+			if repo.Owner+"/"+repo.Name == name && repo.Error != "" {
+				return ": " + repo.Error
+			}
+		}
+		return ""
+	}
+	for _, item := range r.Items {
+		if item.Kind == kind && item.Name == name && item.Error != "" {
+			return ": " + item.Error
+		}
+	}
+	return ""
+}
+
+// Empty reports whether nothing changed since the previous run.
+func (d Digest) Empty() bool {
+	return len(d.NewRepos)+len(d.MembersAdded)+len(d.MembersRemoved)+len(d.Failures)+len(d.Resolved) == 0
+}
+
+// String formats the digest as a short plain text message.
+func (d Digest) String() string {
+	var b strings.Builder
+	if d.Previous.IsZero() {
+		b.WriteString("github2gitea sync digest, first run\n")
+	} else {
+		fmt.Fprintf(&b, "github2gitea sync digest since %s\n", d.Previous.Format("2006-01-02 15:04 MST"))
+	}
+	if d.Empty() {
+		b.WriteString("\nNothing changed.\n")
+		return b.String()
+	}
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s (%d):\n", title, len(lines))
+		for _, line := range lines {
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+	}
+	section("New repositories", d.NewRepos)
+	section("Members added", d.MembersAdded)
+	section("Members removed", d.MembersRemoved)
+	section("New failures", d.Failures)
+	section("Resolved failures", d.Resolved)
+	return b.String()
+}
+
+// WriteDigestFile writes the digest as plain text to path.
+func WriteDigestFile(path string, d Digest) error {
+	return os.WriteFile(path, []byte(d.String()), 0o644)
+}
+
+// PostDigest publishes the digest to an incoming webhook as {"text": "..."}, the
+// payload Slack, Mattermost and Rocket.Chat accept.
+func PostDigest(ctx context.Context, url string, d Digest) error {
+	body, err := json.Marshal(map[string]string{"text": d.String()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("digest webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
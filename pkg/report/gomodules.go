@@ -0,0 +1,76 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+)
+
+// GoModule records the module path change of a migrated Go repository.
+type GoModule struct {
+	// Repo is the full name of the migrated Gitea repository.
+	Repo string `json:"repo"`
+	// Old and New are the module paths on GitHub and in Gitea.
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// AddGoModules appends module path changes to the report.
+func (r *Report) AddGoModules(modules ...GoModule) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.GoModules = append(r.GoModules, modules...)
+}
+
+// goModulesFile is the artifact helping Go teams to update imports and CI.
+type goModulesFile struct {
+	// GOPRIVATE lists the Gitea owners holding migrated modules, for GOPRIVATE,
+	// which also keeps them out of the public proxy and checksum database.
+	GOPRIVATE string           `json:"goprivate"`
+	Modules   []goModuleChange `json:"modules"`
+}
+
+type goModuleChange struct {
+	GoModule
+	// Replace is the shell command adding a replace directive to a dependent module,
+	// at the version it already requires as the tags move with the repository, so
+	// the old path keeps building until its imports are updated.
+	Replace string `json:"replace"`
+}
+
+// WriteGoModulesFile writes the module path changes of the report as JSON to path,
+// with the suggested GOPRIVATE value and replace directives.
+func (r *Report) WriteGoModulesFile(path string) error {
+	r.mu.Lock()
+	modules := slices.Clone(r.GoModules)
+	r.mu.Unlock()
+	slices.SortFunc(modules, func(a, b GoModule) int { return strings.Compare(a.Old, b.Old) })
+
+	out := goModulesFile{Modules: make([]goModuleChange, 0, len(modules))}
+	var private []string
+	for _, module := range modules {
+		out.Modules = append(out.Modules, goModuleChange{
+			GoModule: module,
+			Replace:  "go mod edit -replace=" + module.Old + "=" + module.New + "@$(go list -m -f '{{.Version}}' " + module.Old + ")",
+		})
+		// host/owner, the path prefix of every module of the owner
+		parts := strings.SplitN(module.New, "/", 3)
+		if len(parts) == 3 {
+			if prefix := parts[0] + "/" + parts[1]; !slices.Contains(private, prefix) {
+				private = append(private, prefix)
+			}
+		}
+	}
+	slices.Sort(private)
+	out.GOPRIVATE = strings.Join(private, ",")
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,694 @@
+package report
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/redact"
+)
+
+// Result status values used in the report.
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+	// StatusStuck marks a repository whose migration task was abandoned without progress.
+	StatusStuck = "stuck"
+)
+
+// Kinds of migrated resources other than repositories.
+const (
+	KindOrg  = "org"
+	KindTeam = "team"
+	KindUser = "user"
+	KindKey  = "key"
+	// KindGPG is a GPG key of a user.
+	KindGPG  = "gpg"
+	KindRepo = "repo"
+	KindFork = "fork"
+	// KindRole is a GitHub organization role assignment.
+	KindRole = "role"
+	// KindCollaborator is an outside collaborator added to a migrated repository.
+	KindCollaborator = "collaborator"
+	// KindApp is a GitHub App installed on the organization.
+	KindApp = "app"
+	// KindOAuth2 is a Gitea OAuth2 application created for an integration.
+	KindOAuth2 = "oauth2"
+	// KindProtection is a branch protection rule of the protection policy.
+	KindProtection = "protection"
+	// KindWebhook is a repository or organization webhook.
+	KindWebhook = "webhook"
+	// KindDrift is a team or repository changed in Gitea since the last run.
+	KindDrift = "drift"
+	// KindGist, KindStar and KindFollow are personal data of users who opted in.
+	KindGist   = "gist"
+	KindStar   = "star"
+	KindFollow = "follow"
+	KindWatch  = "watch"
+	// KindVariable and KindSecret are Actions variables and secrets.
+	KindVariable = "variable"
+	KindSecret   = "secret"
+	// KindLabel is an issue label compared with its GitHub source.
+	KindLabel = "label"
+	// KindBot is a GitHub bot or machine account left out of the migration.
+	KindBot = "bot"
+	// KindWelcome is the welcome email sent to a created user.
+	KindWelcome = "welcome"
+	// KindAuthor is a GitHub author of migrated issues and comments without Gitea user.
+	KindAuthor = "author"
+	// KindAttachment is an issue or comment whose GitHub attachments were copied.
+	KindAttachment = "attachment"
+	// KindAsset is a release asset uploaded after the migration.
+	KindAsset = "asset"
+	// KindCutover is the replacement of a pull mirror by a regular repository.
+	KindCutover = "cutover"
+	// KindInventory is a GitHub repository listed by the inventory export.
+	KindInventory = "inventory"
+	// KindImports is a repository whose Go import paths were rewritten for Gitea.
+	KindImports = "imports"
+	// KindSigningKey is a key which signed verified commits.
+	KindSigningKey = "signing-key"
+	// KindModule is the path change of a migrated Go module.
+	KindModule = "module"
+	// KindMetadata is the backfilled metadata of a migrated repository.
+	KindMetadata = "metadata"
+	// KindWorkflow is a GitHub Actions workflow converted for Gitea Actions.
+	KindWorkflow = "workflow"
+	// KindRunner is the Actions unit and runner registration token of a migrated
+	// organization or repository.
+	KindRunner = "runner"
+	// KindRedirect is a GitHub repository renamed or moved since the last run, named
+	// old -> new.
+	KindRedirect = "redirect"
+	// KindVerify is a migrated repository compared with GitHub by the verify command,
+	// or a GitHub team missing in Gitea.
+	KindVerify = "verify"
+	// KindCanary is a canary repository verified before the rest of the migration.
+	KindCanary = "canary"
+	// KindLicense is the detected license of a repository in the compliance section.
+	KindLicense = "license"
+	// KindCheck is a preflight check of the check command, failed when it would stop
+	// the migration.
+	KindCheck = "check"
+)
+
+// Report file formats.
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatHTML = "html"
+)
+
+// Duration is a time.Duration encoded as a human readable string such as "1.5s".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).Round(time.Millisecond).String()
+}
+
+// Since returns the Duration elapsed since start.
+func Since(start time.Time) Duration {
+	return Duration(time.Since(start))
+}
+
+// Item records the migration result of an organization, team, user or key.
+type Item struct {
+	Kind     string   `json:"kind"`
+	Name     string   `json:"name"`
+	Status   string   `json:"status"`
+	Duration Duration `json:"duration"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// RepoStats is a snapshot of the source repository counters taken at migration time.
+// Watchers is zero for the repositories skipped as unchanged or preserved.
+type RepoStats struct {
+	Stars      int `json:"stars"`
+	Watchers   int `json:"watchers"`
+	Forks      int `json:"forks"`
+	OpenIssues int `json:"open_issues"`
+}
+
+// Repo records the migration result of a single repository.
+type Repo struct {
+	Owner    string    `json:"owner"`
+	Name     string    `json:"name"`
+	Source   string    `json:"source"`
+	Status   string    `json:"status"`
+	Duration Duration  `json:"duration"`
+	Error    string    `json:"error,omitempty"`
+	Stats    RepoStats `json:"stats"`
+	// License is the SPDX identifier of the license GitHub detected, if any.
+	License string `json:"license,omitempty"`
+}
+
+// Fork records a fork of a migrated repository, which keeps pointing at GitHub after the move.
+type Fork struct {
+	// Upstream is the full name of the forked source repository.
+	Upstream string `json:"upstream"`
+	// Name is the full name of the fork.
+	Name string `json:"name"`
+	// Internal reports whether the fork belongs to the source organization or user,
+	// or for a migrated fork whether its parent is migrated too.
+	Internal bool `json:"internal"`
+	// Recreated reports whether the fork relationship was recreated in Gitea.
+	Recreated bool `json:"recreated,omitempty"`
+}
+
+// RoleAssignment records a GitHub organization role granted to a user or team. These
+// roles have no Gitea equivalent and have to be granted again in other tooling.
+type RoleAssignment struct {
+	// Org is the GitHub organization.
+	Org string `json:"org"`
+	// Role is the organization role, e.g. security_manager.
+	Role string `json:"role"`
+	// Assignee is the user login or team slug holding the role.
+	Assignee string `json:"assignee"`
+	// Type is user or team.
+	Type string `json:"type"`
+}
+
+// App records a GitHub App installed on an organization, to be replaced after the
+// migration since Gitea has no apps.
+type App struct {
+	// Org is the GitHub organization.
+	Org string `json:"org"`
+	// Slug identifies the app, e.g. renovate.
+	Slug string `json:"slug"`
+	// Permissions maps the granted permissions to their level, e.g. contents: write.
+	Permissions map[string]string `json:"permissions,omitempty"`
+	// Events are the webhook events the app subscribes to.
+	Events []string `json:"events,omitempty"`
+	// Repositories is all or selected.
+	Repositories string `json:"repositories"`
+	// Suspended is set for the installations suspended on GitHub.
+	Suspended bool `json:"suspended,omitempty"`
+	// Scopes are the Gitea access token scopes covering the permissions, for the bot
+	// user or OAuth2 application replacing the app.
+	Scopes []string `json:"scopes,omitempty"`
+	// Template is the OAuth2 application template of the integration, when known.
+	Template string `json:"template,omitempty"`
+}
+
+// InventoryRepo describes a GitHub repository for the migration planning.
+type InventoryRepo struct {
+	// Name is the full name of the repository.
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"`
+	Archived   bool   `json:"archived"`
+	Fork       bool   `json:"fork"`
+	// SizeKB is the size GitHub reports for the repository, in kilobytes.
+	SizeKB        int    `json:"size_kb"`
+	DefaultBranch string `json:"default_branch"`
+	// Language is the primary language, Languages the bytes of code per language.
+	Language  string         `json:"language,omitempty"`
+	Languages map[string]int `json:"languages,omitempty"`
+	// License is the SPDX identifier of the license GitHub detected, if any.
+	License string `json:"license,omitempty"`
+}
+
+// License categories of the compliance section.
+const (
+	LicensePermissive   = "permissive"
+	LicenseWeakCopyleft = "weak-copyleft"
+	LicenseCopyleft     = "copyleft"
+	// LicenseOther is a license GitHub does not identify, NOASSERTION, or one
+	// outside of the known families.
+	LicenseOther = "other"
+	// LicenseNone is a repository without license file.
+	LicenseNone = "none"
+)
+
+// License records the license GitHub detected in a repository, for the legal
+// and compliance review of the migrated code.
+type License struct {
+	// Repo is the full name of the GitHub repository.
+	Repo string `json:"repo"`
+	// SPDX is the SPDX identifier, e.g. GPL-3.0, empty without license.
+	SPDX string `json:"spdx,omitempty"`
+	// Name is the license name, e.g. GNU General Public License v3.0.
+	Name string `json:"name,omitempty"`
+	// Category is permissive, weak-copyleft, copyleft, other or none.
+	Category string `json:"category"`
+	// Topic is the topic tagging the Gitea repository with its license, if any.
+	Topic string `json:"topic,omitempty"`
+}
+
+// Signing key types.
+const (
+	SigningGPG = "gpg"
+	SigningSSH = "ssh"
+)
+
+// SigningKey records a key which signed verified commits of the migrated repositories.
+type SigningKey struct {
+	// User is the GitHub login of the committer.
+	User string `json:"user"`
+	// Type is gpg or ssh.
+	Type string `json:"type"`
+	// ID is the GPG key id, or the SHA256 fingerprint of the SSH key.
+	ID string `json:"id"`
+	// Commits is the number of verified commits signed with the key.
+	Commits int `json:"commits"`
+	// PublicKey is the armored GPG key or the SSH public key, empty when the key
+	// is no longer on the GitHub account.
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// Report collects the results of a migration run.
+type Report struct {
+	mu       sync.Mutex
+	observer func(Item)
+
+	// Stable leaves out the times and durations when writing, so two runs against
+	// the same source write identical reports.
+	Stable bool `json:"-"`
+
+	StartedAt   time.Time        `json:"started_at"`
+	FinishedAt  time.Time        `json:"finished_at"`
+	Items       []Item           `json:"items"`
+	Repos       []Repo           `json:"repos"`
+	Forks       []Fork           `json:"forks,omitempty"`
+	Roles       []RoleAssignment `json:"roles,omitempty"`
+	Apps        []App            `json:"apps,omitempty"`
+	Inventory   []InventoryRepo  `json:"inventory,omitempty"`
+	GoModules   []GoModule       `json:"go_modules,omitempty"`
+	SigningKeys []SigningKey     `json:"signing_keys,omitempty"`
+	Licenses    []License        `json:"licenses,omitempty"`
+}
+
+// New creates an empty report and marks the start time.
+func New() *Report {
+	return &Report{
+		StartedAt: time.Now(),
+		Items:     make([]Item, 0),
+		Repos:     make([]Repo, 0),
+	}
+}
+
+// Observe calls fn with every result added to the report from now on,
+// repositories included as items of KindRepo. fn must not call the report.
+func (r *Report) Observe(fn func(Item)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observer = fn
+}
+
+// Add appends an organization, team, user or key result to the report.
+// It is a no-op on a nil report.
+func (r *Report) Add(item Item) {
+	if r == nil {
+		return
+	}
+	item.Error = redact.String(item.Error)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Items = append(r.Items, item)
+	if r.observer != nil {
+		r.observer(item)
+	}
+}
+
+// AddRepo appends a repository result to the report.
+func (r *Report) AddRepo(repo Repo) {
+	repo.Error = redact.String(repo.Error)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Repos = append(r.Repos, repo)
+	if r.observer != nil {
+		r.observer(Item{
+			Kind:     KindRepo,
+			Name:     repo.Owner + "/" + repo.Name,
+			Status:   repo.Status,
+			Duration: repo.Duration,
+			Error:    repo.Error,
+		})
+	}
+}
+
+// AddForks appends the fork network of a repository to the report.
+func (r *Report) AddForks(forks ...Fork) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Forks = append(r.Forks, forks...)
+}
+
+// AddRoles appends organization role assignments to the report.
+func (r *Report) AddRoles(roles ...RoleAssignment) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Roles = append(r.Roles, roles...)
+}
+
+// AddApps appends organization GitHub Apps to the report.
+func (r *Report) AddApps(apps ...App) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Apps = append(r.Apps, apps...)
+}
+
+// AddInventory records GitHub repositories of the inventory.
+func (r *Report) AddInventory(repos ...InventoryRepo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Inventory = append(r.Inventory, repos...)
+}
+
+// AddLicenses appends repository licenses to the compliance section.
+func (r *Report) AddLicenses(licenses ...License) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Licenses = append(r.Licenses, licenses...)
+}
+
+// AddSigningKeys appends commit signing keys to the report.
+func (r *Report) AddSigningKeys(keys ...SigningKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.SigningKeys = append(r.SigningKeys, keys...)
+}
+
+// Summary returns the number of successful and failed repositories, stuck ones count as failed.
+func (r *Report) Summary() (success, failed int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, repo := range r.Repos {
+		if repo.Status == StatusFailed || repo.Status == StatusStuck {
+			failed++
+			continue
+		}
+		success++
+	}
+	return success, failed
+}
+
+// Names returns the sorted names of the items of a kind, e.g. the skipped bot accounts.
+func (r *Report) Names(kind string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for _, item := range r.Items {
+		if item.Kind == kind && !slices.Contains(names, item.Name) {
+			names = append(names, item.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Counts returns the number of processed results by kind, repositories counted as KindRepo,
+// and the number of failed or stuck ones among them.
+func (r *Report) Counts() (kinds map[string]int, failed int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kinds = make(map[string]int)
+	for _, item := range r.Items {
+		kinds[item.Kind]++
+		if item.Status == StatusFailed {
+			failed++
+		}
+	}
+	for _, repo := range r.Repos {
+		kinds[KindRepo]++
+		if repo.Status == StatusFailed || repo.Status == StatusStuck {
+			failed++
+		}
+	}
+	return kinds, failed
+}
+
+// ValidFormat reports whether format is a supported report format.
+func ValidFormat(format string) bool {
+	switch format {
+	case FormatJSON, FormatCSV, FormatHTML:
+		return true
+	default:
+		return false
+	}
+}
+
+// WriteFile writes the report in the given format (json, csv or html) to the given path.
+func (r *Report) WriteFile(path, format string) error {
+	if !ValidFormat(format) {
+		return errors.New("unsupported report format: " + format)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := r.Write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Write encodes the report in the given format to w. The results are sorted by
+// kind and name, whatever order the concurrent migrations finished in.
+func (r *Report) Write(w io.Writer, format string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.FinishedAt.IsZero() {
+		r.FinishedAt = time.Now()
+	}
+	out := r.sorted()
+
+	switch format {
+	case FormatCSV:
+		return out.writeCSV(w)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, struct {
+			StartedAt  time.Time
+			FinishedAt time.Time
+			Rows       [][]string
+		}{out.StartedAt, out.FinishedAt, out.rows()})
+	default:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+}
+
+// sorted returns a sorted copy of the results, without times and durations when Stable.
+func (r *Report) sorted() *Report {
+	out := &Report{
+		StartedAt:   r.StartedAt,
+		FinishedAt:  r.FinishedAt,
+		Items:       slices.Clone(r.Items),
+		Repos:       slices.Clone(r.Repos),
+		Forks:       slices.Clone(r.Forks),
+		Roles:       slices.Clone(r.Roles),
+		Apps:        slices.Clone(r.Apps),
+		Inventory:   slices.Clone(r.Inventory),
+		GoModules:   slices.Clone(r.GoModules),
+		SigningKeys: slices.Clone(r.SigningKeys),
+		Licenses:    slices.Clone(r.Licenses),
+	}
+	slices.SortStableFunc(out.Items, func(a, b Item) int {
+		return cmp.Or(
+			strings.Compare(a.Kind, b.Kind),
+			strings.Compare(a.Name, b.Name),
+			strings.Compare(a.Status, b.Status),
+			strings.Compare(a.Error, b.Error),
+		)
+	})
+	slices.SortStableFunc(out.Repos, func(a, b Repo) int {
+		return cmp.Or(strings.Compare(a.Owner, b.Owner), strings.Compare(a.Name, b.Name))
+	})
+	slices.SortStableFunc(out.Forks, func(a, b Fork) int {
+		return cmp.Or(strings.Compare(a.Upstream, b.Upstream), strings.Compare(a.Name, b.Name))
+	})
+	slices.SortStableFunc(out.GoModules, func(a, b GoModule) int {
+		return strings.Compare(a.Old, b.Old)
+	})
+	slices.SortStableFunc(out.SigningKeys, func(a, b SigningKey) int {
+		return cmp.Or(strings.Compare(a.User, b.User), strings.Compare(a.Type, b.Type), strings.Compare(a.ID, b.ID))
+	})
+	slices.SortStableFunc(out.Inventory, func(a, b InventoryRepo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	slices.SortStableFunc(out.Licenses, func(a, b License) int {
+		return strings.Compare(a.Repo, b.Repo)
+	})
+	slices.SortStableFunc(out.Apps, func(a, b App) int {
+		return cmp.Or(strings.Compare(a.Org, b.Org), strings.Compare(a.Slug, b.Slug))
+	})
+	slices.SortStableFunc(out.Roles, func(a, b RoleAssignment) int {
+		return cmp.Or(
+			strings.Compare(a.Org, b.Org),
+			strings.Compare(a.Role, b.Role),
+			strings.Compare(a.Type, b.Type),
+			strings.Compare(a.Assignee, b.Assignee),
+		)
+	})
+
+	if r.Stable {
+		out.StartedAt = time.Time{}
+		out.FinishedAt = time.Time{}
+		for i := range out.Items {
+			out.Items[i].Duration = 0
+		}
+		for i := range out.Repos {
+			out.Repos[i].Duration = 0
+		}
+	}
+	return out
+}
+
+// rows flattens items and repositories into a single table. The status column only
+// holds result statuses; the sections without result, such as forks, roles, apps,
+// modules, signing keys, inventory and licenses, leave it empty and describe the
+// entry in the detail column instead.
+func (r *Report) rows() [][]string {
+	rows := make([][]string, 0, len(r.Items)+len(r.Repos))
+	for _, item := range r.Items {
+		rows = append(rows, []string{item.Kind, item.Name, "", item.Status, item.Duration.String(), item.Error, ""})
+	}
+	for _, repo := range r.Repos {
+		rows = append(rows, []string{KindRepo, repo.Owner + "/" + repo.Name, repo.Source, repo.Status, repo.Duration.String(), repo.Error,
+			details("license", repo.License)})
+	}
+	for _, fork := range r.Forks {
+		relation := "external"
+		switch {
+		case fork.Recreated:
+			relation = "recreated"
+		case fork.Internal:
+			relation = "internal"
+		}
+		rows = append(rows, []string{KindFork, fork.Name, fork.Upstream, "", "", "", details("fork", relation)})
+	}
+	for _, role := range r.Roles {
+		rows = append(rows, []string{KindRole, role.Assignee, role.Org, "", "", "", details("role", role.Role, "type", role.Type)})
+	}
+	for _, app := range r.Apps {
+		suspended := ""
+		if app.Suspended {
+			suspended = "true"
+		}
+		rows = append(rows, []string{KindApp, app.Slug, app.Org, "", "", "", details(
+			"repositories", app.Repositories,
+			"suspended", suspended,
+			"scopes", strings.Join(app.Scopes, ","),
+		)})
+	}
+	for _, module := range r.GoModules {
+		rows = append(rows, []string{KindModule, module.New, module.Old, "", "", "", ""})
+	}
+	for _, key := range r.SigningKeys {
+		found := "false"
+		if key.PublicKey != "" {
+			found = "true"
+		}
+		rows = append(rows, []string{KindSigningKey, key.User, "", "", "", "", details(
+			"type", key.Type,
+			"id", key.ID,
+			"commits", strconv.Itoa(key.Commits),
+			"found", found,
+		)})
+	}
+	for _, repo := range r.Inventory {
+		rows = append(rows, []string{KindInventory, repo.Name, "", "", "", "", details(
+			"visibility", repo.Visibility,
+			"languages", languageShares(repo.Languages),
+		)})
+	}
+	for _, license := range r.Licenses {
+		rows = append(rows, []string{KindLicense, license.Repo, "", "", "", "", details(
+			"spdx", license.SPDX,
+			"category", license.Category,
+			"name", license.Name,
+		)})
+	}
+	return rows
+}
+
+// details formats key value pairs as key=value separated by semicolons, leaving out
+// the empty values.
+func details(pairs ...string) string {
+	var out []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			out = append(out, pairs[i]+"="+pairs[i+1])
+		}
+	}
+	return strings.Join(out, "; ")
+}
+
+// languageShares formats the languages of a repository by decreasing share,
+// e.g. Go 82.5%, Shell 17.5%.
+func languageShares(languages map[string]int) string {
+	total := 0
+	names := make([]string, 0, len(languages))
+	for name, bytes := range languages {
+		total += bytes
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(languages[b], languages[a]), strings.Compare(a, b))
+	})
+	shares := make([]string, 0, len(names))
+	for _, name := range names {
+		shares = append(shares, fmt.Sprintf("%s %.1f%%", name, float64(languages[name])*100/float64(total)))
+	}
+	return strings.Join(shares, ", ")
+}
+
+var columns = []string{"kind", "name", "source", "status", "duration", "error", "detail"}
+
+func (r *Report) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(r.rows()); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"columns": func() []string { return columns },
+	"title":   strings.ToUpper,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>github2gitea migration report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+tr.failed, tr.stuck { background: #fdd; }
+tr.skipped { color: #888; }
+</style>
+</head>
+<body>
+<h1>github2gitea migration report</h1>
+{{if not .StartedAt.IsZero}}<p>Started {{.StartedAt.Format "2006-01-02 15:04:05 MST"}}, finished {{.FinishedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+{{end}}
+<table>
+<tr>{{range columns}}<th>{{title .}}</th>{{end}}</tr>
+{{range .Rows}}<tr class="{{index . 3}}">{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,124 @@
+package report
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SignatureNamespace is the namespace of report signatures, verify them with
+//
+//	ssh-keygen -Y verify -f allowed_signers -I <identity> -n github2gitea -s report.json.sig < report.json
+const SignatureNamespace = "github2gitea"
+
+// SignFile writes the SHA-256 checksum of the file at path to path.sha256, in the
+// sha256sum format. When keyPath is set, the file is also signed with that SSH private
+// key into path.sig, in the armored format of ssh-keygen -Y sign. passphrase is only
+// used for encrypted keys.
+func SignFile(path, keyPath string, passphrase []byte) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:]) + "  " + filepath.Base(path) + "\n"
+	if err := os.WriteFile(path+".sha256", []byte(checksum), 0o644); err != nil {
+		return err
+	}
+
+	if keyPath == "" {
+		return nil
+	}
+	signer, err := loadSigner(keyPath, passphrase)
+	if err != nil {
+		return err
+	}
+	signature, err := sshSign(signer, data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+".sig", signature, 0o644)
+}
+
+func loadSigner(keyPath string, passphrase []byte) (ssh.Signer, error) {
+	pemBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(pemBytes)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("signing key %s is encrypted, a passphrase is required", keyPath)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key %s: %w", keyPath, err)
+	}
+	return signer, nil
+}
+
+// sshSign creates an SSHSIG signature of data, see PROTOCOL.sshsig in OpenSSH.
+func sshSign(signer ssh.Signer, data []byte) ([]byte, error) {
+	const hashAlgorithm = "sha512"
+	digest := sha512.Sum512(data)
+
+	var signed []byte
+	signed = append(signed, "SSHSIG"...)
+	signed = appendString(signed, []byte(SignatureNamespace))
+	signed = appendString(signed, nil) // reserved
+	signed = appendString(signed, []byte(hashAlgorithm))
+	signed = appendString(signed, digest[:])
+
+	var (
+		sig *ssh.Signature
+		err error
+	)
+	// ssh-rsa signatures use SHA-1, which ssh-keygen rejects for SSHSIG
+	if algorithmSigner, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		sig, err = algorithmSigner.SignWithAlgorithm(rand.Reader, signed, ssh.SigAlgoRSASHA2512)
+	} else {
+		sig, err = signer.Sign(rand.Reader, signed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var blob []byte
+	blob = append(blob, "SSHSIG"...)
+	blob = binary.BigEndian.AppendUint32(blob, 1) // version
+	blob = appendString(blob, signer.PublicKey().Marshal())
+	blob = appendString(blob, []byte(SignatureNamespace))
+	blob = appendString(blob, nil) // reserved
+	blob = appendString(blob, []byte(hashAlgorithm))
+	blob = appendString(blob, ssh.Marshal(sig))
+
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var armored strings.Builder
+	armored.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		armored.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	armored.WriteString(encoded + "\n")
+	armored.WriteString("-----END SSH SIGNATURE-----\n")
+	return []byte(armored.String()), nil
+}
+
+// appendString appends b as an SSH wire format string.
+func appendString(buf, b []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(b)))
+	return append(buf, b...)
+}
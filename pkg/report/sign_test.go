@@ -0,0 +1,131 @@
+package report
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// readString reads an SSH wire format string off buf.
+func readString(t *testing.T, buf []byte) ([]byte, []byte) {
+	t.Helper()
+	if len(buf) < 4 {
+		t.Fatal("truncated signature")
+	}
+	n := binary.BigEndian.Uint32(buf)
+	if uint32(len(buf)-4) < n {
+		t.Fatal("truncated signature")
+	}
+	return buf[4 : 4+n], buf[4+n:]
+}
+
+func TestSignFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.json")
+	data := []byte(`{"items":[]}`)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SignFile(path, keyPath, nil); err == nil || !strings.Contains(err.Error(), "passphrase is required") {
+		t.Fatalf("SignFile without passphrase = %v", err)
+	}
+	if err := SignFile(path, keyPath, []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256(data)
+	checksum, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := hex.EncodeToString(sum[:]) + "  report.json\n"; string(checksum) != want {
+		t.Errorf("checksum = %q, want %q", checksum, want)
+	}
+
+	armored, err := os.ReadFile(path + ".sig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(string(armored), "-----BEGIN SSH SIGNATURE-----\n"), "-----END SSH SIGNATURE-----\n")
+	blob, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(blob, []byte("SSHSIG")) || binary.BigEndian.Uint32(blob[6:]) != 1 {
+		t.Fatal("missing SSHSIG magic or version")
+	}
+	rest := blob[10:]
+	var publicKey, namespace, hashAlgorithm, signature []byte
+	publicKey, rest = readString(t, rest)
+	namespace, rest = readString(t, rest)
+	_, rest = readString(t, rest) // reserved
+	hashAlgorithm, rest = readString(t, rest)
+	signature, _ = readString(t, rest)
+	if string(namespace) != SignatureNamespace || string(hashAlgorithm) != "sha512" {
+		t.Errorf("namespace %q, hash %q", namespace, hashAlgorithm)
+	}
+
+	pub, err := ssh.ParsePublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(signature, &sig); err != nil {
+		t.Fatal(err)
+	}
+	digest := sha512.Sum512(data)
+	var signed []byte
+	signed = append(signed, "SSHSIG"...)
+	signed = appendString(signed, []byte(SignatureNamespace))
+	signed = appendString(signed, nil)
+	signed = appendString(signed, []byte("sha512"))
+	signed = appendString(signed, digest[:])
+	if err := pub.Verify(signed, &sig); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	if err := pub.Verify(append(signed, '!'), &sig); err == nil {
+		t.Error("signature verifies altered data")
+	}
+}
+
+func TestSignFileChecksumOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, []byte("{}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SignFile(path, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".sha256"); err != nil {
+		t.Errorf("checksum missing: %v", err)
+	}
+	if _, err := os.Stat(path + ".sig"); !os.IsNotExist(err) {
+		t.Errorf("signature written without a key: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Package retry provides an http.RoundTripper retrying transient API errors
+// with exponential backoff and jitter.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the default number of retries after the first attempt.
+	DefaultMaxRetries = 3
+	// DefaultBackoff is the default delay before the first retry.
+	DefaultBackoff = time.Second
+	// maxBackoff caps the delay between two attempts.
+	maxBackoff = time.Minute
+)
+
+// Transport retries requests failing with 5xx responses, network timeouts
+// or connection resets. Requests with a body are only retried when the body
+// can be rewound through Request.GetBody, non-idempotent requests only when
+// the connection was refused.
+type Transport struct {
+	// Base is the underlying transport, http.DefaultTransport when nil.
+	Base http.RoundTripper
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	// Backoff is the delay before the first retry, doubled on every attempt.
+	Backoff time.Duration
+	// Logger logs every retry when set.
+	Logger *slog.Logger
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := base.RoundTrip(req)
+		if attempt >= t.MaxRetries || !t.retryable(req, resp, err) {
+			return resp, err
+		}
+
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		if resp != nil {
+			// drain the body so the connection can be reused
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		delay := t.delay(attempt)
+		if t.Logger != nil {
+			args := []any{
+				"method", req.Method,
+				"url", req.URL.Redacted(),
+				"attempt", attempt + 1,
+				"wait", delay,
+			}
+			if err != nil {
+				args = append(args, "error", err)
+			} else {
+				args = append(args, "status", resp.StatusCode)
+			}
+			t.Logger.Warn("retrying request", args...)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns the exponential backoff for the given attempt with full jitter
+// between half and the whole computed delay.
+func (t *Transport) delay(attempt int) time.Duration {
+	backoff := t.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	d := backoff << attempt
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	return d/2 + rand.N(d/2+1) //nolint:gosec
+}
+
+// retryable reports whether the request failed with a transient error.
+// Non-idempotent requests are only retried when they were never sent, i.e. the
+// connection was refused: a gateway error or reset connection may hide a request
+// the server already started, e.g. a repository migration.
+func (t *Transport) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return true
+		}
+		if !idempotent(req) {
+			return false
+		}
+		if errors.Is(err, syscall.ECONNRESET) {
+			return true
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return true
+		}
+		return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+	}
+
+	if !idempotent(req) {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+		http.StatusInternalServerError:
+		return true
+	default:
+		return false
+	}
+}
+
+// idempotent reports whether the request may be sent twice. Like net/http, a
+// request with an Idempotency-Key or X-Idempotency-Key header, even a nil one
+// that is not sent, is treated as idempotent whatever its method.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	_, ok := req.Header["X-Idempotency-Key"]
+	return ok
+}
@@ -0,0 +1,114 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func response(status int) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}
+}
+
+func TestTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int
+		err      error
+		want     int
+		attempts int
+	}{
+		{"success", http.MethodGet, []int{200}, nil, 200, 1},
+		{"retry server errors", http.MethodGet, []int{502, 503, 200}, nil, 200, 3},
+		{"give up after max retries", http.MethodGet, []int{500, 500, 500, 500, 500}, nil, 500, 3},
+		{"keep client errors", http.MethodGet, []int{404, 200}, nil, 404, 1},
+		{"keep server errors of posts", http.MethodPost, []int{502, 200}, nil, 502, 1},
+		{"retry refused posts", http.MethodPost, nil, syscall.ECONNREFUSED, 0, 3},
+		{"keep reset posts", http.MethodPost, nil, syscall.ECONNRESET, 0, 1},
+		{"retry reset gets", http.MethodGet, nil, syscall.ECONNRESET, 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			transport := &Transport{
+				MaxRetries: 2,
+				Backoff:    time.Millisecond,
+				Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					body, _ := io.ReadAll(req.Body)
+					bodies = append(bodies, string(body))
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return response(tt.statuses[len(bodies)-1]), nil
+				}),
+			}
+			req, _ := http.NewRequest(tt.method, "http://gitea.local/api/v1/repos/migrate", bytes.NewReader([]byte("payload")))
+			resp, err := transport.RoundTrip(req)
+			if len(bodies) != tt.attempts {
+				t.Errorf("%d attempts, want %d", len(bodies), tt.attempts)
+			}
+			// every attempt sends the whole body again
+			for i, body := range bodies {
+				if body != "payload" {
+					t.Errorf("attempt %d sent body %q", i+1, body)
+				}
+			}
+			if tt.err != nil {
+				if err == nil {
+					t.Error("error not returned")
+				}
+				return
+			}
+			if err != nil || resp.StatusCode != tt.want {
+				t.Errorf("RoundTrip = %v, %v, want status %d", resp, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransportCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	transport := &Transport{
+		MaxRetries: 5,
+		Backoff:    time.Hour,
+		Base: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			attempts++
+			cancel()
+			return response(http.StatusServiceUnavailable), nil
+		}),
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://gitea.local/api/v1/version", nil)
+	if _, err := transport.RoundTrip(req); err != context.Canceled {
+		t.Errorf("RoundTrip error = %v, want context.Canceled", err)
+	}
+	if attempts != 1 {
+		t.Errorf("%d attempts after cancel, want 1", attempts)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	transport := &Transport{Backoff: time.Second}
+	for attempt, limit := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		for range 20 {
+			if d := transport.delay(attempt); d < limit/2 || d > limit {
+				t.Errorf("delay(%d) = %v, want between %v and %v", attempt, d, limit/2, limit)
+			}
+		}
+	}
+	if d := transport.delay(40); d > maxBackoff {
+		t.Errorf("delay(40) = %v, over %v", d, maxBackoff)
+	}
+}
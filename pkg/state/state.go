@@ -0,0 +1,276 @@
+// Package state persists what previous migration runs did, so repeated runs
+// can skip work that is already up to date.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Repo is the state of a migrated repository.
+type Repo struct {
+	// Source is the full name of the GitHub repository.
+	Source string `json:"source"`
+	// ID is the GitHub id of the repository, kept across renames.
+	ID int64 `json:"id,omitempty"`
+	// PushedAt is the GitHub pushed_at timestamp at migration time.
+	PushedAt time.Time `json:"pushed_at"`
+	// UpdatedAt is the GitHub updated_at timestamp at migration time.
+	UpdatedAt time.Time `json:"updated_at"`
+	// MigratedAt is the time the repository was last migrated or synced.
+	MigratedAt time.Time `json:"migrated_at"`
+}
+
+// Changed reports whether the GitHub repository was pushed or updated since this state was recorded.
+func (r Repo) Changed(pushedAt, updatedAt time.Time) bool {
+	return pushedAt.After(r.PushedAt) || updatedAt.After(r.UpdatedAt)
+}
+
+// Attachment is an issue attachment uploaded to Gitea, to be linked again instead of
+// uploading the same file for another issue.
+type Attachment struct {
+	// URL is the Gitea download URL of the attachment.
+	URL string `json:"url"`
+	// Repo is the Gitea owner/name of the repository holding the attachment.
+	Repo string `json:"repo"`
+	// Public is set when everyone can read the repository, and so the attachment.
+	Public bool `json:"public,omitempty"`
+}
+
+// Store is a JSON file keyed by Gitea owner/name. A Store without path keeps the state in memory.
+type Store struct {
+	path string
+
+	mu    sync.Mutex
+	Repos map[string]Repo `json:"repos"`
+	// Fingerprints hashes the settings of the teams and repositories as last applied, keyed by kind:owner/name.
+	Fingerprints map[string]string `json:"fingerprints,omitempty"`
+	// LastRun is the time of the last run recording its results.
+	LastRun time.Time `json:"last_run,omitzero"`
+	// Results are the statuses of the last run, keyed by kind:name, to compute the sync digest.
+	Results map[string]string `json:"results,omitempty"`
+	// Attachments are the uploaded issue attachments keyed by the SHA-256 of their content,
+	// and AttachmentLinks the content hashes of the GitHub attachment URLs already downloaded.
+	Attachments     map[string]Attachment `json:"attachments,omitempty"`
+	AttachmentLinks map[string]string     `json:"attachment_links,omitempty"`
+	// PausedAt is the time the repository migrations were paused, zero while they run, so
+	// a restarted run starts paused.
+	PausedAt time.Time `json:"paused_at,omitzero"`
+}
+
+// Open loads the store from path, starting empty when the file does not exist.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, Repos: make(map[string]Repo), Fingerprints: make(map[string]string)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Repos == nil {
+		s.Repos = make(map[string]Repo)
+	}
+	if s.Fingerprints == nil {
+		s.Fingerprints = make(map[string]string)
+	}
+	return s, nil
+}
+
+func key(owner, name string) string {
+	return strings.ToLower(owner + "/" + name)
+}
+
+// Repo returns the recorded state of a repository.
+func (s *Store) Repo(owner, name string) (Repo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo, ok := s.Repos[key(owner, name)]
+	return repo, ok
+}
+
+// SetRepo records the state of a repository and saves the store.
+func (s *Store) SetRepo(owner, name string, repo Repo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Repos[key(owner, name)] = repo
+	return s.save()
+}
+
+// DropRepo forgets a repository, e.g. deleted from Gitea, and saves the store.
+func (s *Store) DropRepo(owner, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Repos, key(owner, name))
+	return s.save()
+}
+
+// OwnerRepos returns the recorded repositories of a Gitea owner keyed by lowercase name.
+func (s *Store) OwnerRepos(owner string) map[string]Repo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prefix := strings.ToLower(owner) + "/"
+	repos := make(map[string]Repo)
+	for k, repo := range s.Repos {
+		if name, ok := strings.CutPrefix(k, prefix); ok {
+			repos[name] = repo
+		}
+	}
+	return repos
+}
+
+// AllRepos returns the recorded repositories keyed by lowercase Gitea owner/name.
+func (s *Store) AllRepos() map[string]Repo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.Repos)
+}
+
+// RenameRepo moves the state of a repository renamed in Gitea to its new name and saves the store.
+func (s *Store) RenameRepo(owner, oldName, newName string, repo Repo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Repos, key(owner, oldName))
+	s.Repos[key(owner, newName)] = repo
+	return s.save()
+}
+
+// Fingerprint returns the recorded fingerprint of a target object, such as a team or repository.
+func (s *Store) Fingerprint(kind, name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fingerprint, ok := s.Fingerprints[kind+":"+strings.ToLower(name)]
+	return fingerprint, ok
+}
+
+// SetFingerprint records the fingerprint of a target object and saves the store.
+func (s *Store) SetFingerprint(kind, name, fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Fingerprints[kind+":"+strings.ToLower(name)] = fingerprint
+	return s.save()
+}
+
+// LastResults returns the statuses recorded by the last run and its time.
+func (s *Store) LastResults() (map[string]string, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Results, s.LastRun
+}
+
+// SetResults records the statuses of a run and saves the store.
+func (s *Store) SetResults(at time.Time, results map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.LastRun = at
+	s.Results = results
+	return s.save()
+}
+
+// PausedSince returns the time the repository migrations were paused, zero when they
+// are not.
+func (s *Store) PausedSince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.PausedAt
+}
+
+// SetPaused records whether the repository migrations are paused and saves the store.
+func (s *Store) SetPaused(paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if paused == !s.PausedAt.IsZero() {
+		return nil
+	}
+	s.PausedAt = time.Time{}
+	if paused {
+		s.PausedAt = time.Now()
+	}
+	return s.save()
+}
+
+// Attachment returns the uploaded attachment with the given content hash.
+func (s *Store) Attachment(hash string) (Attachment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attachment, ok := s.Attachments[hash]
+	return attachment, ok
+}
+
+// AttachmentLink returns the content hash of a GitHub attachment URL already downloaded.
+func (s *Store) AttachmentLink(link string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hash, ok := s.AttachmentLinks[link]
+	return hash, ok
+}
+
+// SetAttachment records the content hash of a GitHub attachment URL and, when
+// attachment has a URL, the uploaded attachment. The store is saved by Save, not
+// for every attachment.
+func (s *Store) SetAttachment(link, hash string, attachment Attachment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.AttachmentLinks == nil {
+		s.AttachmentLinks = make(map[string]string)
+	}
+	s.AttachmentLinks[link] = hash
+	if attachment.URL != "" {
+		if s.Attachments == nil {
+			s.Attachments = make(map[string]Attachment)
+		}
+		s.Attachments[hash] = attachment
+	}
+}
+
+// DropAttachment forgets the uploaded attachment with the given content hash, e.g.
+// deleted with its repository.
+func (s *Store) DropAttachment(hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Attachments, hash)
+}
+
+// Save writes the changes recorded without saving the store.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+// save writes the store atomically through a temporary file.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,145 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRepoChanged(t *testing.T) {
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := Repo{PushedAt: at, UpdatedAt: at}
+	tests := []struct {
+		name      string
+		pushedAt  time.Time
+		updatedAt time.Time
+		want      bool
+	}{
+		{"unchanged", at, at, false},
+		{"pushed", at.Add(time.Second), at, true},
+		{"updated", at, at.Add(time.Minute), true},
+		{"older", at.Add(-time.Hour), at.Add(-time.Hour), false},
+	}
+	for _, tt := range tests {
+		if got := repo.Changed(tt.pushedAt, tt.updatedAt); got != tt.want {
+			t.Errorf("%s: Changed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("store written before any change: %v", err)
+	}
+
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := Repo{Source: "acme/api", ID: 42, PushedAt: at, UpdatedAt: at, MigratedAt: at}
+	if err := store.SetRepo("Acme", "API", repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetRepo("acme", "web", Repo{Source: "acme/web"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetRepo("other", "api", Repo{Source: "other/api"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetFingerprint("team", "acme/platform", "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new run reads what the last one saved, keyed case insensitively
+	store, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := store.Repo("acme", "api")
+	if !ok || got != repo {
+		t.Errorf("Repo = %+v, %v, want %+v", got, ok, repo)
+	}
+	if owned := store.OwnerRepos("ACME"); len(owned) != 2 || owned["web"].Source != "acme/web" {
+		t.Errorf("OwnerRepos = %v", owned)
+	}
+	if fingerprint, ok := store.Fingerprint("team", "acme/platform"); !ok || fingerprint != "abc" {
+		t.Errorf("Fingerprint = %q, %v", fingerprint, ok)
+	}
+
+	if err := store.RenameRepo("acme", "api", "api-v2", repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DropRepo("acme", "web"); err != nil {
+		t.Fatal(err)
+	}
+	store, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Repo("acme", "api"); ok {
+		t.Error("renamed repo still recorded under its old name")
+	}
+	if got, _ := store.Repo("acme", "api-v2"); got.ID != 42 {
+		t.Errorf("renamed repo = %+v", got)
+	}
+	if _, ok := store.Repo("acme", "web"); ok {
+		t.Error("dropped repo still recorded")
+	}
+	if all := store.AllRepos(); len(all) != 2 {
+		t.Errorf("AllRepos = %v", all)
+	}
+
+	// attachments are only written by Save
+	store.SetAttachment("https://github.com/a.png", "hash", Attachment{URL: "https://gitea/a.png", Repo: "acme/api-v2"})
+	if reopened, _ := Open(path); len(reopened.Attachments) != 0 {
+		t.Error("attachment saved before Save")
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	store, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash, ok := store.AttachmentLink("https://github.com/a.png"); !ok || hash != "hash" {
+		t.Errorf("AttachmentLink = %q, %v", hash, ok)
+	}
+	if attachment, ok := store.Attachment("hash"); !ok || attachment.Repo != "acme/api-v2" {
+		t.Errorf("Attachment = %+v, %v", attachment, ok)
+	}
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d files next to the store, want 1", len(entries))
+	}
+}
+
+func TestStoreInMemory(t *testing.T) {
+	store, err := Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetRepo("acme", "api", Repo{Source: "acme/api"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Repo("acme", "api"); !ok {
+		t.Error("repo not kept in memory")
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("Open accepted an invalid state file")
+	}
+}
@@ -0,0 +1,229 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// exportInterval is the delay between two exports of the queued spans.
+	exportInterval = 5 * time.Second
+	// maxQueueSize is the number of queued spans triggering an early export.
+	maxQueueSize = 512
+)
+
+// Config configures the OTLP exporter.
+type Config struct {
+	// Endpoint is the OTLP/HTTP base URL, e.g. http://localhost:4318.
+	Endpoint string
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string
+	// Version is reported as the service.version resource attribute.
+	Version string
+	// Headers are sent with every export, e.g. the authentication of the collector.
+	Headers map[string]string
+	// Logger logs export failures when set.
+	Logger *slog.Logger
+}
+
+// Provider queues finished spans and exports them in batches.
+type Provider struct {
+	url     string
+	headers map[string]string
+	service string
+	version string
+	logger  *slog.Logger
+	client  *http.Client
+
+	mu    sync.Mutex
+	queue []*Span
+
+	flush chan struct{}
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// ParseHeaders parses the headers of the OTEL_EXPORTER_OTLP_HEADERS environment
+// variable, comma separated key=value pairs with URL encoded values.
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for pair := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid otlp header %q, expected key=value", strings.TrimSpace(pair))
+		}
+		value, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid otlp header %s: %w", key, err)
+		}
+		headers[key] = value
+	}
+	return headers, nil
+}
+
+// NewProvider creates a provider exporting spans to the OTLP endpoint in the background.
+func NewProvider(cfg Config) *Provider {
+	p := &Provider{
+		url:     strings.TrimRight(cfg.Endpoint, "/") + "/v1/traces",
+		headers: cfg.Headers,
+		service: cfg.ServiceName,
+		version: cfg.Version,
+		logger:  cfg.Logger,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		flush:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+func (p *Provider) run() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		case <-p.flush:
+		}
+		p.export(context.Background())
+	}
+}
+
+func (p *Provider) enqueue(s *Span) {
+	p.mu.Lock()
+	p.queue = append(p.queue, s)
+	full := len(p.queue) >= maxQueueSize
+	p.mu.Unlock()
+	if full {
+		select {
+		case p.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Shutdown stops the background export and sends the remaining spans.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	close(p.done)
+	p.wg.Wait()
+	return p.export(ctx)
+}
+
+func (p *Provider) export(ctx context.Context) error {
+	p.mu.Lock()
+	spans := p.queue
+	p.queue = nil
+	p.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	err := p.send(ctx, spans)
+	if err != nil && p.logger != nil {
+		p.logger.Warn("failed to export traces", "endpoint", p.url, "spans", len(spans), "error", err)
+	}
+	return err
+}
+
+func (p *Provider) send(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(p.encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, value := range p.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// encode builds an OTLP ExportTraceServiceRequest in its JSON form.
+func (p *Provider) encode(spans []*Span) map[string]any {
+	items := make([]map[string]any, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		item := map[string]any{
+			"traceId":           hex.EncodeToString(s.traceID[:]),
+			"spanId":            hex.EncodeToString(s.spanID[:]),
+			"name":              s.name,
+			"kind":              s.kind,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        encodeAttrs(s.attrs),
+			"status":            map[string]any{"code": s.statusCode, "message": s.statusMsg},
+		}
+		if s.parentID != [8]byte{} {
+			item["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+		}
+		s.mu.Unlock()
+		items = append(items, item)
+	}
+
+	return map[string]any{
+		"resourceSpans": []any{
+			map[string]any{
+				"resource": map[string]any{
+					"attributes": encodeAttrs([]Attr{
+						String("service.name", p.service),
+						String("service.version", p.version),
+					}),
+				},
+				"scopeSpans": []any{
+					map[string]any{
+						"scope": map[string]any{"name": "github.com/appleboy/github2gitea"},
+						"spans": items,
+					},
+				},
+			},
+		},
+	}
+}
+
+func encodeAttrs(attrs []Attr) []any {
+	out := make([]any, 0, len(attrs))
+	for _, attr := range attrs {
+		var value map[string]any
+		switch v := attr.Value.(type) {
+		case string:
+			value = map[string]any{"stringValue": v}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		out = append(out, map[string]any{"key": attr.Key, "value": value})
+	}
+	return out
+}
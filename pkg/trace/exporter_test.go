@@ -0,0 +1,97 @@
+package trace
+
+import (
+	"context"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func ok(*http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"", map[string]string{}, false},
+		{"Authorization=Bearer%20token, x-tenant = acme ,", map[string]string{"Authorization": "Bearer token", "x-tenant": "acme"}, false},
+		{"api-key=a=b", map[string]string{"api-key": "a=b"}, false},
+		{"missing", nil, true},
+		{"key=%zz", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHeaders(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseHeaders(%q) error = %v", tt.value, err)
+			continue
+		}
+		if !tt.wantErr && !maps.Equal(got, tt.want) {
+			t.Errorf("ParseHeaders(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	var exported *http.Request
+	p := NewProvider(Config{
+		Endpoint: "http://collector:4318/",
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	})
+	p.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		exported = req
+		return ok(req)
+	})
+	SetProvider(p)
+	defer SetProvider(nil)
+
+	var traceparent string
+	client := &http.Client{Transport: &Transport{
+		System: "gitea",
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			traceparent = req.Header.Get("traceparent")
+			return ok(req)
+		}),
+	}}
+	ctx, span := Start(context.Background(), "run")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://gitea.local/api/v1/version", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	span.End()
+
+	// version-traceid-spanid-flags, in the trace of the parent span
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[2]) != 16 || parts[3] != "01" {
+		t.Fatalf("traceparent = %q", traceparent)
+	}
+	if want := span.traceparent(); parts[1] != strings.Split(want, "-")[1] || traceparent == want {
+		t.Errorf("traceparent %q is not a child of %q", traceparent, want)
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if exported == nil {
+		t.Fatal("no spans exported")
+	}
+	if exported.URL.String() != "http://collector:4318/v1/traces" {
+		t.Errorf("exported to %s", exported.URL)
+	}
+	if got := exported.Header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
@@ -0,0 +1,66 @@
+package trace
+
+import (
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// SlowTransport logs every HTTP request taking longer than Threshold, to find the
+// endpoints of a server that slow down a migration.
+type SlowTransport struct {
+	// Base is the underlying transport, http.DefaultTransport when nil.
+	Base http.RoundTripper
+	// System names the remote API, e.g. github or gitea.
+	System string
+	// Threshold is the duration above which a request is logged, disabled when zero.
+	Threshold time.Duration
+	Logger    *slog.Logger
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *SlowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if t.Threshold <= 0 || t.Logger == nil {
+		return base.RoundTrip(req)
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	elapsed := time.Since(start)
+	if elapsed < t.Threshold {
+		return resp, err
+	}
+
+	args := []any{
+		"system", t.System,
+		"method", req.Method,
+		"url", req.URL.Redacted(),
+		"duration", elapsed.Round(time.Millisecond),
+		"threshold", t.Threshold,
+	}
+	if req.Header.Get("Sudo") != "" {
+		args = append(args, "sudo", req.Header.Get("Sudo"))
+	}
+	if err != nil {
+		args = append(args, "error", err)
+	} else {
+		args = append(args, "status", resp.StatusCode, "content_length", resp.ContentLength)
+		// request IDs to look the call up in the server logs
+		for _, header := range []string{"X-GitHub-Request-Id", "X-Request-Id"} {
+			if id := resp.Header.Get(header); id != "" {
+				args = append(args, "request_id", id)
+				break
+			}
+		}
+	}
+	if span := FromContext(req.Context()); span != nil && span.provider != nil {
+		args = append(args, "span", span.name, "trace_id", hex.EncodeToString(span.traceID[:]))
+	}
+	t.Logger.Warn("slow api call", args...)
+	return resp, err
+}
@@ -0,0 +1,152 @@
+// Package trace records OpenTelemetry compatible spans and exports them
+// to an OTLP/HTTP endpoint using the JSON encoding.
+//
+// It is not the OpenTelemetry SDK and only implements the subset the migration
+// needs: OTLP/HTTP with JSON, no gRPC, protobuf, compression, headers or client
+// certificates; spans with attributes and status, no events, links or sampling;
+// service.name and service.version as the only resource attributes. A batch the
+// endpoint refuses is logged and dropped without retry.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/appleboy/github2gitea/pkg/redact"
+)
+
+// Span kinds, as defined by the OTLP protocol.
+const (
+	KindInternal = 1
+	KindClient   = 3
+)
+
+// Span status codes, as defined by the OTLP protocol.
+const (
+	statusOK    = 1
+	statusError = 2
+)
+
+// Attr is a span attribute.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attr { return Attr{Key: key, Value: value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attr { return Attr{Key: key, Value: int64(value)} }
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attr { return Attr{Key: key, Value: value} }
+
+// Span is a timed operation. A span started without a provider records nothing.
+type Span struct {
+	provider *Provider
+
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attrs      []Attr
+	statusCode int
+	statusMsg  string
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil || s.provider == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// RecordError marks the span as failed when err is not nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || s.provider == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statusCode = statusError
+	s.statusMsg = redact.String(err.Error())
+}
+
+// End finishes the span and queues it for export.
+func (s *Span) End() {
+	if s == nil || s.provider == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	if s.statusCode == 0 {
+		s.statusCode = statusOK
+	}
+	s.mu.Unlock()
+	s.provider.enqueue(s)
+}
+
+// traceparent returns the W3C trace context header value of the span.
+func (s *Span) traceparent() string {
+	return "00-" + hex.EncodeToString(s.traceID[:]) + "-" + hex.EncodeToString(s.spanID[:]) + "-01"
+}
+
+type spanKey struct{}
+
+// FromContext returns the current span of the context, or nil.
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+var global atomic.Pointer[Provider]
+
+// SetProvider installs the provider used by Start. A nil provider disables tracing.
+func SetProvider(p *Provider) {
+	global.Store(p)
+}
+
+// Start starts an internal span as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return start(ctx, name, KindInternal, attrs...)
+}
+
+func start(ctx context.Context, name string, kind int, attrs ...Attr) (context.Context, *Span) {
+	p := global.Load()
+	if p == nil {
+		return ctx, &Span{}
+	}
+
+	span := &Span{
+		provider: p,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+		attrs:    attrs,
+	}
+	if parent := FromContext(ctx); parent != nil && parent.provider != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+	}
+	_, _ = rand.Read(span.spanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
@@ -0,0 +1,55 @@
+package trace
+
+import (
+	"net/http"
+)
+
+// Transport records a client span for every HTTP request and propagates
+// the trace context through the traceparent header.
+type Transport struct {
+	// Base is the underlying transport, http.DefaultTransport when nil.
+	Base http.RoundTripper
+	// System names the remote API, e.g. github or gitea.
+	System string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if global.Load() == nil {
+		return base.RoundTrip(req)
+	}
+
+	ctx, span := start(req.Context(), t.System+" "+req.Method+" "+req.URL.Path, KindClient,
+		String("rpc.system", t.System),
+		String("http.request.method", req.Method),
+		String("server.address", req.URL.Host),
+		String("url.full", req.URL.Redacted()),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	req.Header.Set("traceparent", span.traceparent())
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes(Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.RecordError(&httpStatusError{resp.Status})
+	}
+	return resp, nil
+}
+
+type httpStatusError struct {
+	status string
+}
+
+func (e *httpStatusError) Error() string {
+	return e.status
+}